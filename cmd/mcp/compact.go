@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"agent-memory/internal/application/service"
+	"agent-memory/internal/domain/task"
+)
+
+// runCompact runs the compact subcommand, which rolls old artifacts into
+// summaries like the compact_artifacts tool. Without an MCP client to sample
+// from, summaries are always extractive.
+//
+//	agent-memory [flags] compact [-project id [-task id]] [-older-than 168h] [-types file_read,search] [-dry-run]
+func runCompact(ctx context.Context, taskSvc *service.TaskService, args []string) error {
+	flags := flag.NewFlagSet("compact", flag.ContinueOnError)
+	projectID := flags.String("project", "", "Only tasks in this project (default: all projects)")
+	taskID := flags.String("task", "", "Only this task (requires -project)")
+	olderThan := flags.Duration("older-than", 7*24*time.Hour, "Only artifacts last written longer ago than this")
+	types := flags.String("types", "", "Comma-separated artifact types to compact (default: file_read,file_list,search)")
+	dryRun := flags.Bool("dry-run", false, "Print what would be compacted without saving anything")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	req := service.CompactRequest{
+		ProjectID: *projectID,
+		TaskID:    *taskID,
+		OlderThan: *olderThan,
+		DryRun:    *dryRun,
+	}
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			req.Types = append(req.Types, task.ArtifactType(t))
+		}
+	}
+
+	compactions, err := taskSvc.Compact(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	compacted := 0
+	for _, c := range compactions {
+		compacted += len(c.Artifacts)
+		if *dryRun {
+			fmt.Printf("%s: would compact %d artifacts into\n\n%s\n", c.Task.Ref(), len(c.Artifacts), c.Summary.Content)
+		} else {
+			fmt.Printf("%s: compacted %d artifacts into summary %s\n", c.Task.Ref(), len(c.Artifacts), c.Summary.ID)
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("Would compact %d artifacts in %d tasks (dry run, nothing saved)\n", compacted, len(compactions))
+	} else {
+		fmt.Printf("Compacted %d artifacts in %d tasks\n", compacted, len(compactions))
+	}
+	return nil
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
 	"agent-memory/internal/application/service"
+	"agent-memory/internal/domain/task"
 	"agent-memory/internal/infrastructure/config"
 	"agent-memory/internal/infrastructure/storage/filesystem"
+	"agent-memory/internal/infrastructure/storage/readonly"
+	"agent-memory/internal/infrastructure/storage/sqlite"
 	mcptransport "agent-memory/internal/transport/mcp"
 )
 
@@ -17,6 +24,12 @@ func main() {
 	configPath := flag.String("config", "", "Path to config file (default: auto-detect)")
 	tasksPath := flag.String("tasks-path", "", "Path to tasks directory (overrides config)")
 	logLevel := flag.String("log-level", "", "Log level: debug, info, warn, error (overrides config)")
+	storage := flag.String("storage", "", "Storage backend: filesystem, sqlite (overrides config)")
+	reindex := flag.Bool("reindex", false, "Rebuild the artifact search index and exit")
+	transport := flag.String("transport", "", "Transport: stdio, sse, http (overrides config)")
+	listen := flag.String("listen", "", "Address the sse and http transports listen on (overrides config)")
+	basePath := flag.String("base-path", "", "URL path of the sse and http endpoints (overrides config)")
+	readOnly := flag.Bool("read-only", false, "Serve the store without the tools that change it (overrides config)")
 	flag.Parse()
 
 	// Load configuration
@@ -44,6 +57,21 @@ func main() {
 	if *logLevel != "" {
 		cfg.LogLevel = *logLevel
 	}
+	if *storage != "" {
+		cfg.Storage.Backend = *storage
+	}
+	if *transport != "" {
+		cfg.Server.Transport = *transport
+	}
+	if *listen != "" {
+		cfg.Server.Listen = *listen
+	}
+	if *basePath != "" {
+		cfg.Server.BasePath = *basePath
+	}
+	if *readOnly {
+		cfg.Server.ReadOnly = true
+	}
 
 	// Setup logger
 	var level slog.Level
@@ -70,30 +98,138 @@ func main() {
 	}
 
 	// Create repository
-	repo, err := filesystem.NewRepository(path)
+	repo, err := openRepository(cfg.Storage.Backend, path, cfg.Server.ReadOnly, logger)
 	if err != nil {
-		logger.Error("failed to create repository", "path", path, "error", err)
+		logger.Error("failed to create repository", "backend", cfg.Storage.Backend, "path", path, "error", err)
 		os.Exit(1)
 	}
 	defer repo.Close()
 
-	logger.Info("using filesystem storage", "path", path)
-
 	// Create services
 	taskSvc := service.NewTaskService(repo, logger)
 	workspaceSvc := service.NewWorkspaceService(repo, logger)
 
+	if *reindex {
+		n, err := taskSvc.RebuildSearchIndex(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to rebuild search index: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Search index rebuilt with %d artifacts and memories\n", n)
+		return
+	}
+
+	if flag.Arg(0) == "compact" {
+		if err := runCompact(context.Background(), taskSvc, flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to compact artifacts: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create and run MCP server
 	server := mcptransport.NewServer(taskSvc, workspaceSvc, logger)
+	if err := server.SetTokens(authTokens(cfg.Server.Auth)); err != nil {
+		logger.Error("invalid auth config", "error", err)
+		fmt.Fprintf(os.Stderr, "Invalid auth config: %v\n", err)
+		os.Exit(1)
+	}
 
 	logger.Info("starting agent-memory MCP server",
 		"version", cfg.Server.Version,
 		"name", cfg.Server.Name,
+		"transport", cfg.Server.Transport,
+		"read_only", cfg.Server.ReadOnly,
 	)
 
-	if err := server.ServeStdio(); err != nil {
+	if cfg.Server.Transport == mcptransport.TransportStdio || cfg.Server.Transport == "" {
+		err = server.ServeStdio()
+	} else {
+		if len(cfg.Server.Auth.Tokens) == 0 {
+			logger.Warn("no auth tokens configured; every client has full access", "address", cfg.Server.Listen)
+		}
+		// One daemon for several clients; stops gracefully on SIGTERM or SIGINT
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		err = server.ListenAndServe(ctx, cfg.Server.Transport, cfg.Server.Listen, cfg.Server.BasePath)
+		stop()
+	}
+	if err != nil {
 		logger.Error("server error", "error", err)
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// authTokens converts the configured tokens for the MCP server.
+func authTokens(cfg config.AuthConfig) []mcptransport.Token {
+	tokens := make([]mcptransport.Token, 0, len(cfg.Tokens))
+	for _, t := range cfg.Tokens {
+		scopes := make([]mcptransport.Scope, len(t.Scopes))
+		for i, scope := range t.Scopes {
+			scopes[i] = mcptransport.Scope(scope)
+		}
+		tokens = append(tokens, mcptransport.Token{
+			Name:     t.Name,
+			Secret:   t.Token,
+			Scopes:   scopes,
+			Projects: t.Projects,
+		})
+	}
+	return tokens
+}
+
+// openRepository creates the task.Repository for the configured storage
+// backend, wrapped to reject every change when readOnly is set.
+func openRepository(backend, path string, readOnly bool, logger *slog.Logger) (task.Repository, error) {
+	repo, err := openBackend(backend, path, readOnly, logger)
+	if err != nil || !readOnly {
+		return repo, err
+	}
+	return readonly.NewRepository(repo), nil
+}
+
+// openBackend opens the configured storage backend. A read-only backend is
+// opened without writing anything to the store: no directories are created,
+// the filesystem search index stays in memory and nothing is migrated.
+func openBackend(backend, path string, readOnly bool, logger *slog.Logger) (task.Repository, error) {
+	switch backend {
+	case config.StorageBackendFilesystem, "":
+		if readOnly {
+			repo, err := filesystem.OpenReadOnly(path)
+			if err != nil {
+				return nil, err
+			}
+			logger.Info("using filesystem storage", "path", path)
+			return repo, nil
+		}
+
+		repo, err := filesystem.NewRepository(path)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("using filesystem storage", "path", path)
+
+		// Rewrite artifacts created before frontmatter was real YAML
+		if n, err := repo.MigrateArtifactFrontmatter(context.Background()); err != nil {
+			logger.Warn("failed to migrate artifact frontmatter", "error", err)
+		} else if n > 0 {
+			logger.Info("migrated artifact frontmatter", "files", n)
+		}
+		return repo, nil
+	case config.StorageBackendSQLite:
+		dbPath := filepath.Join(path, sqlite.DatabaseFile)
+		open := sqlite.NewRepository
+		if readOnly {
+			open = sqlite.OpenReadOnly
+		}
+		repo, err := open(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("using sqlite storage", "path", dbPath)
+		return repo, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (want %s or %s)",
+			backend, config.StorageBackendFilesystem, config.StorageBackendSQLite)
+	}
+}
@@ -184,12 +184,12 @@ function setupIPC() {
             const frontmatter = frontmatterMatch[1]
             const body = frontmatterMatch[2]
 
-            // Simple YAML parsing
+            // Simple YAML parsing (top-level scalars only, quotes stripped)
             const metadata: Record<string, string> = {}
             frontmatter.split('\n').forEach(line => {
               const [key, ...valueParts] = line.split(':')
               if (key && valueParts.length) {
-                metadata[key.trim()] = valueParts.join(':').trim()
+                metadata[key.trim()] = valueParts.join(':').trim().replace(/^(["'])(.*)\1$/, '$2')
               }
             })
 
@@ -1,5 +1,5 @@
 import { useEffect, useState } from 'react'
-import { ArrowLeft, RefreshCw, FileText, Code, Lightbulb, MessageSquare, Link, FileSearch, FolderSearch, Package } from 'lucide-react'
+import { ArrowLeft, RefreshCw, FileText, Code, Lightbulb, MessageSquare, Link, FileSearch, FolderSearch, Package, Layers } from 'lucide-react'
 import { Button } from '@/components/ui/button'
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
 import { Badge } from '@/components/ui/badge'
@@ -20,6 +20,7 @@ const typeConfig: Record<string, { icon: React.ElementType; label: string }> = {
   file_read: { icon: FileSearch, label: 'File Read' },
   file_list: { icon: FolderSearch, label: 'File List' },
   search: { icon: FileSearch, label: 'Search' },
+  summary: { icon: Layers, label: 'Summary' },
   artifact: { icon: Package, label: 'Artifact' },
 }
 
@@ -64,7 +64,7 @@ export function Tasks({ project, onBack, onSelectTask }: TasksProps) {
       ) : (
         <div className="grid gap-3">
           {tasks.map((task) => {
-            const status = statusConfig[task.status] || statusConfig.open
+            const status = statusConfig[task.status as keyof typeof statusConfig] || { ...statusConfig.open, label: task.status }
             const StatusIcon = status.icon
 
             return (
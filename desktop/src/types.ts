@@ -3,29 +3,59 @@ export interface Project {
   name: string
   description: string
   workspacePath: string
+  workflow?: Workflow
   metadata: Record<string, string>
   createdAt: string
   updatedAt: string
 }
 
+export interface Workflow {
+  statuses: { name: string; transitions?: string[]; terminal?: boolean }[]
+}
+
+export interface TaskRef {
+  project_id: string
+  task_id: string
+}
+
 export interface Task {
   id: string
   projectId: string
   name: string
   description: string
-  status: 'open' | 'in_progress' | 'completed' | 'archived'
+  status: string // 'open' | 'in_progress' | 'completed' | 'archived', or a status of the project workflow
+  parent_id?: string // Parent task in the same project, as stored in task.json
+  priority?: string // 'p0' (most urgent) to 'p3'; absent means 'p2'
+  due_date?: string // RFC 3339
+  tags?: string[]
+  blocked_by?: TaskRef[]
+  blocks?: TaskRef[]
   workspacePath: string
   metadata: Record<string, string>
   createdAt: string
   updatedAt: string
 }
 
+export interface ArtifactRef {
+  project_id: string
+  task_id: string
+  artifact_id?: string // Absent when the link points at a task
+}
+
+export interface ArtifactLink extends ArtifactRef {
+  relation: 'supersedes' | 'implements' | 'relates_to' | 'derived_from'
+}
+
 export interface Artifact {
   id: string
   projectId: string
   taskId: string
   type: string
   content: string
+  tags?: string[]
+  version?: number // Absent for artifacts that were never updated
+  links?: ArtifactLink[]
+  superseded_by?: ArtifactRef[]
   createdAt: string
 }
 
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+// ClaimRequest identifies a task and the agent claiming, renewing or
+// releasing it.
+type ClaimRequest struct {
+	ProjectID string
+	TaskID    string
+	Owner     string        // Identity of the agent, unique among those sharing the store
+	Lease     time.Duration // How long the claim holds without renewal (0 = task.DefaultLease); unused by ReleaseTask
+}
+
+// ClaimTask records req.Owner as working on the task until the lease runs
+// out. It fails with task.ErrTaskClaimed while another agent's claim holds.
+// The claim is stored with the task and written like any task update, so it
+// outlives the server and is seen by every process sharing the store.
+func (s *TaskService) ClaimTask(ctx context.Context, req ClaimRequest) (*task.Task, error) {
+	return s.updateClaim(ctx, req, "task claimed", func(t *task.Task, now time.Time) (bool, error) {
+		return true, t.ClaimFor(req.Owner, now, req.Lease)
+	})
+}
+
+// RenewClaim extends req.Owner's claim on the task by the lease.
+func (s *TaskService) RenewClaim(ctx context.Context, req ClaimRequest) (*task.Task, error) {
+	return s.updateClaim(ctx, req, "claim renewed", func(t *task.Task, now time.Time) (bool, error) {
+		return true, t.RenewClaim(req.Owner, now, req.Lease)
+	})
+}
+
+// ReleaseTask removes req.Owner's claim on the task, making it available
+// again. Releasing a task without a claim is a no-op.
+func (s *TaskService) ReleaseTask(ctx context.Context, req ClaimRequest) (*task.Task, error) {
+	return s.updateClaim(ctx, req, "task released", func(t *task.Task, now time.Time) (bool, error) {
+		return t.ReleaseClaim(req.Owner, now)
+	})
+}
+
+// updateClaim applies change to the stored task, writes it if it changed
+// and logs done. Another process may claim the task between the read and
+// the write; the revision check of UpdateTask catches that, and the change
+// is then applied again to what that process wrote.
+func (s *TaskService) updateClaim(ctx context.Context, req ClaimRequest, done string, change func(*task.Task, time.Time) (bool, error)) (*task.Task, error) {
+	projectID := task.NewProjectID(req.ProjectID)
+	taskID := task.NewTaskID(req.TaskID)
+
+	t, err := retryConflicts(nil, func() (*task.Task, error) {
+		t, err := s.repo.GetTask(ctx, projectID, taskID)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := change(t, time.Now().UTC())
+		if err != nil || !changed {
+			return t, err
+		}
+
+		if err := s.repo.UpdateTask(ctx, t); err != nil {
+			if errors.Is(err, task.ErrConflict) {
+				return nil, err
+			}
+			s.logger.Error("failed to update claim", "project_id", projectID, "task_id", taskID, "owner", req.Owner, "error", err)
+			return nil, fmt.Errorf("updating task: %w", err)
+		}
+		s.events.Publish(Event{Kind: EventTaskUpdated, ProjectID: projectID, TaskID: taskID})
+		return t, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(done, "project_id", projectID, "task_id", taskID, "owner", req.Owner)
+	return t, nil
+}
+
+// unclaimed returns the tasks without an active claim at now.
+func unclaimed(tasks []*task.Task, now time.Time) []*task.Task {
+	var available []*task.Task
+	for _, t := range tasks {
+		if t.ActiveClaim(now) == nil {
+			available = append(available, t)
+		}
+	}
+	return available
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/storage/filesystem"
+)
+
+// openClaimService opens a service on the store in dir, like another
+// process sharing it would.
+func openClaimService(t *testing.T, dir string) *TaskService {
+	t.Helper()
+
+	repo, err := filesystem.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewTaskService(repo, logger)
+}
+
+func TestTaskService_ClaimTask(t *testing.T) {
+	dir := t.TempDir()
+	svc := openClaimService(t, dir)
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "billing"})
+
+	claimed, err := svc.ClaimTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-a", Lease: time.Hour})
+	if err != nil {
+		t.Fatalf("ClaimTask() error = %v", err)
+	}
+	if claimed.Claim == nil || claimed.Claim.Owner != "agent-a" {
+		t.Fatalf("ClaimTask().Claim = %+v, want agent-a", claimed.Claim)
+	}
+
+	// Another process, or the same one after a restart, sees the claim
+	other := openClaimService(t, dir)
+	if _, err := other.ClaimTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-b"}); !errors.Is(err, task.ErrTaskClaimed) {
+		t.Errorf("ClaimTask() by agent-b error = %v, want ErrTaskClaimed", err)
+	}
+
+	// Available views leave the claimed task out
+	available, err := other.ListTasks(ctx, ListTasksRequest{ProjectID: "backend", Available: true})
+	if err != nil {
+		t.Fatalf("ListTasks() error = %v", err)
+	}
+	if available.Total != 1 || available.Items[0].ID != "billing" {
+		t.Errorf("ListTasks(available) = %d tasks, want only billing", available.Total)
+	}
+	if all, _ := other.ListTasks(ctx, ListTasksRequest{ProjectID: "backend"}); all.Total != 2 {
+		t.Errorf("ListTasks() = %d tasks, want 2", all.Total)
+	}
+	if available, _ := other.ListAllTasks(ctx, ListAllTasksRequest{Available: true}); available.Total != 1 {
+		t.Errorf("ListAllTasks(available) = %d tasks, want 1", available.Total)
+	}
+	if ready, _ := other.ListReadyTasks(ctx, ListReadyTasksRequest{ProjectID: "backend"}); ready.Total != 1 {
+		t.Errorf("ListReadyTasks() = %d tasks, want 1", ready.Total)
+	}
+	if suggestions, _ := other.SuggestNextTask(ctx, SuggestNextTaskRequest{ProjectID: "backend"}); len(suggestions) != 1 || suggestions[0].Task.ID != "billing" {
+		t.Errorf("SuggestNextTask() = %d suggestions, want only billing", len(suggestions))
+	}
+
+	// The owner renews and releases
+	renewed, err := other.RenewClaim(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-a", Lease: 2 * time.Hour})
+	if err != nil {
+		t.Fatalf("RenewClaim() error = %v", err)
+	}
+	if !renewed.Claim.ExpiresAt.After(claimed.Claim.ExpiresAt) || renewed.Claim.RenewedAt == nil {
+		t.Errorf("RenewClaim().Claim = %+v, want a later expiry than %v", renewed.Claim, claimed.Claim.ExpiresAt)
+	}
+	if _, err := svc.ReleaseTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-b"}); !errors.Is(err, task.ErrTaskClaimed) {
+		t.Errorf("ReleaseTask() by agent-b error = %v, want ErrTaskClaimed", err)
+	}
+	released, err := svc.ReleaseTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-a"})
+	if err != nil || released.Claim != nil {
+		t.Fatalf("ReleaseTask() = %+v, %v, want no claim", released.Claim, err)
+	}
+	if _, err := svc.ReleaseTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-a"}); err != nil {
+		t.Errorf("ReleaseTask() again error = %v, want a no-op", err)
+	}
+	if _, err := svc.RenewClaim(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-a"}); !errors.Is(err, task.ErrNotClaimed) {
+		t.Errorf("RenewClaim() after release error = %v, want ErrNotClaimed", err)
+	}
+
+	if _, err := svc.ClaimTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "missing", Owner: "agent-a"}); err != task.ErrTaskNotFound {
+		t.Errorf("ClaimTask() missing error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestTaskService_ClaimTask_Expired(t *testing.T) {
+	svc := openClaimService(t, t.TempDir())
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	created, _ := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+
+	// agent-a went away an hour ago without releasing the task
+	past := time.Now().UTC().Add(-2 * time.Hour)
+	created.Claim = &task.Claim{Owner: "agent-a", ClaimedAt: past, ExpiresAt: past.Add(time.Hour)}
+	if err := svc.repo.UpdateTask(ctx, created); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+
+	if available, _ := svc.ListTasks(ctx, ListTasksRequest{ProjectID: "backend", Available: true}); available.Total != 1 {
+		t.Errorf("ListTasks(available) = %d tasks, want the expired claim's task", available.Total)
+	}
+
+	claimed, err := svc.ClaimTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-b"})
+	if err != nil {
+		t.Fatalf("ClaimTask() of an expired claim error = %v", err)
+	}
+	if claimed.Claim.Owner != "agent-b" {
+		t.Errorf("ClaimTask().Claim.Owner = %q, want agent-b", claimed.Claim.Owner)
+	}
+	if _, err := svc.RenewClaim(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: "agent-a"}); !errors.Is(err, task.ErrTaskClaimed) {
+		t.Errorf("RenewClaim() by the previous owner error = %v, want ErrTaskClaimed", err)
+	}
+}
+
+func TestTaskService_ClaimTask_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	svc := openClaimService(t, dir)
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+
+	// Agents with stores of their own race for the same task
+	const agents = 8
+	services := make([]*TaskService, agents)
+	for i := range services {
+		services[i] = openClaimService(t, dir)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	for i, agent := range services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			owner := fmt.Sprintf("agent-%d", i)
+			_, err := agent.ClaimTask(ctx, ClaimRequest{ProjectID: "backend", TaskID: "auth", Owner: owner})
+			switch {
+			case err == nil:
+				mu.Lock()
+				winners = append(winners, owner)
+				mu.Unlock()
+			case !errors.Is(err, task.ErrTaskClaimed) && !errors.Is(err, task.ErrConflict):
+				t.Errorf("ClaimTask(%s) error = %v", owner, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("ClaimTask() succeeded for %v, want exactly one agent", winners)
+	}
+	stored, err := svc.GetTask(ctx, "backend", "auth")
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	if stored.Claim == nil || stored.Claim.Owner != winners[0] {
+		t.Errorf("stored claim = %+v, want %s", stored.Claim, winners[0])
+	}
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+const (
+	defaultCompactAge   = 7 * 24 * time.Hour
+	minCompactArtifacts = 2  // Rolling up a single artifact saves nothing
+	maxSummaryLines     = 50 // Per section of an extractive summary
+)
+
+// defaultCompactTypes are the operation logs saved by the workspace tools,
+// which pile up on long-running tasks.
+var defaultCompactTypes = []task.ArtifactType{
+	task.ArtifactTypeFileRead,
+	task.ArtifactTypeFileList,
+	task.ArtifactTypeSearch,
+}
+
+// Summarizer writes the content of the summary artifact that replaces
+// compacted artifacts, given oldest first.
+type Summarizer interface {
+	Summarize(ctx context.Context, t *task.Task, artifacts []*task.Artifact) (string, error)
+	Name() string // Recorded in the summary's summarized_by metadata
+}
+
+// CompactRequest contains parameters for compacting old artifacts.
+type CompactRequest struct {
+	ProjectID  string              // Empty = all projects
+	TaskID     string              // Empty = every task of the project
+	OlderThan  time.Duration       // Only artifacts last written before this long ago (0 = default 7 days)
+	Types      []task.ArtifactType // Artifact types to compact (empty = file_read, file_list and search)
+	DryRun     bool                // Report what would be compacted without saving anything
+	Summarizer Summarizer          // nil = a deterministic extractive summary
+}
+
+// Compaction is the summary of one task's old artifacts and the artifacts it replaces.
+type Compaction struct {
+	Task      *task.Task
+	Summary   *task.Artifact   // Not saved on a dry run
+	Artifacts []*task.Artifact // Oldest first
+}
+
+// Compact rolls the old artifacts of each task into a single summary
+// artifact. The originals are kept but superseded by the summary, which hides
+// them from listings and search; get_artifact still returns them, and
+// unlinking the summary brings them back. Summaries are never compacted.
+//
+// When the summarizer fails the extractive summary is used instead. A dry
+// run always uses the extractive summary, so it costs no model calls.
+func (s *TaskService) Compact(ctx context.Context, req CompactRequest) ([]*Compaction, error) {
+	tasks, err := s.compactionTasks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	olderThan := req.OlderThan
+	if olderThan <= 0 {
+		olderThan = defaultCompactAge
+	}
+	types := req.Types
+	if len(types) == 0 {
+		types = defaultCompactTypes
+	}
+	cutoff := time.Now().UTC().Add(-olderThan)
+
+	var compactions []*Compaction
+	for _, t := range tasks {
+		c, err := s.compactTask(ctx, t, cutoff, types, req)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			compactions = append(compactions, c)
+		}
+	}
+	return compactions, nil
+}
+
+// compactionTasks returns the tasks a compaction request covers.
+func (s *TaskService) compactionTasks(ctx context.Context, req CompactRequest) ([]*task.Task, error) {
+	if req.TaskID != "" {
+		t, err := s.GetTask(ctx, req.ProjectID, req.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		return []*task.Task{t}, nil
+	}
+
+	all := task.ListOptions{Limit: math.MaxInt32}
+
+	var tasks *task.ListResult[*task.Task]
+	var err error
+	if req.ProjectID != "" {
+		tasks, err = s.repo.ListTasks(ctx, task.NewProjectID(req.ProjectID), all)
+	} else {
+		tasks, err = s.repo.ListAllTasks(ctx, all)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tasks.Items, nil
+}
+
+// compactTask compacts the artifacts of one task, or returns nil when there
+// are too few to bother.
+func (s *TaskService) compactTask(ctx context.Context, t *task.Task, cutoff time.Time, types []task.ArtifactType, req CompactRequest) (*Compaction, error) {
+	artifacts, err := s.repo.ListArtifacts(ctx, t.ProjectID, t.ID, task.ListOptions{Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+
+	var old []*task.Artifact
+	for _, a := range artifacts.Items {
+		if a.Type != task.ArtifactTypeSummary && slices.Contains(types, a.Type) && lastWritten(a).Before(cutoff) {
+			old = append(old, a)
+		}
+	}
+	if len(old) < minCompactArtifacts {
+		return nil, nil
+	}
+	sort.SliceStable(old, func(i, j int) bool {
+		return lastWritten(old[i]).Before(lastWritten(old[j]))
+	})
+
+	var summarizer Summarizer = extractiveSummarizer{}
+	if req.Summarizer != nil && !req.DryRun {
+		summarizer = req.Summarizer
+	}
+	content, err := summarizer.Summarize(ctx, t, old)
+	if err != nil {
+		s.logger.Error("failed to summarize artifacts, using an extractive summary", "task", t.Ref(), "summarizer", summarizer.Name(), "error", err)
+		summarizer = extractiveSummarizer{}
+		content, _ = summarizer.Summarize(ctx, t, old)
+	}
+
+	summary := task.NewArtifact(t.ProjectID, t.ID, task.ArtifactTypeSummary, content)
+	summary.Metadata["compacted"] = strconv.Itoa(len(old))
+	summary.Metadata["from"] = lastWritten(old[0]).Format(time.RFC3339)
+	summary.Metadata["to"] = lastWritten(old[len(old)-1]).Format(time.RFC3339)
+	summary.Metadata["summarized_by"] = summarizer.Name()
+	for _, a := range old {
+		summary.Links = append(summary.Links, task.ArtifactLink{Relation: task.RelationSupersedes, Target: a.Ref()})
+	}
+
+	c := &Compaction{Task: t, Summary: summary, Artifacts: old}
+	if req.DryRun {
+		return c, nil
+	}
+
+	if err := s.repo.SaveArtifact(ctx, summary); err != nil {
+		s.logger.Error("failed to save summary", "task", t.Ref(), "error", err)
+		return nil, err
+	}
+	s.events.Publish(Event{Kind: EventArtifactSaved, ProjectID: t.ProjectID, TaskID: t.ID, ArtifactID: summary.ID})
+
+	// Same as LinkArtifact with a supersedes link, without reloading the summary each time
+	ref := summary.Ref()
+	for _, a := range old {
+		if _, err := s.supersede(ctx, ref, a.Ref()); err != nil {
+			return nil, err
+		}
+		a.SupersededBy = append(a.SupersededBy, ref)
+	}
+
+	s.logger.Info("artifacts compacted", "task", t.Ref(), "summary_id", summary.ID, "artifacts", len(old))
+	return c, nil
+}
+
+// extractiveSummarizer lists what the compacted artifacts were about, by
+// type: the files read, directories listed and searches run with how often,
+// and the first line of anything else. The same artifacts always give the
+// same summary.
+type extractiveSummarizer struct{}
+
+func (extractiveSummarizer) Name() string {
+	return "extractive"
+}
+
+func (extractiveSummarizer) Summarize(_ context.Context, _ *task.Task, artifacts []*task.Artifact) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Compacted %d artifacts written between %s and %s.\n",
+		len(artifacts),
+		lastWritten(artifacts[0]).Format("2006-01-02"),
+		lastWritten(artifacts[len(artifacts)-1]).Format("2006-01-02"))
+
+	byType := make(map[task.ArtifactType][]*task.Artifact)
+	var types []task.ArtifactType
+	for _, a := range artifacts {
+		if _, ok := byType[a.Type]; !ok {
+			types = append(types, a.Type)
+		}
+		byType[a.Type] = append(byType[a.Type], a)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return summaryOrder(types[i]) < summaryOrder(types[j])
+	})
+
+	for _, artifactType := range types {
+		group := byType[artifactType]
+		fmt.Fprintf(&sb, "\n## %s (%d)\n\n", summaryHeading(artifactType), len(group))
+
+		lines := summaryLines(artifactType, group)
+		for _, line := range lines[:min(len(lines), maxSummaryLines)] {
+			fmt.Fprintf(&sb, "- %s\n", line)
+		}
+		if len(lines) > maxSummaryLines {
+			fmt.Fprintf(&sb, "- ... and %d more\n", len(lines)-maxSummaryLines)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// summaryOrder puts the operation logs first, then other types by name.
+func summaryOrder(t task.ArtifactType) string {
+	if i := slices.Index(defaultCompactTypes, t); i >= 0 {
+		return strconv.Itoa(i)
+	}
+	return "~" + string(t)
+}
+
+func summaryHeading(t task.ArtifactType) string {
+	switch t {
+	case task.ArtifactTypeFileRead:
+		return "Files read"
+	case task.ArtifactTypeFileList:
+		return "Directories listed"
+	case task.ArtifactTypeSearch:
+		return "Searches"
+	}
+	return fmt.Sprintf("Artifacts of type %s", t)
+}
+
+// summaryLines describes a group of artifacts of one type. Logs of the same
+// operation are counted, most frequent first; other artifacts get their date
+// and first line, oldest first.
+func summaryLines(t task.ArtifactType, artifacts []*task.Artifact) []string {
+	var key func(a *task.Artifact) string
+	switch t {
+	case task.ArtifactTypeFileRead:
+		key = func(a *task.Artifact) string { return a.Metadata["file_path"] }
+	case task.ArtifactTypeFileList:
+		key = func(a *task.Artifact) string { return withPattern(a.Metadata["base_path"], a.Metadata["pattern"]) }
+	case task.ArtifactTypeSearch:
+		key = func(a *task.Artifact) string {
+			return withPattern(strconv.Quote(a.Metadata["query"]), a.Metadata["pattern"])
+		}
+	default:
+		lines := make([]string, 0, len(artifacts))
+		for _, a := range artifacts {
+			lines = append(lines, fmt.Sprintf("%s: %s", lastWritten(a).Format("2006-01-02"), headline(a.Content)))
+		}
+		return lines
+	}
+
+	counts := make(map[string]int)
+	for _, a := range artifacts {
+		k := key(a)
+		if k == "" || k == `""` {
+			k = headline(a.Content)
+		}
+		counts[k]++
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if n := counts[k]; n > 1 {
+			lines = append(lines, fmt.Sprintf("%s (%d times)", k, n))
+		} else {
+			lines = append(lines, k)
+		}
+	}
+	return lines
+}
+
+func withPattern(s, pattern string) string {
+	if pattern == "" {
+		return s
+	}
+	return fmt.Sprintf("%s (pattern %s)", s, pattern)
+}
+
+// headline returns the first non-empty line of content, shortened to 120 characters.
+func headline(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if r := []rune(line); len(r) > 120 {
+				return string(r[:120]) + "..."
+			}
+			return line
+		}
+	}
+	return "(empty)"
+}
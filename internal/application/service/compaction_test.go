@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+type fakeSummarizer struct {
+	err error
+}
+
+func (f fakeSummarizer) Name() string {
+	return "fake"
+}
+
+func (f fakeSummarizer) Summarize(_ context.Context, t *task.Task, artifacts []*task.Artifact) (string, error) {
+	return "summary of " + t.ID.String(), f.err
+}
+
+func TestTaskService_Compact(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "billing"})
+
+	old := time.Now().UTC().Add(-30 * 24 * time.Hour)
+	save := func(taskID string, artifactType task.ArtifactType, content string, metadata map[string]string, age time.Duration) *task.Artifact {
+		t.Helper()
+		a := task.NewArtifact("backend", task.TaskID(taskID), artifactType, content)
+		a.CreatedAt = old.Add(age)
+		a.ID = strconv.FormatInt(a.CreatedAt.UnixNano(), 10)
+		for k, v := range metadata {
+			a.Metadata[k] = v
+		}
+		if err := svc.repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+		return a
+	}
+
+	save("auth", task.ArtifactTypeFileRead, "a", map[string]string{"file_path": "main.go"}, 0)
+	save("auth", task.ArtifactTypeFileRead, "b", map[string]string{"file_path": "main.go"}, time.Hour)
+	save("auth", task.ArtifactTypeFileRead, "c", map[string]string{"file_path": "auth.go"}, 2*time.Hour)
+	save("auth", task.ArtifactTypeSearch, "d", map[string]string{"query": "token", "pattern": "*.go"}, 3*time.Hour)
+	save("auth", task.ArtifactTypeNote, "old note", nil, 4*time.Hour)
+	recent, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: task.ArtifactTypeFileRead, Content: "recent"})
+	save("billing", task.ArtifactTypeFileList, "e", map[string]string{"base_path": "."}, 0)
+
+	// A dry run reports without saving
+	dry, err := svc.Compact(ctx, CompactRequest{ProjectID: "backend", DryRun: true, Summarizer: fakeSummarizer{}})
+	if err != nil {
+		t.Fatalf("Compact(dry run) error = %v", err)
+	}
+	if len(dry) != 1 || dry[0].Task.ID != "auth" || len(dry[0].Artifacts) != 4 {
+		t.Fatalf("Compact(dry run) = %+v, want 4 artifacts of auth (billing has just one)", dry)
+	}
+	if dry[0].Summary.Metadata["summarized_by"] != "extractive" {
+		t.Errorf("Compact(dry run) summarized by %s, want extractive", dry[0].Summary.Metadata["summarized_by"])
+	}
+	for _, want := range []string{"Compacted 4 artifacts", "## Files read (3)", "- main.go (2 times)", "- auth.go", "## Searches (1)", `"token" (pattern *.go)`} {
+		if !strings.Contains(dry[0].Summary.Content, want) {
+			t.Errorf("Compact(dry run) summary = %q, want it to contain %q", dry[0].Summary.Content, want)
+		}
+	}
+	if list, _ := svc.ListArtifacts(ctx, ListArtifactsRequest{ProjectID: "backend", TaskID: "auth"}); list.Total != 6 {
+		t.Errorf("ListArtifacts() after dry run total = %d, want 6", list.Total)
+	}
+
+	compactions, err := svc.Compact(ctx, CompactRequest{ProjectID: "backend", TaskID: "auth", Summarizer: fakeSummarizer{}})
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	summary := compactions[0].Summary
+	if summary.Content != "summary of auth" || summary.Metadata["summarized_by"] != "fake" || summary.Metadata["compacted"] != "4" || len(summary.Links) != 4 {
+		t.Errorf("Compact() summary = %+v", summary)
+	}
+
+	// The originals are superseded, not deleted
+	list, _ := svc.ListArtifacts(ctx, ListArtifactsRequest{ProjectID: "backend", TaskID: "auth"})
+	if list.Total != 3 {
+		t.Errorf("ListArtifacts() after compaction total = %d, want the summary, the note and the recent log", list.Total)
+	}
+	for _, a := range list.Items {
+		if a.Type == task.ArtifactTypeFileRead && a.ID != recent.ID {
+			t.Errorf("ListArtifacts() after compaction lists compacted artifact %s", a.ID)
+		}
+	}
+	all, _ := svc.ListArtifacts(ctx, ListArtifactsRequest{ProjectID: "backend", TaskID: "auth", IncludeSuperseded: true})
+	if all.Total != 7 {
+		t.Errorf("ListArtifacts(include superseded) total = %d, want 7", all.Total)
+	}
+	original, _ := svc.GetArtifact(ctx, "backend", "auth", compactions[0].Artifacts[0].ID)
+	if original == nil || len(original.SupersededBy) != 1 || original.SupersededBy[0] != summary.Ref() {
+		t.Errorf("GetArtifact(compacted) = %+v, want it superseded by the summary", original)
+	}
+
+	// Nothing is left to compact, and summaries are never compacted
+	again, _ := svc.Compact(ctx, CompactRequest{ProjectID: "backend", TaskID: "auth", Types: []task.ArtifactType{task.ArtifactTypeSummary, task.ArtifactTypeFileRead}})
+	if len(again) != 0 {
+		t.Errorf("Compact(again) = %d compactions, want none", len(again))
+	}
+
+	// Other types on request; a failing summarizer falls back to the extractive summary
+	save("auth", task.ArtifactTypeNote, "older note", nil, -time.Hour)
+	notes, err := svc.Compact(ctx, CompactRequest{ProjectID: "backend", TaskID: "auth", Types: []task.ArtifactType{task.ArtifactTypeNote}, Summarizer: fakeSummarizer{err: errors.New("no model")}})
+	if err != nil {
+		t.Fatalf("Compact(notes) error = %v", err)
+	}
+	if len(notes) != 1 || notes[0].Summary.Metadata["summarized_by"] != "extractive" || !strings.Contains(notes[0].Summary.Content, ": older note") {
+		t.Errorf("Compact(notes) = %+v, want an extractive summary of the notes", notes)
+	}
+
+	if _, err := svc.Compact(ctx, CompactRequest{ProjectID: "backend", TaskID: "missing"}); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("Compact(missing task) error = %v, want ErrTaskNotFound", err)
+	}
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+// DependencyRequest identifies the relation "task is blocked by blocker".
+type DependencyRequest struct {
+	ProjectID          string
+	TaskID             string
+	BlockedByProjectID string // Project of the blocking task (empty = same project)
+	BlockedByTaskID    string
+}
+
+func (r DependencyRequest) refs() (blocked, blocker task.TaskRef) {
+	blocked = task.TaskRef{ProjectID: task.NewProjectID(r.ProjectID), TaskID: task.NewTaskID(r.TaskID)}
+	blocker = task.TaskRef{ProjectID: blocked.ProjectID, TaskID: task.NewTaskID(r.BlockedByTaskID)}
+	if r.BlockedByProjectID != "" {
+		blocker.ProjectID = task.NewProjectID(r.BlockedByProjectID)
+	}
+	return blocked, blocker
+}
+
+// AddDependency records that a task cannot start until another task, possibly
+// in another project, is completed. Adding an existing dependency is a no-op.
+func (s *TaskService) AddDependency(ctx context.Context, req DependencyRequest) (*task.Task, error) {
+	blockedRef, blockerRef := req.refs()
+	if blockedRef == blockerRef {
+		return nil, fmt.Errorf("%w: a task cannot block itself", task.ErrInvalidDependency)
+	}
+
+	t, err := s.repo.GetTask(ctx, blockedRef.ProjectID, blockedRef.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocker, err := s.repo.GetTask(ctx, blockerRef.ProjectID, blockerRef.TaskID)
+	if errors.Is(err, task.ErrTaskNotFound) || errors.Is(err, task.ErrProjectNotFound) {
+		return nil, fmt.Errorf("%w: blocking task '%s' not found", task.ErrInvalidDependency, blockerRef)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if hasTaskRef(t.BlockedBy, blockerRef) {
+		return t, nil
+	}
+
+	waits, err := s.waitsOn(ctx, blocker, blockedRef)
+	if err != nil {
+		return nil, err
+	}
+	if waits {
+		return nil, fmt.Errorf("%w: '%s' already waits on '%s'", task.ErrDependencyCycle, blockerRef, blockedRef)
+	}
+
+	t, err = s.modifyTask(ctx, blockedRef, func(t *task.Task) bool {
+		if hasTaskRef(t.BlockedBy, blockerRef) {
+			return false
+		}
+		t.BlockedBy = append(t.BlockedBy, blockerRef)
+		return true
+	})
+	if err != nil {
+		s.logger.Error("failed to add dependency", "task", blockedRef, "blocked_by", blockerRef, "error", err)
+		return nil, fmt.Errorf("adding dependency: %w", err)
+	}
+
+	_, err = s.modifyTask(ctx, blockerRef, func(blocker *task.Task) bool {
+		if hasTaskRef(blocker.Blocks, blockedRef) {
+			return false
+		}
+		blocker.Blocks = append(blocker.Blocks, blockedRef)
+		return true
+	})
+	if err != nil {
+		s.logger.Error("failed to add dependency", "task", blockerRef, "blocks", blockedRef, "error", err)
+		return nil, fmt.Errorf("adding dependency: %w", err)
+	}
+
+	s.logger.Info("dependency added", "task", blockedRef, "blocked_by", blockerRef)
+	return t, nil
+}
+
+// RemoveDependency removes a dependency added with AddDependency. Removing a
+// dependency that does not exist is a no-op.
+func (s *TaskService) RemoveDependency(ctx context.Context, req DependencyRequest) (*task.Task, error) {
+	blockedRef, blockerRef := req.refs()
+
+	t, err := s.modifyTask(ctx, blockedRef, func(t *task.Task) bool {
+		if !hasTaskRef(t.BlockedBy, blockerRef) {
+			return false
+		}
+		t.BlockedBy = removeTaskRef(t.BlockedBy, blockerRef)
+		return true
+	})
+	if isNotFound(err) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Error("failed to remove dependency", "task", blockedRef, "blocked_by", blockerRef, "error", err)
+		return nil, fmt.Errorf("removing dependency: %w", err)
+	}
+
+	// The blocking task may have been deleted since
+	_, err = s.modifyTask(ctx, blockerRef, func(blocker *task.Task) bool {
+		if !hasTaskRef(blocker.Blocks, blockedRef) {
+			return false
+		}
+		blocker.Blocks = removeTaskRef(blocker.Blocks, blockedRef)
+		return true
+	})
+	if err != nil && !isNotFound(err) {
+		s.logger.Error("failed to remove dependency", "task", blockerRef, "blocks", blockedRef, "error", err)
+		return nil, fmt.Errorf("removing dependency: %w", err)
+	}
+
+	s.logger.Info("dependency removed", "task", blockedRef, "blocked_by", blockerRef)
+	return t, nil
+}
+
+// ListReadyTasksRequest contains parameters for listing tasks ready to start.
+type ListReadyTasksRequest struct {
+	ProjectID string // Empty = all projects
+	Limit     int    // Maximum items to return (0 = default 50)
+	Offset    int    // Items to skip
+}
+
+// ListReadyTasks returns tasks in their workflow's initial status (open by
+// default) whose blockers are all completed and that nobody has claimed,
+// most recently updated first. Blockers that no longer exist do not count.
+func (s *TaskService) ListReadyTasks(ctx context.Context, req ListReadyTasksRequest) (*task.ListResult[*task.Task], error) {
+	opts := task.ListOptions{Limit: math.MaxInt32}
+
+	var candidates *task.ListResult[*task.Task]
+	var err error
+	if req.ProjectID != "" {
+		candidates, err = s.repo.ListTasks(ctx, task.NewProjectID(req.ProjectID), opts)
+	} else {
+		candidates, err = s.repo.ListAllTasks(ctx, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := make(map[task.TaskRef]*task.Task)
+	initial := make(map[task.ProjectID]task.TaskStatus)
+	var ready []*task.Task
+	for _, t := range unclaimed(candidates.Items, time.Now().UTC()) {
+		status, ok := initial[t.ProjectID]
+		if !ok {
+			p, err := s.repo.GetProject(ctx, t.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			status = p.TaskWorkflow().Initial()
+			initial[t.ProjectID] = status
+		}
+		if t.Status != status {
+			continue
+		}
+
+		blockers, err := s.openBlockers(ctx, t, lookup)
+		if err != nil {
+			return nil, err
+		}
+		if len(blockers) == 0 {
+			ready = append(ready, t)
+		}
+	}
+
+	return paginate(ready, task.ListOptions{Limit: req.Limit, Offset: req.Offset}), nil
+}
+
+// checkUnblocked returns ErrTaskBlocked, naming the open blockers, if t has any.
+func (s *TaskService) checkUnblocked(ctx context.Context, t *task.Task) error {
+	blockers, err := s.openBlockers(ctx, t, nil)
+	if err != nil {
+		return err
+	}
+	if len(blockers) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(blockers))
+	for _, b := range blockers {
+		names = append(names, fmt.Sprintf("'%s' (%s)", b.Ref(), b.Status))
+	}
+	return fmt.Errorf("%w: waiting on %s", task.ErrTaskBlocked, strings.Join(names, ", "))
+}
+
+// openBlockers returns the tasks blocking t that are not Done in their
+// project's workflow.
+// lookup caches tasks across calls and may be nil.
+func (s *TaskService) openBlockers(ctx context.Context, t *task.Task, lookup map[task.TaskRef]*task.Task) ([]*task.Task, error) {
+	var open []*task.Task
+	for _, ref := range t.BlockedBy {
+		blocker, cached := lookup[ref]
+		if !cached {
+			var err error
+			blocker, err = s.repo.GetTask(ctx, ref.ProjectID, ref.TaskID)
+			if err != nil && !errors.Is(err, task.ErrTaskNotFound) && !errors.Is(err, task.ErrProjectNotFound) {
+				return nil, err
+			}
+			if lookup != nil {
+				lookup[ref] = blocker
+			}
+		}
+		if blocker == nil {
+			continue
+		}
+
+		p, err := s.repo.GetProject(ctx, blocker.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if p.TaskWorkflow().Done(blocker.Status) {
+			continue
+		}
+		open = append(open, blocker)
+	}
+	return open, nil
+}
+
+// waitsOn reports whether from is blocked, directly or transitively, by target.
+func (s *TaskService) waitsOn(ctx context.Context, from *task.Task, target task.TaskRef) (bool, error) {
+	seen := map[task.TaskRef]bool{from.Ref(): true}
+	stack := append([]task.TaskRef(nil), from.BlockedBy...)
+
+	for len(stack) > 0 {
+		ref := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if ref == target {
+			return true, nil
+		}
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		t, err := s.repo.GetTask(ctx, ref.ProjectID, ref.TaskID)
+		if errors.Is(err, task.ErrTaskNotFound) || errors.Is(err, task.ErrProjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		stack = append(stack, t.BlockedBy...)
+	}
+
+	return false, nil
+}
+
+// unlinkDependencies removes references to a deleted task from the tasks it
+// blocked and the tasks blocking it.
+func (s *TaskService) unlinkDependencies(ctx context.Context, deleted *task.Task) error {
+	ref := deleted.Ref()
+
+	unlink := func(other task.TaskRef, update func(*task.Task) bool) error {
+		_, err := s.modifyTask(ctx, other, update)
+		if errors.Is(err, task.ErrTaskNotFound) || errors.Is(err, task.ErrProjectNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	for _, other := range deleted.Blocks {
+		err := unlink(other, func(t *task.Task) bool {
+			n := len(t.BlockedBy)
+			t.BlockedBy = removeTaskRef(t.BlockedBy, ref)
+			return len(t.BlockedBy) != n
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, other := range deleted.BlockedBy {
+		err := unlink(other, func(t *task.Task) bool {
+			n := len(t.Blocks)
+			t.Blocks = removeTaskRef(t.Blocks, ref)
+			return len(t.Blocks) != n
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasTaskRef(refs []task.TaskRef, ref task.TaskRef) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func removeTaskRef(refs []task.TaskRef, ref task.TaskRef) []task.TaskRef {
+	var kept []task.TaskRef
+	for _, r := range refs {
+		if r != ref {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
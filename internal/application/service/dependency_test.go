@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/storage/filesystem"
+)
+
+// createDependencyTasks creates backend/{schema,api,docs} and frontend/ui.
+func createDependencyTasks(t *testing.T, svc *TaskService) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, id := range []string{"backend", "frontend"} {
+		if _, err := svc.CreateProject(ctx, CreateProjectRequest{ID: id}); err != nil {
+			t.Fatalf("CreateProject(%s) error = %v", id, err)
+		}
+	}
+	for _, req := range []CreateTaskRequest{
+		{ProjectID: "backend", ID: "schema"},
+		{ProjectID: "backend", ID: "api"},
+		{ProjectID: "backend", ID: "docs"},
+		{ProjectID: "frontend", ID: "ui"},
+	} {
+		if _, err := svc.CreateTask(ctx, req); err != nil {
+			t.Fatalf("CreateTask(%s) error = %v", req.ID, err)
+		}
+	}
+}
+
+func TestTaskService_AddDependency(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createDependencyTasks(t, svc)
+	ctx := context.Background()
+
+	// frontend/ui waits on backend/api, which waits on backend/schema
+	ui, err := svc.AddDependency(ctx, DependencyRequest{ProjectID: "frontend", TaskID: "ui", BlockedByProjectID: "backend", BlockedByTaskID: "api"})
+	if err != nil {
+		t.Fatalf("AddDependency(ui <- api) error = %v", err)
+	}
+	if len(ui.BlockedBy) != 1 || ui.BlockedBy[0] != (task.TaskRef{ProjectID: "backend", TaskID: "api"}) {
+		t.Errorf("ui.BlockedBy = %v, want [backend/api]", ui.BlockedBy)
+	}
+	if _, err := svc.AddDependency(ctx, DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"}); err != nil {
+		t.Fatalf("AddDependency(api <- schema) error = %v", err)
+	}
+
+	api, _ := svc.GetTask(ctx, "backend", "api")
+	if len(api.Blocks) != 1 || api.Blocks[0] != ui.Ref() {
+		t.Errorf("api.Blocks = %v, want [frontend/ui]", api.Blocks)
+	}
+
+	// Adding the same dependency again is a no-op
+	ui, err = svc.AddDependency(ctx, DependencyRequest{ProjectID: "frontend", TaskID: "ui", BlockedByProjectID: "backend", BlockedByTaskID: "api"})
+	if err != nil || len(ui.BlockedBy) != 1 {
+		t.Errorf("AddDependency() repeated = %v, %v, want one blocker", ui.BlockedBy, err)
+	}
+
+	tests := []struct {
+		name    string
+		req     DependencyRequest
+		wantErr error
+	}{
+		{
+			name:    "self",
+			req:     DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "api"},
+			wantErr: task.ErrInvalidDependency,
+		},
+		{
+			name:    "missing blocker",
+			req:     DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "missing"},
+			wantErr: task.ErrInvalidDependency,
+		},
+		{
+			name:    "direct cycle",
+			req:     DependencyRequest{ProjectID: "backend", TaskID: "schema", BlockedByTaskID: "api"},
+			wantErr: task.ErrDependencyCycle,
+		},
+		{
+			name:    "cycle across projects",
+			req:     DependencyRequest{ProjectID: "backend", TaskID: "schema", BlockedByProjectID: "frontend", BlockedByTaskID: "ui"},
+			wantErr: task.ErrDependencyCycle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.AddDependency(ctx, tt.req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("AddDependency() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTaskService_AddDependency_Race(t *testing.T) {
+	repo, err := filesystem.NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	racing := &racingRepository{Repository: repo, raced: true}
+	svc := NewTaskService(racing, slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	createDependencyTasks(t, svc)
+	ctx := context.Background()
+
+	// Another writer updates api between the read and the write
+	racing.raced = false
+	api, err := svc.AddDependency(ctx, DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"})
+	if err != nil {
+		t.Fatalf("AddDependency() error = %v", err)
+	}
+	if len(api.BlockedBy) != 1 || len(api.Tags) != 1 {
+		t.Errorf("AddDependency() = blocked by %v, tags %v; want both changes", api.BlockedBy, api.Tags)
+	}
+	schema, _ := svc.GetTask(ctx, "backend", "schema")
+	if len(schema.Blocks) != 1 {
+		t.Errorf("schema.Blocks = %v, want api", schema.Blocks)
+	}
+
+	// And between the reads and writes of removing it
+	racing.raced = false
+	api, err = svc.RemoveDependency(ctx, DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"})
+	if err != nil {
+		t.Fatalf("RemoveDependency() error = %v", err)
+	}
+	if len(api.BlockedBy) != 0 {
+		t.Errorf("RemoveDependency() = blocked by %v, want none", api.BlockedBy)
+	}
+}
+
+func TestTaskService_RemoveDependency(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createDependencyTasks(t, svc)
+	ctx := context.Background()
+
+	req := DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"}
+	if _, err := svc.AddDependency(ctx, req); err != nil {
+		t.Fatalf("AddDependency() error = %v", err)
+	}
+
+	api, err := svc.RemoveDependency(ctx, req)
+	if err != nil {
+		t.Fatalf("RemoveDependency() error = %v", err)
+	}
+	if len(api.BlockedBy) != 0 {
+		t.Errorf("api.BlockedBy = %v, want empty", api.BlockedBy)
+	}
+	schema, _ := svc.GetTask(ctx, "backend", "schema")
+	if len(schema.Blocks) != 0 {
+		t.Errorf("schema.Blocks = %v, want empty", schema.Blocks)
+	}
+
+	if _, err := svc.RemoveDependency(ctx, req); err != nil {
+		t.Errorf("RemoveDependency() repeated error = %v, want nil", err)
+	}
+}
+
+func TestTaskService_UpdateTask_Blocked(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createDependencyTasks(t, svc)
+	ctx := context.Background()
+
+	if _, err := svc.AddDependency(ctx, DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"}); err != nil {
+		t.Fatalf("AddDependency() error = %v", err)
+	}
+
+	inProgress := task.TaskStatusInProgress
+	start := UpdateTaskRequest{ProjectID: "backend", ID: "api", Status: &inProgress}
+	if _, err := svc.UpdateTask(ctx, start); !errors.Is(err, task.ErrTaskBlocked) {
+		t.Fatalf("UpdateTask(in_progress) with open blocker error = %v, want ErrTaskBlocked", err)
+	}
+
+	completed := task.TaskStatusCompleted
+	if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: "schema", Status: &completed}); err != nil {
+		t.Fatalf("UpdateTask(schema completed) error = %v", err)
+	}
+	if _, err := svc.UpdateTask(ctx, start); err != nil {
+		t.Errorf("UpdateTask(in_progress) after blocker completed error = %v", err)
+	}
+}
+
+func TestTaskService_UpdateTask_BlockedCustomWorkflow(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	workflow := &task.Workflow{Statuses: []task.WorkflowStatus{
+		{Name: "todo"},
+		{Name: "doing"},
+		{Name: "review"},
+		{Name: "done", Terminal: true},
+	}}
+	if _, err := svc.CreateProject(ctx, CreateProjectRequest{ID: "backend", Workflow: workflow}); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	for _, id := range []string{"schema", "api"} {
+		if _, err := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: id}); err != nil {
+			t.Fatalf("CreateTask(%s) error = %v", id, err)
+		}
+	}
+	if _, err := svc.AddDependency(ctx, DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"}); err != nil {
+		t.Fatalf("AddDependency() error = %v", err)
+	}
+
+	move := func(id string, status task.TaskStatus) error {
+		_, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: id, Status: &status})
+		return err
+	}
+
+	// Any status past the initial one starts the task
+	for _, status := range []task.TaskStatus{"doing", "review"} {
+		if err := move("api", status); !errors.Is(err, task.ErrTaskBlocked) {
+			t.Errorf("UpdateTask(todo -> %s) with open blocker error = %v, want ErrTaskBlocked", status, err)
+		}
+	}
+
+	if err := move("schema", "done"); err != nil {
+		t.Fatalf("UpdateTask(schema done) error = %v", err)
+	}
+	if err := move("api", "review"); err != nil {
+		t.Errorf("UpdateTask(todo -> review) after blocker done error = %v", err)
+	}
+}
+
+func TestTaskService_ListReadyTasks(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createDependencyTasks(t, svc)
+	ctx := context.Background()
+
+	for _, req := range []DependencyRequest{
+		{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"},
+		{ProjectID: "frontend", TaskID: "ui", BlockedByProjectID: "backend", BlockedByTaskID: "api"},
+		{ProjectID: "backend", TaskID: "docs", BlockedByTaskID: "api"},
+	} {
+		if _, err := svc.AddDependency(ctx, req); err != nil {
+			t.Fatalf("AddDependency() error = %v", err)
+		}
+	}
+
+	ready, err := svc.ListReadyTasks(ctx, ListReadyTasksRequest{})
+	if err != nil {
+		t.Fatalf("ListReadyTasks() error = %v", err)
+	}
+	if ids := taskIDs(ready.Items); ready.Total != 1 || !ids["schema"] {
+		t.Errorf("ListReadyTasks() = %v, want [schema]", ids)
+	}
+
+	completed := task.TaskStatusCompleted
+	for _, id := range []string{"schema", "api"} {
+		if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: id, Status: &completed}); err != nil {
+			t.Fatalf("UpdateTask(%s) error = %v", id, err)
+		}
+	}
+
+	ready, err = svc.ListReadyTasks(ctx, ListReadyTasksRequest{})
+	if err != nil {
+		t.Fatalf("ListReadyTasks() error = %v", err)
+	}
+	if ids := taskIDs(ready.Items); ready.Total != 2 || !ids["ui"] || !ids["docs"] {
+		t.Errorf("ListReadyTasks() after completing blockers = %v, want ui and docs", ids)
+	}
+
+	ready, err = svc.ListReadyTasks(ctx, ListReadyTasksRequest{ProjectID: "frontend"})
+	if err != nil {
+		t.Fatalf("ListReadyTasks(frontend) error = %v", err)
+	}
+	if ready.Total != 1 || ready.Items[0].ID != "ui" {
+		t.Errorf("ListReadyTasks(frontend) total = %d, want [ui]", ready.Total)
+	}
+}
+
+func TestTaskService_DeleteTask_UnlinksDependencies(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createDependencyTasks(t, svc)
+	ctx := context.Background()
+
+	if _, err := svc.AddDependency(ctx, DependencyRequest{ProjectID: "frontend", TaskID: "ui", BlockedByProjectID: "backend", BlockedByTaskID: "api"}); err != nil {
+		t.Fatalf("AddDependency() error = %v", err)
+	}
+	if err := svc.DeleteTask(ctx, "backend", "api"); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+
+	ui, err := svc.GetTask(ctx, "frontend", "ui")
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	if len(ui.BlockedBy) != 0 {
+		t.Errorf("ui.BlockedBy after deleting its blocker = %v, want empty", ui.BlockedBy)
+	}
+}
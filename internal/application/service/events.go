@@ -0,0 +1,80 @@
+package service
+
+import (
+	"sync"
+
+	"agent-memory/internal/domain/task"
+)
+
+// EventKind is what happened to a project, task or artifact.
+type EventKind string
+
+const (
+	EventProjectCreated  EventKind = "project_created"
+	EventProjectUpdated  EventKind = "project_updated"
+	EventProjectDeleted  EventKind = "project_deleted"
+	EventTaskCreated     EventKind = "task_created"
+	EventTaskUpdated     EventKind = "task_updated"
+	EventTaskDeleted     EventKind = "task_deleted"
+	EventArtifactSaved   EventKind = "artifact_saved"
+	EventArtifactUpdated EventKind = "artifact_updated"
+	EventArtifactDeleted EventKind = "artifact_deleted"
+)
+
+// Event describes a change made through the TaskService. TaskID and
+// ArtifactID are empty for changes above them.
+type Event struct {
+	Kind       EventKind
+	ProjectID  task.ProjectID
+	TaskID     task.TaskID
+	ArtifactID string
+}
+
+// EventBus delivers events to subscribers. Handlers run synchronously in the
+// goroutine that made the change, after it is stored, so they must not block.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[int]func(Event)
+	next     int
+}
+
+// NewEventBus creates an event bus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[int]func(Event))}
+}
+
+// Subscribe registers a handler for every event and returns a function that
+// removes it.
+func (b *EventBus) Subscribe(handler func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish delivers an event to every subscriber.
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.handlers))
+	for _, h := range b.handlers {
+		handlers = append(handlers, h)
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+// Subscribe registers a handler for the changes made through the service.
+func (s *TaskService) Subscribe(handler func(Event)) (unsubscribe func()) {
+	return s.events.Subscribe(handler)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+func TestTaskService_Events(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	var events []Event
+	unsubscribe := svc.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	status := task.TaskStatusInProgress
+	svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: "auth", Status: &status})
+	a, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Content: "draft"})
+	content := "final"
+	svc.UpdateArtifact(ctx, UpdateArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: a.ID, Content: &content})
+	svc.UpdateArtifact(ctx, UpdateArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: a.ID, Content: &content})
+	svc.DeleteTask(ctx, "backend", "auth")
+
+	// Failed changes publish nothing
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "missing", ID: "auth"})
+	svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: "auth", Status: &status})
+
+	want := []Event{
+		{Kind: EventProjectCreated, ProjectID: "backend"},
+		{Kind: EventTaskCreated, ProjectID: "backend", TaskID: "auth"},
+		{Kind: EventTaskUpdated, ProjectID: "backend", TaskID: "auth"},
+		{Kind: EventArtifactSaved, ProjectID: "backend", TaskID: "auth", ArtifactID: a.ID},
+		{Kind: EventArtifactUpdated, ProjectID: "backend", TaskID: "auth", ArtifactID: a.ID},
+		{Kind: EventTaskDeleted, ProjectID: "backend", TaskID: "auth"},
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+
+	unsubscribe()
+	svc.DeleteProject(ctx, "backend")
+	if len(events) != len(want) {
+		t.Errorf("got %d events after unsubscribing, want none", len(events)-len(want))
+	}
+}
+
+func TestTaskService_Events_Relations(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createDependencyTasks(t, svc)
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "migration", ParentID: "schema"})
+	old, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "api", Type: task.ArtifactTypeDecision, Content: "REST"})
+	decision, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "api", Type: task.ArtifactTypeDecision, Content: "gRPC"})
+	for i, content := range []string{"a", "b"} {
+		a := task.NewArtifact("backend", "docs", task.ArtifactTypeFileRead, content)
+		a.CreatedAt = time.Now().UTC().Add(-30 * 24 * time.Hour).Add(time.Duration(i) * time.Hour)
+		a.ID = strconv.FormatInt(a.CreatedAt.UnixNano(), 10)
+		svc.repo.SaveArtifact(ctx, a)
+	}
+
+	var events []Event
+	defer svc.Subscribe(func(e Event) {
+		events = append(events, e)
+	})()
+
+	dependency := DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"}
+	svc.AddDependency(ctx, dependency)
+	svc.RemoveDependency(ctx, dependency)
+	svc.RemoveDependency(ctx, dependency)
+	link := LinkArtifactRequest{ProjectID: "backend", TaskID: "api", ArtifactID: decision.ID, Relation: task.RelationSupersedes, TargetArtifactID: old.ID}
+	svc.LinkArtifact(ctx, link)
+	svc.UnlinkArtifact(ctx, link)
+	svc.DeleteTask(ctx, "backend", "schema")
+	compactions, err := svc.Compact(ctx, CompactRequest{ProjectID: "backend", TaskID: "docs"})
+	if err != nil || len(compactions) != 1 {
+		t.Fatalf("Compact() = %d compactions, %v; want 1", len(compactions), err)
+	}
+
+	updated := func(taskID string) Event {
+		return Event{Kind: EventTaskUpdated, ProjectID: "backend", TaskID: task.TaskID(taskID)}
+	}
+	artifactUpdated := func(taskID, id string) Event {
+		return Event{Kind: EventArtifactUpdated, ProjectID: "backend", TaskID: task.TaskID(taskID), ArtifactID: id}
+	}
+	c := compactions[0]
+	want := []Event{
+		updated("api"), updated("schema"), // AddDependency
+		updated("api"), updated("schema"), // RemoveDependency, then a no-op
+		artifactUpdated("api", old.ID), artifactUpdated("api", decision.ID), // LinkArtifact
+		artifactUpdated("api", old.ID), artifactUpdated("api", decision.ID), // UnlinkArtifact
+		updated("migration"), {Kind: EventTaskDeleted, ProjectID: "backend", TaskID: "schema"}, // DeleteTask reparents
+		{Kind: EventArtifactSaved, ProjectID: "backend", TaskID: "docs", ArtifactID: c.Summary.ID}, // Compact
+		artifactUpdated("docs", c.Artifacts[0].ID), artifactUpdated("docs", c.Artifacts[1].ID),
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}
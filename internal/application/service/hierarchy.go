@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"agent-memory/internal/domain/task"
+)
+
+// TaskNode is a task with its subtasks, as returned by GetTaskTree.
+type TaskNode struct {
+	Task     *task.Task
+	Children []*TaskNode
+	Rollup   Rollup // Statuses of every task below this node
+}
+
+// Rollup summarizes the statuses of all descendants of a task. Completed
+// counts the ones Done in the project's workflow.
+type Rollup struct {
+	Total     int                     `json:"total"`
+	Completed int                     `json:"completed"`
+	ByStatus  map[task.TaskStatus]int `json:"by_status"`
+}
+
+// GetTaskTree returns the subtask tree rooted at taskID, or one tree per
+// top-level task of the project when taskID is empty. Children are ordered
+// by creation time.
+func (s *TaskService) GetTaskTree(ctx context.Context, projectID, taskID string) ([]*TaskNode, error) {
+	pid := task.NewProjectID(projectID)
+
+	p, err := s.repo.GetProject(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	w := p.TaskWorkflow()
+
+	tasks, err := s.projectTasks(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	children := childrenByParent(tasks)
+
+	if taskID == "" {
+		roots := children[""]
+		nodes := make([]*TaskNode, 0, len(roots))
+		for _, t := range roots {
+			nodes = append(nodes, buildTaskNode(t, children, w, map[task.TaskID]bool{}))
+		}
+		return nodes, nil
+	}
+
+	tid := task.NewTaskID(taskID)
+	for _, t := range tasks {
+		if t.ID == tid {
+			return []*TaskNode{buildTaskNode(t, children, w, map[task.TaskID]bool{})}, nil
+		}
+	}
+	return nil, task.ErrTaskNotFound
+}
+
+// projectTasks returns every task of a project.
+func (s *TaskService) projectTasks(ctx context.Context, projectID task.ProjectID) ([]*task.Task, error) {
+	result, err := s.repo.ListTasks(ctx, projectID, task.ListOptions{Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// validateParent checks that parentID can become the parent of taskID: it
+// must be another task of the same project and must not descend from taskID.
+func (s *TaskService) validateParent(ctx context.Context, projectID task.ProjectID, taskID, parentID task.TaskID) error {
+	if parentID == taskID {
+		return fmt.Errorf("%w: a task cannot be its own parent", task.ErrInvalidParent)
+	}
+
+	parent, err := s.repo.GetTask(ctx, projectID, parentID)
+	if errors.Is(err, task.ErrTaskNotFound) {
+		return fmt.Errorf("%w: task '%s' not found in project '%s'", task.ErrInvalidParent, parentID, projectID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Walk up from the new parent; reaching the task means it would become its own ancestor
+	seen := map[task.TaskID]bool{parent.ID: true}
+	for ancestor := parent; ancestor.ParentID != ""; {
+		if ancestor.ParentID == taskID {
+			return fmt.Errorf("%w: '%s' is a subtask of '%s'", task.ErrTaskCycle, parentID, taskID)
+		}
+		if seen[ancestor.ParentID] {
+			break
+		}
+		seen[ancestor.ParentID] = true
+
+		ancestor, err = s.repo.GetTask(ctx, projectID, ancestor.ParentID)
+		if errors.Is(err, task.ErrTaskNotFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// childrenByParent groups tasks by parent ID. Tasks whose parent no longer
+// exists are treated as top level (key "").
+func childrenByParent(tasks []*task.Task) map[task.TaskID][]*task.Task {
+	exists := make(map[task.TaskID]bool, len(tasks))
+	for _, t := range tasks {
+		exists[t.ID] = true
+	}
+
+	children := make(map[task.TaskID][]*task.Task)
+	for _, t := range tasks {
+		parent := t.ParentID
+		if !exists[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], t)
+	}
+
+	for _, list := range children {
+		sort.Slice(list, func(i, j int) bool {
+			if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+				return list[i].CreatedAt.Before(list[j].CreatedAt)
+			}
+			return list[i].ID < list[j].ID
+		})
+	}
+	return children
+}
+
+// descendantIDs returns the IDs of every task below root.
+func descendantIDs(tasks []*task.Task, root task.TaskID) map[task.TaskID]bool {
+	children := childrenByParent(tasks)
+	ids := make(map[task.TaskID]bool)
+
+	queue := []task.TaskID{root}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if child.ID == root || ids[child.ID] {
+				continue
+			}
+			ids[child.ID] = true
+			queue = append(queue, child.ID)
+		}
+	}
+	return ids
+}
+
+func buildTaskNode(t *task.Task, children map[task.TaskID][]*task.Task, w *task.Workflow, path map[task.TaskID]bool) *TaskNode {
+	node := &TaskNode{
+		Task:     t,
+		Children: []*TaskNode{},
+		Rollup:   Rollup{ByStatus: map[task.TaskStatus]int{}},
+	}
+
+	// Guard against cycles written to disk by hand
+	path[t.ID] = true
+	defer delete(path, t.ID)
+
+	for _, child := range children[t.ID] {
+		if path[child.ID] {
+			continue
+		}
+		c := buildTaskNode(child, children, w, path)
+		node.Children = append(node.Children, c)
+
+		node.Rollup.add(w, child.Status)
+		node.Rollup.Total += c.Rollup.Total
+		node.Rollup.Completed += c.Rollup.Completed
+		for status, n := range c.Rollup.ByStatus {
+			node.Rollup.ByStatus[status] += n
+		}
+	}
+
+	return node
+}
+
+func (r *Rollup) add(w *task.Workflow, status task.TaskStatus) {
+	r.Total++
+	if w.Done(status) {
+		r.Completed++
+	}
+	r.ByStatus[status]++
+}
+
+func containsTask(tasks []*task.Task, id task.TaskID) bool {
+	for _, t := range tasks {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"agent-memory/internal/domain/task"
+)
+
+// createSubtasks builds the hierarchy
+//
+//	epic
+//	├── design (completed)
+//	└── build
+//	    ├── api (completed)
+//	    └── ui
+//	other
+func createSubtasks(t *testing.T, svc *TaskService) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project"}); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	tasks := []CreateTaskRequest{
+		{ID: "epic"},
+		{ID: "design", ParentID: "epic"},
+		{ID: "build", ParentID: "epic"},
+		{ID: "api", ParentID: "build"},
+		{ID: "ui", ParentID: "build"},
+		{ID: "other"},
+	}
+	for _, req := range tasks {
+		req.ProjectID = "test-project"
+		if _, err := svc.CreateTask(ctx, req); err != nil {
+			t.Fatalf("CreateTask(%s) error = %v", req.ID, err)
+		}
+	}
+
+	completed := task.TaskStatusCompleted
+	for _, id := range []string{"design", "api"} {
+		if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: id, Status: &completed}); err != nil {
+			t.Fatalf("UpdateTask(%s) error = %v", id, err)
+		}
+	}
+}
+
+func taskIDs(tasks []*task.Task) map[task.TaskID]bool {
+	ids := make(map[task.TaskID]bool, len(tasks))
+	for _, t := range tasks {
+		ids[t.ID] = true
+	}
+	return ids
+}
+
+func TestTaskService_CreateTask_Parent(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createSubtasks(t, svc)
+	ctx := context.Background()
+
+	got, err := svc.GetTask(ctx, "test-project", "api")
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	if got.ParentID != "build" {
+		t.Errorf("GetTask().ParentID = %q, want %q", got.ParentID, "build")
+	}
+
+	_, err = svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "orphan", ParentID: "missing"})
+	if !errors.Is(err, task.ErrInvalidParent) {
+		t.Errorf("CreateTask() with missing parent error = %v, want ErrInvalidParent", err)
+	}
+}
+
+func TestTaskService_UpdateTask_ParentCycle(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createSubtasks(t, svc)
+	ctx := context.Background()
+
+	tests := []struct {
+		id      string
+		parent  string
+		wantErr error
+	}{
+		{id: "epic", parent: "epic", wantErr: task.ErrInvalidParent},
+		{id: "epic", parent: "ui", wantErr: task.ErrTaskCycle},
+		{id: "build", parent: "api", wantErr: task.ErrTaskCycle},
+		{id: "build", parent: "missing", wantErr: task.ErrInvalidParent},
+		{id: "ui", parent: "other"},
+		{id: "ui", parent: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id+"->"+tt.parent, func(t *testing.T) {
+			parent := tt.parent
+			got, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: tt.id, ParentID: &parent})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("UpdateTask() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateTask() error = %v", err)
+			}
+			if got.ParentID != task.TaskID(tt.parent) {
+				t.Errorf("UpdateTask().ParentID = %q, want %q", got.ParentID, tt.parent)
+			}
+		})
+	}
+}
+
+func TestTaskService_ListTasks_Hierarchy(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createSubtasks(t, svc)
+	ctx := context.Background()
+
+	children, err := svc.ListTasks(ctx, ListTasksRequest{ProjectID: "test-project", ParentID: "epic"})
+	if err != nil {
+		t.Fatalf("ListTasks(parent_id) error = %v", err)
+	}
+	if ids := taskIDs(children.Items); children.Total != 2 || !ids["design"] || !ids["build"] {
+		t.Errorf("ListTasks(parent_id=epic) = %v, want design and build", ids)
+	}
+
+	descendants, err := svc.ListTasks(ctx, ListTasksRequest{ProjectID: "test-project", DescendantsOf: "epic"})
+	if err != nil {
+		t.Fatalf("ListTasks(descendants_of) error = %v", err)
+	}
+	if descendants.Total != 4 || taskIDs(descendants.Items)["other"] {
+		t.Errorf("ListTasks(descendants_of=epic) total = %d, want 4 without 'other'", descendants.Total)
+	}
+
+	open, err := svc.ListTasks(ctx, ListTasksRequest{ProjectID: "test-project", DescendantsOf: "epic", Status: task.TaskStatusOpen, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListTasks(descendants_of, status) error = %v", err)
+	}
+	if open.Total != 2 || len(open.Items) != 1 || !open.HasMore {
+		t.Errorf("ListTasks(descendants_of=epic, status=open, limit=1) = total %d, items %d, has_more %v, want 2, 1, true",
+			open.Total, len(open.Items), open.HasMore)
+	}
+
+	if _, err := svc.ListTasks(ctx, ListTasksRequest{ProjectID: "test-project", ParentID: "missing"}); err != task.ErrTaskNotFound {
+		t.Errorf("ListTasks(parent_id=missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestTaskService_GetTaskTree(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createSubtasks(t, svc)
+	ctx := context.Background()
+
+	forest, err := svc.GetTaskTree(ctx, "test-project", "")
+	if err != nil {
+		t.Fatalf("GetTaskTree() error = %v", err)
+	}
+	if len(forest) != 2 || forest[0].Task.ID != "epic" || forest[1].Task.ID != "other" {
+		t.Fatalf("GetTaskTree() roots = %d, want [epic other]", len(forest))
+	}
+
+	epic := forest[0]
+	if epic.Rollup.Total != 4 || epic.Rollup.Completed != 2 || epic.Rollup.ByStatus[task.TaskStatusOpen] != 2 {
+		t.Errorf("epic rollup = %+v, want 2/4 completed, 2 open", epic.Rollup)
+	}
+	if len(epic.Children) != 2 || epic.Children[0].Task.ID != "design" || epic.Children[1].Task.ID != "build" {
+		t.Fatalf("epic children = %d, want [design build]", len(epic.Children))
+	}
+
+	build := epic.Children[1]
+	if build.Rollup.Total != 2 || build.Rollup.Completed != 1 || len(build.Children) != 2 {
+		t.Errorf("build rollup = %+v, children = %d, want 1/2 completed and 2 children", build.Rollup, len(build.Children))
+	}
+
+	subtree, err := svc.GetTaskTree(ctx, "test-project", "build")
+	if err != nil {
+		t.Fatalf("GetTaskTree(build) error = %v", err)
+	}
+	if len(subtree) != 1 || subtree[0].Task.ID != "build" || subtree[0].Rollup.Total != 2 {
+		t.Errorf("GetTaskTree(build) = %d roots, want build with 2 subtasks", len(subtree))
+	}
+
+	if _, err := svc.GetTaskTree(ctx, "test-project", "missing"); err != task.ErrTaskNotFound {
+		t.Errorf("GetTaskTree(missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestTaskService_GetTaskTree_CustomWorkflow(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	workflow := &task.Workflow{Statuses: []task.WorkflowStatus{
+		{Name: "todo"},
+		{Name: "doing"},
+		{Name: "done", Terminal: true},
+	}}
+	if _, err := svc.CreateProject(ctx, CreateProjectRequest{ID: "backend", Workflow: workflow}); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	for _, req := range []CreateTaskRequest{{ID: "epic"}, {ID: "schema", ParentID: "epic"}, {ID: "api", ParentID: "epic"}} {
+		req.ProjectID = "backend"
+		if _, err := svc.CreateTask(ctx, req); err != nil {
+			t.Fatalf("CreateTask(%s) error = %v", req.ID, err)
+		}
+	}
+	for id, status := range map[string]task.TaskStatus{"schema": "done", "api": "doing"} {
+		if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: id, Status: &status}); err != nil {
+			t.Fatalf("UpdateTask(%s) error = %v", id, err)
+		}
+	}
+
+	forest, err := svc.GetTaskTree(ctx, "backend", "epic")
+	if err != nil {
+		t.Fatalf("GetTaskTree() error = %v", err)
+	}
+	// The terminal status counts as completed, not only TaskStatusCompleted
+	if len(forest) != 1 {
+		t.Fatalf("GetTaskTree(epic) = %d roots, want 1", len(forest))
+	}
+	if forest[0].Rollup.Total != 2 || forest[0].Rollup.Completed != 1 {
+		t.Errorf("GetTaskTree(epic) rollup = %+v, want 1/2 completed", forest[0].Rollup)
+	}
+}
+
+func TestTaskService_DeleteTask_ReparentsSubtasks(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createSubtasks(t, svc)
+	ctx := context.Background()
+
+	if err := svc.DeleteTask(ctx, "test-project", "build"); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+
+	for _, id := range []string{"api", "ui"} {
+		got, err := svc.GetTask(ctx, "test-project", id)
+		if err != nil {
+			t.Fatalf("GetTask(%s) error = %v", id, err)
+		}
+		if got.ParentID != "epic" {
+			t.Errorf("GetTask(%s).ParentID = %q after deleting its parent, want epic", id, got.ParentID)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+// TaskHistory is a task's status history with the time spent in each status.
+type TaskHistory struct {
+	Task         *task.Task
+	Changes      []task.StatusChange // Oldest first
+	TimeInStatus []StatusDuration    // In the order the statuses were first entered
+}
+
+// StatusDuration is the total time a task spent in one status.
+type StatusDuration struct {
+	Status   task.TaskStatus `json:"status"`
+	Duration time.Duration   `json:"-"`
+	Visits   int             `json:"visits"`  // Number of times the task entered the status
+	Current  bool            `json:"current"` // The task is still in this status; Duration runs until now
+}
+
+// GetTaskHistory returns the status history of a task. Tasks created before
+// history was recorded only have the changes made since.
+func (s *TaskService) GetTaskHistory(ctx context.Context, projectID, taskID string) (*TaskHistory, error) {
+	pid := task.NewProjectID(projectID)
+	tid := task.NewTaskID(taskID)
+
+	t, err := s.repo.GetTask(ctx, pid, tid)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.GetTaskHistory(ctx, pid, tid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TaskHistory{
+		Task:         t,
+		Changes:      changes,
+		TimeInStatus: timeInStatus(changes, time.Now().UTC()),
+	}, nil
+}
+
+// recordStatusChange appends a status change to the task's history. The
+// change itself is already saved, so a failure is logged rather than returned.
+func (s *TaskService) recordStatusChange(ctx context.Context, t *task.Task, change task.StatusChange) {
+	if err := s.repo.AppendTaskHistory(ctx, t.ProjectID, t.ID, change); err != nil {
+		s.logger.Error("failed to record status change", "project_id", t.ProjectID, "task_id", t.ID, "to", change.To, "error", err)
+	}
+}
+
+// timeInStatus sums how long the task stayed in each status: every change
+// starts a stay in its To status that lasts until the next change, or until
+// now for the last one.
+func timeInStatus(changes []task.StatusChange, now time.Time) []StatusDuration {
+	durations := []StatusDuration{}
+	index := make(map[task.TaskStatus]int)
+
+	for i, c := range changes {
+		end := now
+		if i+1 < len(changes) {
+			end = changes[i+1].At
+		}
+
+		j, ok := index[c.To]
+		if !ok {
+			j = len(durations)
+			index[c.To] = j
+			durations = append(durations, StatusDuration{Status: c.To})
+		}
+
+		durations[j].Visits++
+		if end.After(c.At) {
+			durations[j].Duration += end.Sub(c.At)
+		}
+	}
+
+	if n := len(changes); n > 0 {
+		durations[index[changes[n-1].To]].Current = true
+	}
+	return durations
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+func TestTimeInStatus(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	changes := []task.StatusChange{
+		{To: task.TaskStatusOpen, At: start},
+		{From: task.TaskStatusOpen, To: task.TaskStatusInProgress, At: start.Add(1 * time.Hour)},
+		{From: task.TaskStatusInProgress, To: task.TaskStatusOpen, At: start.Add(3 * time.Hour)},
+		{From: task.TaskStatusOpen, To: task.TaskStatusInProgress, At: start.Add(4 * time.Hour)},
+	}
+
+	got := timeInStatus(changes, start.Add(10*time.Hour))
+
+	want := []StatusDuration{
+		{Status: task.TaskStatusOpen, Duration: 2 * time.Hour, Visits: 2},
+		{Status: task.TaskStatusInProgress, Duration: 8 * time.Hour, Visits: 2, Current: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("timeInStatus() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("timeInStatus()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got := timeInStatus(nil, start); len(got) != 0 {
+		t.Errorf("timeInStatus(nil) = %+v, want empty", got)
+	}
+}
+
+func TestTaskService_GetTaskHistory(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project"})
+	if _, err := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Actor: "claude"}); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	inProgress := task.TaskStatusInProgress
+	update := UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Status: &inProgress, Reason: "picked up", Actor: "claude"}
+	if _, err := svc.UpdateTask(ctx, update); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+
+	// Updates that keep the status are not recorded
+	name := "Fix the bug"
+	if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Name: &name, Status: &inProgress}); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+
+	history, err := svc.GetTaskHistory(ctx, "test-project", "fix-bug")
+	if err != nil {
+		t.Fatalf("GetTaskHistory() error = %v", err)
+	}
+
+	if len(history.Changes) != 2 {
+		t.Fatalf("GetTaskHistory().Changes = %+v, want 2", history.Changes)
+	}
+	created, started := history.Changes[0], history.Changes[1]
+	if created.From != "" || created.To != task.TaskStatusOpen || created.Actor != "claude" {
+		t.Errorf("creation change = %+v, want '' -> open by claude", created)
+	}
+	if started.From != task.TaskStatusOpen || started.To != task.TaskStatusInProgress || started.Reason != "picked up" {
+		t.Errorf("status change = %+v, want open -> in_progress 'picked up'", started)
+	}
+
+	if len(history.TimeInStatus) != 2 || !history.TimeInStatus[1].Current || history.TimeInStatus[1].Status != task.TaskStatusInProgress {
+		t.Errorf("GetTaskHistory().TimeInStatus = %+v, want open then current in_progress", history.TimeInStatus)
+	}
+
+	if _, err := svc.GetTaskHistory(ctx, "test-project", "missing"); err != task.ErrTaskNotFound {
+		t.Errorf("GetTaskHistory(missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
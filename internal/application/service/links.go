@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"agent-memory/internal/domain/task"
+)
+
+// LinkArtifactRequest identifies a link from an artifact to a target artifact or task.
+type LinkArtifactRequest struct {
+	ProjectID        string
+	TaskID           string
+	ArtifactID       string
+	Relation         task.LinkRelation
+	TargetProjectID  string // Empty = the artifact's project
+	TargetTaskID     string // Empty = the artifact's task
+	TargetArtifactID string // Empty = link to the target task itself
+}
+
+// link resolves the request's defaults into the source reference and the link.
+func (req LinkArtifactRequest) link() (task.ArtifactRef, task.ArtifactLink, error) {
+	source := task.ArtifactRef{
+		ProjectID:  task.NewProjectID(req.ProjectID),
+		TaskID:     task.NewTaskID(req.TaskID),
+		ArtifactID: req.ArtifactID,
+	}
+
+	relation, err := task.ParseLinkRelation(string(req.Relation))
+	if err != nil {
+		return source, task.ArtifactLink{}, err
+	}
+
+	target := task.ArtifactRef{
+		ProjectID:  source.ProjectID,
+		TaskID:     source.TaskID,
+		ArtifactID: req.TargetArtifactID,
+	}
+	if req.TargetProjectID != "" {
+		target.ProjectID = task.NewProjectID(req.TargetProjectID)
+	}
+	if req.TargetTaskID != "" {
+		target.TaskID = task.NewTaskID(req.TargetTaskID)
+	}
+
+	return source, task.ArtifactLink{Relation: relation, Target: target}, nil
+}
+
+// LinkArtifact adds a typed link from an artifact to another artifact or a task.
+// A supersedes link also marks the target as superseded, which hides it from
+// listings and search. Adding a link that already exists changes nothing.
+func (s *TaskService) LinkArtifact(ctx context.Context, req LinkArtifactRequest) (*task.Artifact, error) {
+	sourceRef, link, err := req.link()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.repo.GetArtifact(ctx, sourceRef.ProjectID, sourceRef.TaskID, sourceRef.ArtifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.Target == sourceRef {
+		return nil, fmt.Errorf("%w: an artifact cannot link to itself", task.ErrInvalidLink)
+	}
+	if link.Relation == task.RelationSupersedes && link.Target.IsTask() {
+		return nil, fmt.Errorf("%w: only artifacts can be superseded, set the target artifact", task.ErrInvalidLink)
+	}
+
+	// The target must exist when the link is made
+	var target *task.Artifact
+	if link.Target.IsTask() {
+		if _, err := s.repo.GetTask(ctx, link.Target.ProjectID, link.Target.TaskID); err != nil {
+			return nil, linkTargetError(err, link.Target)
+		}
+	} else {
+		target, err = s.repo.GetArtifact(ctx, link.Target.ProjectID, link.Target.TaskID, link.Target.ArtifactID)
+		if err != nil {
+			return nil, linkTargetError(err, link.Target)
+		}
+	}
+
+	if source.HasLink(link) {
+		return source, nil
+	}
+	if link.Relation == task.RelationSupersedes && target.HasLink(task.ArtifactLink{Relation: task.RelationSupersedes, Target: sourceRef}) {
+		return nil, fmt.Errorf("%w: %s already supersedes this artifact", task.ErrInvalidLink, link.Target)
+	}
+
+	// The target is marked first so that a failure never leaves a supersedes
+	// link whose target still shows up; the mark is undone if the link fails.
+	marked := false
+	if link.Relation == task.RelationSupersedes {
+		if marked, err = s.supersede(ctx, sourceRef, link.Target); err != nil {
+			return nil, err
+		}
+	}
+
+	// Links are changed on the stored artifact, which may have gained
+	// others since it was read
+	linked := false
+	source, err = s.repo.UpdateArtifactLinks(ctx, sourceRef.ProjectID, sourceRef.TaskID, sourceRef.ArtifactID, func(a *task.Artifact) bool {
+		if a.HasLink(link) {
+			return false
+		}
+		a.Links = append(a.Links, link)
+		linked = true
+		return true
+	})
+	if err != nil {
+		s.logger.Error("failed to link artifact", "artifact", sourceRef, "target", link.Target, "error", err)
+		if marked {
+			if uerr := s.unsupersede(ctx, sourceRef, link.Target); uerr != nil {
+				s.logger.Error("failed to undo superseded mark", "artifact", link.Target, "error", uerr)
+			}
+		}
+		return nil, fmt.Errorf("linking artifact: %w", err)
+	}
+
+	if linked {
+		s.logger.Info("artifact linked", "artifact", sourceRef, "relation", link.Relation, "target", link.Target)
+		s.publishArtifactUpdated(sourceRef)
+	}
+	return source, nil
+}
+
+// UnlinkArtifact removes a link added by LinkArtifact.
+func (s *TaskService) UnlinkArtifact(ctx context.Context, req LinkArtifactRequest) (*task.Artifact, error) {
+	sourceRef, link, err := req.link()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.repo.GetArtifact(ctx, sourceRef.ProjectID, sourceRef.TaskID, sourceRef.ArtifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(source.Links, link) {
+		return nil, fmt.Errorf("%w: %s %s", task.ErrLinkNotFound, link.Relation, link.Target)
+	}
+
+	// The target is unmarked first: if removing the link then fails, the
+	// link is still there to be removed again.
+	if link.Relation == task.RelationSupersedes {
+		if err := s.unsupersede(ctx, sourceRef, link.Target); err != nil {
+			return nil, err
+		}
+	}
+
+	unlinked := false
+	source, err = s.repo.UpdateArtifactLinks(ctx, sourceRef.ProjectID, sourceRef.TaskID, sourceRef.ArtifactID, func(a *task.Artifact) bool {
+		i := slices.Index(a.Links, link)
+		if i < 0 {
+			return false
+		}
+		a.Links = slices.Delete(a.Links, i, i+1)
+		unlinked = true
+		return true
+	})
+	if err != nil {
+		s.logger.Error("failed to unlink artifact", "artifact", sourceRef, "target", link.Target, "error", err)
+		return nil, fmt.Errorf("unlinking artifact: %w", err)
+	}
+	if !unlinked {
+		// Removed by someone else in the meantime
+		return nil, fmt.Errorf("%w: %s %s", task.ErrLinkNotFound, link.Relation, link.Target)
+	}
+
+	s.logger.Info("artifact unlinked", "artifact", sourceRef, "relation", link.Relation, "target", link.Target)
+	s.publishArtifactUpdated(sourceRef)
+	return source, nil
+}
+
+// supersede adds source to the superseded_by list of target, which is then
+// hidden from listings. It reports whether target was not marked yet.
+func (s *TaskService) supersede(ctx context.Context, source, target task.ArtifactRef) (bool, error) {
+	marked := false
+	_, err := s.repo.UpdateArtifactLinks(ctx, target.ProjectID, target.TaskID, target.ArtifactID, func(a *task.Artifact) bool {
+		if slices.Contains(a.SupersededBy, source) {
+			return false
+		}
+		a.SupersededBy = append(a.SupersededBy, source)
+		marked = true
+		return true
+	})
+	if err != nil {
+		s.logger.Error("failed to mark artifact superseded", "artifact", target, "error", err)
+		return false, fmt.Errorf("marking artifact superseded: %w", err)
+	}
+	if marked {
+		s.publishArtifactUpdated(target)
+	}
+	return marked, nil
+}
+
+// unsupersede removes source from the superseded_by list of target, which
+// shows up in listings again. A target that no longer exists is ignored.
+func (s *TaskService) unsupersede(ctx context.Context, source, target task.ArtifactRef) error {
+	unmarked := false
+	_, err := s.repo.UpdateArtifactLinks(ctx, target.ProjectID, target.TaskID, target.ArtifactID, func(a *task.Artifact) bool {
+		i := slices.Index(a.SupersededBy, source)
+		if i < 0 {
+			return false
+		}
+		a.SupersededBy = slices.Delete(a.SupersededBy, i, i+1)
+		unmarked = true
+		return true
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		s.logger.Error("failed to unmark superseded artifact", "artifact", target, "error", err)
+		return fmt.Errorf("unmarking superseded artifact: %w", err)
+	}
+	if unmarked {
+		s.publishArtifactUpdated(target)
+	}
+	return nil
+}
+
+// publishArtifactUpdated publishes a change to the links of an artifact.
+func (s *TaskService) publishArtifactUpdated(ref task.ArtifactRef) {
+	s.events.Publish(Event{Kind: EventArtifactUpdated, ProjectID: ref.ProjectID, TaskID: ref.TaskID, ArtifactID: ref.ArtifactID})
+}
+
+// supersession is a supersedes link from source to target.
+type supersession struct {
+	source, target task.ArtifactRef
+}
+
+// taskSupersessions returns the supersedes links made by the artifacts of a
+// task, whose targets must be unmarked when the task is deleted.
+func (s *TaskService) taskSupersessions(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) ([]supersession, error) {
+	artifacts, err := s.repo.ListArtifacts(ctx, projectID, taskID, task.ListOptions{Limit: math.MaxInt32, IncludeSuperseded: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var links []supersession
+	for _, a := range artifacts.Items {
+		for _, l := range a.Links {
+			if l.Relation == task.RelationSupersedes {
+				links = append(links, supersession{source: a.Ref(), target: l.Target})
+			}
+		}
+	}
+	return links, nil
+}
+
+// unsupersedeAll unmarks the targets of links whose source artifacts were
+// deleted. Targets deleted along with them are ignored.
+func (s *TaskService) unsupersedeAll(ctx context.Context, links []supersession) error {
+	for _, l := range links {
+		if err := s.unsupersede(ctx, l.source, l.target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkedItem is an artifact or task one link away from an artifact.
+type LinkedItem struct {
+	Relation task.LinkRelation
+	Incoming bool             // The link points at the artifact (it is superseded by Ref)
+	Ref      task.ArtifactRef // What the link points to, or where it comes from when Incoming
+	Artifact *task.Artifact   // Set when Ref is an artifact that still exists
+	Task     *task.Task       // Set when Ref is a task that still exists
+}
+
+// GetLinkedItems resolves the outgoing links of an artifact and the artifacts
+// superseding it. Items whose artifact or task was deleted have neither set.
+func (s *TaskService) GetLinkedItems(ctx context.Context, a *task.Artifact) ([]*LinkedItem, error) {
+	items := make([]*LinkedItem, 0, len(a.Links)+len(a.SupersededBy))
+	for _, l := range a.Links {
+		items = append(items, &LinkedItem{Relation: l.Relation, Ref: l.Target})
+	}
+	for _, ref := range a.SupersededBy {
+		items = append(items, &LinkedItem{Relation: task.RelationSupersedes, Incoming: true, Ref: ref})
+	}
+
+	for _, item := range items {
+		var err error
+		if item.Ref.IsTask() {
+			item.Task, err = s.repo.GetTask(ctx, item.Ref.ProjectID, item.Ref.TaskID)
+		} else {
+			item.Artifact, err = s.repo.GetArtifact(ctx, item.Ref.ProjectID, item.Ref.TaskID, item.Ref.ArtifactID)
+		}
+		if err != nil && !isNotFound(err) {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// linkTargetError reports a missing link target as an invalid link.
+func linkTargetError(err error, target task.ArtifactRef) error {
+	if isNotFound(err) {
+		return fmt.Errorf("%w: target %s: %v", task.ErrInvalidLink, target, err)
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, task.ErrProjectNotFound) ||
+		errors.Is(err, task.ErrTaskNotFound) ||
+		errors.Is(err, task.ErrArtifactNotFound)
+}
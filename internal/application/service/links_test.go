@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+
+	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/storage/filesystem"
+)
+
+func TestTaskService_LinkArtifact(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "research"})
+
+	save := func(taskID string, artifactType task.ArtifactType, content string) *task.Artifact {
+		t.Helper()
+		a, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: taskID, Type: artifactType, Content: content})
+		if err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+		return a
+	}
+	oldDecision := save("auth", task.ArtifactTypeDecision, "Use sessions for auth")
+	decision := save("auth", task.ArtifactTypeDecision, "Use JWT for auth")
+	code := save("auth", task.ArtifactTypeCode, "func Middleware() {}")
+	ref := save("auth", task.ArtifactTypeReference, "RFC 7519")
+
+	link := func(a *task.Artifact, relation task.LinkRelation, targetTask, targetArtifact string) (*task.Artifact, error) {
+		return svc.LinkArtifact(ctx, LinkArtifactRequest{
+			ProjectID: "backend", TaskID: "auth", ArtifactID: a.ID,
+			Relation: relation, TargetTaskID: targetTask, TargetArtifactID: targetArtifact,
+		})
+	}
+
+	if _, err := link(code, task.RelationImplements, "", decision.ID); err != nil {
+		t.Fatalf("LinkArtifact(implements) error = %v", err)
+	}
+	if _, err := link(ref, task.RelationDerivedFrom, "research", ""); err != nil {
+		t.Fatalf("LinkArtifact(derived_from task) error = %v", err)
+	}
+	linked, err := link(decision, task.RelationSupersedes, "", oldDecision.ID)
+	if err != nil {
+		t.Fatalf("LinkArtifact(supersedes) error = %v", err)
+	}
+	if len(linked.Links) != 1 || linked.Links[0].Target.ArtifactID != oldDecision.ID {
+		t.Errorf("LinkArtifact() links = %+v, want the supersedes link", linked.Links)
+	}
+
+	// Linking twice is a no-op
+	if again, err := link(code, task.RelationImplements, "", decision.ID); err != nil || len(again.Links) != 1 {
+		t.Errorf("LinkArtifact(again) = %+v, %v, want one link", again, err)
+	}
+
+	for name, tc := range map[string]struct {
+		relation       task.LinkRelation
+		task, artifact string
+	}{
+		"unknown relation":    {relation: "blocks", artifact: decision.ID},
+		"self link":           {relation: task.RelationRelatesTo, artifact: code.ID},
+		"supersede a task":    {relation: task.RelationSupersedes, task: "research"},
+		"missing target":      {relation: task.RelationRelatesTo, artifact: "404"},
+		"missing target task": {relation: task.RelationRelatesTo, task: "nope"},
+	} {
+		if _, err := link(code, tc.relation, tc.task, tc.artifact); !errors.Is(err, task.ErrInvalidLink) {
+			t.Errorf("LinkArtifact(%s) error = %v, want ErrInvalidLink", name, err)
+		}
+	}
+	if _, err := link(oldDecision, task.RelationSupersedes, "", decision.ID); !errors.Is(err, task.ErrInvalidLink) {
+		t.Errorf("LinkArtifact(supersede cycle) error = %v, want ErrInvalidLink", err)
+	}
+
+	// The superseded decision is hidden from listings and search unless asked for
+	list, _ := svc.ListArtifacts(ctx, ListArtifactsRequest{ProjectID: "backend", TaskID: "auth"})
+	if list.Total != 3 {
+		t.Errorf("ListArtifacts() total = %d, want 3 (superseded hidden)", list.Total)
+	}
+	list, _ = svc.ListArtifacts(ctx, ListArtifactsRequest{ProjectID: "backend", TaskID: "auth", IncludeSuperseded: true})
+	if list.Total != 4 {
+		t.Errorf("ListArtifacts(include superseded) total = %d, want 4", list.Total)
+	}
+	hits, _ := svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: "auth"})
+	if hits.Total != 1 || hits.Items[0].Artifact.ID != decision.ID {
+		t.Errorf("SearchArtifacts(auth) total = %d, want only the new decision", hits.Total)
+	}
+	hits, _ = svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: "auth type:decision", IncludeSuperseded: true})
+	if hits.Total != 2 {
+		t.Errorf("SearchArtifacts(auth type:decision, include superseded) total = %d, want 2", hits.Total)
+	}
+
+	old, _ := svc.GetArtifact(ctx, "backend", "auth", oldDecision.ID)
+	items, err := svc.GetLinkedItems(ctx, old)
+	if err != nil {
+		t.Fatalf("GetLinkedItems() error = %v", err)
+	}
+	if len(items) != 1 || !items[0].Incoming || items[0].Artifact == nil || items[0].Artifact.ID != decision.ID {
+		t.Errorf("GetLinkedItems(old decision) = %+v, want superseded by the new decision", items)
+	}
+
+	refArtifact, _ := svc.GetArtifact(ctx, "backend", "auth", ref.ID)
+	items, _ = svc.GetLinkedItems(ctx, refArtifact)
+	if len(items) != 1 || items[0].Task == nil || items[0].Task.ID != "research" {
+		t.Errorf("GetLinkedItems(reference) = %+v, want the research task", items)
+	}
+
+	// Removing the supersedes link brings the old decision back
+	if _, err := svc.UnlinkArtifact(ctx, LinkArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: decision.ID, Relation: task.RelationSupersedes, TargetArtifactID: oldDecision.ID}); err != nil {
+		t.Fatalf("UnlinkArtifact() error = %v", err)
+	}
+	list, _ = svc.ListArtifacts(ctx, ListArtifactsRequest{ProjectID: "backend", TaskID: "auth"})
+	if list.Total != 4 {
+		t.Errorf("ListArtifacts() after unlink total = %d, want 4", list.Total)
+	}
+	if _, err := svc.UnlinkArtifact(ctx, LinkArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: decision.ID, Relation: task.RelationSupersedes, TargetArtifactID: oldDecision.ID}); !errors.Is(err, task.ErrLinkNotFound) {
+		t.Errorf("UnlinkArtifact(again) error = %v, want ErrLinkNotFound", err)
+	}
+
+	// Deleting the superseding artifact brings the old one back too
+	if _, err := link(decision, task.RelationSupersedes, "", oldDecision.ID); err != nil {
+		t.Fatalf("LinkArtifact(supersedes) error = %v", err)
+	}
+	if err := svc.DeleteArtifact(ctx, "backend", "auth", decision.ID); err != nil {
+		t.Fatalf("DeleteArtifact() error = %v", err)
+	}
+	old, _ = svc.GetArtifact(ctx, "backend", "auth", oldDecision.ID)
+	if old.IsSuperseded() {
+		t.Errorf("old decision still superseded by %v after the new one was deleted", old.SupersededBy)
+	}
+}
+
+// failingLinksRepository fails to save the links of one artifact.
+type failingLinksRepository struct {
+	task.Repository
+	artifactID string
+}
+
+func (r *failingLinksRepository) UpdateArtifactLinks(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string, change func(*task.Artifact) bool) (*task.Artifact, error) {
+	if artifactID == r.artifactID {
+		return nil, fmt.Errorf("%w: disk full", task.ErrStorageFailed)
+	}
+	return r.Repository.UpdateArtifactLinks(ctx, projectID, taskID, artifactID, change)
+}
+
+func TestTaskService_LinkArtifact_SaveFails(t *testing.T) {
+	repo, err := filesystem.NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	failing := &failingLinksRepository{Repository: repo}
+	svc := NewTaskService(failing, slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	old, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: task.ArtifactTypeDecision, Content: "Use sessions"})
+	decision, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: task.ArtifactTypeDecision, Content: "Use JWT"})
+
+	// The link cannot be saved, so the old decision must not stay hidden
+	failing.artifactID = decision.ID
+	req := LinkArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: decision.ID, Relation: task.RelationSupersedes, TargetArtifactID: old.ID}
+	if _, err := svc.LinkArtifact(ctx, req); !errors.Is(err, task.ErrStorageFailed) {
+		t.Fatalf("LinkArtifact() error = %v, want ErrStorageFailed", err)
+	}
+	if got, _ := svc.GetArtifact(ctx, "backend", "auth", old.ID); got.IsSuperseded() {
+		t.Errorf("old decision superseded by %v after the link failed", got.SupersededBy)
+	}
+
+	// Unlinking keeps the link if the target cannot be unmarked
+	failing.artifactID = ""
+	if _, err := svc.LinkArtifact(ctx, req); err != nil {
+		t.Fatalf("LinkArtifact() error = %v", err)
+	}
+	failing.artifactID = old.ID
+	if _, err := svc.UnlinkArtifact(ctx, req); !errors.Is(err, task.ErrStorageFailed) {
+		t.Fatalf("UnlinkArtifact() error = %v, want ErrStorageFailed", err)
+	}
+	failing.artifactID = ""
+	if _, err := svc.UnlinkArtifact(ctx, req); err != nil {
+		t.Errorf("UnlinkArtifact() retry error = %v", err)
+	}
+	if got, _ := svc.GetArtifact(ctx, "backend", "auth", old.ID); got.IsSuperseded() {
+		t.Errorf("old decision superseded by %v after unlinking", got.SupersededBy)
+	}
+}
+
+func TestTaskService_LinkArtifact_Concurrent(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	summary, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: task.ArtifactTypeSummary, Content: "Summary"})
+
+	const targets = 8
+	ids := make([]string, targets)
+	for i := range ids {
+		a, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: task.ArtifactTypeNote, Content: fmt.Sprintf("Note %d", i)})
+		ids[i] = a.ID
+	}
+
+	// Links to every target and content updates of the source at once
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			req := LinkArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: summary.ID, Relation: task.RelationSupersedes, TargetArtifactID: id}
+			if _, err := svc.LinkArtifact(ctx, req); err != nil {
+				t.Errorf("LinkArtifact(%s) error = %v", id, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			content := fmt.Sprintf("Summary %d", i)
+			if _, err := svc.UpdateArtifact(ctx, UpdateArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: summary.ID, Content: &content}); err != nil {
+				t.Errorf("UpdateArtifact() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := svc.GetArtifact(ctx, "backend", "auth", summary.ID)
+	if err != nil {
+		t.Fatalf("GetArtifact() error = %v", err)
+	}
+	if len(got.Links) != targets {
+		t.Errorf("summary has %d links, want %d", len(got.Links), targets)
+	}
+	for _, id := range ids {
+		if a, _ := svc.GetArtifact(ctx, "backend", "auth", id); !a.IsSuperseded() {
+			t.Errorf("artifact %s is not superseded", id)
+		}
+	}
+}
+
+func TestTaskService_DeleteTask_Unsupersedes(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "frontend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth-v2"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "frontend", ID: "login"})
+
+	old, _ := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: task.ArtifactTypeDecision, Content: "Use sessions"})
+	supersede := func(projectID, taskID string) {
+		t.Helper()
+		a, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: projectID, TaskID: taskID, Type: task.ArtifactTypeDecision, Content: "Use JWT"})
+		if err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+		if _, err := svc.LinkArtifact(ctx, LinkArtifactRequest{
+			ProjectID: projectID, TaskID: taskID, ArtifactID: a.ID, Relation: task.RelationSupersedes,
+			TargetProjectID: "backend", TargetTaskID: "auth", TargetArtifactID: old.ID,
+		}); err != nil {
+			t.Fatalf("LinkArtifact() error = %v", err)
+		}
+	}
+	supersede("backend", "auth-v2")
+	supersede("frontend", "login")
+
+	supersededBy := func() []task.ArtifactRef {
+		t.Helper()
+		a, err := svc.GetArtifact(ctx, "backend", "auth", old.ID)
+		if err != nil {
+			t.Fatalf("GetArtifact() error = %v", err)
+		}
+		return a.SupersededBy
+	}
+	if got := supersededBy(); len(got) != 2 {
+		t.Fatalf("SupersededBy = %v, want both decisions", got)
+	}
+
+	if err := svc.DeleteTask(ctx, "backend", "auth-v2"); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+	if got := supersededBy(); len(got) != 1 || got[0].ProjectID != "frontend" {
+		t.Errorf("SupersededBy after DeleteTask = %v, want only the frontend decision", got)
+	}
+
+	if err := svc.DeleteProject(ctx, "frontend"); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if got := supersededBy(); len(got) != 0 {
+		t.Errorf("SupersededBy after DeleteProject = %v, want none", got)
+	}
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+
+	"agent-memory/internal/domain/task"
+)
+
+// CreateMemoryRequest contains parameters for creating a memory.
+type CreateMemoryRequest struct {
+	ProjectID string // Empty = a global memory shared by every project
+	ID        string // Empty = derived from Title
+	Title     string
+	Content   string
+	Tags      []string
+	Pinned    bool // Loaded with the project at session start
+}
+
+// CreateMemory creates a project or global memory.
+func (s *TaskService) CreateMemory(ctx context.Context, req CreateMemoryRequest) (*task.Memory, error) {
+	pid, err := memoryScope(req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := req.ID
+	if id == "" {
+		id = req.Title
+	}
+	memoryID := task.NewMemoryID(id)
+	if !memoryID.IsValid() {
+		return nil, task.ErrInvalidMemoryID
+	}
+
+	title := req.Title
+	if title == "" {
+		title = req.ID
+	}
+
+	m := task.NewMemory(pid, memoryID, title, req.Content)
+	m.Tags = task.NormalizeTags(req.Tags)
+	m.Pinned = req.Pinned
+
+	if err := s.repo.CreateMemory(ctx, m); err != nil {
+		s.logger.Error("failed to create memory", "project_id", pid, "memory_id", memoryID, "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("memory created", "project_id", pid, "memory_id", memoryID)
+	return m, nil
+}
+
+// GetMemory retrieves a memory; an empty projectID means a global memory.
+func (s *TaskService) GetMemory(ctx context.Context, projectID, memoryID string) (*task.Memory, error) {
+	pid, err := memoryScope(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetMemory(ctx, pid, task.NewMemoryID(memoryID))
+}
+
+// ListMemoriesRequest contains parameters for listing memories.
+type ListMemoriesRequest struct {
+	ProjectID string        // Empty = global memories
+	Tags      []string      // Filter by tags (empty = all)
+	TagMatch  task.TagMatch // How Tags are matched (empty = any)
+	Limit     int
+	Offset    int
+}
+
+// ListMemories lists the memories of a project or the global ones, pinned first.
+func (s *TaskService) ListMemories(ctx context.Context, req ListMemoriesRequest) (*task.ListResult[*task.Memory], error) {
+	pid, err := memoryScope(req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := task.ListOptions{
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+		Tags:     task.NormalizeTags(req.Tags),
+		TagMatch: req.TagMatch,
+	}
+	return s.repo.ListMemories(ctx, pid, opts)
+}
+
+// PinnedMemories returns the pinned memories of a project followed by the
+// pinned global ones, i.e. what an agent should know when it starts working
+// on the project.
+func (s *TaskService) PinnedMemories(ctx context.Context, projectID string) ([]*task.Memory, error) {
+	pid, err := memoryScope(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []task.ProjectID{pid}
+	if pid != task.GlobalScope {
+		scopes = append(scopes, task.GlobalScope)
+	}
+
+	var pinned []*task.Memory
+	for _, scope := range scopes {
+		result, err := s.repo.ListMemories(ctx, scope, task.ListOptions{Limit: math.MaxInt32})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range result.Items {
+			if m.Pinned {
+				pinned = append(pinned, m)
+			}
+		}
+	}
+	return pinned, nil
+}
+
+// UpdateMemoryRequest contains parameters for updating a memory.
+// Nil fields are left unchanged.
+type UpdateMemoryRequest struct {
+	ProjectID string // Empty = a global memory
+	ID        string
+	Title     *string
+	Content   *string
+	Tags      *[]string
+	Pinned    *bool
+}
+
+// UpdateMemory updates a memory in place. Memories keep no history.
+func (s *TaskService) UpdateMemory(ctx context.Context, req UpdateMemoryRequest) (*task.Memory, error) {
+	pid, err := memoryScope(req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.GetMemory(ctx, pid, task.NewMemoryID(req.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		m.Title = *req.Title
+	}
+	if req.Content != nil {
+		m.Content = *req.Content
+	}
+	if req.Tags != nil {
+		m.Tags = task.NormalizeTags(*req.Tags)
+	}
+	if req.Pinned != nil {
+		m.Pinned = *req.Pinned
+	}
+
+	if err := s.repo.UpdateMemory(ctx, m); err != nil {
+		s.logger.Error("failed to update memory", "project_id", pid, "memory_id", m.ID, "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("memory updated", "project_id", pid, "memory_id", m.ID)
+	return m, nil
+}
+
+// DeleteMemory removes a memory; an empty projectID means a global memory.
+func (s *TaskService) DeleteMemory(ctx context.Context, projectID, memoryID string) error {
+	pid, err := memoryScope(projectID)
+	if err != nil {
+		return err
+	}
+
+	mid := task.NewMemoryID(memoryID)
+	if err := s.repo.DeleteMemory(ctx, pid, mid); err != nil {
+		s.logger.Error("failed to delete memory", "project_id", pid, "memory_id", mid, "error", err)
+		return err
+	}
+
+	s.logger.Info("memory deleted", "project_id", pid, "memory_id", mid)
+	return nil
+}
+
+// SearchMemoriesRequest contains parameters for searching memories.
+type SearchMemoriesRequest struct {
+	Query         string        // Same syntax as SearchArtifactsRequest.Query
+	ProjectID     string        // Optional: this project's memories and the global ones
+	Tags          []string      // Filter by tags (empty = all)
+	TagMatch      task.TagMatch // How Tags are matched (empty = any)
+	ExcludeGlobal bool          // Leave out the global memories
+	Limit         int
+	Offset        int
+}
+
+// MemoryHit is a memory matched by a search, with excerpts around the matched text.
+type MemoryHit struct {
+	Memory   *task.Memory
+	Score    float64
+	Snippets []Snippet // Empty when the query has no free text
+}
+
+// SearchMemories searches memories with the search_artifacts query syntax,
+// most relevant first. Memories have no task, type or metadata, so a query
+// scoped to a task or filtering on anything but project: and tag: matches none.
+func (s *TaskService) SearchMemories(ctx context.Context, req SearchMemoriesRequest) (*task.ListResult[*MemoryHit], error) {
+	q, err := parseArtifactQuery(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := task.ListOptions{Limit: req.Limit, Offset: req.Offset}
+
+	scopeProject, scopeTask := q.scope()
+	if req.ProjectID != "" {
+		scopeProject = req.ProjectID
+	}
+	if scopeTask != "" {
+		return paginate([]*MemoryHit(nil), opts), nil
+	}
+
+	all := task.ListOptions{Limit: math.MaxInt32, Tags: task.NormalizeTags(req.Tags), TagMatch: req.TagMatch}
+	search := func(text string) ([]*task.MemorySearchResult, error) {
+		if scopeProject == "" {
+			result, err := s.repo.SearchMemories(ctx, text, nil, all)
+			if err != nil {
+				return nil, err
+			}
+			if req.ExcludeGlobal {
+				return slices.DeleteFunc(result.Items, func(r *task.MemorySearchResult) bool { return r.Memory.IsGlobal() }), nil
+			}
+			return result.Items, nil
+		}
+
+		scopes := []task.ProjectID{task.NewProjectID(scopeProject)}
+		if !req.ExcludeGlobal {
+			scopes = append(scopes, task.GlobalScope)
+		}
+		var results []*task.MemorySearchResult
+		for _, pid := range scopes {
+			result, err := s.repo.SearchMemories(ctx, text, &pid, all)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result.Items...)
+		}
+		return results, nil
+	}
+
+	candidates, err := search(q.text())
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool)
+	for _, text := range q.excludedText() {
+		hits, err := search(text)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hits {
+			excluded[memoryKey(h.Memory)] = true
+		}
+	}
+
+	terms := snippetTerms(q)
+	var hits []*MemoryHit
+	for _, r := range candidates {
+		if excluded[memoryKey(r.Memory)] || !q.matchesMemory(r.Memory) {
+			continue
+		}
+		hits = append(hits, &MemoryHit{
+			Memory:   r.Memory,
+			Score:    r.Score,
+			Snippets: buildSnippets(r.Memory.Content, terms),
+		})
+	}
+
+	// Project and global results come from separate searches
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+
+	return paginate(hits, opts), nil
+}
+
+// memoryScope converts the project ID of a memory request; empty is the global scope.
+func memoryScope(projectID string) (task.ProjectID, error) {
+	if projectID == "" {
+		return task.GlobalScope, nil
+	}
+	pid := task.NewProjectID(projectID)
+	if !pid.IsValid() {
+		return "", task.ErrInvalidProjectID
+	}
+	return pid, nil
+}
+
+func memoryKey(m *task.Memory) string {
+	return m.ProjectID.String() + "/" + m.ID.String()
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"agent-memory/internal/domain/task"
+)
+
+func TestTaskService_Memories(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "frontend"})
+
+	conventions, err := svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "backend", Title: "Coding Conventions", Content: "Wrap errors with %w", Tags: []string{"Style"}, Pinned: true})
+	if err != nil {
+		t.Fatalf("CreateMemory() error = %v", err)
+	}
+	if conventions.ID != "coding-conventions" || conventions.ProjectID != "backend" || conventions.Tags[0] != "style" {
+		t.Errorf("CreateMemory() = %+v, want coding-conventions in backend tagged style", conventions)
+	}
+	if _, err := svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "backend", ID: "architecture", Content: "Hexagonal layers, errors wrapped at the service"}); err != nil {
+		t.Fatalf("CreateMemory(architecture) error = %v", err)
+	}
+	if _, err := svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "frontend", ID: "stack", Content: "React, errors shown as toasts"}); err != nil {
+		t.Fatalf("CreateMemory(frontend) error = %v", err)
+	}
+	prefs, err := svc.CreateMemory(ctx, CreateMemoryRequest{ID: "user-preferences", Content: "Prefers small commits and errors over panics", Pinned: true})
+	if err != nil {
+		t.Fatalf("CreateMemory(global) error = %v", err)
+	}
+	if !prefs.IsGlobal() || prefs.Title != "user-preferences" {
+		t.Errorf("CreateMemory(global) = %+v, want a global memory titled by its ID", prefs)
+	}
+
+	if _, err := svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "backend", Title: "coding conventions"}); !errors.Is(err, task.ErrMemoryAlreadyExists) {
+		t.Errorf("CreateMemory(duplicate) error = %v, want ErrMemoryAlreadyExists", err)
+	}
+	if _, err := svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "backend"}); !errors.Is(err, task.ErrInvalidMemoryID) {
+		t.Errorf("CreateMemory(no id or title) error = %v, want ErrInvalidMemoryID", err)
+	}
+	if _, err := svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "missing", ID: "x"}); !errors.Is(err, task.ErrProjectNotFound) {
+		t.Errorf("CreateMemory(missing project) error = %v, want ErrProjectNotFound", err)
+	}
+
+	// Pinned memories come first
+	list, err := svc.ListMemories(ctx, ListMemoriesRequest{ProjectID: "backend"})
+	if err != nil {
+		t.Fatalf("ListMemories() error = %v", err)
+	}
+	if list.Total != 2 || list.Items[0].ID != "coding-conventions" {
+		t.Errorf("ListMemories(backend) = %d items, first %v, want 2 with the pinned one first", list.Total, list.Items[0].ID)
+	}
+	global, _ := svc.ListMemories(ctx, ListMemoriesRequest{})
+	if global.Total != 1 || global.Items[0].ID != "user-preferences" {
+		t.Errorf("ListMemories(global) total = %d, want only the global memory", global.Total)
+	}
+
+	pinned, err := svc.PinnedMemories(ctx, "backend")
+	if err != nil {
+		t.Fatalf("PinnedMemories() error = %v", err)
+	}
+	if len(pinned) != 2 || pinned[0].ID != "coding-conventions" || pinned[1].ID != "user-preferences" {
+		t.Errorf("PinnedMemories(backend) = %+v, want the project's then the global one", pinned)
+	}
+
+	// Search covers the project's memories and the global ones
+	hits, err := svc.SearchMemories(ctx, SearchMemoriesRequest{Query: "errors", ProjectID: "backend"})
+	if err != nil {
+		t.Fatalf("SearchMemories() error = %v", err)
+	}
+	if hits.Total != 3 {
+		t.Errorf("SearchMemories(errors, backend) total = %d, want 3", hits.Total)
+	}
+	if hits.Total > 0 && len(hits.Items[0].Snippets) == 0 {
+		t.Errorf("SearchMemories() hit has no snippets")
+	}
+	hits, _ = svc.SearchMemories(ctx, SearchMemoriesRequest{Query: "errors"})
+	if hits.Total != 4 {
+		t.Errorf("SearchMemories(errors) total = %d, want 4", hits.Total)
+	}
+	hits, _ = svc.SearchMemories(ctx, SearchMemoriesRequest{Query: "errors tag:style -commits"})
+	if hits.Total != 1 || hits.Items[0].Memory.ID != "coding-conventions" {
+		t.Errorf("SearchMemories(errors tag:style -commits) total = %d, want the conventions", hits.Total)
+	}
+	for _, query := range []string{"errors type:decision", "errors task:auth"} {
+		if hits, _ := svc.SearchMemories(ctx, SearchMemoriesRequest{Query: query}); hits.Total != 0 {
+			t.Errorf("SearchMemories(%s) total = %d, want 0", query, hits.Total)
+		}
+	}
+
+	// Memories stay out of artifact search
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth"})
+	svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: task.ArtifactTypeNote, Content: "errors from the token endpoint"})
+	artifacts, _ := svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: "errors"})
+	if artifacts.Total != 1 {
+		t.Errorf("SearchArtifacts(errors) total = %d, want only the artifact", artifacts.Total)
+	}
+
+	content := "Wrap errors with %w, never panic"
+	unpin := false
+	updated, err := svc.UpdateMemory(ctx, UpdateMemoryRequest{ProjectID: "backend", ID: "coding-conventions", Content: &content, Pinned: &unpin})
+	if err != nil {
+		t.Fatalf("UpdateMemory() error = %v", err)
+	}
+	if updated.Content != content || updated.Pinned || updated.Title != "Coding Conventions" {
+		t.Errorf("UpdateMemory() = %+v, want new content, unpinned, same title", updated)
+	}
+	got, _ := svc.GetMemory(ctx, "backend", "coding-conventions")
+	if got.Content != content || got.Pinned {
+		t.Errorf("GetMemory() after update = %+v", got)
+	}
+	if hits, _ := svc.SearchMemories(ctx, SearchMemoriesRequest{Query: "panic", ProjectID: "backend"}); hits.Total != 1 {
+		t.Errorf("SearchMemories(panic) total = %d, want the updated memory", hits.Total)
+	}
+
+	if err := svc.DeleteMemory(ctx, "", "user-preferences"); err != nil {
+		t.Fatalf("DeleteMemory() error = %v", err)
+	}
+	if _, err := svc.GetMemory(ctx, "", "user-preferences"); !errors.Is(err, task.ErrMemoryNotFound) {
+		t.Errorf("GetMemory(deleted) error = %v, want ErrMemoryNotFound", err)
+	}
+	if err := svc.DeleteMemory(ctx, "", "user-preferences"); !errors.Is(err, task.ErrMemoryNotFound) {
+		t.Errorf("DeleteMemory(again) error = %v, want ErrMemoryNotFound", err)
+	}
+
+	// Memories go with their project
+	if err := svc.DeleteProject(ctx, "frontend"); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if hits, _ := svc.SearchMemories(ctx, SearchMemoriesRequest{Query: "react"}); hits.Total != 0 {
+		t.Errorf("SearchMemories(react) after deleting the project total = %d, want 0", hits.Total)
+	}
+}
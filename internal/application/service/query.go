@@ -0,0 +1,343 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"agent-memory/internal/domain/task"
+)
+
+// Query fields understood by search_artifacts. Any other "word:value" is
+// treated as free text, so queries like "error: connection refused" still work.
+const (
+	fieldType    = "type"
+	fieldProject = "project"
+	fieldTask    = "task"
+	fieldStatus  = "status"
+	fieldTag     = "tag"
+	fieldCreated = "created"
+	fieldMeta    = "meta."
+)
+
+// queryClause is a node of the query AST. A query is the conjunction of its
+// clauses; each clause can be negated with a leading "-".
+type queryClause struct {
+	Negate bool
+	Field  string   // "" for free text, otherwise one of the field* constants (meta fields keep their key: "meta.file_path")
+	Values []string // Alternatives, any of which may match (type:note,decision)
+	Op     string   // Comparison for created: "=", ">", ">=", "<", "<="
+	Text   string   // Free text, passed to the full-text index as written
+
+	from, to time.Time // created: the half-open interval [from, to) named by the value
+	pattern  *regexp.Regexp
+}
+
+// artifactQuery is a parsed search_artifacts query.
+type artifactQuery struct {
+	Clauses []queryClause
+}
+
+// parseArtifactQuery parses a query such as
+//
+//	type:decision project:backend created:>2026-01-01 status:in_progress "exact phrase" -excluded meta.file_path:*.go
+//
+// Free text (words, "phrases", prefix*) goes to the full-text index; field
+// clauses filter on the artifact, its task and its metadata.
+func parseArtifactQuery(input string) (*artifactQuery, error) {
+	q := &artifactQuery{}
+
+	for _, word := range splitQuery(input) {
+		c := queryClause{}
+		if len(word) > 1 && word[0] == '-' {
+			c.Negate = true
+			word = word[1:]
+		}
+
+		name, value, ok := strings.Cut(word, ":")
+		field := queryField(name)
+		if !ok || word[0] == '"' || !isQueryField(field) {
+			c.Text = word
+			q.Clauses = append(q.Clauses, c)
+			continue
+		}
+
+		c.Field = field
+		if err := c.parseValue(unquote(value)); err != nil {
+			return nil, err
+		}
+		q.Clauses = append(q.Clauses, c)
+	}
+
+	return q, nil
+}
+
+func (c *queryClause) parseValue(value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: %s: needs a value", task.ErrInvalidQuery, c.Field)
+	}
+
+	switch {
+	case c.Field == fieldCreated:
+		c.Op = "="
+		for _, op := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(value, op) {
+				c.Op = op
+				value = value[len(op):]
+				break
+			}
+		}
+
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			c.from, c.to = t, t.AddDate(0, 0, 1)
+		} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+			c.from, c.to = t, t.Add(time.Nanosecond)
+		} else {
+			return fmt.Errorf("%w: created: expects YYYY-MM-DD or RFC 3339, got %q", task.ErrInvalidQuery, value)
+		}
+		c.Values = []string{value}
+
+	case strings.HasPrefix(c.Field, fieldMeta):
+		if c.Field == fieldMeta {
+			return fmt.Errorf("%w: meta. needs a key, e.g. meta.file_path:*.go", task.ErrInvalidQuery)
+		}
+		c.Values = []string{value}
+		c.pattern = globPattern(value)
+
+	default:
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			switch c.Field {
+			case fieldProject:
+				v = task.NewProjectID(v).String()
+			case fieldTask:
+				v = task.NewTaskID(v).String()
+			default:
+				v = strings.ToLower(v)
+			}
+			if v != "" {
+				c.Values = append(c.Values, v)
+			}
+		}
+		if len(c.Values) == 0 {
+			return fmt.Errorf("%w: %s: needs a value", task.ErrInvalidQuery, c.Field)
+		}
+	}
+
+	return nil
+}
+
+// text returns the positive free text of the query for the full-text index.
+func (q *artifactQuery) text() string {
+	var parts []string
+	for _, c := range q.Clauses {
+		if c.Field == "" && !c.Negate {
+			parts = append(parts, c.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// excludedText returns the free text of negated clauses, one entry per clause.
+func (q *artifactQuery) excludedText() []string {
+	var texts []string
+	for _, c := range q.Clauses {
+		if c.Field == "" && c.Negate {
+			texts = append(texts, c.Text)
+		}
+	}
+	return texts
+}
+
+// hasFilters reports whether the query needs more than the full-text index.
+func (q *artifactQuery) hasFilters() bool {
+	for _, c := range q.Clauses {
+		if c.Field != "" || c.Negate {
+			return true
+		}
+	}
+	return false
+}
+
+// usesStatus reports whether evaluating the query needs the owning task.
+func (q *artifactQuery) usesStatus() bool {
+	for _, c := range q.Clauses {
+		if c.Field == fieldStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// scope returns the single project and task the query is restricted to, if any,
+// so the full-text search can be narrowed before filtering.
+func (q *artifactQuery) scope() (projectID, taskID string) {
+	for _, c := range q.Clauses {
+		if c.Negate || len(c.Values) != 1 {
+			continue
+		}
+		switch c.Field {
+		case fieldProject:
+			projectID = c.Values[0]
+		case fieldTask:
+			taskID = c.Values[0]
+		}
+	}
+	return projectID, taskID
+}
+
+// matches evaluates the field clauses against an artifact and, for status:,
+// its task (nil when not needed). Free-text clauses are handled by the index.
+func (q *artifactQuery) matches(a *task.Artifact, t *task.Task) bool {
+	for _, c := range q.Clauses {
+		if c.Field == "" {
+			continue
+		}
+		if c.matches(a, t) == c.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMemory evaluates the field clauses against a memory. Only project:
+// and tag: apply to memories; any other field clause excludes them. Global
+// memories belong to every project.
+func (q *artifactQuery) matchesMemory(m *task.Memory) bool {
+	for _, c := range q.Clauses {
+		var ok bool
+		switch c.Field {
+		case "":
+			continue
+		case fieldProject:
+			ok = m.IsGlobal() || c.oneOf(m.ProjectID.String())
+		case fieldTag:
+			ok = slices.ContainsFunc(m.Tags, c.oneOf)
+		default:
+			return false
+		}
+		if ok == c.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *queryClause) matches(a *task.Artifact, t *task.Task) bool {
+	switch {
+	case c.Field == fieldType:
+		return c.oneOf(string(a.Type))
+	case c.Field == fieldProject:
+		return c.oneOf(a.ProjectID.String())
+	case c.Field == fieldTask:
+		return c.oneOf(a.TaskID.String())
+	case c.Field == fieldStatus:
+		return t != nil && c.oneOf(string(t.Status))
+	case c.Field == fieldTag:
+		for _, tag := range a.Tags {
+			if c.oneOf(tag) {
+				return true
+			}
+		}
+		return false
+	case c.Field == fieldCreated:
+		switch c.Op {
+		case ">":
+			return !a.CreatedAt.Before(c.to)
+		case ">=":
+			return !a.CreatedAt.Before(c.from)
+		case "<":
+			return a.CreatedAt.Before(c.from)
+		case "<=":
+			return a.CreatedAt.Before(c.to)
+		default:
+			return !a.CreatedAt.Before(c.from) && a.CreatedAt.Before(c.to)
+		}
+	case strings.HasPrefix(c.Field, fieldMeta):
+		v, ok := a.Metadata[strings.TrimPrefix(c.Field, fieldMeta)]
+		return ok && c.pattern.MatchString(v)
+	}
+	return false
+}
+
+func (c *queryClause) oneOf(v string) bool {
+	for _, want := range c.Values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// queryField lowercases a field name, except the key of a meta field:
+// metadata keys are matched as stored.
+func queryField(name string) string {
+	if len(name) > len(fieldMeta) && strings.EqualFold(name[:len(fieldMeta)], fieldMeta) {
+		return fieldMeta + name[len(fieldMeta):]
+	}
+	return strings.ToLower(name)
+}
+
+func isQueryField(field string) bool {
+	switch field {
+	case fieldType, fieldProject, fieldTask, fieldStatus, fieldTag, fieldCreated:
+		return true
+	}
+	return strings.HasPrefix(field, fieldMeta)
+}
+
+// splitQuery splits a query on whitespace outside double quotes.
+func splitQuery(input string) []string {
+	var words []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				words = append(words, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		words = append(words, current.String())
+	}
+
+	return words
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return strings.TrimPrefix(s, `"`)
+}
+
+// globPattern compiles a case-insensitive glob where * matches any run of
+// characters (including "/") and ? matches one character.
+func globPattern(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+func TestParseArtifactQuery(t *testing.T) {
+	q, err := parseArtifactQuery(`type:decision,Note project:Backend created:>2026-01-01 status:in_progress "exact phrase" -excluded meta.file_path:*.go error: auth*`)
+	if err != nil {
+		t.Fatalf("parseArtifactQuery() error = %v", err)
+	}
+
+	type clause struct {
+		Negate bool
+		Field  string
+		Values []string
+		Op     string
+		Text   string
+	}
+	var got []clause
+	for _, c := range q.Clauses {
+		got = append(got, clause{c.Negate, c.Field, c.Values, c.Op, c.Text})
+	}
+
+	want := []clause{
+		{Field: "type", Values: []string{"decision", "note"}},
+		{Field: "project", Values: []string{"backend"}},
+		{Field: "created", Values: []string{"2026-01-01"}, Op: ">"},
+		{Field: "status", Values: []string{"in_progress"}},
+		{Text: `"exact phrase"`},
+		{Negate: true, Text: "excluded"},
+		{Field: "meta.file_path", Values: []string{"*.go"}},
+		{Text: "error:"},
+		{Text: "auth*"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseArtifactQuery() clauses =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if text := q.text(); text != `"exact phrase" error: auth*` {
+		t.Errorf("text() = %q", text)
+	}
+	if excluded := q.excludedText(); !reflect.DeepEqual(excluded, []string{"excluded"}) {
+		t.Errorf("excludedText() = %v", excluded)
+	}
+	if pid, tid := q.scope(); pid != "backend" || tid != "" {
+		t.Errorf("scope() = %q, %q, want backend, \"\"", pid, tid)
+	}
+}
+
+func TestParseArtifactQuery_Errors(t *testing.T) {
+	tests := []string{
+		"type:",
+		"created:yesterday",
+		"created:>2026-13-01",
+		"meta.:x",
+		"status:,",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := parseArtifactQuery(input)
+			if !errors.Is(err, task.ErrInvalidQuery) {
+				t.Errorf("parseArtifactQuery(%q) error = %v, want ErrInvalidQuery", input, err)
+			}
+		})
+	}
+}
+
+func TestArtifactQuery_Matches(t *testing.T) {
+	a := &task.Artifact{
+		ID:        "1",
+		ProjectID: "backend",
+		TaskID:    "fix-login",
+		Type:      task.ArtifactTypeDecision,
+		Tags:      []string{"auth", "security"},
+		Metadata:  map[string]string{"file_path": "/src/Auth/main.go", "reviewer": "alice", "Owner": "bob"},
+		CreatedAt: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+	inProgress := &task.Task{Status: task.TaskStatusInProgress}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "type:decision", want: true},
+		{query: "type:note,decision", want: true},
+		{query: "-type:decision", want: false},
+		{query: "project:backend task:fix-login", want: true},
+		{query: "project:frontend", want: false},
+		{query: "status:in_progress", want: true},
+		{query: "status:completed", want: false},
+		{query: "tag:ui,auth", want: true},
+		{query: "tag:auth tag:security", want: true},
+		{query: "tag:ui", want: false},
+		{query: "-tag:security", want: false},
+		{query: "created:>2026-01-01", want: true},
+		{query: "created:>2026-01-15", want: false},
+		{query: "created:>=2026-01-15", want: true},
+		{query: "created:2026-01-15", want: true},
+		{query: "created:<2026-01-15", want: false},
+		{query: "created:<=2026-01-15", want: true},
+		{query: "created:<2026-01-15T12:00:01Z", want: true},
+		{query: "meta.file_path:*.go", want: true},
+		{query: "meta.file_path:*auth*", want: true},
+		{query: "meta.file_path:*.ts", want: false},
+		{query: "meta.reviewer:*", want: true},
+		{query: "meta.missing:*", want: false},
+		{query: "-meta.missing:*", want: true},
+		{query: `meta.reviewer:"alice"`, want: true},
+		{query: "meta.Owner:bob", want: true},
+		{query: "META.Owner:bob", want: true},
+		{query: "meta.owner:bob", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseArtifactQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseArtifactQuery() error = %v", err)
+			}
+			if got := q.matches(a, inProgress); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
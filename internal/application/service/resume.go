@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+// Token budgeting for ResumeTask. Tokens are estimated at four bytes each,
+// which is close enough for English text and code (and errs on the large side
+// for anything else) without shipping a tokenizer.
+const (
+	defaultResumeBudget = 4000
+	minResumeBudget     = 500
+	bytesPerToken       = 4
+	minExcerptTokens    = 50 // Smaller leftovers are not worth an excerpt
+	maxItemShare        = 4  // One item gets at most a quarter of the budget
+	resumeRecencyScale  = 72 * time.Hour
+)
+
+// ContextSection groups the items of a ContextPack.
+type ContextSection string
+
+const (
+	SectionMemory   ContextSection = "memory"
+	SectionDecision ContextSection = "decision"
+	SectionQuestion ContextSection = "question"
+	SectionNote     ContextSection = "note"
+	SectionOther    ContextSection = "other"
+)
+
+// Weights ranking the items of a context pack before recency is applied.
+// Pinned memories come first, then decisions, open questions and notes;
+// operation logs come last.
+var artifactWeights = map[task.ArtifactType]float64{
+	task.ArtifactTypeDecision:   1.0,
+	task.ArtifactTypeNote:       0.7,
+	task.ArtifactTypeSummary:    0.6,
+	task.ArtifactTypeCode:       0.5,
+	task.ArtifactTypeDiscussion: 0.5,
+	task.ArtifactTypeReference:  0.4,
+	task.ArtifactTypeSearch:     0.2,
+	task.ArtifactTypeFileRead:   0.1,
+	task.ArtifactTypeFileList:   0.1,
+}
+
+const (
+	memoryWeight          = 1.1
+	questionWeight        = 0.9
+	defaultArtifactWeight = 0.3
+)
+
+// questionTags mark an artifact as an open question. Superseding it, e.g.
+// with the decision that answers it, closes it.
+var questionTags = []string{"question", "open-question"}
+
+// ResumeTaskRequest contains parameters for building a context pack.
+type ResumeTaskRequest struct {
+	ProjectID             string
+	TaskID                string
+	TokenBudget           int  // Approximate size of the pack (0 = default 4000, at least 500)
+	ExcludeGlobalMemories bool // Leave out the pinned global memories
+}
+
+// ContextPack is what an agent needs to pick up a task, cut to a token budget.
+type ContextPack struct {
+	Task       *task.Task
+	Project    *task.Project
+	Items      []*ContextItem // Included, best first
+	Omitted    []*ContextItem // Left out to stay within the budget, best first, without Content
+	Budget     int
+	UsedTokens int // Estimated size of the task, project and included content
+}
+
+// ContextItem is a pinned memory or an artifact in a context pack.
+type ContextItem struct {
+	Section   ContextSection
+	Memory    *task.Memory   // Set for SectionMemory
+	Artifact  *task.Artifact // Set for the other sections
+	Score     float64        // Section weight scaled by recency
+	Content   string         // The full content, or its beginning when Truncated
+	Truncated bool
+	Tokens    int // Estimated size of the full content
+}
+
+// Section returns the included items of one section, best first.
+func (p *ContextPack) Section(section ContextSection) []*ContextItem {
+	var items []*ContextItem
+	for _, item := range p.Items {
+		if item.Section == section {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ResumeTask gathers the task, its project, the pinned memories and the
+// task's artifacts in one call. Items are ranked by section weight and
+// recency and added best first; an item bigger than a quarter of the budget
+// or than what is left is cut short, and once too little is left the rest
+// is reported in Omitted so the agent can fetch what it needs. Superseded
+// artifacts are left out altogether.
+func (s *TaskService) ResumeTask(ctx context.Context, req ResumeTaskRequest) (*ContextPack, error) {
+	t, err := s.GetTask(ctx, req.ProjectID, req.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.repo.GetProject(ctx, t.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	memories, err := s.PinnedMemories(ctx, t.ProjectID.String())
+	if err != nil {
+		return nil, err
+	}
+	if req.ExcludeGlobalMemories {
+		memories = slices.DeleteFunc(memories, (*task.Memory).IsGlobal)
+	}
+
+	artifacts, err := s.repo.ListArtifacts(ctx, t.ProjectID, t.ID, task.ListOptions{Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+
+	budget := req.TokenBudget
+	if budget <= 0 {
+		budget = defaultResumeBudget
+	}
+	budget = max(budget, minResumeBudget)
+
+	pack := &ContextPack{
+		Task:       t,
+		Project:    p,
+		Budget:     budget,
+		UsedTokens: estimateTokens(t.Name + t.Description + p.Name + p.Description),
+	}
+	for _, item := range rankContext(memories, artifacts.Items) {
+		pack.add(item)
+	}
+
+	return pack, nil
+}
+
+// add includes as much of an item as the budget allows, or omits it.
+func (p *ContextPack) add(item *ContextItem) {
+	var content string
+	if item.Memory != nil {
+		content = item.Memory.Content
+	} else {
+		content = item.Artifact.Content
+	}
+
+	limit := min(p.Budget-p.UsedTokens, p.Budget/maxItemShare)
+	switch {
+	case item.Tokens <= limit:
+		item.Content = content
+	case limit >= minExcerptTokens:
+		item.Content = headOf(content, limit)
+		item.Truncated = true
+	default:
+		p.Omitted = append(p.Omitted, item)
+		return
+	}
+
+	p.UsedTokens += estimateTokens(item.Content)
+	p.Items = append(p.Items, item)
+}
+
+// rankContext orders the pinned memories and artifacts of a task by weight
+// times recency. Recency is measured from the task's latest artifact, so
+// a task left alone for a month ranks the same when it is picked up again.
+func rankContext(memories []*task.Memory, artifacts []*task.Artifact) []*ContextItem {
+	items := make([]*ContextItem, 0, len(memories)+len(artifacts))
+	for _, m := range memories {
+		items = append(items, &ContextItem{
+			Section: SectionMemory,
+			Memory:  m,
+			Score:   memoryWeight,
+			Tokens:  estimateTokens(m.Content),
+		})
+	}
+
+	var latest time.Time
+	for _, a := range artifacts {
+		if written := lastWritten(a); written.After(latest) {
+			latest = written
+		}
+	}
+
+	for _, a := range artifacts {
+		section, weight := classifyArtifact(a)
+		age := latest.Sub(lastWritten(a))
+		items = append(items, &ContextItem{
+			Section:  section,
+			Artifact: a,
+			Score:    weight / (1 + age.Hours()/resumeRecencyScale.Hours()),
+			Tokens:   estimateTokens(a.Content),
+		})
+	}
+
+	// Stable, so memories win ties and keep their pinned order
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+	return items
+}
+
+// classifyArtifact returns the section of an artifact and its weight.
+func classifyArtifact(a *task.Artifact) (ContextSection, float64) {
+	if slices.ContainsFunc(a.Tags, func(tag string) bool { return slices.Contains(questionTags, tag) }) {
+		return SectionQuestion, questionWeight
+	}
+
+	weight, ok := artifactWeights[a.Type]
+	if !ok {
+		weight = defaultArtifactWeight
+	}
+	switch a.Type {
+	case task.ArtifactTypeDecision:
+		return SectionDecision, weight
+	case task.ArtifactTypeNote:
+		return SectionNote, weight
+	}
+	return SectionOther, weight
+}
+
+// lastWritten returns when the current version of an artifact was written.
+func lastWritten(a *task.Artifact) time.Time {
+	if a.UpdatedAt != nil {
+		return *a.UpdatedAt
+	}
+	return a.CreatedAt
+}
+
+// estimateTokens approximates the number of tokens in s.
+func estimateTokens(s string) int {
+	return (len(s) + bytesPerToken - 1) / bytesPerToken
+}
+
+// headOf returns the beginning of content that fits in about tokens,
+// cut at a line break when one is reasonably close to the limit.
+func headOf(content string, tokens int) string {
+	limit := tokens * bytesPerToken
+	if len(content) <= limit {
+		return content
+	}
+
+	cut := content[:limit]
+	if i := strings.LastIndexByte(cut, '\n'); i > limit/2 {
+		cut = cut[:i]
+	}
+	// Cutting inside a multi-byte character leaves an invalid tail
+	return strings.ToValidUTF8(cut, "")
+}
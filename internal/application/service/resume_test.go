@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"agent-memory/internal/domain/task"
+)
+
+func TestTaskService_ResumeTask(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend", Description: "Billing API"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "auth", Description: "Token auth"})
+	svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "backend", ID: "conventions", Content: "Wrap errors with %w", Pinned: true})
+	svc.CreateMemory(ctx, CreateMemoryRequest{ProjectID: "backend", ID: "scratch", Content: "Not pinned"})
+
+	save := func(artifactType task.ArtifactType, content string, tags ...string) *task.Artifact {
+		t.Helper()
+		a, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "auth", Type: artifactType, Content: content, Tags: tags})
+		if err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+		return a
+	}
+
+	for i := 0; i < 10; i++ {
+		save(task.ArtifactTypeFileRead, strings.Repeat("file contents ", 100))
+	}
+	answered := save(task.ArtifactTypeNote, "Which signing algorithm?", "question")
+	decision := save(task.ArtifactTypeDecision, "Use RS256")
+	save(task.ArtifactTypeNote, "Open: how long do refresh tokens live?", "open-question")
+	save(task.ArtifactTypeNote, "Login endpoint done")
+	code := save(task.ArtifactTypeCode, strings.Repeat("func handler() {}\n", 2000))
+	svc.LinkArtifact(ctx, LinkArtifactRequest{ProjectID: "backend", TaskID: "auth", ArtifactID: decision.ID, Relation: task.RelationSupersedes, TargetArtifactID: answered.ID})
+
+	pack, err := svc.ResumeTask(ctx, ResumeTaskRequest{ProjectID: "backend", TaskID: "auth"})
+	if err != nil {
+		t.Fatalf("ResumeTask() error = %v", err)
+	}
+	if pack.Task.ID != "auth" || pack.Project.Description != "Billing API" || pack.Budget != defaultResumeBudget {
+		t.Errorf("ResumeTask() = task %s, project %+v, budget %d", pack.Task.ID, pack.Project, pack.Budget)
+	}
+	if pack.UsedTokens > pack.Budget {
+		t.Errorf("ResumeTask() used %d tokens, budget %d", pack.UsedTokens, pack.Budget)
+	}
+
+	// Pinned memory, then the decision, the open question and the notes
+	wantOrder := []ContextSection{SectionMemory, SectionDecision, SectionQuestion, SectionNote}
+	for i, want := range wantOrder {
+		if pack.Items[i].Section != want {
+			t.Errorf("ResumeTask() item %d section = %s, want %s", i, pack.Items[i].Section, want)
+		}
+	}
+	if mem := pack.Section(SectionMemory); len(mem) != 1 || mem[0].Memory.ID != "conventions" {
+		t.Errorf("ResumeTask() memories = %v, want only the pinned one", mem)
+	}
+	if q := pack.Section(SectionQuestion); len(q) != 1 || !strings.Contains(q[0].Content, "refresh tokens") {
+		t.Errorf("ResumeTask() questions = %v, want only the open one", q)
+	}
+
+	// The code artifact is cut to a quarter of the budget
+	var codeItem *ContextItem
+	for _, item := range pack.Items {
+		if item.Artifact != nil && item.Artifact.ID == code.ID {
+			codeItem = item
+		}
+	}
+	if codeItem == nil || !codeItem.Truncated || estimateTokens(codeItem.Content) > pack.Budget/maxItemShare {
+		t.Errorf("ResumeTask() code item = %+v, want it truncated to a quarter of the budget", codeItem)
+	}
+
+	// File logs rank last and do not all fit
+	if len(pack.Omitted) == 0 {
+		t.Fatalf("ResumeTask() omitted nothing, want some file logs left out")
+	}
+	for _, item := range pack.Omitted {
+		if item.Artifact == nil || item.Artifact.Type != task.ArtifactTypeFileRead || item.Content != "" || item.Tokens == 0 {
+			t.Errorf("ResumeTask() omitted %+v, want file logs with their size and no content", item)
+		}
+	}
+
+	// A small budget keeps the best items
+	small, err := svc.ResumeTask(ctx, ResumeTaskRequest{ProjectID: "backend", TaskID: "auth", TokenBudget: 10})
+	if err != nil {
+		t.Fatalf("ResumeTask(small) error = %v", err)
+	}
+	if small.Budget != minResumeBudget || small.UsedTokens > small.Budget {
+		t.Errorf("ResumeTask(small) budget = %d, used %d, want %d at most", small.Budget, small.UsedTokens, minResumeBudget)
+	}
+	if len(small.Omitted) <= len(pack.Omitted) || small.Items[0].Section != SectionMemory {
+		t.Errorf("ResumeTask(small) omitted %d items, first %s", len(small.Omitted), small.Items[0].Section)
+	}
+
+	if _, err := svc.ResumeTask(ctx, ResumeTaskRequest{ProjectID: "backend", TaskID: "missing"}); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("ResumeTask(missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestHeadOf(t *testing.T) {
+	if got := headOf("short", 10); got != "short" {
+		t.Errorf("headOf(short) = %q", got)
+	}
+	if got := headOf("first line\nsecond line", 3); got != "first line" {
+		t.Errorf("headOf() = %q, want the first line", got)
+	}
+	if got := headOf(strings.Repeat("é", 10), 1); !utf8.ValidString(got) || got != "éé" {
+		t.Errorf("headOf() = %q, want whole characters", got)
+	}
+}
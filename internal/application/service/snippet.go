@@ -0,0 +1,221 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxSnippets is the number of snippets returned per search hit.
+	maxSnippets = 3
+
+	// snippetWidth is the maximum snippet length in bytes. Longer lines are
+	// cut to a window centered on the first match.
+	snippetWidth = 200
+)
+
+// Highlight is a matched range in a snippet, as byte offsets into its text.
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"` // Exclusive
+}
+
+// Snippet is an excerpt of one line around one or more matches.
+type Snippet struct {
+	Line       int         `json:"line"`   // 1-based line number
+	Offset     int         `json:"offset"` // Byte offset of Text in the whole content
+	Text       string      `json:"text"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// span is a byte range in a text.
+type span struct {
+	start, end int
+}
+
+// snippetTerm is a word or phrase to highlight.
+type snippetTerm struct {
+	words  []string // Lowercase words that must appear consecutively
+	prefix bool     // The last word only needs to be a prefix of the token
+}
+
+// snippetTerms extracts the positive free-text terms of a query.
+func snippetTerms(q *artifactQuery) []snippetTerm {
+	var terms []snippetTerm
+	for _, c := range q.Clauses {
+		if c.Field != "" || c.Negate {
+			continue
+		}
+
+		text := strings.Trim(c.Text, `"`)
+		prefix := strings.HasSuffix(c.Text, "*")
+		text = strings.TrimRight(text, `*"`)
+
+		var words []string
+		for _, s := range wordSpans(text) {
+			words = append(words, strings.ToLower(text[s.start:s.end]))
+		}
+		if len(words) > 0 {
+			terms = append(terms, snippetTerm{words: words, prefix: prefix})
+		}
+	}
+	return terms
+}
+
+// buildSnippets returns up to maxSnippets excerpts of content around the
+// places where terms match, using the same word boundaries as the search index.
+func buildSnippets(content string, terms []snippetTerm) []Snippet {
+	if len(terms) == 0 {
+		return []Snippet{}
+	}
+
+	tokens := wordSpans(content)
+	lower := make([]string, len(tokens))
+	for i, t := range tokens {
+		lower[i] = strings.ToLower(content[t.start:t.end])
+	}
+
+	var matches []span
+	for i := range tokens {
+		for _, term := range terms {
+			if n := len(term.words); i+n <= len(tokens) && termMatchesAt(lower[i:i+n], term) {
+				matches = append(matches, span{tokens[i].start, tokens[i+n-1].end})
+			}
+		}
+	}
+
+	return snippetsFor(content, matches)
+}
+
+// substringMatches returns every non-overlapping occurrence of query in line.
+func substringMatches(line, query string, ignoreCase bool) []span {
+	if query == "" {
+		return nil
+	}
+
+	var matches []span
+	for i := 0; i+len(query) <= len(line); {
+		candidate := line[i : i+len(query)]
+		if candidate == query || (ignoreCase && strings.EqualFold(candidate, query)) {
+			matches = append(matches, span{i, i + len(query)})
+			i += len(query)
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+	}
+	return matches
+}
+
+func termMatchesAt(tokens []string, term snippetTerm) bool {
+	last := len(term.words) - 1
+	for i, w := range term.words {
+		if i == last && term.prefix {
+			if !strings.HasPrefix(tokens[i], w) {
+				return false
+			}
+			continue
+		}
+		if tokens[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// snippetsFor groups matches into per-line snippets.
+func snippetsFor(content string, matches []span) []Snippet {
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	snippets := []Snippet{}
+	for _, m := range matches {
+		if n := len(snippets); n > 0 {
+			last := &snippets[n-1]
+			if m.start < last.Offset+len(last.Text) {
+				last.addHighlight(m)
+				continue
+			}
+		}
+		if len(snippets) == maxSnippets {
+			break
+		}
+
+		lineStart := strings.LastIndexByte(content[:m.start], '\n') + 1
+		lineEnd := len(content)
+		if i := strings.IndexByte(content[m.start:], '\n'); i != -1 {
+			lineEnd = m.start + i
+		}
+
+		window := excerpt(content, span{lineStart, lineEnd}, m)
+		s := Snippet{
+			Line:   strings.Count(content[:lineStart], "\n") + 1,
+			Offset: window.start,
+			Text:   content[window.start:window.end],
+		}
+		s.addHighlight(m)
+		snippets = append(snippets, s)
+	}
+
+	return snippets
+}
+
+// addHighlight records a match, clipped to the snippet and merged with an
+// overlapping previous highlight (e.g. a phrase and one of its words).
+func (s *Snippet) addHighlight(m span) {
+	start := max(m.start-s.Offset, 0)
+	end := min(m.end-s.Offset, len(s.Text))
+	if end <= start {
+		return
+	}
+
+	if n := len(s.Highlights); n > 0 && start <= s.Highlights[n-1].End {
+		s.Highlights[n-1].End = max(s.Highlights[n-1].End, end)
+		return
+	}
+	s.Highlights = append(s.Highlights, Highlight{Start: start, End: end})
+}
+
+// excerpt returns the part of line, at most snippetWidth bytes, centered on
+// match and cut at rune boundaries.
+func excerpt(text string, line, match span) span {
+	if line.end-line.start <= snippetWidth {
+		return line
+	}
+
+	size := match.end - match.start
+	start := max(line.start, match.start-(snippetWidth-size)/2)
+	end := min(line.end, start+snippetWidth)
+	start = max(line.start, end-snippetWidth)
+
+	for start < match.start && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end > match.end && end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return span{start, end}
+}
+
+// wordSpans returns the byte ranges of the runs of letters and digits in text.
+func wordSpans(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
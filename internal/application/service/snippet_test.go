@@ -0,0 +1,114 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func highlighted(s Snippet) []string {
+	var out []string
+	for _, h := range s.Highlights {
+		out = append(out, s.Text[h.Start:h.End])
+	}
+	return out
+}
+
+func TestBuildSnippets(t *testing.T) {
+	content := "# Notes\n\nFirst line mentions nothing.\nThe Token refresh failed, token expired.\nUnrelated.\nrefresh again"
+
+	q, err := parseArtifactQuery(`"token refresh" expir* -nothing type:note`)
+	if err != nil {
+		t.Fatalf("parseArtifactQuery() error = %v", err)
+	}
+
+	snippets := buildSnippets(content, snippetTerms(q))
+	if len(snippets) != 1 {
+		t.Fatalf("buildSnippets() returned %d snippets, want 1: %+v", len(snippets), snippets)
+	}
+
+	s := snippets[0]
+	if s.Line != 4 {
+		t.Errorf("Snippet.Line = %d, want 4", s.Line)
+	}
+	if s.Text != "The Token refresh failed, token expired." {
+		t.Errorf("Snippet.Text = %q", s.Text)
+	}
+	if content[s.Offset:s.Offset+len(s.Text)] != s.Text {
+		t.Errorf("Snippet.Offset = %d does not point at the snippet text", s.Offset)
+	}
+
+	got := highlighted(s)
+	want := []string{"Token refresh", "expired"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("highlights = %q, want %q", got, want)
+	}
+}
+
+func TestBuildSnippets_Limits(t *testing.T) {
+	// Matches on many lines are capped at maxSnippets
+	content := strings.Repeat("needle\n", 10)
+	snippets := buildSnippets(content, []snippetTerm{{words: []string{"needle"}}})
+	if len(snippets) != maxSnippets {
+		t.Errorf("buildSnippets() returned %d snippets, want %d", len(snippets), maxSnippets)
+	}
+	if snippets[2].Line != 3 || snippets[2].Offset != 14 {
+		t.Errorf("third snippet Line = %d, Offset = %d, want 3, 14", snippets[2].Line, snippets[2].Offset)
+	}
+
+	// Long lines are windowed around the match
+	long := strings.Repeat("ä", 300) + " needle " + strings.Repeat("ö", 300)
+	snippets = buildSnippets(long, []snippetTerm{{words: []string{"needle"}}})
+	if len(snippets) != 1 {
+		t.Fatalf("buildSnippets() long line returned %d snippets, want 1", len(snippets))
+	}
+	s := snippets[0]
+	if len(s.Text) > snippetWidth {
+		t.Errorf("Snippet.Text is %d bytes, want at most %d", len(s.Text), snippetWidth)
+	}
+	if got := highlighted(s); len(got) != 1 || got[0] != "needle" {
+		t.Errorf("highlights = %q, want [needle]", got)
+	}
+	if long[s.Offset:s.Offset+len(s.Text)] != s.Text {
+		t.Error("Snippet.Offset does not point at the snippet text")
+	}
+
+	// A match longer than a snippet is clipped to it on both sides
+	phrase := strings.Fields(strings.Repeat("lorem ", 50))
+	long = "intro " + strings.Join(phrase, " ") + " outro"
+	snippets = buildSnippets(long, []snippetTerm{{words: phrase}})
+	if len(snippets) != 1 {
+		t.Fatalf("buildSnippets() long match returned %d snippets, want 1", len(snippets))
+	}
+	s = snippets[0]
+	if len(s.Highlights) != 1 || s.Highlights[0] != (Highlight{Start: 0, End: len(s.Text)}) {
+		t.Errorf("long match Highlights = %+v, want all of the %d bytes", s.Highlights, len(s.Text))
+	}
+
+	// No free text, no snippets
+	if snippets := buildSnippets(content, nil); len(snippets) != 0 {
+		t.Errorf("buildSnippets() without terms = %+v, want none", snippets)
+	}
+}
+
+func TestLineMatch(t *testing.T) {
+	line := strings.Repeat("x", 250) + " World and world " + strings.Repeat("y", 250)
+
+	m := lineMatch("a.txt", 7, 1000, line, "world", true)
+	if m.LineNumber != 7 || m.FilePath != "a.txt" {
+		t.Errorf("lineMatch() = %+v", m)
+	}
+	if len(m.Line) > snippetWidth {
+		t.Errorf("lineMatch().Line is %d bytes, want at most %d", len(m.Line), snippetWidth)
+	}
+	if m.Offset != 1000+strings.Index(line, m.Line) {
+		t.Errorf("lineMatch().Offset = %d, want file offset of the window", m.Offset)
+	}
+	if len(m.Highlights) != 2 {
+		t.Fatalf("lineMatch() highlights = %+v, want 2", m.Highlights)
+	}
+	for _, h := range m.Highlights {
+		if !strings.EqualFold(m.Line[h.Start:h.End], "world") {
+			t.Errorf("highlight %+v = %q, want world", h, m.Line[h.Start:h.End])
+		}
+	}
+}
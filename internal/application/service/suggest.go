@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+// Points awarded by scoreTask. Priority dominates, an approaching due date
+// can lift a task by about one priority level, and blocked tasks sink.
+var priorityPoints = map[task.Priority]float64{
+	task.PriorityP0: 40,
+	task.PriorityP1: 30,
+	task.PriorityP2: 20,
+	task.PriorityP3: 10,
+}
+
+const (
+	overduePoints      = 30
+	dueTodayPoints     = 25
+	dueIn3DaysPoints   = 20
+	dueIn7DaysPoints   = 10
+	readyPoints        = 10
+	blockedPoints      = -50
+	inProgressPoints   = 15
+	touchedTodayPoints = 10
+	touchedWeekPoints  = 5
+)
+
+// SuggestNextTaskRequest contains parameters for suggesting what to work on next.
+type SuggestNextTaskRequest struct {
+	ProjectID string // Empty = all projects
+	Limit     int    // Number of suggestions (0 = default 5)
+}
+
+// TaskSuggestion is a task ranked by SuggestNextTask.
+type TaskSuggestion struct {
+	Task    *task.Task
+	Score   float64
+	Blocked bool
+	Factors []ScoreFactor // The parts of the score, in the order they were evaluated
+}
+
+// ScoreFactor is one part of a suggestion's score.
+type ScoreFactor struct {
+	Factor string  `json:"factor"` // priority, due_date, readiness, in_progress or recency
+	Points float64 `json:"points"`
+	Detail string  `json:"detail"`
+}
+
+// Explain summarizes the factors, e.g. "p0 (+40), overdue by 2 days (+30)".
+func (s *TaskSuggestion) Explain() string {
+	parts := make([]string, 0, len(s.Factors))
+	for _, f := range s.Factors {
+		parts = append(parts, fmt.Sprintf("%s (%+g)", f.Detail, f.Points))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SuggestNextTask ranks the open and in-progress tasks nobody has claimed
+// (any status that is not completed, archived or terminal) by priority, due
+// date, dependency readiness and recency, best first.
+func (s *TaskService) SuggestNextTask(ctx context.Context, req SuggestNextTaskRequest) ([]*TaskSuggestion, error) {
+	all := task.ListOptions{Limit: math.MaxInt32}
+
+	var tasks *task.ListResult[*task.Task]
+	var err error
+	if req.ProjectID != "" {
+		tasks, err = s.repo.ListTasks(ctx, task.NewProjectID(req.ProjectID), all)
+	} else {
+		tasks, err = s.repo.ListAllTasks(ctx, all)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	workflows := make(map[task.ProjectID]*task.Workflow)
+	lookup := make(map[task.TaskRef]*task.Task)
+
+	var suggestions []*TaskSuggestion
+	for _, t := range unclaimed(tasks.Items, now) {
+		w, ok := workflows[t.ProjectID]
+		if !ok {
+			p, err := s.repo.GetProject(ctx, t.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			w = p.TaskWorkflow()
+			workflows[t.ProjectID] = w
+		}
+		if w.Done(t.Status) {
+			continue
+		}
+
+		blockers, err := s.openBlockers(ctx, t, lookup)
+		if err != nil {
+			return nil, err
+		}
+		started := t.Status != w.Initial()
+		suggestions = append(suggestions, scoreTask(t, started, blockers, now))
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if ra, rb := a.Task.Priority.Rank(), b.Task.Priority.Rank(); ra != rb {
+			return ra < rb
+		}
+		return a.Task.UpdatedAt.After(b.Task.UpdatedAt)
+	})
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// scoreTask computes the suggestion score of a task. started is true when the
+// task has left its workflow's initial status; blockers are its open blockers.
+func scoreTask(t *task.Task, started bool, blockers []*task.Task, now time.Time) *TaskSuggestion {
+	s := &TaskSuggestion{Task: t, Blocked: len(blockers) > 0}
+	add := func(factor string, points float64, detail string) {
+		s.Factors = append(s.Factors, ScoreFactor{Factor: factor, Points: points, Detail: detail})
+		s.Score += points
+	}
+
+	priority := t.EffectivePriority()
+	add("priority", priorityPoints[priority], string(priority))
+
+	if t.DueDate != nil {
+		left := t.DueDate.Sub(now)
+		switch {
+		case left < 0:
+			add("due_date", overduePoints, "overdue by "+approxDuration(-left))
+		case left < 24*time.Hour:
+			add("due_date", dueTodayPoints, "due in "+approxDuration(left))
+		case left < 3*24*time.Hour:
+			add("due_date", dueIn3DaysPoints, "due in "+approxDuration(left))
+		case left < 7*24*time.Hour:
+			add("due_date", dueIn7DaysPoints, "due in "+approxDuration(left))
+		default:
+			add("due_date", 0, "due in "+approxDuration(left))
+		}
+	}
+
+	if len(blockers) > 0 {
+		refs := make([]string, 0, len(blockers))
+		for _, b := range blockers {
+			refs = append(refs, b.Ref().String())
+		}
+		add("readiness", blockedPoints, "blocked by "+strings.Join(refs, ", "))
+	} else {
+		add("readiness", readyPoints, "no open blockers")
+	}
+
+	if started {
+		add("in_progress", inProgressPoints, fmt.Sprintf("already %s", t.Status))
+	}
+
+	age := now.Sub(t.UpdatedAt)
+	switch {
+	case age < 24*time.Hour:
+		add("recency", touchedTodayPoints, "updated "+approxDuration(age)+" ago")
+	case age < 7*24*time.Hour:
+		add("recency", touchedWeekPoints, "updated "+approxDuration(age)+" ago")
+	default:
+		add("recency", 0, "untouched for "+approxDuration(age))
+	}
+
+	return s
+}
+
+// approxDuration renders a duration in whole minutes, hours or days.
+func approxDuration(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/(24*time.Hour)), "day")
+	}
+}
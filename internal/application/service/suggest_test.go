@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+func TestScoreTask(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	overdue := now.Add(-50 * time.Hour)
+
+	urgent := &task.Task{ID: "urgent", ProjectID: "p", Status: task.TaskStatusInProgress, Priority: task.PriorityP0, DueDate: &overdue, UpdatedAt: now.Add(-2 * time.Hour)}
+	got := scoreTask(urgent, true, nil, now)
+
+	// p0 + overdue + ready + in progress + touched today
+	if want := 40.0 + 30 + 10 + 15 + 10; got.Score != want {
+		t.Errorf("score = %v, want %v (%s)", got.Score, want, got.Explain())
+	}
+	if want := "p0 (+40), overdue by 2 days (+30), no open blockers (+10), already in_progress (+15), updated 2 hours ago (+10)"; got.Explain() != want {
+		t.Errorf("Explain() = %q, want %q", got.Explain(), want)
+	}
+
+	stale := &task.Task{ID: "stale", ProjectID: "p", UpdatedAt: now.AddDate(0, 0, -40)}
+	blocker := &task.Task{ID: "schema", ProjectID: "p"}
+	got = scoreTask(stale, false, []*task.Task{blocker}, now)
+
+	// Default p2 + blocked + untouched
+	if want := 20.0 - 50; got.Score != want || !got.Blocked {
+		t.Errorf("blocked score = %v (blocked %v), want %v (%s)", got.Score, got.Blocked, want, got.Explain())
+	}
+}
+
+func TestTaskService_SuggestNextTask(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createDependencyTasks(t, svc)
+	ctx := context.Background()
+
+	// backend/api is blocked; frontend/ui is p0; backend/docs is done
+	if _, err := svc.AddDependency(ctx, DependencyRequest{ProjectID: "backend", TaskID: "api", BlockedByTaskID: "schema"}); err != nil {
+		t.Fatalf("AddDependency() error = %v", err)
+	}
+	p0, p3 := task.PriorityP0, task.PriorityP3
+	completed := task.TaskStatusCompleted
+	for _, req := range []UpdateTaskRequest{
+		{ProjectID: "frontend", ID: "ui", Priority: &p0},
+		{ProjectID: "backend", ID: "schema", Priority: &p3},
+		{ProjectID: "backend", ID: "docs", Status: &completed},
+	} {
+		if _, err := svc.UpdateTask(ctx, req); err != nil {
+			t.Fatalf("UpdateTask(%s) error = %v", req.ID, err)
+		}
+	}
+
+	got, err := svc.SuggestNextTask(ctx, SuggestNextTaskRequest{})
+	if err != nil {
+		t.Fatalf("SuggestNextTask() error = %v", err)
+	}
+
+	order := make([]task.TaskID, 0, len(got))
+	for _, s := range got {
+		order = append(order, s.Task.ID)
+	}
+	want := []task.TaskID{"ui", "schema", "api"}
+	if len(order) != len(want) {
+		t.Fatalf("SuggestNextTask() = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("SuggestNextTask() = %v, want %v", order, want)
+		}
+	}
+	if !got[2].Blocked {
+		t.Errorf("api suggestion Blocked = false, want true")
+	}
+
+	got, err = svc.SuggestNextTask(ctx, SuggestNextTaskRequest{ProjectID: "backend", Limit: 1})
+	if err != nil || len(got) != 1 || got[0].Task.ID != "schema" {
+		t.Errorf("SuggestNextTask(backend, limit 1) = %v, %v, want [schema]", got, err)
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"agent-memory/internal/domain/task"
+)
+
+// TagUsage is the number of tasks and artifacts carrying a tag.
+type TagUsage struct {
+	Tag       string `json:"tag"`
+	Tasks     int    `json:"tasks"`
+	Artifacts int    `json:"artifacts"`
+}
+
+// ListTags returns the tags used in a project (or in all projects when
+// projectID is empty), most used first.
+func (s *TaskService) ListTags(ctx context.Context, projectID string) ([]TagUsage, error) {
+	var scope *task.ProjectID
+	if projectID != "" {
+		pid := task.NewProjectID(projectID)
+		scope = &pid
+	}
+
+	counts, err := s.repo.CountTags(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]TagUsage, 0, len(counts))
+	for _, c := range counts {
+		tags = append(tags, TagUsage{Tag: c.Tag, Tasks: c.Tasks, Artifacts: c.Artifacts})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		ni, nj := tags[i].Tasks+tags[i].Artifacts, tags[j].Tasks+tags[j].Artifacts
+		if ni != nj {
+			return ni > nj
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
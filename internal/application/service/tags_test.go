@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"agent-memory/internal/domain/task"
+)
+
+// createTaggedTasks creates backend/{login,schema} and frontend/form with tags.
+func createTaggedTasks(t *testing.T, svc *TaskService) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, id := range []string{"backend", "frontend"} {
+		if _, err := svc.CreateProject(ctx, CreateProjectRequest{ID: id}); err != nil {
+			t.Fatalf("CreateProject(%s) error = %v", id, err)
+		}
+	}
+	for _, req := range []CreateTaskRequest{
+		{ProjectID: "backend", ID: "login", Tags: []string{"Auth", "backend"}},
+		{ProjectID: "backend", ID: "schema", Tags: []string{"backend"}},
+		{ProjectID: "frontend", ID: "form", Tags: []string{"auth", "ui"}},
+	} {
+		if _, err := svc.CreateTask(ctx, req); err != nil {
+			t.Fatalf("CreateTask(%s) error = %v", req.ID, err)
+		}
+	}
+}
+
+func TestTaskService_Tags(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createTaggedTasks(t, svc)
+	ctx := context.Background()
+
+	login, _ := svc.GetTask(ctx, "backend", "login")
+	if !reflect.DeepEqual(login.Tags, []string{"auth", "backend"}) {
+		t.Errorf("CreateTask() Tags = %v, want normalized [auth backend]", login.Tags)
+	}
+
+	all, err := svc.ListAllTasks(ctx, ListAllTasksRequest{Tags: []string{"AUTH"}})
+	if err != nil {
+		t.Fatalf("ListAllTasks(auth) error = %v", err)
+	}
+	if ids := taskIDs(all.Items); all.Total != 2 || !ids["login"] || !ids["form"] {
+		t.Errorf("ListAllTasks(auth) = %v, want login and form", ids)
+	}
+
+	both, err := svc.ListTasks(ctx, ListTasksRequest{ProjectID: "backend", Tags: []string{"auth", "backend"}, TagMatch: task.TagMatchAll})
+	if err != nil {
+		t.Fatalf("ListTasks(all) error = %v", err)
+	}
+	if both.Total != 1 || both.Items[0].ID != "login" {
+		t.Errorf("ListTasks(auth AND backend) total = %d, want [login]", both.Total)
+	}
+
+	tags := []string{}
+	updated, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: "schema", Tags: &tags})
+	if err != nil || len(updated.Tags) != 0 {
+		t.Errorf("UpdateTask(no tags) = %v, %v, want tags removed", updated.Tags, err)
+	}
+
+	if _, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "login", Type: task.ArtifactTypeDecision, Content: "Use JWT", Tags: []string{"auth"}}); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+	if _, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "login", Content: "JWT notes"}); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	hits, err := svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: "jwt", Tags: []string{"auth"}})
+	if err != nil || hits.Total != 1 || hits.Items[0].Artifact.Type != task.ArtifactTypeDecision {
+		t.Errorf("SearchArtifacts(jwt, auth) = %v, %v, want the decision", hits, err)
+	}
+	// The slow path (query filters) honours the tag filter too
+	hits, err = svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: "jwt project:backend", Tags: []string{"auth"}})
+	if err != nil || hits.Total != 1 {
+		t.Errorf("SearchArtifacts(jwt project:backend, auth) = %v, %v, want 1 hit", hits, err)
+	}
+}
+
+func TestTaskService_ListTags(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	createTaggedTasks(t, svc)
+	ctx := context.Background()
+
+	if _, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "schema", Content: "Index on email", Tags: []string{"db", "auth"}}); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	got, err := svc.ListTags(ctx, "backend")
+	if err != nil {
+		t.Fatalf("ListTags(backend) error = %v", err)
+	}
+	want := []TagUsage{
+		{Tag: "auth", Tasks: 1, Artifacts: 1},
+		{Tag: "backend", Tasks: 2},
+		{Tag: "db", Artifacts: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListTags(backend) = %+v, want %+v", got, want)
+	}
+
+	got, err = svc.ListTags(ctx, "")
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(got) != 4 || got[0] != (TagUsage{Tag: "auth", Tasks: 2, Artifacts: 1}) {
+		t.Errorf("ListTags() = %+v, want auth first with 2 tasks and 1 artifact", got)
+	}
+
+	if _, err := svc.ListTags(ctx, "missing"); err != task.ErrProjectNotFound {
+		t.Errorf("ListTags(missing) error = %v, want ErrProjectNotFound", err)
+	}
+}
@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"time"
 
 	"agent-memory/internal/domain/task"
 )
@@ -11,6 +16,7 @@ import (
 // TaskService provides project, task, and artifact management operations.
 type TaskService struct {
 	repo   task.Repository
+	events *EventBus
 	logger *slog.Logger
 }
 
@@ -18,10 +24,17 @@ type TaskService struct {
 func NewTaskService(repo task.Repository, logger *slog.Logger) *TaskService {
 	return &TaskService{
 		repo:   repo,
+		events: NewEventBus(),
 		logger: logger,
 	}
 }
 
+// ReadOnly reports whether the store rejects changes, so callers can hide
+// the operations that would fail.
+func (s *TaskService) ReadOnly() bool {
+	return task.IsReadOnly(s.repo)
+}
+
 // Project operations
 
 // CreateProjectRequest contains parameters for creating a project.
@@ -30,6 +43,7 @@ type CreateProjectRequest struct {
 	Name          string
 	Description   string
 	WorkspacePath string
+	Workflow      *task.Workflow // Custom task statuses (nil = default workflow)
 	Metadata      map[string]string
 }
 
@@ -48,6 +62,12 @@ func (s *TaskService) CreateProject(ctx context.Context, req CreateProjectReques
 	p := task.NewProject(projectID, name)
 	p.Description = req.Description
 	p.WorkspacePath = req.WorkspacePath
+	if req.Workflow != nil {
+		if err := req.Workflow.Validate(); err != nil {
+			return nil, err
+		}
+		p.Workflow = req.Workflow
+	}
 	if req.Metadata != nil {
 		p.Metadata = req.Metadata
 	}
@@ -58,6 +78,7 @@ func (s *TaskService) CreateProject(ctx context.Context, req CreateProjectReques
 	}
 
 	s.logger.Info("project created", "id", projectID, "name", name)
+	s.events.Publish(Event{Kind: EventProjectCreated, ProjectID: projectID})
 	return p, nil
 }
 
@@ -69,8 +90,9 @@ func (s *TaskService) GetProject(ctx context.Context, id string) (*task.Project,
 
 // ListProjectsRequest contains parameters for listing projects.
 type ListProjectsRequest struct {
-	Limit  int // Maximum items to return (0 = default 50)
-	Offset int // Items to skip
+	Limit  int      // Maximum items to return (0 = default 50)
+	Offset int      // Items to skip
+	IDs    []string // Only these projects (nil = all)
 }
 
 // ListProjects returns projects with pagination.
@@ -79,46 +101,83 @@ func (s *TaskService) ListProjects(ctx context.Context, req ListProjectsRequest)
 		Limit:  req.Limit,
 		Offset: req.Offset,
 	}
-	return s.repo.ListProjects(ctx, opts)
+	if req.IDs == nil {
+		return s.repo.ListProjects(ctx, opts)
+	}
+
+	all, err := s.repo.ListProjects(ctx, task.ListOptions{Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+	var projects []*task.Project
+	for _, p := range all.Items {
+		if slices.Contains(req.IDs, p.ID.String()) {
+			projects = append(projects, p)
+		}
+	}
+	return paginate(projects, opts), nil
 }
 
 // UpdateProjectRequest contains parameters for updating a project.
 type UpdateProjectRequest struct {
-	ID            string
-	Name          *string
-	Description   *string
-	WorkspacePath *string
-	Metadata      map[string]string
+	ID               string
+	Name             *string
+	Description      *string
+	WorkspacePath    *string
+	Workflow         *task.Workflow // Replaces the workflow; existing tasks keep their status
+	Metadata         map[string]string
+	ExpectedRevision *int // Fails with a conflict if the project is at another revision (nil = no check)
 }
 
-// UpdateProject updates a project.
+// UpdateProject updates a project. With ExpectedRevision set, it fails with
+// a *task.ConflictError if the project changed since that revision; without,
+// the update is applied on top of whatever changed meanwhile.
 func (s *TaskService) UpdateProject(ctx context.Context, req UpdateProjectRequest) (*task.Project, error) {
 	projectID := task.NewProjectID(req.ID)
 
-	p, err := s.repo.GetProject(ctx, projectID)
+	p, err := retryConflicts(req.ExpectedRevision, func() (*task.Project, error) {
+		p, err := s.repo.GetProject(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if req.ExpectedRevision != nil && *req.ExpectedRevision != p.Revision {
+			return nil, &task.ConflictError{Revision: *req.ExpectedRevision, Project: p}
+		}
+
+		if req.Name != nil {
+			p.Name = *req.Name
+		}
+		if req.Description != nil {
+			p.Description = *req.Description
+		}
+		if req.WorkspacePath != nil {
+			p.WorkspacePath = *req.WorkspacePath
+		}
+		if req.Workflow != nil {
+			if err := req.Workflow.Validate(); err != nil {
+				return nil, err
+			}
+			p.Workflow = req.Workflow
+		}
+		if req.Metadata != nil {
+			p.Metadata = req.Metadata
+		}
+
+		if err := s.repo.UpdateProject(ctx, p); err != nil {
+			if errors.Is(err, task.ErrConflict) {
+				return nil, err
+			}
+			s.logger.Error("failed to update project", "id", projectID, "error", err)
+			return nil, fmt.Errorf("updating project: %w", err)
+		}
+		return p, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Name != nil {
-		p.Name = *req.Name
-	}
-	if req.Description != nil {
-		p.Description = *req.Description
-	}
-	if req.WorkspacePath != nil {
-		p.WorkspacePath = *req.WorkspacePath
-	}
-	if req.Metadata != nil {
-		p.Metadata = req.Metadata
-	}
-
-	if err := s.repo.UpdateProject(ctx, p); err != nil {
-		s.logger.Error("failed to update project", "id", projectID, "error", err)
-		return nil, fmt.Errorf("updating project: %w", err)
-	}
-
-	s.logger.Info("project updated", "id", projectID)
+	s.logger.Info("project updated", "id", projectID, "revision", p.Revision)
+	s.events.Publish(Event{Kind: EventProjectUpdated, ProjectID: projectID})
 	return p, nil
 }
 
@@ -126,12 +185,31 @@ func (s *TaskService) UpdateProject(ctx context.Context, req UpdateProjectReques
 func (s *TaskService) DeleteProject(ctx context.Context, id string) error {
 	projectID := task.NewProjectID(id)
 
+	tasks, err := s.projectTasks(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	var superseded []supersession
+	for _, t := range tasks {
+		links, err := s.taskSupersessions(ctx, projectID, t.ID)
+		if err != nil {
+			return err
+		}
+		superseded = append(superseded, links...)
+	}
+
 	if err := s.repo.DeleteProject(ctx, projectID); err != nil {
 		s.logger.Error("failed to delete project", "id", projectID, "error", err)
 		return err
 	}
 
+	// Artifacts of other projects superseded by the deleted ones show up again
+	if err := s.unsupersedeAll(ctx, superseded); err != nil {
+		return err
+	}
+
 	s.logger.Info("project deleted", "id", projectID)
+	s.events.Publish(Event{Kind: EventProjectDeleted, ProjectID: projectID})
 	return nil
 }
 
@@ -143,7 +221,12 @@ type CreateTaskRequest struct {
 	ID            string
 	Name          string
 	Description   string
-	WorkspacePath string // Overrides project workspace if set
+	WorkspacePath string        // Overrides project workspace if set
+	ParentID      string        // Makes the task a subtask of another task in the project
+	Priority      task.Priority // p0-p3 (empty = task.DefaultPriority)
+	DueDate       string        // YYYY-MM-DD or RFC 3339 (empty = none)
+	Tags          []string
+	Actor         string // Recorded in the status history
 	Metadata      map[string]string
 }
 
@@ -161,9 +244,33 @@ func (s *TaskService) CreateTask(ctx context.Context, req CreateTaskRequest) (*t
 		name = req.ID
 	}
 
+	p, err := s.repo.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	priority, err := task.ParsePriority(string(req.Priority))
+	if err != nil {
+		return nil, err
+	}
+	dueDate, err := task.ParseDueDate(req.DueDate)
+	if err != nil {
+		return nil, err
+	}
+
 	t := task.NewTask(projectID, taskID, name)
+	t.Status = p.TaskWorkflow().Initial()
+	t.Priority = priority
+	t.DueDate = dueDate
 	t.Description = req.Description
 	t.WorkspacePath = req.WorkspacePath
+	t.Tags = task.NormalizeTags(req.Tags)
+	if req.ParentID != "" {
+		t.ParentID = task.NewTaskID(req.ParentID)
+		if err := s.validateParent(ctx, projectID, taskID, t.ParentID); err != nil {
+			return nil, err
+		}
+	}
 	if req.Metadata != nil {
 		t.Metadata = req.Metadata
 	}
@@ -172,8 +279,10 @@ func (s *TaskService) CreateTask(ctx context.Context, req CreateTaskRequest) (*t
 		s.logger.Error("failed to create task", "project_id", projectID, "task_id", taskID, "error", err)
 		return nil, fmt.Errorf("creating task: %w", err)
 	}
+	s.recordStatusChange(ctx, t, task.StatusChange{To: t.Status, At: t.CreatedAt, Actor: req.Actor})
 
 	s.logger.Info("task created", "project_id", projectID, "task_id", taskID, "name", name)
+	s.events.Publish(Event{Kind: EventTaskCreated, ProjectID: projectID, TaskID: taskID})
 	return t, nil
 }
 
@@ -186,97 +295,273 @@ func (s *TaskService) GetTask(ctx context.Context, projectID, taskID string) (*t
 
 // ListTasksRequest contains parameters for listing tasks.
 type ListTasksRequest struct {
-	ProjectID string
-	Limit     int             // Maximum items to return (0 = default 50)
-	Offset    int             // Items to skip
-	Status    task.TaskStatus // Filter by status (empty = all)
+	ProjectID     string
+	Limit         int             // Maximum items to return (0 = default 50)
+	Offset        int             // Items to skip
+	Status        task.TaskStatus // Filter by status (empty = all)
+	ParentID      string          // Only direct subtasks of this task
+	DescendantsOf string          // Only subtasks of this task, at any depth
+	Tags          []string        // Filter by tags (empty = all)
+	TagMatch      task.TagMatch   // How Tags are matched (empty = any)
+	Sort          task.TaskSort   // Order (empty = most recently updated first)
+	Available     bool            // Leave out tasks with an active claim
 }
 
 // ListTasks returns tasks for a project with pagination.
 func (s *TaskService) ListTasks(ctx context.Context, req ListTasksRequest) (*task.ListResult[*task.Task], error) {
 	pid := task.NewProjectID(req.ProjectID)
 	opts := task.ListOptions{
-		Limit:  req.Limit,
-		Offset: req.Offset,
-		Status: req.Status,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+		Status:   req.Status,
+		Tags:     task.NormalizeTags(req.Tags),
+		TagMatch: req.TagMatch,
+		Sort:     req.Sort,
+	}
+	if req.ParentID == "" && req.DescendantsOf == "" && !req.Available {
+		return s.repo.ListTasks(ctx, pid, opts)
+	}
+
+	tasks, err := s.projectTasks(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := func(t *task.Task) bool { return true }
+	if req.ParentID != "" {
+		parentID := task.NewTaskID(req.ParentID)
+		if !containsTask(tasks, parentID) {
+			return nil, task.ErrTaskNotFound
+		}
+		keep = func(t *task.Task) bool { return t.ParentID == parentID }
+	}
+	if req.DescendantsOf != "" {
+		rootID := task.NewTaskID(req.DescendantsOf)
+		if !containsTask(tasks, rootID) {
+			return nil, task.ErrTaskNotFound
+		}
+		descendants := descendantIDs(tasks, rootID)
+		isChild := keep
+		keep = func(t *task.Task) bool { return descendants[t.ID] && isChild(t) }
 	}
-	return s.repo.ListTasks(ctx, pid, opts)
+
+	var filtered []*task.Task
+	for _, t := range tasks {
+		if keep(t) && (req.Status == "" || t.Status == req.Status) && opts.MatchesTags(t.Tags) {
+			filtered = append(filtered, t)
+		}
+	}
+	if req.Available {
+		filtered = unclaimed(filtered, time.Now().UTC())
+	}
+	task.SortTasks(filtered, req.Sort)
+	return paginate(filtered, opts), nil
 }
 
 // ListAllTasksRequest contains parameters for listing all tasks across projects.
 type ListAllTasksRequest struct {
-	Limit  int             // Maximum items to return (0 = default 50)
-	Offset int             // Items to skip
-	Status task.TaskStatus // Filter by status (empty = all)
+	Limit     int             // Maximum items to return (0 = default 50)
+	Offset    int             // Items to skip
+	Status    task.TaskStatus // Filter by status (empty = all)
+	Tags      []string        // Filter by tags (empty = all)
+	TagMatch  task.TagMatch   // How Tags are matched (empty = any)
+	Sort      task.TaskSort   // Order (empty = most recently updated first)
+	Available bool            // Leave out tasks with an active claim
 }
 
 // ListAllTasks returns tasks from all projects with pagination.
 func (s *TaskService) ListAllTasks(ctx context.Context, req ListAllTasksRequest) (*task.ListResult[*task.Task], error) {
 	opts := task.ListOptions{
-		Limit:  req.Limit,
-		Offset: req.Offset,
-		Status: req.Status,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+		Status:   req.Status,
+		Tags:     task.NormalizeTags(req.Tags),
+		TagMatch: req.TagMatch,
+		Sort:     req.Sort,
+	}
+	if !req.Available {
+		return s.repo.ListAllTasks(ctx, opts)
 	}
-	return s.repo.ListAllTasks(ctx, opts)
+
+	all := opts
+	all.Limit, all.Offset = math.MaxInt32, 0
+	tasks, err := s.repo.ListAllTasks(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(unclaimed(tasks.Items, time.Now().UTC()), opts), nil
 }
 
 // UpdateTaskRequest contains parameters for updating a task.
 type UpdateTaskRequest struct {
-	ProjectID     string
-	ID            string
-	Name          *string
-	Description   *string
-	WorkspacePath *string
-	Status        *task.TaskStatus
-	Metadata      map[string]string
+	ProjectID        string
+	ID               string
+	Name             *string
+	Description      *string
+	WorkspacePath    *string
+	Status           *task.TaskStatus
+	Priority         *task.Priority
+	DueDate          *string   // YYYY-MM-DD or RFC 3339; empty string removes the due date
+	ParentID         *string   // Empty string moves the task to the top level
+	Tags             *[]string // Replaces the tags; empty removes them
+	Metadata         map[string]string
+	Reason           string // Why the status changed, recorded in the status history
+	Actor            string // Who changed it, recorded in the status history
+	ExpectedRevision *int   // Fails with a conflict if the task is at another revision (nil = no check)
 }
 
-// UpdateTask updates a task.
+// UpdateTask updates a task. With ExpectedRevision set, it fails with a
+// *task.ConflictError if the task changed since that revision; without, the
+// update is applied on top of whatever changed meanwhile.
 func (s *TaskService) UpdateTask(ctx context.Context, req UpdateTaskRequest) (*task.Task, error) {
 	projectID := task.NewProjectID(req.ProjectID)
 	taskID := task.NewTaskID(req.ID)
 
-	t, err := s.repo.GetTask(ctx, projectID, taskID)
+	var previousStatus task.TaskStatus
+	t, err := retryConflicts(req.ExpectedRevision, func() (*task.Task, error) {
+		t, err := s.repo.GetTask(ctx, projectID, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if req.ExpectedRevision != nil && *req.ExpectedRevision != t.Revision {
+			return nil, &task.ConflictError{Revision: *req.ExpectedRevision, Task: t}
+		}
+		previousStatus = t.Status
+
+		if req.Name != nil {
+			t.Name = *req.Name
+		}
+		if req.Description != nil {
+			t.Description = *req.Description
+		}
+		if req.WorkspacePath != nil {
+			t.WorkspacePath = *req.WorkspacePath
+		}
+		if req.Status != nil {
+			p, err := s.repo.GetProject(ctx, projectID)
+			if err != nil {
+				return nil, err
+			}
+			workflow := p.TaskWorkflow()
+			if err := workflow.CheckTransition(t.Status, *req.Status); err != nil {
+				return nil, err
+			}
+			if workflow.Started(*req.Status) && !workflow.Started(t.Status) {
+				if err := s.checkUnblocked(ctx, t); err != nil {
+					return nil, err
+				}
+			}
+			t.Status = *req.Status
+		}
+		if req.Priority != nil {
+			priority, err := task.ParsePriority(string(*req.Priority))
+			if err != nil {
+				return nil, err
+			}
+			t.Priority = priority
+		}
+		if req.DueDate != nil {
+			dueDate, err := task.ParseDueDate(*req.DueDate)
+			if err != nil {
+				return nil, err
+			}
+			t.DueDate = dueDate
+		}
+		if req.ParentID != nil {
+			parentID := task.NewTaskID(*req.ParentID)
+			if parentID != "" && parentID != t.ParentID {
+				if err := s.validateParent(ctx, projectID, taskID, parentID); err != nil {
+					return nil, err
+				}
+			}
+			t.ParentID = parentID
+		}
+		if req.Tags != nil {
+			t.Tags = task.NormalizeTags(*req.Tags)
+		}
+		if req.Metadata != nil {
+			t.Metadata = req.Metadata
+		}
+
+		if err := s.repo.UpdateTask(ctx, t); err != nil {
+			if errors.Is(err, task.ErrConflict) {
+				return nil, err
+			}
+			s.logger.Error("failed to update task", "project_id", projectID, "task_id", taskID, "error", err)
+			return nil, fmt.Errorf("updating task: %w", err)
+		}
+		return t, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	if req.Name != nil {
-		t.Name = *req.Name
-	}
-	if req.Description != nil {
-		t.Description = *req.Description
-	}
-	if req.WorkspacePath != nil {
-		t.WorkspacePath = *req.WorkspacePath
-	}
-	if req.Status != nil {
-		t.Status = *req.Status
-	}
-	if req.Metadata != nil {
-		t.Metadata = req.Metadata
-	}
-
-	if err := s.repo.UpdateTask(ctx, t); err != nil {
-		s.logger.Error("failed to update task", "project_id", projectID, "task_id", taskID, "error", err)
-		return nil, fmt.Errorf("updating task: %w", err)
+	if t.Status != previousStatus {
+		s.recordStatusChange(ctx, t, task.StatusChange{
+			From:   previousStatus,
+			To:     t.Status,
+			At:     t.UpdatedAt,
+			Reason: req.Reason,
+			Actor:  req.Actor,
+		})
 	}
 
-	s.logger.Info("task updated", "project_id", projectID, "task_id", taskID)
+	s.logger.Info("task updated", "project_id", projectID, "task_id", taskID, "revision", t.Revision)
+	s.events.Publish(Event{Kind: EventTaskUpdated, ProjectID: projectID, TaskID: taskID})
 	return t, nil
 }
 
-// DeleteTask deletes a task and all its artifacts.
+// DeleteTask deletes a task and all its artifacts. Its subtasks are moved up
+// to the deleted task's parent and its dependencies are removed from the
+// tasks on the other side.
 func (s *TaskService) DeleteTask(ctx context.Context, projectID, taskID string) error {
 	pid := task.NewProjectID(projectID)
 	tid := task.NewTaskID(taskID)
 
+	deleted, err := s.repo.GetTask(ctx, pid, tid)
+	if err != nil {
+		return err
+	}
+	superseded, err := s.taskSupersessions(ctx, pid, tid)
+	if err != nil {
+		return err
+	}
+
 	if err := s.repo.DeleteTask(ctx, pid, tid); err != nil {
 		s.logger.Error("failed to delete task", "project_id", pid, "task_id", tid, "error", err)
 		return err
 	}
 
+	// Artifacts of other tasks superseded by the deleted ones show up again
+	if err := s.unsupersedeAll(ctx, superseded); err != nil {
+		return err
+	}
+
+	children, err := s.repo.ListTasks(ctx, pid, task.ListOptions{Limit: math.MaxInt32, Parent: tid})
+	if err != nil {
+		return fmt.Errorf("reparenting subtasks: %w", err)
+	}
+	for _, child := range children.Items {
+		_, err := s.modifyTask(ctx, child.Ref(), func(t *task.Task) bool {
+			if t.ParentID != tid {
+				return false
+			}
+			t.ParentID = deleted.ParentID
+			return true
+		})
+		if err != nil && !errors.Is(err, task.ErrTaskNotFound) {
+			s.logger.Error("failed to reparent subtask", "project_id", pid, "task_id", child.ID, "error", err)
+			return fmt.Errorf("reparenting subtasks: %w", err)
+		}
+	}
+
+	// Re-reads the tasks on the other side, so runs after the updates above
+	if err := s.unlinkDependencies(ctx, deleted); err != nil {
+		s.logger.Error("failed to remove dependencies", "project_id", pid, "task_id", tid, "error", err)
+		return fmt.Errorf("removing dependencies: %w", err)
+	}
+
 	s.logger.Info("task deleted", "project_id", pid, "task_id", tid)
+	s.events.Publish(Event{Kind: EventTaskDeleted, ProjectID: pid, TaskID: tid})
 	return nil
 }
 
@@ -288,6 +573,7 @@ type SaveArtifactRequest struct {
 	TaskID    string
 	Type      task.ArtifactType
 	Content   string
+	Tags      []string
 	Metadata  map[string]string
 }
 
@@ -307,6 +593,7 @@ func (s *TaskService) SaveArtifact(ctx context.Context, req SaveArtifactRequest)
 	}
 
 	a := task.NewArtifact(projectID, taskID, artifactType, req.Content)
+	a.Tags = task.NormalizeTags(req.Tags)
 	if req.Metadata != nil {
 		a.Metadata = req.Metadata
 	}
@@ -317,9 +604,59 @@ func (s *TaskService) SaveArtifact(ctx context.Context, req SaveArtifactRequest)
 	}
 
 	s.logger.Info("artifact saved", "project_id", projectID, "task_id", taskID, "type", artifactType, "id", a.ID)
+	s.events.Publish(Event{Kind: EventArtifactSaved, ProjectID: projectID, TaskID: taskID, ArtifactID: a.ID})
 	return a, nil
 }
 
+// UpdateArtifactRequest contains parameters for updating an artifact.
+// Nil fields are left unchanged.
+type UpdateArtifactRequest struct {
+	ProjectID  string
+	TaskID     string
+	ArtifactID string
+	Content    *string
+	Tags       *[]string // Replaces the tags; empty removes them
+	Metadata   map[string]string
+}
+
+// UpdateArtifact replaces an artifact's content, tags or metadata, keeping the
+// previous version. An update that changes nothing does not add a version.
+func (s *TaskService) UpdateArtifact(ctx context.Context, req UpdateArtifactRequest) (*task.Artifact, error) {
+	projectID := task.NewProjectID(req.ProjectID)
+	taskID := task.NewTaskID(req.TaskID)
+
+	current, err := s.repo.GetArtifact(ctx, projectID, taskID, req.ArtifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *current
+	if req.Content != nil {
+		updated.Content = *req.Content
+	}
+	if req.Tags != nil {
+		updated.Tags = task.NormalizeTags(*req.Tags)
+	}
+	if req.Metadata != nil {
+		updated.Metadata = req.Metadata
+	}
+
+	if updated.Content == current.Content &&
+		slices.Equal(updated.Tags, current.Tags) &&
+		maps.Equal(updated.Metadata, current.Metadata) {
+		return current, nil
+	}
+
+	if err := s.repo.UpdateArtifact(ctx, &updated); err != nil {
+		s.logger.Error("failed to update artifact", "project_id", projectID, "task_id", taskID, "artifact_id", req.ArtifactID, "error", err)
+		return nil, fmt.Errorf("updating artifact: %w", err)
+	}
+
+	s.logger.Info("artifact updated", "project_id", projectID, "task_id", taskID, "artifact_id", updated.ID, "version", updated.Version)
+	s.events.Publish(Event{Kind: EventArtifactUpdated, ProjectID: projectID, TaskID: taskID, ArtifactID: updated.ID})
+	return &updated, nil
+}
+
 // GetArtifact retrieves an artifact.
 func (s *TaskService) GetArtifact(ctx context.Context, projectID, taskID, artifactID string) (*task.Artifact, error) {
 	pid := task.NewProjectID(projectID)
@@ -329,10 +666,13 @@ func (s *TaskService) GetArtifact(ctx context.Context, projectID, taskID, artifa
 
 // ListArtifactsRequest contains parameters for listing artifacts.
 type ListArtifactsRequest struct {
-	ProjectID string
-	TaskID    string
-	Limit     int // Maximum items to return (0 = default 50)
-	Offset    int // Items to skip
+	ProjectID         string
+	TaskID            string
+	Limit             int           // Maximum items to return (0 = default 50)
+	Offset            int           // Items to skip
+	Tags              []string      // Filter by tags (empty = all)
+	TagMatch          task.TagMatch // How Tags are matched (empty = any)
+	IncludeSuperseded bool          // Include artifacts superseded by another artifact
 }
 
 // ListArtifacts returns artifacts for a task with pagination.
@@ -340,41 +680,191 @@ func (s *TaskService) ListArtifacts(ctx context.Context, req ListArtifactsReques
 	pid := task.NewProjectID(req.ProjectID)
 	tid := task.NewTaskID(req.TaskID)
 	opts := task.ListOptions{
-		Limit:  req.Limit,
-		Offset: req.Offset,
+		Limit:             req.Limit,
+		Offset:            req.Offset,
+		Tags:              task.NormalizeTags(req.Tags),
+		TagMatch:          req.TagMatch,
+		IncludeSuperseded: req.IncludeSuperseded,
 	}
 	return s.repo.ListArtifacts(ctx, pid, tid, opts)
 }
 
 // SearchArtifactsRequest contains parameters for searching artifacts.
 type SearchArtifactsRequest struct {
-	Query     string
-	ProjectID string // Optional: limit search to specific project
-	TaskID    string // Optional: limit search to specific task
-	Limit     int    // Maximum items to return (0 = default 50)
-	Offset    int    // Items to skip
+	Query             string        // Free text plus optional filters, see parseArtifactQuery
+	ProjectID         string        // Optional: limit search to specific project
+	TaskID            string        // Optional: limit search to specific task
+	Limit             int           // Maximum items to return (0 = default 50)
+	Offset            int           // Items to skip
+	Tags              []string      // Filter by tags (empty = all)
+	TagMatch          task.TagMatch // How Tags are matched (empty = any)
+	IncludeSuperseded bool          // Include artifacts superseded by another artifact
+}
+
+// ArtifactHit is a search result with excerpts around the matched text.
+type ArtifactHit struct {
+	Artifact *task.Artifact
+	Score    float64
+	Snippets []Snippet // Empty when the query has no free text
+}
+
+// SearchArtifacts searches artifact content with pagination, most relevant first.
+// The query may combine free text with filters such as type:, project:, task:,
+// status:, created: and meta.<key>:, each of which can be negated with "-".
+func (s *TaskService) SearchArtifacts(ctx context.Context, req SearchArtifactsRequest) (*task.ListResult[*ArtifactHit], error) {
+	q, err := parseArtifactQuery(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.searchArtifacts(ctx, q, req)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := snippetTerms(q)
+	hits := make([]*ArtifactHit, 0, len(result.Items))
+	for _, r := range result.Items {
+		hits = append(hits, &ArtifactHit{
+			Artifact: r.Artifact,
+			Score:    r.Score,
+			Snippets: buildSnippets(r.Artifact.Content, terms),
+		})
+	}
+
+	return &task.ListResult[*ArtifactHit]{
+		Items:   hits,
+		Total:   result.Total,
+		Limit:   result.Limit,
+		Offset:  result.Offset,
+		HasMore: result.HasMore,
+	}, nil
 }
 
-// SearchArtifacts searches artifact content with pagination.
-func (s *TaskService) SearchArtifacts(ctx context.Context, req SearchArtifactsRequest) (*task.ListResult[*task.Artifact], error) {
+// searchArtifacts runs the full-text search and evaluates the query's filters.
+func (s *TaskService) searchArtifacts(ctx context.Context, q *artifactQuery, req SearchArtifactsRequest) (*task.ListResult[*task.SearchResult], error) {
 	var projectID *task.ProjectID
 	var taskID *task.TaskID
 
+	scopeProject, scopeTask := q.scope()
 	if req.ProjectID != "" {
-		pid := task.NewProjectID(req.ProjectID)
-		projectID = &pid
+		scopeProject = req.ProjectID
 	}
 	if req.TaskID != "" {
-		tid := task.NewTaskID(req.TaskID)
+		scopeTask = req.TaskID
+	}
+	if scopeProject != "" {
+		pid := task.NewProjectID(scopeProject)
+		projectID = &pid
+	}
+	if scopeTask != "" {
+		tid := task.NewTaskID(scopeTask)
 		taskID = &tid
 	}
 
 	opts := task.ListOptions{
-		Limit:  req.Limit,
-		Offset: req.Offset,
+		Limit:             req.Limit,
+		Offset:            req.Offset,
+		Tags:              task.NormalizeTags(req.Tags),
+		TagMatch:          req.TagMatch,
+		IncludeSuperseded: req.IncludeSuperseded,
+	}
+
+	if !q.hasFilters() {
+		return s.repo.SearchArtifacts(ctx, q.text(), projectID, taskID, opts)
+	}
+
+	// Filters are evaluated here: go through every text match, keeping only
+	// the requested page of those that pass
+	excluded := make(map[string]bool)
+	for _, text := range q.excludedText() {
+		err := s.eachSearchResult(ctx, text, projectID, taskID, opts, func(r *task.SearchResult) {
+			excluded[artifactKey(r.Artifact)] = true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page := paginate[*task.SearchResult](nil, opts)
+	tasks := make(map[string]*task.Task)
+	matched := 0
+	err := s.eachSearchResult(ctx, q.text(), projectID, taskID, opts, func(r *task.SearchResult) {
+		a := r.Artifact
+		if excluded[artifactKey(a)] {
+			return
+		}
+
+		var t *task.Task
+		if q.usesStatus() {
+			key := a.ProjectID.String() + "/" + a.TaskID.String()
+			if _, ok := tasks[key]; !ok {
+				tasks[key], _ = s.repo.GetTask(ctx, a.ProjectID, a.TaskID)
+			}
+			t = tasks[key]
+		}
+
+		if q.matches(a, t) {
+			if matched >= page.Offset && len(page.Items) < page.Limit {
+				page.Items = append(page.Items, r)
+			}
+			matched++
+		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repo.SearchArtifacts(ctx, req.Query, projectID, taskID, opts)
+	page.Total = matched
+	page.HasMore = page.Offset+len(page.Items) < matched
+	return page, nil
+}
+
+// searchPageSize is how many artifacts eachSearchResult loads at a time.
+const searchPageSize = 200
+
+// eachSearchResult calls fn for every artifact matching text, best first,
+// loading them a page at a time rather than all at once. Only the filters
+// of opts are used.
+func (s *TaskService) eachSearchResult(ctx context.Context, text string, projectID *task.ProjectID, taskID *task.TaskID, opts task.ListOptions, fn func(*task.SearchResult)) error {
+	opts.Limit, opts.Offset = searchPageSize, 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := s.repo.SearchArtifacts(ctx, text, projectID, taskID, opts)
+		if err != nil {
+			return err
+		}
+		for _, r := range page.Items {
+			fn(r)
+		}
+		if !page.HasMore {
+			return nil
+		}
+		opts.Offset += searchPageSize
+	}
+}
+
+// RebuildSearchIndex re-indexes all artifacts and memories from storage.
+// It returns the number of documents indexed.
+func (s *TaskService) RebuildSearchIndex(ctx context.Context) (int, error) {
+	if s.ReadOnly() {
+		return 0, task.ErrReadOnly
+	}
+	indexer, ok := s.repo.(task.SearchIndexer)
+	if !ok {
+		return 0, fmt.Errorf("storage backend does not support rebuilding the search index")
+	}
+
+	n, err := indexer.RebuildSearchIndex(ctx)
+	if err != nil {
+		s.logger.Error("failed to rebuild search index", "error", err)
+		return 0, fmt.Errorf("rebuilding search index: %w", err)
+	}
+
+	s.logger.Info("search index rebuilt", "documents", n)
+	return n, nil
 }
 
 // DeleteArtifact removes an artifact.
@@ -382,12 +872,27 @@ func (s *TaskService) DeleteArtifact(ctx context.Context, projectID, taskID, art
 	pid := task.NewProjectID(projectID)
 	tid := task.NewTaskID(taskID)
 
+	a, err := s.repo.GetArtifact(ctx, pid, tid, artifactID)
+	if err != nil {
+		return err
+	}
+
 	if err := s.repo.DeleteArtifact(ctx, pid, tid, artifactID); err != nil {
 		s.logger.Error("failed to delete artifact", "project_id", pid, "task_id", tid, "artifact_id", artifactID, "error", err)
 		return err
 	}
 
+	// Artifacts this one superseded are no longer hidden
+	for _, l := range a.Links {
+		if l.Relation == task.RelationSupersedes {
+			if err := s.unsupersede(ctx, a.Ref(), l.Target); err != nil {
+				return err
+			}
+		}
+	}
+
 	s.logger.Info("artifact deleted", "project_id", pid, "task_id", tid, "artifact_id", artifactID)
+	s.events.Publish(Event{Kind: EventArtifactDeleted, ProjectID: pid, TaskID: tid, ArtifactID: artifactID})
 	return nil
 }
 
@@ -414,3 +919,76 @@ func (s *TaskService) GetEffectiveWorkspacePath(ctx context.Context, projectID,
 
 	return p.WorkspacePath, nil
 }
+
+func artifactKey(a *task.Artifact) string {
+	return a.ProjectID.String() + "/" + a.TaskID.String() + "/" + a.ID
+}
+
+// paginate applies ListOptions to results filtered in the service, with the
+// same defaults as the repositories.
+func paginate[T any](items []T, opts task.ListOptions) *task.ListResult[T] {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	offset := opts.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	total := len(items)
+	items = items[min(offset, total):]
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []T{}
+	}
+
+	return &task.ListResult[T]{
+		Items:   items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: hasMore,
+	}
+}
+
+// maxUpdateAttempts bounds how often an update without an expected revision
+// is reapplied after other writers got in between its read and its write.
+const maxUpdateAttempts = 5
+
+// retryConflicts runs a read-modify-write update, and runs it again when
+// another writer got in first. With an expected revision the conflict is
+// the caller's to resolve, so it is returned instead.
+func retryConflicts[T any](expectedRevision *int, update func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := update()
+		if !errors.Is(err, task.ErrConflict) || expectedRevision != nil || attempt == maxUpdateAttempts {
+			return result, err
+		}
+	}
+}
+
+// modifyTask reads a task, applies change and saves it, starting over when
+// another writer updated the task in between. change reports whether it
+// modified the task; an unmodified task is not saved and publishes no event.
+func (s *TaskService) modifyTask(ctx context.Context, ref task.TaskRef, change func(*task.Task) bool) (*task.Task, error) {
+	return retryConflicts(nil, func() (*task.Task, error) {
+		t, err := s.repo.GetTask(ctx, ref.ProjectID, ref.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if !change(t) {
+			return t, nil
+		}
+		if err := s.repo.UpdateTask(ctx, t); err != nil {
+			return nil, err
+		}
+		s.events.Publish(Event{Kind: EventTaskUpdated, ProjectID: ref.ProjectID, TaskID: ref.TaskID})
+		return t, nil
+	})
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"testing"
@@ -190,6 +192,39 @@ func TestTaskService_ListProjects_Pagination(t *testing.T) {
 	}
 }
 
+func TestTaskService_ListProjects_IDs(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if _, err := svc.CreateProject(ctx, CreateProjectRequest{ID: id}); err != nil {
+			t.Fatalf("CreateProject() error = %v", err)
+		}
+	}
+
+	result, err := svc.ListProjects(ctx, ListProjectsRequest{Limit: 1, IDs: []string{"b", "d", "missing"}})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if result.Total != 2 || !result.HasMore {
+		t.Errorf("ListProjects() Total = %d, HasMore = %v, want 2, true", result.Total, result.HasMore)
+	}
+	if len(result.Items) != 1 || result.Items[0].ID != "d" {
+		t.Errorf("ListProjects() = %v, want the newest project, d", result.Items)
+	}
+
+	// An empty allow-list matches nothing
+	result, err = svc.ListProjects(ctx, ListProjectsRequest{IDs: []string{}})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if len(result.Items) != 0 {
+		t.Errorf("ListProjects() returned %d items, want 0", len(result.Items))
+	}
+}
+
 func TestTaskService_UpdateProject(t *testing.T) {
 	svc, cleanup := setupTestService(t)
 	defer cleanup()
@@ -473,6 +508,207 @@ func TestTaskService_UpdateTask(t *testing.T) {
 	}
 }
 
+func TestTaskService_UpdateTask_ExpectedRevision(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "fix-bug"})
+
+	// Two agents read revision 0; the first one to write wins
+	revision := 0
+	first, second := "first", "second"
+	updated, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Description: &first, ExpectedRevision: &revision})
+	if err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if updated.Revision != 1 {
+		t.Errorf("UpdateTask().Revision = %d, want 1", updated.Revision)
+	}
+
+	_, err = svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Description: &second, ExpectedRevision: &revision})
+	var conflict *task.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("UpdateTask() stale error = %v, want ConflictError", err)
+	}
+	if conflict.Task.Description != "first" || conflict.Current() != 1 {
+		t.Errorf("ConflictError task = %q at revision %d, want %q at 1", conflict.Task.Description, conflict.Current(), "first")
+	}
+
+	// Retrying with the current revision succeeds
+	revision = conflict.Current()
+	if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Description: &second, ExpectedRevision: &revision}); err != nil {
+		t.Errorf("UpdateTask() retry error = %v", err)
+	}
+
+	// Projects too
+	name := "Renamed"
+	stale := 3
+	if _, err := svc.UpdateProject(ctx, UpdateProjectRequest{ID: "test-project", Name: &name, ExpectedRevision: &stale}); !errors.Is(err, task.ErrConflict) {
+		t.Errorf("UpdateProject() stale error = %v, want ErrConflict", err)
+	}
+}
+
+// racingRepository makes another writer update a task between the read and
+// the write of the next UpdateTask.
+type racingRepository struct {
+	task.Repository
+	raced bool
+}
+
+func (r *racingRepository) UpdateTask(ctx context.Context, t *task.Task) error {
+	if !r.raced {
+		r.raced = true
+		other, err := r.Repository.GetTask(ctx, t.ProjectID, t.ID)
+		if err != nil {
+			return err
+		}
+		other.Tags = []string{"other"}
+		if err := r.Repository.UpdateTask(ctx, other); err != nil {
+			return err
+		}
+	}
+	return r.Repository.UpdateTask(ctx, t)
+}
+
+func TestTaskService_UpdateTask_Race(t *testing.T) {
+	repo, err := filesystem.NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	racing := &racingRepository{Repository: repo}
+	svc := NewTaskService(racing, slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "fix-bug"})
+
+	// Without an expected revision the change is reapplied on top
+	desc := "mine"
+	updated, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Description: &desc})
+	if err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if updated.Description != "mine" || len(updated.Tags) != 1 || updated.Revision != 2 {
+		t.Errorf("UpdateTask() = %q, tags %v, revision %d; want both changes at revision 2", updated.Description, updated.Tags, updated.Revision)
+	}
+
+	// With one, the conflict is returned
+	racing.raced = false
+	revision := updated.Revision
+	if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Description: &desc, ExpectedRevision: &revision}); !errors.Is(err, task.ErrConflict) {
+		t.Errorf("UpdateTask() with expected revision error = %v, want ErrConflict", err)
+	}
+}
+
+func TestTaskService_UpdateTask_Workflow(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	workflow := &task.Workflow{Statuses: []task.WorkflowStatus{
+		{Name: "todo", Transitions: []task.TaskStatus{"doing"}},
+		{Name: "doing", Transitions: []task.TaskStatus{"review"}},
+		{Name: "review", Transitions: []task.TaskStatus{"done", "doing"}},
+		{Name: "done", Terminal: true},
+	}}
+	if _, err := svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project", Workflow: workflow}); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	created, err := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "fix-bug"})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if created.Status != "todo" {
+		t.Errorf("CreateTask().Status = %q, want todo", created.Status)
+	}
+
+	move := func(status task.TaskStatus) error {
+		_, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "fix-bug", Status: &status})
+		return err
+	}
+
+	if err := move("done"); !errors.Is(err, task.ErrInvalidTransition) {
+		t.Errorf("UpdateTask(todo -> done) error = %v, want ErrInvalidTransition", err)
+	}
+	if err := move(task.TaskStatusCompleted); !errors.Is(err, task.ErrInvalidTransition) {
+		t.Errorf("UpdateTask(todo -> completed) error = %v, want ErrInvalidTransition", err)
+	}
+	for _, status := range []task.TaskStatus{"doing", "review", "done"} {
+		if err := move(status); err != nil {
+			t.Fatalf("UpdateTask(-> %s) error = %v", status, err)
+		}
+	}
+	if err := move("doing"); !errors.Is(err, task.ErrInvalidTransition) {
+		t.Errorf("UpdateTask(done -> doing) error = %v, want ErrInvalidTransition", err)
+	}
+
+	got, err := svc.GetTask(ctx, "test-project", "fix-bug")
+	if err != nil || got.Status != "done" {
+		t.Errorf("GetTask() = %v, %v, want status done", got, err)
+	}
+
+	// Projects without a workflow still accept the default statuses only
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "plain"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "plain", ID: "task"})
+	bogus := task.TaskStatus("Done!")
+	if _, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "plain", ID: "task", Status: &bogus}); !errors.Is(err, task.ErrInvalidTransition) {
+		t.Errorf("UpdateTask(Done!) error = %v, want ErrInvalidTransition", err)
+	}
+
+	invalid := &task.Workflow{Statuses: []task.WorkflowStatus{{Name: "In Review"}}}
+	if _, err := svc.UpdateProject(ctx, UpdateProjectRequest{ID: "plain", Workflow: invalid}); !errors.Is(err, task.ErrInvalidWorkflow) {
+		t.Errorf("UpdateProject() with invalid workflow error = %v, want ErrInvalidWorkflow", err)
+	}
+}
+
+func TestTaskService_PriorityAndDueDate(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project"})
+
+	created, err := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "release", Priority: "P1", DueDate: "2026-03-01"})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if created.Priority != task.PriorityP1 || created.DueDate == nil || created.DueDate.Day() != 1 {
+		t.Errorf("CreateTask() priority = %q, due = %v, want p1 due 2026-03-01", created.Priority, created.DueDate)
+	}
+
+	plain, _ := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "plain"})
+	if plain.Priority != task.DefaultPriority || plain.DueDate != nil {
+		t.Errorf("CreateTask() defaults = %q, %v, want p2 and no due date", plain.Priority, plain.DueDate)
+	}
+
+	if _, err := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "bad", Priority: "urgent"}); !errors.Is(err, task.ErrInvalidPriority) {
+		t.Errorf("CreateTask(urgent) error = %v, want ErrInvalidPriority", err)
+	}
+	if _, err := svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "bad", DueDate: "tomorrow"}); !errors.Is(err, task.ErrInvalidDueDate) {
+		t.Errorf("CreateTask(tomorrow) error = %v, want ErrInvalidDueDate", err)
+	}
+
+	p0, noDue := task.PriorityP0, ""
+	updated, err := svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "test-project", ID: "release", Priority: &p0, DueDate: &noDue})
+	if err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if updated.Priority != task.PriorityP0 || updated.DueDate != nil {
+		t.Errorf("UpdateTask() priority = %q, due = %v, want p0 and no due date", updated.Priority, updated.DueDate)
+	}
+
+	// "plain" was updated last but "release" is more urgent
+	result, err := svc.ListTasks(ctx, ListTasksRequest{ProjectID: "test-project", Sort: task.SortPriority})
+	if err != nil || result.Items[0].ID != "release" {
+		t.Errorf("ListTasks(sort=priority) = %v, %v, want release first", result, err)
+	}
+}
+
 func TestTaskService_DeleteTask(t *testing.T) {
 	svc, cleanup := setupTestService(t)
 	defer cleanup()
@@ -501,6 +737,12 @@ func TestTaskService_DeleteTask(t *testing.T) {
 	if err != task.ErrTaskNotFound {
 		t.Errorf("GetTask() after delete error = %v, want ErrTaskNotFound", err)
 	}
+
+	for _, project := range []string{"test-project", "missing"} {
+		if err := svc.DeleteTask(ctx, project, "fix-bug"); err != task.ErrTaskNotFound {
+			t.Errorf("DeleteTask(%s) of a missing task error = %v, want ErrTaskNotFound", project, err)
+		}
+	}
 }
 
 func TestTaskService_SaveArtifact(t *testing.T) {
@@ -701,7 +943,159 @@ func TestTaskService_SearchArtifacts(t *testing.T) {
 	}
 
 	if len(result.Items) != 1 {
-		t.Errorf("SearchArtifacts() returned %d items, want 1", len(result.Items))
+		t.Fatalf("SearchArtifacts() returned %d items, want 1", len(result.Items))
+	}
+	if result.Items[0].Artifact.Content != "Authentication error found" {
+		t.Errorf("SearchArtifacts() content = %q, want %q", result.Items[0].Artifact.Content, "Authentication error found")
+	}
+}
+
+func TestTaskService_SearchArtifacts_Filters(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "frontend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "fix-login"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "old-work"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "frontend", ID: "styling"})
+
+	inProgress := task.TaskStatusInProgress
+	svc.UpdateTask(ctx, UpdateTaskRequest{ProjectID: "backend", ID: "fix-login", Status: &inProgress})
+
+	save := func(projectID, taskID string, typ task.ArtifactType, content string, metadata map[string]string) {
+		t.Helper()
+		if _, err := svc.SaveArtifact(ctx, SaveArtifactRequest{
+			ProjectID: projectID,
+			TaskID:    taskID,
+			Type:      typ,
+			Content:   content,
+			Metadata:  metadata,
+		}); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	save("backend", "fix-login", task.ArtifactTypeDecision, "Use JWT for session tokens", nil)
+	save("backend", "fix-login", task.ArtifactTypeCode, "JWT parsing code", map[string]string{"file_path": "/src/auth/jwt.go"})
+	save("backend", "fix-login", task.ArtifactTypeCode, "JWT parsing code", map[string]string{"file_path": "/web/jwt.ts"})
+	save("backend", "old-work", task.ArtifactTypeDecision, "Drop legacy JWT cookies", nil)
+	save("frontend", "styling", task.ArtifactTypeDecision, "Use CSS modules, no JWT here", nil)
+
+	tests := []struct {
+		query string
+		total int
+	}{
+		{query: "jwt", total: 5},
+		{query: "type:decision", total: 3},
+		{query: "type:decision project:backend", total: 2},
+		{query: "type:decision status:in_progress", total: 1},
+		{query: "jwt -type:code", total: 3},
+		{query: "jwt -legacy -css", total: 3},
+		{query: "meta.file_path:*.go", total: 1},
+		{query: `"parsing code" -meta.file_path:*.ts`, total: 1},
+		{query: "created:>2000-01-01 type:code", total: 2},
+		{query: "created:<2000-01-01", total: 0},
+		{query: "task:styling", total: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result, err := svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: tt.query})
+			if err != nil {
+				t.Fatalf("SearchArtifacts(%q) error = %v", tt.query, err)
+			}
+			if result.Total != tt.total || len(result.Items) != tt.total {
+				t.Errorf("SearchArtifacts(%q) Total = %d, items = %d, want %d", tt.query, result.Total, len(result.Items), tt.total)
+			}
+		})
+	}
+
+	// Filters combine with the request scope and pagination
+	result, err := svc.SearchArtifacts(ctx, SearchArtifactsRequest{
+		Query:     "type:decision",
+		ProjectID: "backend",
+		Limit:     1,
+	})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() paginated error = %v", err)
+	}
+	if result.Total != 2 || len(result.Items) != 1 || !result.HasMore {
+		t.Errorf("SearchArtifacts() paginated Total = %d, items = %d, HasMore = %v", result.Total, len(result.Items), result.HasMore)
+	}
+
+	if _, err := svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: "created:soon"}); !errors.Is(err, task.ErrInvalidQuery) {
+		t.Errorf("SearchArtifacts() invalid query error = %v, want ErrInvalidQuery", err)
+	}
+}
+
+func TestTaskService_SearchArtifacts_FilterPages(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "backend"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "backend", ID: "notes"})
+
+	// More matches than one page of the repository search
+	for i := 0; i < searchPageSize+50; i++ {
+		typ := task.ArtifactTypeNote
+		if i%2 == 0 {
+			typ = task.ArtifactTypeDecision
+		}
+		if _, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "backend", TaskID: "notes", Type: typ, Content: fmt.Sprintf("Entry %d", i)}); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for offset := 0; ; offset += 50 {
+		result, err := svc.SearchArtifacts(ctx, SearchArtifactsRequest{Query: "type:decision", Limit: 50, Offset: offset})
+		if err != nil {
+			t.Fatalf("SearchArtifacts() error = %v", err)
+		}
+		if result.Total != 125 {
+			t.Fatalf("SearchArtifacts() Total = %d, want 125", result.Total)
+		}
+		for _, hit := range result.Items {
+			if hit.Artifact.Type != task.ArtifactTypeDecision || seen[hit.Artifact.ID] {
+				t.Errorf("SearchArtifacts() offset %d returned %s %s again or unfiltered", offset, hit.Artifact.Type, hit.Artifact.ID)
+			}
+			seen[hit.Artifact.ID] = true
+		}
+		if !result.HasMore {
+			break
+		}
+	}
+	if len(seen) != 125 {
+		t.Errorf("SearchArtifacts() pages held %d artifacts, want 125", len(seen))
+	}
+}
+
+func TestTaskService_RebuildSearchIndex(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "fix-bug"})
+	svc.SaveArtifact(ctx, SaveArtifactRequest{
+		ProjectID: "test-project",
+		TaskID:    "fix-bug",
+		Type:      task.ArtifactTypeNote,
+		Content:   "Indexed note",
+	})
+
+	n, err := svc.RebuildSearchIndex(ctx)
+	if err != nil {
+		t.Fatalf("RebuildSearchIndex() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("RebuildSearchIndex() = %d, want 1", n)
 	}
 }
 
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"agent-memory/internal/domain/task"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxDiffEdits is the most added and removed lines a diff is computed for;
+// versions that differ more are only reported as different.
+const maxDiffEdits = 5000
+
+// ListArtifactVersions returns every version of an artifact, oldest first.
+// The last one is the current version.
+func (s *TaskService) ListArtifactVersions(ctx context.Context, projectID, taskID, artifactID string) ([]*task.Artifact, error) {
+	pid := task.NewProjectID(projectID)
+	tid := task.NewTaskID(taskID)
+	return s.repo.ListArtifactVersions(ctx, pid, tid, artifactID)
+}
+
+// GetArtifactVersion retrieves a specific version of an artifact.
+func (s *TaskService) GetArtifactVersion(ctx context.Context, projectID, taskID, artifactID string, version int) (*task.Artifact, error) {
+	pid := task.NewProjectID(projectID)
+	tid := task.NewTaskID(taskID)
+	return s.repo.GetArtifactVersion(ctx, pid, tid, artifactID, version)
+}
+
+// DiffArtifactVersionsRequest contains parameters for comparing two versions of an artifact.
+type DiffArtifactVersionsRequest struct {
+	ProjectID  string
+	TaskID     string
+	ArtifactID string
+	From       int // 0 = the version before To
+	To         int // 0 = the current version
+}
+
+// ArtifactDiff is the difference between two versions of an artifact.
+type ArtifactDiff struct {
+	From    *task.Artifact
+	To      *task.Artifact
+	Diff    string // Unified diff of the content; empty when it did not change
+	Added   int    // Lines added
+	Removed int    // Lines removed
+	// TooLarge is set when the versions differ in too many lines to diff;
+	// Diff, Added and Removed are then left empty
+	TooLarge bool
+}
+
+// DiffArtifactVersions compares the content of two versions of an artifact.
+func (s *TaskService) DiffArtifactVersions(ctx context.Context, req DiffArtifactVersionsRequest) (*ArtifactDiff, error) {
+	pid := task.NewProjectID(req.ProjectID)
+	tid := task.NewTaskID(req.TaskID)
+
+	to, err := s.repo.GetArtifact(ctx, pid, tid, req.ArtifactID)
+	if err != nil {
+		return nil, err
+	}
+	if req.To != 0 && req.To != to.Version {
+		if to, err = s.repo.GetArtifactVersion(ctx, pid, tid, req.ArtifactID, req.To); err != nil {
+			return nil, err
+		}
+	}
+
+	fromVersion := req.From
+	if fromVersion == 0 {
+		fromVersion = to.Version - 1
+	}
+	if fromVersion < 1 {
+		return nil, fmt.Errorf("%w: artifact '%s' has no version before %d", task.ErrArtifactVersionNotFound, req.ArtifactID, to.Version)
+	}
+	from, err := s.repo.GetArtifactVersion(ctx, pid, tid, req.ArtifactID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &ArtifactDiff{From: from, To: to}
+	ops, ok := diffLines(splitLines(from.Content), splitLines(to.Content))
+	if !ok {
+		d.TooLarge = true
+		return d, nil
+	}
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			d.Added++
+		case '-':
+			d.Removed++
+		}
+	}
+	if d.Added > 0 || d.Removed > 0 {
+		d.Diff = unifiedDiff(ops, fmt.Sprintf("v%d", from.Version), fmt.Sprintf("v%d", to.Version))
+	}
+	return d, nil
+}
+
+// diffOp is one line of a diff: ' ' kept, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines computes a line diff of a and b with Myers' algorithm in linear
+// space. It gives up, returning false, when the versions differ in more than
+// maxDiffEdits lines, which bounds the running time to O((N+M)·maxDiffEdits).
+func diffLines(a, b []string) ([]diffOp, bool) {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	return diffRegion(ops, a, b, maxDiffEdits)
+}
+
+// diffRegion appends the diff of a and b to ops, giving up when it needs more
+// than limit edits.
+func diffRegion(ops []diffOp, a, b []string, limit int) ([]diffOp, bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	switch {
+	case len(ma) == 0:
+		for _, line := range mb {
+			ops = append(ops, diffOp{'+', line})
+		}
+	case len(mb) == 0:
+		for _, line := range ma {
+			ops = append(ops, diffOp{'-', line})
+		}
+	default:
+		// Both sides are left and differ at both ends, so the middle snake
+		// splits them into two regions of fewer edits each
+		x, y, u, v, ok := middleSnake(ma, mb, limit)
+		if !ok {
+			return ops, false
+		}
+		if ops, ok = diffRegion(ops, ma[:x], mb[:y], limit); !ok {
+			return ops, false
+		}
+		for _, line := range ma[x:u] {
+			ops = append(ops, diffOp{' ', line})
+		}
+		if ops, ok = diffRegion(ops, ma[u:], mb[v:], limit); !ok {
+			return ops, false
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops, true
+}
+
+// middleSnake finds the middle snake of a shortest edit script from a to b: the
+// run of equal lines from a[x], b[y] to a[u], b[v] that the script crosses
+// halfway. It searches from both ends at once, keeping only the furthest point
+// reached on each diagonal, and reports false when the script needs more than
+// limit edits.
+func middleSnake(a, b []string, limit int) (x, y, u, v int, ok bool) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := min((n+m+1)/2, (limit+1)/2)
+
+	// forward[off+k] is the furthest x on diagonal k = x-y from the start;
+	// backward[off+k] the furthest distance from the end on diagonal k of the
+	// reversed sequences, which is diagonal delta-k going forward
+	off := maxD + 1
+	forward := make([]int, 2*off+1)
+	backward := make([]int, 2*off+1)
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[off+k-1] < forward[off+k+1]) {
+				x = forward[off+k+1]
+			} else {
+				x = forward[off+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[off+k] = u
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && u+backward[off+r] >= n {
+				return x, y, u, v, 2*d-1 <= limit
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var bx int
+			if k == -d || (k != d && backward[off+k-1] < backward[off+k+1]) {
+				bx = backward[off+k+1]
+			} else {
+				bx = backward[off+k-1] + 1
+			}
+			by := bx - k
+			ex, ey := bx, by
+			for ex < n && ey < m && a[n-1-ex] == b[m-1-ey] {
+				ex++
+				ey++
+			}
+			backward[off+k] = ex
+			if f := delta - k; !odd && f >= -d && f <= d && forward[off+f]+ex >= n {
+				return n - ex, m - ey, n - bx, m - by, 2*d <= limit
+			}
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// unifiedDiff renders ops in unified diff format with diffContext lines of
+// context around each hunk.
+func unifiedDiff(ops []diffOp, fromLabel, toLabel string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromLabel, toLabel)
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		last := first
+		for k := first + 1; k < len(ops) && k-last <= 2*diffContext; k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			}
+		}
+
+		from := max(first-diffContext, 0)
+		to := min(last+diffContext+1, len(ops))
+
+		// Line numbers are 1-based; a hunk with no lines on one side starts after line n
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldLine--
+		}
+		if newCount == 0 {
+			newLine--
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+		for _, op := range ops[from:to] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+
+		start = to
+	}
+
+	return sb.String()
+}
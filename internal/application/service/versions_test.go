@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"agent-memory/internal/domain/task"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	diff := func(a, b []string) []diffOp {
+		t.Helper()
+		ops, ok := diffLines(a, b)
+		if !ok {
+			t.Fatalf("diffLines() gave up on %d and %d lines", len(a), len(b))
+		}
+		return ops
+	}
+
+	from := "# Auth\nUse sessions\nStore in redis\n\nNext: tests"
+	to := "# Auth\nUse JWT\nStore in redis\n\nNext: tests\nNext: refresh tokens"
+
+	got := unifiedDiff(diff(splitLines(from), splitLines(to)), "v1", "v2")
+	want := "--- v1\n+++ v2\n" +
+		"@@ -1,5 +1,6 @@\n" +
+		" # Auth\n" +
+		"-Use sessions\n" +
+		"+Use JWT\n" +
+		" Store in redis\n" +
+		" \n" +
+		" Next: tests\n" +
+		"+Next: refresh tokens\n"
+	if got != want {
+		t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Changes far apart get their own hunks
+	lines := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"}
+	changed := append([]string{"one"}, lines[1:11]...)
+	changed = append(changed, "twelve")
+	got = unifiedDiff(diff(lines, changed), "a", "b")
+	want = `--- a
++++ b
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`
+	if got != want {
+		t.Errorf("unifiedDiff(two hunks) =\n%s\nwant\n%s", got, want)
+	}
+
+	got = unifiedDiff(diff(nil, []string{"new"}), "a", "b")
+	if want := "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+new\n"; got != want {
+		t.Errorf("unifiedDiff(from empty) = %q, want %q", got, want)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// lcs is the length of the longest common subsequence, which a shortest
+	// diff keeps
+	lcs := func(a, b []string) int {
+		table := make([][]int, len(a)+1)
+		for i := range table {
+			table[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					table[i][j] = table[i+1][j+1] + 1
+				} else {
+					table[i][j] = max(table[i+1][j], table[i][j+1])
+				}
+			}
+		}
+		return table[0][0]
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	random := func() []string {
+		lines := make([]string, rng.IntN(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.IntN(4)))
+		}
+		return lines
+	}
+	for range 500 {
+		a, b := random(), random()
+		ops, ok := diffLines(a, b)
+		if !ok {
+			t.Fatalf("diffLines(%v, %v) gave up", a, b)
+		}
+		var gotA, gotB []string
+		kept := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+			if op.kind == ' ' {
+				kept++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("diffLines(%v, %v) = %v, does not turn one into the other", a, b, ops)
+		}
+		if want := lcs(a, b); kept != want {
+			t.Fatalf("diffLines(%v, %v) keeps %d lines, want %d", a, b, kept, want)
+		}
+	}
+
+	// Versions that share nothing are not diffed line by line
+	large := make([]string, 2*maxDiffEdits)
+	other := make([]string, 2*maxDiffEdits)
+	for i := range large {
+		large[i] = fmt.Sprint("old ", i)
+		other[i] = fmt.Sprint("new ", i)
+	}
+	if _, ok := diffLines(large, other); ok {
+		t.Errorf("diffLines() of %d changed lines succeeded, want it to give up", 2*len(large))
+	}
+	// A few changes in a large file are still diffed
+	edited := slices.Clone(large)
+	edited[len(edited)/2] = "changed"
+	if ops, ok := diffLines(large, edited); !ok || len(ops) != len(large)+1 {
+		t.Errorf("diffLines() with one changed line = %d ops, %v, want %d, true", len(ops), ok, len(large)+1)
+	}
+}
+
+func TestTaskService_ArtifactVersions(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	svc.CreateProject(ctx, CreateProjectRequest{ID: "test-project"})
+	svc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "auth"})
+	a, err := svc.SaveArtifact(ctx, SaveArtifactRequest{ProjectID: "test-project", TaskID: "auth", Type: task.ArtifactTypeDecision, Content: "Use sessions"})
+	if err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	content := "Use JWT\nRS256 keys"
+	tags := []string{"Auth"}
+	updated, err := svc.UpdateArtifact(ctx, UpdateArtifactRequest{ProjectID: "test-project", TaskID: "auth", ArtifactID: a.ID, Content: &content, Tags: &tags})
+	if err != nil {
+		t.Fatalf("UpdateArtifact() error = %v", err)
+	}
+	if updated.Version != 2 || updated.UpdatedAt == nil || updated.Type != task.ArtifactTypeDecision || len(updated.Tags) != 1 || updated.Tags[0] != "auth" {
+		t.Errorf("UpdateArtifact() = %+v, want version 2 of the decision tagged auth", updated)
+	}
+
+	// Unchanged content does not add a version
+	same, err := svc.UpdateArtifact(ctx, UpdateArtifactRequest{ProjectID: "test-project", TaskID: "auth", ArtifactID: a.ID, Content: &content})
+	if err != nil || same.Version != 2 {
+		t.Errorf("UpdateArtifact(unchanged) = %v, %v, want version 2", same, err)
+	}
+
+	latest, err := svc.GetArtifact(ctx, "test-project", "auth", a.ID)
+	if err != nil || latest.Content != content || latest.Version != 2 {
+		t.Errorf("GetArtifact() = %+v, %v, want the latest version", latest, err)
+	}
+
+	versions, err := svc.ListArtifactVersions(ctx, "test-project", "auth", a.ID)
+	if err != nil {
+		t.Fatalf("ListArtifactVersions() error = %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[0].Content != "Use sessions" || versions[1].Version != 2 {
+		t.Errorf("ListArtifactVersions() = %+v, want v1 then v2", versions)
+	}
+
+	v1, err := svc.GetArtifactVersion(ctx, "test-project", "auth", a.ID, 1)
+	if err != nil || v1.Content != "Use sessions" {
+		t.Errorf("GetArtifactVersion(1) = %+v, %v", v1, err)
+	}
+	if _, err := svc.GetArtifactVersion(ctx, "test-project", "auth", a.ID, 3); !errors.Is(err, task.ErrArtifactVersionNotFound) {
+		t.Errorf("GetArtifactVersion(3) error = %v, want ErrArtifactVersionNotFound", err)
+	}
+
+	d, err := svc.DiffArtifactVersions(ctx, DiffArtifactVersionsRequest{ProjectID: "test-project", TaskID: "auth", ArtifactID: a.ID})
+	if err != nil {
+		t.Fatalf("DiffArtifactVersions() error = %v", err)
+	}
+	if d.From.Version != 1 || d.To.Version != 2 || d.Added != 2 || d.Removed != 1 {
+		t.Errorf("DiffArtifactVersions() = v%d..v%d +%d -%d, want v1..v2 +2 -1", d.From.Version, d.To.Version, d.Added, d.Removed)
+	}
+
+	if _, err := svc.DiffArtifactVersions(ctx, DiffArtifactVersionsRequest{ProjectID: "test-project", TaskID: "auth", ArtifactID: a.ID, To: 1}); !errors.Is(err, task.ErrArtifactVersionNotFound) {
+		t.Errorf("DiffArtifactVersions(to v1) error = %v, want ErrArtifactVersionNotFound", err)
+	}
+
+	large := strings.Repeat("generated line\n", 2*maxDiffEdits)
+	if _, err := svc.UpdateArtifact(ctx, UpdateArtifactRequest{ProjectID: "test-project", TaskID: "auth", ArtifactID: a.ID, Content: &large}); err != nil {
+		t.Fatalf("UpdateArtifact(large) error = %v", err)
+	}
+	d, err = svc.DiffArtifactVersions(ctx, DiffArtifactVersionsRequest{ProjectID: "test-project", TaskID: "auth", ArtifactID: a.ID})
+	if err != nil {
+		t.Fatalf("DiffArtifactVersions(large) error = %v", err)
+	}
+	if !d.TooLarge || d.Diff != "" || d.To.Version != 3 {
+		t.Errorf("DiffArtifactVersions(large) = v%d too large %v, want v3 too large", d.To.Version, d.TooLarge)
+	}
+
+	missing := "x"
+	if _, err := svc.UpdateArtifact(ctx, UpdateArtifactRequest{ProjectID: "test-project", TaskID: "auth", ArtifactID: "missing", Content: &missing}); err != task.ErrArtifactNotFound {
+		t.Errorf("UpdateArtifact(missing) error = %v, want ErrArtifactNotFound", err)
+	}
+}
@@ -17,13 +17,16 @@ import (
 type WorkspaceService struct {
 	taskRepo task.Repository
 	logger   *slog.Logger
+	noLogs   bool // Skip the file_read, file_list and search artifacts
 }
 
-// NewWorkspaceService creates a new workspace service.
+// NewWorkspaceService creates a new workspace service. With a read-only
+// repository it never logs operations as artifacts.
 func NewWorkspaceService(taskRepo task.Repository, logger *slog.Logger) *WorkspaceService {
 	return &WorkspaceService{
 		taskRepo: taskRepo,
 		logger:   logger,
+		noLogs:   task.IsReadOnly(taskRepo),
 	}
 }
 
@@ -99,7 +102,7 @@ func (s *WorkspaceService) ReadFile(ctx context.Context, req ReadFileRequest) (*
 	}
 
 	// Log read as artifact if requested
-	if req.LogRead {
+	if req.LogRead && !s.noLogs {
 		artifactContent := fmt.Sprintf("# File Read: %s\n\nSize: %d bytes, Lines: %d\n\n```\n%s\n```",
 			filePath, stat.Size(), lineCount, truncateContent(string(content), 5000))
 
@@ -257,7 +260,7 @@ func (s *WorkspaceService) ListFiles(ctx context.Context, req ListFilesRequest)
 	}
 
 	// Log listing as artifact if requested
-	if req.LogList {
+	if req.LogList && !s.noLogs {
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("# Directory Listing: %s\n\n", basePath))
 		sb.WriteString(fmt.Sprintf("Total: %d items\n\n", len(files)))
@@ -295,10 +298,14 @@ type SearchFilesRequest struct {
 }
 
 // SearchMatch represents a search match.
+// Line is the matching line, or a window of it centered on the first match
+// when the line is long; Highlights are byte ranges within Line.
 type SearchMatch struct {
-	FilePath   string `json:"file_path"`
-	LineNumber int    `json:"line_number"`
-	Line       string `json:"line"`
+	FilePath   string      `json:"file_path"`
+	LineNumber int         `json:"line_number"`
+	Line       string      `json:"line"`
+	Offset     int         `json:"offset"` // Byte offset of Line in the file
+	Highlights []Highlight `json:"highlights"`
 }
 
 // SearchFilesResult contains search results.
@@ -376,11 +383,18 @@ func (s *WorkspaceService) SearchFiles(ctx context.Context, req SearchFilesReque
 		}
 		defer file.Close()
 
+		// Track byte offsets: ScanLines drops the line terminator
+		consumed := 0
 		scanner := bufio.NewScanner(file)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			consumed += advance
+			return advance, token, err
+		})
 		lineNum := 0
 		relPath, _ := filepath.Rel(workspacePath, path)
 
-		for scanner.Scan() {
+		for lineStart := 0; scanner.Scan(); lineStart = consumed {
 			lineNum++
 			line := scanner.Text()
 			searchLine := line
@@ -389,11 +403,7 @@ func (s *WorkspaceService) SearchFiles(ctx context.Context, req SearchFilesReque
 			}
 
 			if strings.Contains(searchLine, query) {
-				matches = append(matches, SearchMatch{
-					FilePath:   relPath,
-					LineNumber: lineNum,
-					Line:       truncateLine(line, 200),
-				})
+				matches = append(matches, lineMatch(relPath, lineNum, lineStart, line, req.Query, req.IgnoreCase))
 
 				if len(matches) >= maxResults {
 					return filepath.SkipAll
@@ -415,7 +425,7 @@ func (s *WorkspaceService) SearchFiles(ctx context.Context, req SearchFilesReque
 	}
 
 	// Log search as artifact if requested
-	if req.LogSearch {
+	if req.LogSearch && !s.noLogs {
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("# Search: \"%s\"\n\n", req.Query))
 		sb.WriteString(fmt.Sprintf("Pattern: %s, Results: %d\n\n", req.Pattern, len(matches)))
@@ -439,18 +449,36 @@ func (s *WorkspaceService) SearchFiles(ctx context.Context, req SearchFilesReque
 
 // Helper functions
 
-func truncateContent(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// lineMatch builds a SearchMatch for a line, centered on the first occurrence
+// of query and with every occurrence highlighted.
+func lineMatch(filePath string, lineNum, lineStart int, line, query string, ignoreCase bool) SearchMatch {
+	found := substringMatches(line, query, ignoreCase)
+
+	var first span
+	if len(found) > 0 {
+		first = found[0]
+	}
+	window := excerpt(line, span{0, len(line)}, first)
+
+	snippet := Snippet{Offset: window.start, Text: line[window.start:window.end], Highlights: []Highlight{}}
+	for _, m := range found {
+		snippet.addHighlight(m)
+	}
+
+	return SearchMatch{
+		FilePath:   filePath,
+		LineNumber: lineNum,
+		Line:       snippet.Text,
+		Offset:     lineStart + snippet.Offset,
+		Highlights: snippet.Highlights,
 	}
-	return s[:maxLen] + "\n... (truncated)"
 }
 
-func truncateLine(s string, maxLen int) string {
+func truncateContent(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
 	}
-	return s[:maxLen] + "..."
+	return s[:maxLen] + "\n... (truncated)"
 }
 
 func isBinaryExtension(ext string) bool {
@@ -5,9 +5,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"agent-memory/internal/infrastructure/storage/filesystem"
+	"agent-memory/internal/infrastructure/storage/readonly"
 )
 
 func setupWorkspaceTestService(t *testing.T) (*WorkspaceService, *TaskService, string, func()) {
@@ -141,6 +143,49 @@ func TestWorkspaceService_ReadFile_WithLogging(t *testing.T) {
 	}
 }
 
+func TestWorkspaceService_ReadOnly(t *testing.T) {
+	_, taskSvc, tmpDir, cleanup := setupWorkspaceTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	workspaceDir := filepath.Join(tmpDir, "workspace")
+	os.MkdirAll(workspaceDir, 0755)
+	os.WriteFile(filepath.Join(workspaceDir, "test.txt"), []byte("Test content"), 0644)
+	taskSvc.CreateProject(ctx, CreateProjectRequest{ID: "test-project", WorkspacePath: workspaceDir})
+	taskSvc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "fix-bug"})
+
+	repo, err := filesystem.NewRepository(filepath.Join(tmpDir, "repo"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	var logs strings.Builder
+	workspaceSvc := NewWorkspaceService(readonly.NewRepository(repo), slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	// Logging is requested but skipped, not attempted
+	if _, err := workspaceSvc.ReadFile(ctx, ReadFileRequest{ProjectID: "test-project", TaskID: "fix-bug", FilePath: "test.txt", LogRead: true}); err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if _, err := workspaceSvc.ListFiles(ctx, ListFilesRequest{ProjectID: "test-project", TaskID: "fix-bug", LogList: true}); err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	if _, err := workspaceSvc.SearchFiles(ctx, SearchFilesRequest{ProjectID: "test-project", TaskID: "fix-bug", Query: "content", LogSearch: true}); err != nil {
+		t.Fatalf("SearchFiles() error = %v", err)
+	}
+
+	artifacts, err := taskSvc.ListArtifacts(ctx, ListArtifactsRequest{ProjectID: "test-project", TaskID: "fix-bug"})
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+	if len(artifacts.Items) != 0 {
+		t.Errorf("read-only workspace service logged %d artifacts, want 0", len(artifacts.Items))
+	}
+	if logs.Len() > 0 {
+		t.Errorf("read-only workspace service tried to log: %s", logs.String())
+	}
+}
+
 func TestWorkspaceService_ReadFile_OutsideWorkspace(t *testing.T) {
 	workspaceSvc, taskSvc, tmpDir, cleanup := setupWorkspaceTestService(t)
 	defer cleanup()
@@ -359,6 +404,49 @@ func TestWorkspaceService_SearchFiles(t *testing.T) {
 	}
 }
 
+func TestWorkspaceService_SearchFiles_Offsets(t *testing.T) {
+	workspaceSvc, taskSvc, tmpDir, cleanup := setupWorkspaceTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	workspaceDir := filepath.Join(tmpDir, "workspace")
+	os.MkdirAll(workspaceDir, 0755)
+	content := "package main\r\n\nfunc main() { fmt.Println(\"main\") }\n"
+	os.WriteFile(filepath.Join(workspaceDir, "main.go"), []byte(content), 0644)
+
+	taskSvc.CreateProject(ctx, CreateProjectRequest{ID: "test-project", WorkspacePath: workspaceDir})
+	taskSvc.CreateTask(ctx, CreateTaskRequest{ProjectID: "test-project", ID: "fix-bug"})
+
+	result, err := workspaceSvc.SearchFiles(ctx, SearchFilesRequest{
+		ProjectID: "test-project",
+		TaskID:    "fix-bug",
+		Query:     "main",
+	})
+	if err != nil {
+		t.Fatalf("SearchFiles() error = %v", err)
+	}
+	if result.Total != 2 {
+		t.Fatalf("SearchFiles().Total = %d, want 2", result.Total)
+	}
+
+	m := result.Matches[1]
+	if m.LineNumber != 3 {
+		t.Errorf("LineNumber = %d, want 3", m.LineNumber)
+	}
+	if content[m.Offset:m.Offset+len(m.Line)] != m.Line {
+		t.Errorf("Offset = %d does not point at the line in the file", m.Offset)
+	}
+	if len(m.Highlights) != 2 {
+		t.Fatalf("Highlights = %+v, want 2", m.Highlights)
+	}
+	for _, h := range m.Highlights {
+		if got := m.Line[h.Start:h.End]; got != "main" {
+			t.Errorf("highlight %+v = %q, want main", h, got)
+		}
+	}
+}
+
 func TestWorkspaceService_SearchFiles_CaseInsensitive(t *testing.T) {
 	workspaceSvc, taskSvc, tmpDir, cleanup := setupWorkspaceTestService(t)
 	defer cleanup()
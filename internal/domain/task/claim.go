@@ -0,0 +1,118 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLease is how long a claim lasts when no lease is given.
+	DefaultLease = 30 * time.Minute
+
+	// MaxLease bounds a claim's lease, so a task whose agent went away
+	// without releasing it does not stay taken for long.
+	MaxLease = 24 * time.Hour
+)
+
+// Claim records which agent is working on a task. It lapses at ExpiresAt
+// unless renewed, after which anyone may claim the task.
+type Claim struct {
+	Owner     string     `json:"owner"`
+	ClaimedAt time.Time  `json:"claimed_at"`
+	RenewedAt *time.Time `json:"renewed_at,omitempty"` // Last renewal; nil if never renewed
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// Active reports whether the claim still holds at now.
+func (c *Claim) Active(now time.Time) bool {
+	return c != nil && now.Before(c.ExpiresAt)
+}
+
+// ActiveClaim returns the task's claim, or nil if it has none or it expired.
+func (t *Task) ActiveClaim(now time.Time) *Claim {
+	if t.Claim.Active(now) {
+		return t.Claim
+	}
+	return nil
+}
+
+// ClaimFor gives the task to owner until now+lease. Claiming an expired
+// claim of someone else takes it over; claiming one's own active claim
+// renews it. It fails with ErrTaskClaimed while another owner's claim holds.
+func (t *Task) ClaimFor(owner string, now time.Time, lease time.Duration) error {
+	owner, lease, err := claimArgs(owner, lease)
+	if err != nil {
+		return err
+	}
+	if c := t.ActiveClaim(now); c != nil {
+		if c.Owner != owner {
+			return claimedError(c)
+		}
+		return t.RenewClaim(owner, now, lease)
+	}
+
+	t.Claim = &Claim{Owner: owner, ClaimedAt: now, ExpiresAt: now.Add(lease)}
+	return nil
+}
+
+// RenewClaim extends owner's claim to now+lease. An expired claim can be
+// renewed as long as nobody else claimed the task meanwhile. It fails with
+// ErrNotClaimed if owner has no claim, and ErrTaskClaimed if someone else has.
+func (t *Task) RenewClaim(owner string, now time.Time, lease time.Duration) error {
+	owner, lease, err := claimArgs(owner, lease)
+	if err != nil {
+		return err
+	}
+	if t.Claim == nil {
+		return fmt.Errorf("%w: '%s' has no claim on task '%s'", ErrNotClaimed, owner, t.ID)
+	}
+	if t.Claim.Owner != owner {
+		if t.Claim.Active(now) {
+			return claimedError(t.Claim)
+		}
+		return fmt.Errorf("%w: '%s' has no claim on task '%s'", ErrNotClaimed, owner, t.ID)
+	}
+
+	t.Claim.RenewedAt = &now
+	t.Claim.ExpiresAt = now.Add(lease)
+	return nil
+}
+
+// ReleaseClaim removes owner's claim, or an expired claim of anyone, and
+// reports whether the task had a claim to remove. It fails with
+// ErrTaskClaimed while another owner's claim holds.
+func (t *Task) ReleaseClaim(owner string, now time.Time) (bool, error) {
+	owner, _, err := claimArgs(owner, 0)
+	if err != nil {
+		return false, err
+	}
+	if t.Claim == nil {
+		return false, nil
+	}
+	if t.Claim.Owner != owner && t.Claim.Active(now) {
+		return false, claimedError(t.Claim)
+	}
+
+	t.Claim = nil
+	return true, nil
+}
+
+// claimArgs validates an owner and a lease, 0 meaning DefaultLease.
+func claimArgs(owner string, lease time.Duration) (string, time.Duration, error) {
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return "", 0, fmt.Errorf("%w: owner is required", ErrInvalidClaim)
+	}
+	if lease == 0 {
+		lease = DefaultLease
+	}
+	if lease < 0 || lease > MaxLease {
+		return "", 0, fmt.Errorf("%w: lease must be between 0 and %s, got %s", ErrInvalidClaim, MaxLease, lease)
+	}
+	return owner, lease, nil
+}
+
+func claimedError(c *Claim) error {
+	return fmt.Errorf("%w by '%s' until %s", ErrTaskClaimed, c.Owner, c.ExpiresAt.Format(time.RFC3339))
+}
@@ -0,0 +1,83 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTask_Claim(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tsk := NewTask("backend", "auth", "Auth")
+
+	if err := tsk.ClaimFor("agent-a", now, 0); err != nil {
+		t.Fatalf("ClaimFor() error = %v", err)
+	}
+	if c := tsk.ActiveClaim(now); c == nil || c.Owner != "agent-a" || !c.ExpiresAt.Equal(now.Add(DefaultLease)) {
+		t.Fatalf("ActiveClaim() = %+v, want agent-a until now+DefaultLease", c)
+	}
+
+	// Others are turned away while the lease runs
+	if err := tsk.ClaimFor("agent-b", now.Add(time.Minute), 0); !errors.Is(err, ErrTaskClaimed) {
+		t.Errorf("ClaimFor() by another owner error = %v, want ErrTaskClaimed", err)
+	}
+	if err := tsk.RenewClaim("agent-b", now.Add(time.Minute), 0); !errors.Is(err, ErrTaskClaimed) {
+		t.Errorf("RenewClaim() by another owner error = %v, want ErrTaskClaimed", err)
+	}
+	if _, err := tsk.ReleaseClaim("agent-b", now.Add(time.Minute)); !errors.Is(err, ErrTaskClaimed) {
+		t.Errorf("ReleaseClaim() by another owner error = %v, want ErrTaskClaimed", err)
+	}
+
+	// The owner renews, keeping the original claim time
+	renewal := now.Add(20 * time.Minute)
+	if err := tsk.RenewClaim("agent-a", renewal, time.Hour); err != nil {
+		t.Fatalf("RenewClaim() error = %v", err)
+	}
+	if !tsk.Claim.ClaimedAt.Equal(now) || !tsk.Claim.ExpiresAt.Equal(renewal.Add(time.Hour)) {
+		t.Errorf("RenewClaim() claim = %+v, want claimed at %v until %v", tsk.Claim, now, renewal.Add(time.Hour))
+	}
+
+	// Once the lease expires the task is up for grabs
+	expired := renewal.Add(time.Hour)
+	if tsk.ActiveClaim(expired) != nil {
+		t.Error("ActiveClaim() after expiry != nil")
+	}
+	if err := tsk.ClaimFor("agent-b", expired, 0); err != nil {
+		t.Fatalf("ClaimFor() of an expired claim error = %v", err)
+	}
+	if err := tsk.RenewClaim("agent-a", expired, 0); !errors.Is(err, ErrTaskClaimed) {
+		t.Errorf("RenewClaim() after takeover error = %v, want ErrTaskClaimed", err)
+	}
+
+	released, err := tsk.ReleaseClaim("agent-b", expired)
+	if err != nil || !released || tsk.Claim != nil {
+		t.Errorf("ReleaseClaim() = %v, %v, claim %+v, want released", released, err, tsk.Claim)
+	}
+	if released, err := tsk.ReleaseClaim("agent-b", expired); err != nil || released {
+		t.Errorf("ReleaseClaim() again = %v, %v, want nothing to release", released, err)
+	}
+	if err := tsk.RenewClaim("agent-b", expired, 0); !errors.Is(err, ErrNotClaimed) {
+		t.Errorf("RenewClaim() without a claim error = %v, want ErrNotClaimed", err)
+	}
+}
+
+func TestTask_ClaimArgs(t *testing.T) {
+	now := time.Now()
+	tsk := NewTask("backend", "auth", "Auth")
+
+	for _, tt := range []struct {
+		owner string
+		lease time.Duration
+	}{
+		{owner: " ", lease: time.Minute},
+		{owner: "agent-a", lease: -time.Minute},
+		{owner: "agent-a", lease: MaxLease + time.Second},
+	} {
+		if err := tsk.ClaimFor(tt.owner, now, tt.lease); !errors.Is(err, ErrInvalidClaim) {
+			t.Errorf("ClaimFor(%q, %s) error = %v, want ErrInvalidClaim", tt.owner, tt.lease, err)
+		}
+	}
+	if tsk.Claim != nil {
+		t.Errorf("invalid claims left %+v", tsk.Claim)
+	}
+}
@@ -40,7 +40,9 @@ type Project struct {
 	Name          string            `json:"name"`
 	Description   string            `json:"description,omitempty"`
 	WorkspacePath string            `json:"workspace_path,omitempty"` // Default workspace for tasks
+	Workflow      *Workflow         `json:"workflow,omitempty"`       // Task statuses and transitions (nil = DefaultWorkflow)
 	Metadata      map[string]string `json:"metadata,omitempty"`
+	Revision      int               `json:"revision"` // 0 at creation, bumped by every UpdateProject
 	CreatedAt     time.Time         `json:"created_at"`
 	UpdatedAt     time.Time         `json:"updated_at"`
 }
@@ -87,17 +89,41 @@ func (t TaskID) IsValid() bool {
 // Task represents a task/issue that the agent is working on.
 type Task struct {
 	ID            TaskID            `json:"id"`
-	ProjectID     ProjectID         `json:"project_id"`               // Parent project
+	ProjectID     ProjectID         `json:"project_id"`          // Parent project
+	ParentID      TaskID            `json:"parent_id,omitempty"` // Parent task in the same project (empty = top level)
 	Name          string            `json:"name"`
 	Description   string            `json:"description,omitempty"`
 	Status        TaskStatus        `json:"status"`
+	Priority      Priority          `json:"priority,omitempty"` // p0 (most urgent) to p3; empty = DefaultPriority
+	DueDate       *time.Time        `json:"due_date,omitempty"`
 	WorkspacePath string            `json:"workspace_path,omitempty"` // Root directory for file operations (overrides project)
+	Tags          []string          `json:"tags,omitempty"`           // Normalized with NormalizeTags
+	BlockedBy     []TaskRef         `json:"blocked_by,omitempty"`     // Tasks that must be completed before this one can start
+	Blocks        []TaskRef         `json:"blocks,omitempty"`         // Tasks waiting on this one (inverse of BlockedBy)
+	Claim         *Claim            `json:"claim,omitempty"`          // Agent working on the task, until its lease expires
 	Metadata      map[string]string `json:"metadata,omitempty"`
+	Revision      int               `json:"revision"` // 0 at creation, bumped by every UpdateTask
 	CreatedAt     time.Time         `json:"created_at"`
 	UpdatedAt     time.Time         `json:"updated_at"`
 }
 
-// TaskStatus represents the status of a task.
+// TaskRef identifies a task in any project.
+type TaskRef struct {
+	ProjectID ProjectID `json:"project_id"`
+	TaskID    TaskID    `json:"task_id"`
+}
+
+// Ref returns a reference to the task.
+func (t *Task) Ref() TaskRef {
+	return TaskRef{ProjectID: t.ProjectID, TaskID: t.ID}
+}
+
+func (r TaskRef) String() string {
+	return fmt.Sprintf("%s/%s", r.ProjectID, r.TaskID)
+}
+
+// TaskStatus represents the status of a task. Projects can define their own
+// statuses with a Workflow; these are the ones of the default workflow.
 type TaskStatus string
 
 const (
@@ -107,6 +133,15 @@ const (
 	TaskStatusArchived   TaskStatus = "archived"
 )
 
+// StatusChange is an entry of a task's append-only status history.
+type StatusChange struct {
+	From   TaskStatus `json:"from,omitempty"` // Empty for the status given at creation
+	To     TaskStatus `json:"to"`
+	At     time.Time  `json:"at"`
+	Reason string     `json:"reason,omitempty"`
+	Actor  string     `json:"actor,omitempty"` // Who made the change, e.g. the MCP client name
+}
+
 // StatusEmoji returns an emoji for the status (for visual display).
 func (s TaskStatus) StatusEmoji() string {
 	switch s {
@@ -145,13 +180,18 @@ func NewTask(projectID ProjectID, id TaskID, name string) *Task {
 
 // Artifact represents a piece of work artifact associated with a task.
 type Artifact struct {
-	ID        string            `json:"id"`
-	ProjectID ProjectID         `json:"project_id"`
-	TaskID    TaskID            `json:"task_id"`
-	Type      ArtifactType      `json:"type"`
-	Content   string            `json:"content"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
-	CreatedAt time.Time         `json:"created_at"`
+	ID           string            `json:"id"`
+	ProjectID    ProjectID         `json:"project_id"`
+	TaskID       TaskID            `json:"task_id"`
+	Type         ArtifactType      `json:"type"`
+	Content      string            `json:"content"`
+	Tags         []string          `json:"tags,omitempty"` // Normalized with NormalizeTags
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Links        []ArtifactLink    `json:"links,omitempty"`         // Outgoing links
+	SupersededBy []ArtifactRef     `json:"superseded_by,omitempty"` // Artifacts with a supersedes link to this one
+	Version      int               `json:"version,omitempty"`       // 1 for the original content, bumped by every update
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    *time.Time        `json:"updated_at,omitempty"` // When this version was written; nil for the original
 }
 
 // ArtifactType defines the type of artifact.
@@ -163,9 +203,10 @@ const (
 	ArtifactTypeDecision   ArtifactType = "decision"
 	ArtifactTypeDiscussion ArtifactType = "discussion"
 	ArtifactTypeReference  ArtifactType = "reference"
-	ArtifactTypeFileRead   ArtifactType = "file_read"   // Log of file read operation
-	ArtifactTypeFileList   ArtifactType = "file_list"   // Log of directory listing
-	ArtifactTypeSearch     ArtifactType = "search"      // Log of search operation
+	ArtifactTypeFileRead   ArtifactType = "file_read" // Log of file read operation
+	ArtifactTypeFileList   ArtifactType = "file_list" // Log of directory listing
+	ArtifactTypeSearch     ArtifactType = "search"    // Log of search operation
+	ArtifactTypeSummary    ArtifactType = "summary"   // Roll-up of compacted artifacts
 	ArtifactTypeGeneric    ArtifactType = "artifact"
 )
 
@@ -179,6 +220,7 @@ func NewArtifact(projectID ProjectID, taskID TaskID, artifactType ArtifactType,
 		Type:      artifactType,
 		Content:   content,
 		Metadata:  make(map[string]string),
+		Version:   1,
 		CreatedAt: now,
 	}
 }
@@ -189,6 +231,11 @@ func (a *Artifact) Filename() string {
 	return fmt.Sprintf("%s.%s.md", a.Type, a.ID)
 }
 
+// VersionFilename returns the filename an older version of this artifact is kept under.
+func (a *Artifact) VersionFilename(version int) string {
+	return fmt.Sprintf("%s.%s.v%d.md", a.Type, a.ID, version)
+}
+
 func generateArtifactID(t time.Time) string {
 	return fmt.Sprintf("%d", t.UnixNano())
 }
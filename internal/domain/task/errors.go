@@ -1,6 +1,9 @@
 package task
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrProjectNotFound indicates the project was not found.
@@ -18,12 +21,107 @@ var (
 	// ErrTaskAlreadyExists indicates a task with the given ID already exists.
 	ErrTaskAlreadyExists = errors.New("task already exists")
 
+	// ErrInvalidParent indicates the parent task does not exist or is the task itself.
+	ErrInvalidParent = errors.New("invalid parent task")
+
+	// ErrTaskCycle indicates a parent change would make a task its own ancestor.
+	ErrTaskCycle = errors.New("task hierarchy cycle")
+
+	// ErrInvalidDependency indicates a dependency refers to a missing task or to the task itself.
+	ErrInvalidDependency = errors.New("invalid dependency")
+
+	// ErrDependencyCycle indicates a dependency would make a task wait on itself.
+	ErrDependencyCycle = errors.New("dependency cycle")
+
+	// ErrTaskBlocked indicates a task cannot start while it has open blockers.
+	ErrTaskBlocked = errors.New("task is blocked")
+
+	// ErrTaskClaimed indicates a task claimed by another agent whose lease has not expired.
+	ErrTaskClaimed = errors.New("task is claimed")
+
+	// ErrNotClaimed indicates a claim renewal by an agent that holds no claim on the task.
+	ErrNotClaimed = errors.New("task is not claimed")
+
+	// ErrInvalidClaim indicates a claim without an owner or with a lease out of range.
+	ErrInvalidClaim = errors.New("invalid claim")
+
+	// ErrInvalidTransition indicates a status change the project's workflow does not allow.
+	ErrInvalidTransition = errors.New("invalid status transition")
+
+	// ErrInvalidWorkflow indicates a workflow definition is malformed.
+	ErrInvalidWorkflow = errors.New("invalid workflow")
+
+	// ErrInvalidPriority indicates a priority other than p0-p3.
+	ErrInvalidPriority = errors.New("invalid priority")
+
+	// ErrInvalidDueDate indicates a due date that could not be parsed.
+	ErrInvalidDueDate = errors.New("invalid due date")
+
+	// ErrInvalidSort indicates a task sort order other than updated, priority, due_date or stale.
+	ErrInvalidSort = errors.New("invalid sort order")
+
 	// ErrArtifactNotFound indicates the artifact was not found.
 	ErrArtifactNotFound = errors.New("artifact not found")
 
+	// ErrArtifactVersionNotFound indicates the artifact has no such version.
+	ErrArtifactVersionNotFound = errors.New("artifact version not found")
+
+	// ErrInvalidLink indicates a link with an unknown relation or an invalid target.
+	ErrInvalidLink = errors.New("invalid link")
+
+	// ErrLinkNotFound indicates the artifact has no such link.
+	ErrLinkNotFound = errors.New("link not found")
+
+	// ErrMemoryNotFound indicates the memory was not found.
+	ErrMemoryNotFound = errors.New("memory not found")
+
+	// ErrMemoryAlreadyExists indicates a memory with the given ID already exists in its project.
+	ErrMemoryAlreadyExists = errors.New("memory already exists")
+
+	// ErrInvalidMemoryID indicates the provided memory ID is invalid.
+	ErrInvalidMemoryID = errors.New("invalid memory ID")
+
 	// ErrInvalidTaskID indicates the provided task ID is invalid.
 	ErrInvalidTaskID = errors.New("invalid task ID")
 
+	// ErrConflict indicates an update based on a revision that is no longer the stored one.
+	ErrConflict = errors.New("revision conflict")
+
+	// ErrReadOnly indicates a change to a store opened read-only.
+	ErrReadOnly = errors.New("store is read-only")
+
 	// ErrStorageFailed indicates a storage operation failed.
 	ErrStorageFailed = errors.New("storage operation failed")
+
+	// ErrInvalidQuery indicates a search query could not be parsed.
+	ErrInvalidQuery = errors.New("invalid search query")
 )
+
+// ConflictError is the ErrConflict returned by an update made against a
+// stale revision. It carries the stored project or task, so the caller can
+// reapply its change on top and retry with the current revision.
+type ConflictError struct {
+	Revision int      // The revision the update was based on
+	Project  *Project // The stored project, for project updates
+	Task     *Task    // The stored task, for task updates
+}
+
+// Current returns the stored revision.
+func (e *ConflictError) Current() int {
+	if e.Task != nil {
+		return e.Task.Revision
+	}
+	if e.Project != nil {
+		return e.Project.Revision
+	}
+	return 0
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: expected revision %d, stored revision is %d", ErrConflict, e.Revision, e.Current())
+}
+
+// Unwrap makes errors.Is(err, ErrConflict) match.
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
@@ -0,0 +1,72 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LinkRelation is the type of a link from an artifact to another artifact or a task.
+type LinkRelation string
+
+const (
+	RelationSupersedes  LinkRelation = "supersedes"   // Replaces the target artifact, which is hidden from listings
+	RelationImplements  LinkRelation = "implements"   // E.g. a code artifact implementing a decision
+	RelationRelatesTo   LinkRelation = "relates_to"   // Loosely related
+	RelationDerivedFrom LinkRelation = "derived_from" // E.g. a reference found while working on a task
+)
+
+// ParseLinkRelation parses a link relation such as "implements".
+func ParseLinkRelation(s string) (LinkRelation, error) {
+	switch r := LinkRelation(strings.ToLower(strings.TrimSpace(s))); r {
+	case RelationSupersedes, RelationImplements, RelationRelatesTo, RelationDerivedFrom:
+		return r, nil
+	default:
+		return "", fmt.Errorf("%w: unknown relation '%s' (allowed: supersedes, implements, relates_to, derived_from)", ErrInvalidLink, s)
+	}
+}
+
+// ArtifactRef identifies an artifact, or a task when ArtifactID is empty.
+type ArtifactRef struct {
+	ProjectID  ProjectID `json:"project_id"`
+	TaskID     TaskID    `json:"task_id"`
+	ArtifactID string    `json:"artifact_id,omitempty"`
+}
+
+// Ref returns the reference to an artifact.
+func (a *Artifact) Ref() ArtifactRef {
+	return ArtifactRef{ProjectID: a.ProjectID, TaskID: a.TaskID, ArtifactID: a.ID}
+}
+
+// IsTask reports whether the reference points at a task rather than an artifact.
+func (r ArtifactRef) IsTask() bool {
+	return r.ArtifactID == ""
+}
+
+// String returns "project/task" or "project/task/artifact".
+func (r ArtifactRef) String() string {
+	if r.IsTask() {
+		return fmt.Sprintf("%s/%s", r.ProjectID, r.TaskID)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.ProjectID, r.TaskID, r.ArtifactID)
+}
+
+// ArtifactLink is a typed link from an artifact to a target artifact or task.
+type ArtifactLink struct {
+	Relation LinkRelation `json:"relation"`
+	Target   ArtifactRef  `json:"target"`
+}
+
+// IsSuperseded reports whether another artifact supersedes this one.
+func (a *Artifact) IsSuperseded() bool {
+	return len(a.SupersededBy) > 0
+}
+
+// HasLink reports whether the artifact already has the link.
+func (a *Artifact) HasLink(link ArtifactLink) bool {
+	for _, l := range a.Links {
+		if l == link {
+			return true
+		}
+	}
+	return false
+}
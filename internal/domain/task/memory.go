@@ -0,0 +1,69 @@
+package task
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// GlobalScope is the ProjectID of memories shared by every project.
+const GlobalScope ProjectID = ""
+
+// MemoryID identifies a memory within its project, e.g. "coding-conventions".
+type MemoryID string
+
+// NewMemoryID creates a MemoryID from a string, normalized like task IDs.
+func NewMemoryID(id string) MemoryID {
+	normalized := strings.ToLower(strings.TrimSpace(id))
+	normalized = regexp.MustCompile(`[^a-z0-9-]`).ReplaceAllString(normalized, "-")
+	normalized = regexp.MustCompile(`-+`).ReplaceAllString(normalized, "-")
+	normalized = strings.Trim(normalized, "-")
+	return MemoryID(normalized)
+}
+
+func (m MemoryID) String() string {
+	return string(m)
+}
+
+// IsValid checks if the MemoryID is valid.
+func (m MemoryID) IsValid() bool {
+	return ProjectID(m).IsValid()
+}
+
+// Memory is long-lived knowledge that does not belong to a task, such as
+// coding conventions, architecture notes or user preferences. It belongs to a
+// project, or to every project when ProjectID is GlobalScope.
+type Memory struct {
+	ID        MemoryID  `json:"id"`
+	ProjectID ProjectID `json:"project_id,omitempty"` // GlobalScope for global memories
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags,omitempty"`   // Normalized with NormalizeTags
+	Pinned    bool      `json:"pinned,omitempty"` // Loaded with the project at session start
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewMemory creates a new Memory.
+func NewMemory(projectID ProjectID, id MemoryID, title, content string) *Memory {
+	now := time.Now().UTC()
+	return &Memory{
+		ID:        id,
+		ProjectID: projectID,
+		Title:     title,
+		Content:   content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// IsGlobal reports whether the memory is shared by every project.
+func (m *Memory) IsGlobal() bool {
+	return m.ProjectID == GlobalScope
+}
+
+// MemorySearchResult is a memory matched by a search query.
+type MemorySearchResult struct {
+	Memory *Memory `json:"memory"`
+	Score  float64 `json:"score"` // Relevance, higher is better (0 for an empty query)
+}
@@ -0,0 +1,31 @@
+package task
+
+import "testing"
+
+func TestNewMemoryID(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  MemoryID
+		valid bool
+	}{
+		{in: "Coding Conventions", want: "coding-conventions", valid: true},
+		{in: "api_style", want: "api-style", valid: true},
+		{in: " -- ", want: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		got := NewMemoryID(tt.in)
+		if got != tt.want || got.IsValid() != tt.valid {
+			t.Errorf("NewMemoryID(%q) = %q (valid %v), want %q (valid %v)", tt.in, got, got.IsValid(), tt.want, tt.valid)
+		}
+	}
+}
+
+func TestMemory_IsGlobal(t *testing.T) {
+	if !NewMemory(GlobalScope, "style", "Style", "").IsGlobal() {
+		t.Error("memory in GlobalScope is not global")
+	}
+	if NewMemory("backend", "style", "Style", "").IsGlobal() {
+		t.Error("project memory is global")
+	}
+}
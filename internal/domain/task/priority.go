@@ -0,0 +1,131 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Priority is the urgency of a task, p0 being the most urgent.
+type Priority string
+
+const (
+	PriorityP0 Priority = "p0" // Drop everything
+	PriorityP1 Priority = "p1" // Next up
+	PriorityP2 Priority = "p2" // Normal
+	PriorityP3 Priority = "p3" // Whenever there is time
+
+	// DefaultPriority is assumed for tasks without a priority.
+	DefaultPriority = PriorityP2
+)
+
+// ParsePriority parses a priority such as "p1" or "P1"; empty means DefaultPriority.
+func ParsePriority(s string) (Priority, error) {
+	p := Priority(strings.ToLower(strings.TrimSpace(s)))
+	if p == "" {
+		return DefaultPriority, nil
+	}
+	if p.Rank() < 0 {
+		return "", fmt.Errorf("%w: '%s' (allowed: p0, p1, p2, p3)", ErrInvalidPriority, s)
+	}
+	return p, nil
+}
+
+// Rank returns 0 for p0 through 3 for p3, the rank of DefaultPriority for an
+// empty priority, and -1 for an unknown one.
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityP0:
+		return 0
+	case PriorityP1:
+		return 1
+	case PriorityP2, "":
+		return 2
+	case PriorityP3:
+		return 3
+	default:
+		return -1
+	}
+}
+
+// EffectivePriority returns the task's priority, or DefaultPriority if it has none.
+func (t *Task) EffectivePriority() Priority {
+	if t.Priority == "" {
+		return DefaultPriority
+	}
+	return t.Priority
+}
+
+// ParseDueDate parses a due date given as YYYY-MM-DD, meaning the end of that
+// day in UTC, or as RFC 3339. Empty returns nil.
+func ParseDueDate(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if day, err := time.Parse("2006-01-02", s); err == nil {
+		due := day.AddDate(0, 0, 1).Add(-time.Second)
+		return &due, nil
+	}
+	due, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: expected YYYY-MM-DD or RFC 3339, got '%s'", ErrInvalidDueDate, s)
+	}
+	due = due.UTC()
+	return &due, nil
+}
+
+// TaskSort is the order of a task listing.
+type TaskSort string
+
+const (
+	SortUpdated  TaskSort = "updated"  // Most recently updated first (default)
+	SortPriority TaskSort = "priority" // Most urgent first, then most recently updated
+	SortDueDate  TaskSort = "due_date" // Earliest due date first, tasks without one last
+	SortStale    TaskSort = "stale"    // Least recently updated first
+)
+
+// ParseTaskSort parses a sort order; empty means SortUpdated.
+func ParseTaskSort(s string) (TaskSort, error) {
+	switch o := TaskSort(strings.ToLower(strings.TrimSpace(s))); o {
+	case "":
+		return SortUpdated, nil
+	case SortUpdated, SortPriority, SortDueDate, SortStale:
+		return o, nil
+	default:
+		return "", fmt.Errorf("%w: '%s' (allowed: updated, priority, due_date, stale)", ErrInvalidSort, s)
+	}
+}
+
+// SortTasks orders tasks in place. Ties are broken by most recent update, then ID.
+func SortTasks(tasks []*Task, order TaskSort) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		switch order {
+		case SortPriority:
+			if ra, rb := a.Priority.Rank(), b.Priority.Rank(); ra != rb {
+				return ra < rb
+			}
+		case SortDueDate:
+			switch {
+			case a.DueDate != nil && b.DueDate != nil && !a.DueDate.Equal(*b.DueDate):
+				return a.DueDate.Before(*b.DueDate)
+			case (a.DueDate == nil) != (b.DueDate == nil):
+				return a.DueDate != nil
+			}
+			if ra, rb := a.Priority.Rank(), b.Priority.Rank(); ra != rb {
+				return ra < rb
+			}
+		case SortStale:
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+			return a.ID < b.ID
+		}
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		return a.ID < b.ID
+	})
+}
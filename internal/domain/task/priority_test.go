@@ -0,0 +1,103 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Priority
+		wantErr bool
+	}{
+		{in: "", want: DefaultPriority},
+		{in: "p0", want: PriorityP0},
+		{in: " P3 ", want: PriorityP3},
+		{in: "high", wantErr: true},
+		{in: "p4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePriority(tt.in)
+		if tt.wantErr && !errors.Is(err, ErrInvalidPriority) {
+			t.Errorf("ParsePriority(%q) error = %v, want ErrInvalidPriority", tt.in, err)
+		}
+		if !tt.wantErr && (err != nil || got != tt.want) {
+			t.Errorf("ParsePriority(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseDueDate(t *testing.T) {
+	due, err := ParseDueDate("2026-03-01")
+	if err != nil {
+		t.Fatalf("ParseDueDate(date) error = %v", err)
+	}
+	if want := time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC); !due.Equal(want) {
+		t.Errorf("ParseDueDate(date) = %v, want end of day %v", due, want)
+	}
+
+	due, err = ParseDueDate("2026-03-01T10:00:00+02:00")
+	if err != nil || !due.Equal(time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseDueDate(RFC 3339) = %v, %v", due, err)
+	}
+
+	if due, err := ParseDueDate(""); due != nil || err != nil {
+		t.Errorf("ParseDueDate(\"\") = %v, %v, want nil, nil", due, err)
+	}
+	if _, err := ParseDueDate("next week"); !errors.Is(err, ErrInvalidDueDate) {
+		t.Errorf("ParseDueDate(invalid) error = %v, want ErrInvalidDueDate", err)
+	}
+}
+
+func TestParseTaskSort(t *testing.T) {
+	for in, want := range map[string]TaskSort{"": SortUpdated, " Priority ": SortPriority, "due_date": SortDueDate, "stale": SortStale} {
+		if got, err := ParseTaskSort(in); got != want || err != nil {
+			t.Errorf("ParseTaskSort(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseTaskSort("newest"); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("ParseTaskSort(newest) error = %v, want ErrInvalidSort", err)
+	}
+}
+
+func TestSortTasks(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	due := func(days int) *time.Time {
+		d := now.AddDate(0, 0, days)
+		return &d
+	}
+
+	newTasks := func() []*Task {
+		return []*Task{
+			{ID: "recent", Priority: PriorityP3, UpdatedAt: now},
+			{ID: "urgent", Priority: PriorityP0, DueDate: due(5), UpdatedAt: now.Add(-time.Hour)},
+			{ID: "default", DueDate: due(1), UpdatedAt: now.Add(-2 * time.Hour)},
+			{ID: "old", Priority: PriorityP1, UpdatedAt: now.AddDate(0, 0, -30)},
+		}
+	}
+
+	tests := []struct {
+		order TaskSort
+		want  []TaskID
+	}{
+		{order: SortUpdated, want: []TaskID{"recent", "urgent", "default", "old"}},
+		{order: SortPriority, want: []TaskID{"urgent", "old", "default", "recent"}},
+		{order: SortDueDate, want: []TaskID{"default", "urgent", "old", "recent"}},
+		{order: SortStale, want: []TaskID{"old", "default", "urgent", "recent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			tasks := newTasks()
+			SortTasks(tasks, tt.order)
+			for i, id := range tt.want {
+				if tasks[i].ID != id {
+					t.Fatalf("SortTasks(%s)[%d] = %s, want order %v", tt.order, i, tasks[i].ID, tt.want)
+				}
+			}
+		})
+	}
+}
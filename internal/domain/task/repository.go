@@ -6,18 +6,29 @@ import (
 
 // ListOptions contains pagination and filtering options for list operations.
 type ListOptions struct {
-	Limit  int        // Maximum number of items to return (0 = no limit, default 50)
-	Offset int        // Number of items to skip
-	Status TaskStatus // Filter by status (empty = all)
+	Limit             int        // Maximum number of items to return (0 = no limit, default 50)
+	Offset            int        // Number of items to skip
+	Status            TaskStatus // Filter by status (empty = all)
+	Parent            TaskID     // Filter tasks by parent, only direct subtasks (empty = all)
+	Tags              []string   // Filter by tags, normalized (empty = all)
+	TagMatch          TagMatch   // How Tags are matched (empty = any)
+	Sort              TaskSort   // Order of task listings (empty = most recently updated first)
+	IncludeSuperseded bool       // Include superseded artifacts, which are hidden by default
 }
 
 // ListResult contains paginated results with metadata.
 type ListResult[T any] struct {
-	Items      []T  `json:"items"`
-	Total      int  `json:"total"`       // Total count without pagination
-	Limit      int  `json:"limit"`       // Applied limit
-	Offset     int  `json:"offset"`      // Applied offset
-	HasMore    bool `json:"has_more"`    // Whether there are more items
+	Items   []T  `json:"items"`
+	Total   int  `json:"total"`    // Total count without pagination
+	Limit   int  `json:"limit"`    // Applied limit
+	Offset  int  `json:"offset"`   // Applied offset
+	HasMore bool `json:"has_more"` // Whether there are more items
+}
+
+// SearchResult is an artifact matched by a search query.
+type SearchResult struct {
+	Artifact *Artifact `json:"artifact"`
+	Score    float64   `json:"score"` // Relevance, higher is better (0 for an empty query)
 }
 
 // Repository defines the contract for project, task, and artifact storage.
@@ -33,10 +44,11 @@ type Repository interface {
 	// ListProjects returns projects with pagination.
 	ListProjects(ctx context.Context, opts ListOptions) (*ListResult[*Project], error)
 
-	// UpdateProject updates project metadata.
+	// UpdateProject updates project metadata and bumps its revision. It fails
+	// with a *ConflictError if the stored revision is not project.Revision.
 	UpdateProject(ctx context.Context, project *Project) error
 
-	// DeleteProject removes a project and all its tasks.
+	// DeleteProject removes a project with its tasks and memories.
 	DeleteProject(ctx context.Context, id ProjectID) error
 
 	// Task operations
@@ -53,12 +65,19 @@ type Repository interface {
 	// ListAllTasks returns tasks from all projects with pagination.
 	ListAllTasks(ctx context.Context, opts ListOptions) (*ListResult[*Task], error)
 
-	// UpdateTask updates task metadata.
+	// UpdateTask updates task metadata and bumps its revision. It fails with a
+	// *ConflictError if the stored revision is not task.Revision.
 	UpdateTask(ctx context.Context, task *Task) error
 
 	// DeleteTask removes a task and all its artifacts.
 	DeleteTask(ctx context.Context, projectID ProjectID, taskID TaskID) error
 
+	// AppendTaskHistory appends a status change to the task's history.
+	AppendTaskHistory(ctx context.Context, projectID ProjectID, taskID TaskID, change StatusChange) error
+
+	// GetTaskHistory returns the task's status changes, oldest first.
+	GetTaskHistory(ctx context.Context, projectID ProjectID, taskID TaskID) ([]StatusChange, error)
+
 	// Artifact operations
 
 	// SaveArtifact saves an artifact to a task.
@@ -67,19 +86,83 @@ type Repository interface {
 	// GetArtifact retrieves an artifact by project ID, task ID, and artifact ID.
 	GetArtifact(ctx context.Context, projectID ProjectID, taskID TaskID, artifactID string) (*Artifact, error)
 
+	// UpdateArtifact replaces the content, tags and metadata of an existing
+	// artifact, keeping the stored one as a prior version. It sets a.Version
+	// and a.UpdatedAt.
+	UpdateArtifact(ctx context.Context, artifact *Artifact) error
+
+	// UpdateArtifactLinks calls change with the stored artifact, with no other
+	// change made to it in between, and stores the Links and SupersededBy
+	// change leaves unless it returns false. Content and version stay as they
+	// are. It returns the artifact as stored.
+	UpdateArtifactLinks(ctx context.Context, projectID ProjectID, taskID TaskID, artifactID string, change func(*Artifact) bool) (*Artifact, error)
+
+	// ListArtifactVersions returns every version of an artifact, oldest first.
+	ListArtifactVersions(ctx context.Context, projectID ProjectID, taskID TaskID, artifactID string) ([]*Artifact, error)
+
+	// GetArtifactVersion retrieves a specific version of an artifact.
+	GetArtifactVersion(ctx context.Context, projectID ProjectID, taskID TaskID, artifactID string, version int) (*Artifact, error)
+
 	// ListArtifacts returns artifacts for a task with pagination.
 	ListArtifacts(ctx context.Context, projectID ProjectID, taskID TaskID, opts ListOptions) (*ListResult[*Artifact], error)
 
 	// SearchArtifacts searches artifact content across all projects/tasks or specific project/task.
-	SearchArtifacts(ctx context.Context, query string, projectID *ProjectID, taskID *TaskID, opts ListOptions) (*ListResult[*Artifact], error)
+	// Results are ordered by relevance, best first.
+	SearchArtifacts(ctx context.Context, query string, projectID *ProjectID, taskID *TaskID, opts ListOptions) (*ListResult[*SearchResult], error)
+
+	// CountTags returns how many tasks and artifacts carry each tag, across all
+	// projects or in a specific one, in no particular order. Superseded
+	// artifacts are counted.
+	CountTags(ctx context.Context, projectID *ProjectID) ([]TagCount, error)
 
 	// DeleteArtifact removes an artifact.
 	DeleteArtifact(ctx context.Context, projectID ProjectID, taskID TaskID, artifactID string) error
 
+	// Memory operations. GlobalScope as the project ID addresses global memories.
+
+	// CreateMemory saves a new memory in its project or the global scope.
+	CreateMemory(ctx context.Context, memory *Memory) error
+
+	// GetMemory retrieves a memory by project ID and memory ID.
+	GetMemory(ctx context.Context, projectID ProjectID, memoryID MemoryID) (*Memory, error)
+
+	// ListMemories returns the memories of a project, or the global ones, with pagination.
+	ListMemories(ctx context.Context, projectID ProjectID, opts ListOptions) (*ListResult[*Memory], error)
+
+	// UpdateMemory replaces an existing memory and sets its UpdatedAt.
+	UpdateMemory(ctx context.Context, memory *Memory) error
+
+	// DeleteMemory removes a memory.
+	DeleteMemory(ctx context.Context, projectID ProjectID, memoryID MemoryID) error
+
+	// SearchMemories searches memory titles and content in every scope or in
+	// one project (GlobalScope for global memories), best first.
+	SearchMemories(ctx context.Context, query string, projectID *ProjectID, opts ListOptions) (*ListResult[*MemorySearchResult], error)
+
 	// Close releases any resources.
 	Close() error
 }
 
+// SearchIndexer is implemented by repositories whose search index can get out
+// of sync with stored artifacts, e.g. when files are edited outside the server.
+type SearchIndexer interface {
+	// RebuildSearchIndex re-indexes every artifact and memory and returns how many were indexed.
+	RebuildSearchIndex(ctx context.Context) (int, error)
+}
+
+// ReadOnly is implemented by repositories that may reject every change, so
+// services can skip writes that are only side effects.
+type ReadOnly interface {
+	// ReadOnly reports whether changes fail with ErrReadOnly.
+	ReadOnly() bool
+}
+
+// IsReadOnly reports whether repo rejects every change.
+func IsReadOnly(repo Repository) bool {
+	r, ok := repo.(ReadOnly)
+	return ok && r.ReadOnly()
+}
+
 // DefaultListOptions returns default pagination options.
 func DefaultListOptions() ListOptions {
 	return ListOptions{
@@ -0,0 +1,72 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TagMatch selects how a tag filter is matched against an item's tags.
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any" // The item has at least one of the tags
+	TagMatchAll TagMatch = "all" // The item has every tag
+)
+
+// TagCount is the number of tasks and artifacts carrying a tag.
+type TagCount struct {
+	Tag       string
+	Tasks     int
+	Artifacts int
+}
+
+// ParseTagMatch parses a tag match mode; empty means TagMatchAny.
+func ParseTagMatch(s string) (TagMatch, error) {
+	switch m := TagMatch(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return TagMatchAny, nil
+	case TagMatchAny, TagMatchAll:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid tag match '%s' (allowed: any, all)", s)
+	}
+}
+
+// NormalizeTags lowercases and trims tags, replaces inner whitespace with
+// dashes, and drops empty and duplicate tags. Order is preserved.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// MatchesTags reports whether an item with the given tags passes the tag
+// filter. Tags are expected to be normalized.
+func (o ListOptions) MatchesTags(tags []string) bool {
+	if len(o.Tags) == 0 {
+		return true
+	}
+
+	has := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		has[tag] = true
+	}
+
+	for _, want := range o.Tags {
+		if has[want] && o.TagMatch != TagMatchAll {
+			return true
+		}
+		if !has[want] && o.TagMatch == TagMatchAll {
+			return false
+		}
+	}
+	return o.TagMatch == TagMatchAll
+}
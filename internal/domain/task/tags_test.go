@@ -0,0 +1,58 @@
+package task
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Backend ", "auth", "", "backend", "Tech Debt"})
+	want := []string{"backend", "auth", "tech-debt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags() = %v, want %v", got, want)
+	}
+}
+
+func TestParseTagMatch(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    TagMatch
+		wantErr bool
+	}{
+		{in: "", want: TagMatchAny},
+		{in: "any", want: TagMatchAny},
+		{in: "ALL", want: TagMatchAll},
+		{in: "some", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTagMatch(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTagMatch(%q) = %q, %v, want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestListOptions_MatchesTags(t *testing.T) {
+	tags := []string{"backend", "auth"}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want bool
+	}{
+		{name: "no filter", opts: ListOptions{}, want: true},
+		{name: "any one", opts: ListOptions{Tags: []string{"auth", "ui"}}, want: true},
+		{name: "any none", opts: ListOptions{Tags: []string{"ui"}}, want: false},
+		{name: "all present", opts: ListOptions{Tags: []string{"auth", "backend"}, TagMatch: TagMatchAll}, want: true},
+		{name: "all missing one", opts: ListOptions{Tags: []string{"auth", "ui"}, TagMatch: TagMatchAll}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.MatchesTags(tags); got != tt.want {
+				t.Errorf("MatchesTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// statusNamePattern restricts status names to characters that are safe in
+// task directory names ([status]-task-id).
+var statusNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Workflow defines the statuses a project's tasks can have and the allowed
+// transitions between them. It is stored in project.json.
+type Workflow struct {
+	// Statuses in display order. The first one is given to new tasks.
+	Statuses []WorkflowStatus `json:"statuses"`
+}
+
+// WorkflowStatus is one state of a workflow.
+type WorkflowStatus struct {
+	Name TaskStatus `json:"name"`
+
+	// Transitions lists the statuses a task can move to from this one.
+	// Empty allows any status of the workflow.
+	Transitions []TaskStatus `json:"transitions,omitempty"`
+
+	// Terminal marks a final status: tasks cannot leave it.
+	Terminal bool `json:"terminal,omitempty"`
+}
+
+// DefaultWorkflow returns the workflow used by projects without one:
+// open, in_progress, completed and archived, with any transition allowed.
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		Statuses: []WorkflowStatus{
+			{Name: TaskStatusOpen},
+			{Name: TaskStatusInProgress},
+			{Name: TaskStatusCompleted},
+			{Name: TaskStatusArchived},
+		},
+	}
+}
+
+// TaskWorkflow returns the project's workflow, or DefaultWorkflow if it has none.
+func (p *Project) TaskWorkflow() *Workflow {
+	if p.Workflow == nil || len(p.Workflow.Statuses) == 0 {
+		return DefaultWorkflow()
+	}
+	return p.Workflow
+}
+
+// Initial returns the status given to new tasks.
+func (w *Workflow) Initial() TaskStatus {
+	return w.Statuses[0].Name
+}
+
+// Status returns the definition of a status.
+func (w *Workflow) Status(name TaskStatus) (WorkflowStatus, bool) {
+	for _, s := range w.Statuses {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return WorkflowStatus{}, false
+}
+
+// Done reports whether a task in status is finished with: in a terminal
+// status, or completed or archived, which the default workflow does not mark
+// terminal so that tasks can be reopened.
+func (w *Workflow) Done(status TaskStatus) bool {
+	if status == TaskStatusCompleted || status == TaskStatusArchived {
+		return true
+	}
+	s, ok := w.Status(status)
+	return ok && s.Terminal
+}
+
+// Started reports whether a task in status is being worked on: in a status
+// of the workflow past the initial one, and not Done.
+func (w *Workflow) Started(status TaskStatus) bool {
+	if status == w.Initial() || w.Done(status) {
+		return false
+	}
+	_, ok := w.Status(status)
+	return ok
+}
+
+// StatusNames returns the names of all statuses in order.
+func (w *Workflow) StatusNames() []TaskStatus {
+	names := make([]TaskStatus, 0, len(w.Statuses))
+	for _, s := range w.Statuses {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// CheckTransition returns ErrInvalidTransition if a task cannot move from one
+// status to another. A task whose current status is not part of the workflow
+// (e.g. after the workflow was changed) may move to any status of it.
+func (w *Workflow) CheckTransition(from, to TaskStatus) error {
+	if _, ok := w.Status(to); !ok {
+		return fmt.Errorf("%w: unknown status '%s' (allowed: %v)", ErrInvalidTransition, to, w.StatusNames())
+	}
+	if from == to {
+		return nil
+	}
+
+	current, ok := w.Status(from)
+	if !ok {
+		return nil
+	}
+	if current.Terminal {
+		return fmt.Errorf("%w: '%s' is a terminal status", ErrInvalidTransition, from)
+	}
+	if len(current.Transitions) == 0 {
+		return nil
+	}
+	for _, next := range current.Transitions {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: '%s' -> '%s' (allowed from '%s': %v)", ErrInvalidTransition, from, to, from, current.Transitions)
+}
+
+// Validate checks that status names are unique and usable in directory names,
+// and that transitions only refer to statuses of the workflow.
+func (w *Workflow) Validate() error {
+	if len(w.Statuses) == 0 {
+		return fmt.Errorf("%w: at least one status is required", ErrInvalidWorkflow)
+	}
+
+	seen := make(map[TaskStatus]bool, len(w.Statuses))
+	for _, s := range w.Statuses {
+		if !statusNamePattern.MatchString(string(s.Name)) {
+			return fmt.Errorf("%w: status '%s' must be lowercase letters, digits and underscores", ErrInvalidWorkflow, s.Name)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("%w: duplicate status '%s'", ErrInvalidWorkflow, s.Name)
+		}
+		seen[s.Name] = true
+	}
+
+	for _, s := range w.Statuses {
+		if s.Terminal && len(s.Transitions) > 0 {
+			return fmt.Errorf("%w: terminal status '%s' cannot have transitions", ErrInvalidWorkflow, s.Name)
+		}
+		for _, next := range s.Transitions {
+			if !seen[next] {
+				return fmt.Errorf("%w: status '%s' has a transition to unknown status '%s'", ErrInvalidWorkflow, s.Name, next)
+			}
+		}
+	}
+
+	return nil
+}
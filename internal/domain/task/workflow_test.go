@@ -0,0 +1,155 @@
+package task
+
+import (
+	"errors"
+	"testing"
+)
+
+func reviewWorkflow() *Workflow {
+	return &Workflow{
+		Statuses: []WorkflowStatus{
+			{Name: "todo", Transitions: []TaskStatus{"doing"}},
+			{Name: "doing", Transitions: []TaskStatus{"review", "blocked", "todo"}},
+			{Name: "blocked"},
+			{Name: "review", Transitions: []TaskStatus{"done", "doing"}},
+			{Name: "done", Terminal: true},
+		},
+	}
+}
+
+func TestWorkflow_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow *Workflow
+		wantErr  bool
+	}{
+		{name: "default", workflow: DefaultWorkflow()},
+		{name: "custom", workflow: reviewWorkflow()},
+		{name: "empty", workflow: &Workflow{}, wantErr: true},
+		{
+			name:     "invalid name",
+			workflow: &Workflow{Statuses: []WorkflowStatus{{Name: "In Review"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "duplicate",
+			workflow: &Workflow{Statuses: []WorkflowStatus{{Name: "todo"}, {Name: "todo"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown transition",
+			workflow: &Workflow{Statuses: []WorkflowStatus{{Name: "todo", Transitions: []TaskStatus{"done"}}}},
+			wantErr:  true,
+		},
+		{
+			name: "terminal with transitions",
+			workflow: &Workflow{Statuses: []WorkflowStatus{
+				{Name: "todo"},
+				{Name: "done", Terminal: true, Transitions: []TaskStatus{"todo"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.workflow.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidWorkflow) {
+				t.Errorf("Validate() error = %v, want ErrInvalidWorkflow", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestWorkflow_CheckTransition(t *testing.T) {
+	w := reviewWorkflow()
+
+	tests := []struct {
+		from, to TaskStatus
+		allowed  bool
+	}{
+		{from: "todo", to: "doing", allowed: true},
+		{from: "todo", to: "done", allowed: false},
+		{from: "doing", to: "review", allowed: true},
+		{from: "review", to: "done", allowed: true},
+		{from: "blocked", to: "todo", allowed: true}, // No transitions listed: any
+		{from: "done", to: "doing", allowed: false},  // Terminal
+		{from: "done", to: "done", allowed: true},
+		{from: "doing", to: "unknown", allowed: false},
+		{from: "open", to: "todo", allowed: true}, // Status from a previous workflow
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := w.CheckTransition(tt.from, tt.to)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckTransition() error = %v, want nil", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("CheckTransition() error = %v, want ErrInvalidTransition", err)
+			}
+		})
+	}
+}
+
+func TestWorkflow_Started(t *testing.T) {
+	tests := []struct {
+		workflow *Workflow
+		status   TaskStatus
+		want     bool
+	}{
+		{workflow: reviewWorkflow(), status: "todo", want: false}, // Initial
+		{workflow: reviewWorkflow(), status: "doing", want: true},
+		{workflow: reviewWorkflow(), status: "review", want: true},
+		{workflow: reviewWorkflow(), status: "done", want: false}, // Terminal
+		{workflow: reviewWorkflow(), status: "unknown", want: false},
+		{workflow: DefaultWorkflow(), status: TaskStatusOpen, want: false},
+		{workflow: DefaultWorkflow(), status: TaskStatusInProgress, want: true},
+		{workflow: DefaultWorkflow(), status: TaskStatusCompleted, want: false},
+		{workflow: DefaultWorkflow(), status: TaskStatusArchived, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.workflow.Started(tt.status); got != tt.want {
+			t.Errorf("Started(%q) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestWorkflow_Done(t *testing.T) {
+	tests := []struct {
+		workflow *Workflow
+		status   TaskStatus
+		want     bool
+	}{
+		{workflow: reviewWorkflow(), status: "todo", want: false},
+		{workflow: reviewWorkflow(), status: "review", want: false},
+		{workflow: reviewWorkflow(), status: "done", want: true}, // Terminal
+		{workflow: reviewWorkflow(), status: "unknown", want: false},
+		{workflow: reviewWorkflow(), status: TaskStatusCompleted, want: true},
+		{workflow: DefaultWorkflow(), status: TaskStatusInProgress, want: false},
+		{workflow: DefaultWorkflow(), status: TaskStatusCompleted, want: true},
+		{workflow: DefaultWorkflow(), status: TaskStatusArchived, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.workflow.Done(tt.status); got != tt.want {
+			t.Errorf("Done(%q) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestProject_TaskWorkflow(t *testing.T) {
+	p := NewProject("test", "Test")
+	if got := p.TaskWorkflow().Initial(); got != TaskStatusOpen {
+		t.Errorf("default Initial() = %q, want open", got)
+	}
+
+	p.Workflow = reviewWorkflow()
+	if got := p.TaskWorkflow().Initial(); got != "todo" {
+		t.Errorf("custom Initial() = %q, want todo", got)
+	}
+}
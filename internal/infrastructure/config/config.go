@@ -16,10 +16,25 @@ type Config struct {
 	// LogLevel is the logging level: debug, info, warn, error.
 	LogLevel string `yaml:"log_level"`
 
+	// Storage selects and configures the repository backend.
+	Storage StorageConfig `yaml:"storage"`
+
 	// Server contains MCP server-specific configuration.
 	Server ServerConfig `yaml:"server"`
 }
 
+// Storage backends supported by StorageConfig.Backend.
+const (
+	StorageBackendFilesystem = "filesystem"
+	StorageBackendSQLite     = "sqlite"
+)
+
+// StorageConfig contains storage backend configuration.
+type StorageConfig struct {
+	// Backend is the repository implementation: filesystem or sqlite.
+	Backend string `yaml:"backend"`
+}
+
 // ServerConfig contains MCP server configuration.
 type ServerConfig struct {
 	// Name is the server name exposed via MCP.
@@ -27,6 +42,44 @@ type ServerConfig struct {
 
 	// Version is the server version.
 	Version string `yaml:"version"`
+
+	// Transport is how clients connect: stdio, sse or http.
+	Transport string `yaml:"transport"`
+
+	// Listen is the address the sse and http transports listen on.
+	Listen string `yaml:"listen"`
+
+	// BasePath is the URL path the sse and http endpoints are served under.
+	BasePath string `yaml:"base_path"`
+
+	// ReadOnly serves the store without the tools that change it.
+	ReadOnly bool `yaml:"read_only"`
+
+	// Auth configures bearer-token authentication for the sse and http
+	// transports.
+	Auth AuthConfig `yaml:"auth"`
+}
+
+// AuthConfig contains the tokens clients of the network transports present.
+// Without tokens every client has full access.
+type AuthConfig struct {
+	Tokens []TokenConfig `yaml:"tokens"`
+}
+
+// TokenConfig is a static bearer token.
+type TokenConfig struct {
+	// Name identifies the token in logs.
+	Name string `yaml:"name"`
+
+	// Token is the secret clients send as "Authorization: Bearer <token>".
+	Token string `yaml:"token"`
+
+	// Scopes are the permissions the token grants: read, write, delete,
+	// workspace.
+	Scopes []string `yaml:"scopes"`
+
+	// Projects limits the token to these project IDs; empty allows all.
+	Projects []string `yaml:"projects"`
 }
 
 // DefaultConfig returns the default configuration.
@@ -34,9 +87,15 @@ func DefaultConfig() *Config {
 	return &Config{
 		TasksPath: "",
 		LogLevel:  "info",
+		Storage: StorageConfig{
+			Backend: StorageBackendFilesystem,
+		},
 		Server: ServerConfig{
-			Name:    "agent-memory",
-			Version: "1.0.0",
+			Name:      "agent-memory",
+			Version:   "1.0.0",
+			Transport: "stdio",
+			Listen:    "127.0.0.1:8765",
+			BasePath:  "/",
 		},
 	}
 }
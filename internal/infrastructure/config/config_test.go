@@ -15,12 +15,18 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.LogLevel != "info" {
 		t.Errorf("DefaultConfig().LogLevel = %q, want \"info\"", cfg.LogLevel)
 	}
+	if cfg.Storage.Backend != StorageBackendFilesystem {
+		t.Errorf("DefaultConfig().Storage.Backend = %q, want %q", cfg.Storage.Backend, StorageBackendFilesystem)
+	}
 	if cfg.Server.Name != "agent-memory" {
 		t.Errorf("DefaultConfig().Server.Name = %q, want \"agent-memory\"", cfg.Server.Name)
 	}
 	if cfg.Server.Version != "1.0.0" {
 		t.Errorf("DefaultConfig().Server.Version = %q, want \"1.0.0\"", cfg.Server.Version)
 	}
+	if cfg.Server.Transport != "stdio" || cfg.Server.Listen != "127.0.0.1:8765" || cfg.Server.BasePath != "/" {
+		t.Errorf("DefaultConfig().Server = %+v, want stdio, 127.0.0.1:8765 and /", cfg.Server)
+	}
 }
 
 func TestLoad(t *testing.T) {
@@ -31,9 +37,19 @@ func TestLoad(t *testing.T) {
 	yamlContent := `
 tasks_path: /custom/tasks
 log_level: debug
+storage:
+  backend: sqlite
 server:
   name: test-server
   version: "2.0.0"
+  transport: http
+  read_only: true
+  auth:
+    tokens:
+      - name: ci
+        token: secret
+        scopes: [read, write]
+        projects: [backend]
 `
 	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
 		t.Fatalf("Failed to write test config: %v", err)
@@ -50,12 +66,22 @@ server:
 	if cfg.LogLevel != "debug" {
 		t.Errorf("Config.LogLevel = %q, want \"debug\"", cfg.LogLevel)
 	}
+	if cfg.Storage.Backend != StorageBackendSQLite {
+		t.Errorf("Config.Storage.Backend = %q, want %q", cfg.Storage.Backend, StorageBackendSQLite)
+	}
 	if cfg.Server.Name != "test-server" {
 		t.Errorf("Config.Server.Name = %q, want \"test-server\"", cfg.Server.Name)
 	}
 	if cfg.Server.Version != "2.0.0" {
 		t.Errorf("Config.Server.Version = %q, want \"2.0.0\"", cfg.Server.Version)
 	}
+	if cfg.Server.Transport != "http" || cfg.Server.Listen != "127.0.0.1:8765" || !cfg.Server.ReadOnly {
+		t.Errorf("Config.Server = %+v, want the read-only http transport on the default address", cfg.Server)
+	}
+	if tokens := cfg.Server.Auth.Tokens; len(tokens) != 1 || tokens[0].Name != "ci" || tokens[0].Token != "secret" ||
+		len(tokens[0].Scopes) != 2 || len(tokens[0].Projects) != 1 {
+		t.Errorf("Config.Server.Auth.Tokens = %+v, want the ci token", tokens)
+	}
 }
 
 func TestLoad_NotFound(t *testing.T) {
@@ -0,0 +1,748 @@
+package search
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+const (
+	snapshotVersion = 2 // 2: documents carry tags
+
+	// journalSuffix is appended to the snapshot path to get the journal path.
+	journalSuffix = ".journal"
+
+	// compactAfter is the number of journal entries after which the journal
+	// is folded into a new snapshot.
+	compactAfter = 1000
+)
+
+// Journal operations.
+const (
+	opAdd           = "add"
+	opRemove        = "remove"
+	opRemoveTask    = "remove_task"
+	opRemoveProject = "remove_project"
+)
+
+// Doc identifies an indexed artifact, or a memory when TaskID is empty.
+type Doc struct {
+	ProjectID  string   `json:"project_id"`
+	TaskID     string   `json:"task_id"`
+	ID         string   `json:"id"`
+	Type       string   `json:"type"`
+	Tags       []string `json:"tags,omitempty"`       // For filtering, not indexed as text
+	Superseded bool     `json:"superseded,omitempty"` // For hiding superseded artifacts
+	CreatedAt  int64    `json:"created_at"`           // Unix nanoseconds, breaks score ties
+	Text       string   `json:"-"`                    // Indexed text, not persisted
+}
+
+// Hit is a document matched by a query.
+type Hit struct {
+	Doc
+	Score float64
+}
+
+type docKey struct {
+	projectID string
+	taskID    string
+	id        string
+}
+
+func (d Doc) key() docKey {
+	return docKey{projectID: d.ProjectID, taskID: d.TaskID, id: d.ID}
+}
+
+// entry is an indexed document with its token positions.
+type entry struct {
+	Doc
+	Length int              `json:"length"`
+	Terms  map[string][]int `json:"terms"` // term -> token positions
+}
+
+func newEntry(d Doc) *entry {
+	tokens := Tokenize(d.Text)
+	e := &entry{Doc: d, Length: len(tokens), Terms: make(map[string][]int)}
+	e.Text = ""
+	for pos, tok := range tokens {
+		e.Terms[tok] = append(e.Terms[tok], pos)
+	}
+	return e
+}
+
+type snapshot struct {
+	Version int      `json:"version"`
+	Docs    []*entry `json:"docs"`
+}
+
+type journalEntry struct {
+	Op        string `json:"op"`
+	Doc       *entry `json:"doc,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	TaskID    string `json:"task_id,omitempty"`
+	ID        string `json:"id,omitempty"`
+}
+
+// Locker takes the lock guarding an index shared by several processes:
+// exclusive to change the persisted index, shared to read it. It returns the
+// function that releases the lock.
+type Locker func(exclusive bool) (unlock func(), err error)
+
+// Index is a positional inverted index with BM25 ranking.
+//
+// It is persisted as a JSON snapshot plus an append-only journal of changes
+// made since the snapshot; the journal is replayed on open and periodically
+// compacted into a new snapshot. An Index with an empty path lives in memory only.
+//
+// Several processes may share a persisted index when they open it with the
+// same Locker: each change first applies the changes of the others, and
+// Refresh picks them up before reading.
+type Index struct {
+	mu sync.RWMutex
+
+	path     string
+	lock     Locker
+	readOnly bool // Loaded with Load: changes stay in memory
+
+	// The persisted state applied so far, to notice changes of other processes
+	snapshotInfo os.FileInfo
+	journalInfo  os.FileInfo
+	journalSize  int64
+	journalOps   int
+
+	docs        map[docKey]*entry
+	postings    map[string]map[docKey][]int
+	totalLength int
+}
+
+// NewIndex creates an empty in-memory index.
+func NewIndex() *Index {
+	idx := &Index{}
+	idx.reset()
+	return idx
+}
+
+// Open loads the index persisted at path. found is false when nothing has
+// been persisted there yet, in which case the caller should populate the
+// index with Rebuild. lock may be nil when no other process uses the index.
+func Open(path string, lock Locker) (idx *Index, found bool, err error) {
+	idx = NewIndex()
+	idx.path = path
+	idx.lock = lock
+
+	unlock, err := idx.lockFiles(true)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	found, clean, err := idx.load()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A torn last line (crash mid-append) is dropped by compacting, and a
+	// snapshot of an older version is replaced by an empty one
+	if !found || !clean || idx.journalOps >= compactAfter {
+		if err := idx.compact(); err != nil {
+			return nil, false, err
+		}
+	}
+	return idx, found, nil
+}
+
+// Load reads the index persisted at path without ever writing to it, for
+// read-only stores: changes are kept in memory, and Refresh picks up the
+// ones processes writing the index persist. found is as for Open.
+func Load(path string) (idx *Index, found bool, err error) {
+	idx = NewIndex()
+	idx.path = path
+	idx.readOnly = true
+
+	found, _, err = idx.load()
+	if err != nil {
+		return nil, false, err
+	}
+	return idx, found, nil
+}
+
+// Len returns the number of indexed documents.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Add indexes a document, replacing any previous version of it.
+func (idx *Index) Add(d Doc) error {
+	e := newEntry(d)
+	return idx.update(journalEntry{Op: opAdd, Doc: e})
+}
+
+// Remove drops a single document.
+func (idx *Index) Remove(projectID, taskID, id string) error {
+	return idx.update(journalEntry{Op: opRemove, ProjectID: projectID, TaskID: taskID, ID: id})
+}
+
+// RemoveTask drops every document of a task.
+func (idx *Index) RemoveTask(projectID, taskID string) error {
+	return idx.update(journalEntry{Op: opRemoveTask, ProjectID: projectID, TaskID: taskID})
+}
+
+// RemoveProject drops every document of a project.
+func (idx *Index) RemoveProject(projectID string) error {
+	return idx.update(journalEntry{Op: opRemoveProject, ProjectID: projectID})
+}
+
+// Rebuild replaces the whole index with docs and persists it as a fresh snapshot.
+func (idx *Index) Rebuild(docs []Doc) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	unlock, err := idx.lockFiles(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	idx.reset()
+	for _, d := range docs {
+		idx.add(newEntry(d))
+	}
+	if idx.readOnly {
+		return nil
+	}
+	return idx.compact()
+}
+
+// Refresh applies the changes other processes persisted since the index was
+// last read or changed. Search and Docs only see them after a Refresh.
+func (idx *Index) Refresh() error {
+	if idx.path == "" {
+		return nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	unlock, err := idx.lockFiles(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, err = idx.sync()
+	return err
+}
+
+// Search returns the documents matching every clause of q, best first.
+// keep, when non-nil, restricts the documents considered.
+// An empty query matches every document with a zero score.
+func (idx *Index) Search(q Query, keep func(Doc) bool) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var hits []Hit
+	if q.Empty() {
+		for _, e := range idx.docs {
+			if keep == nil || keep(e.Doc) {
+				hits = append(hits, Hit{Doc: e.Doc})
+			}
+		}
+		sortHits(hits)
+		return hits
+	}
+
+	// Term frequency of each clause per matching document
+	matches := make([]map[docKey]int, len(q.Clauses))
+	smallest := 0
+	for i, c := range q.Clauses {
+		matches[i] = idx.match(c)
+		if len(matches[i]) == 0 {
+			return nil
+		}
+		if len(matches[i]) < len(matches[smallest]) {
+			smallest = i
+		}
+	}
+
+	n := float64(len(idx.docs))
+	avgLength := float64(idx.totalLength) / n
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+candidates:
+	for k := range matches[smallest] {
+		for _, m := range matches {
+			if _, ok := m[k]; !ok {
+				continue candidates
+			}
+		}
+
+		e := idx.docs[k]
+		if keep != nil && !keep(e.Doc) {
+			continue
+		}
+
+		score := 0.0
+		norm := bm25K1 * (1 - bm25B + bm25B*float64(e.Length)/avgLength)
+		for _, m := range matches {
+			df := float64(len(m))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			tf := float64(m[k])
+			score += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+		hits = append(hits, Hit{Doc: e.Doc, Score: score})
+	}
+
+	sortHits(hits)
+	return hits
+}
+
+// Docs returns the indexed documents kept by keep, in no particular order.
+func (idx *Index) Docs(keep func(Doc) bool) []Doc {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var docs []Doc
+	for _, e := range idx.docs {
+		if keep(e.Doc) {
+			docs = append(docs, e.Doc)
+		}
+	}
+	return docs
+}
+
+// Close releases the index. The journal is only open while it is written,
+// so there is nothing to release yet.
+func (idx *Index) Close() error {
+	return nil
+}
+
+// match returns the term frequency of clause c in every document containing it.
+func (idx *Index) match(c Clause) map[docKey]int {
+	// Each slot is the set of terms accepted at that phrase position
+	slots := make([][]string, len(c.Terms))
+	for i, term := range c.Terms {
+		if c.Prefix && i == len(c.Terms)-1 {
+			slots[i] = idx.expand(term)
+		} else {
+			slots[i] = []string{term}
+		}
+	}
+
+	result := make(map[docKey]int)
+	if !c.IsPhrase() {
+		for _, term := range slots[0] {
+			for k, positions := range idx.postings[term] {
+				result[k] += len(positions)
+			}
+		}
+		return result
+	}
+
+	for _, term := range slots[0] {
+		for k := range idx.postings[term] {
+			if _, done := result[k]; done {
+				continue
+			}
+			if n := countPhrase(idx.docs[k], slots); n > 0 {
+				result[k] = n
+			}
+		}
+	}
+	return result
+}
+
+// expand returns every indexed term starting with prefix.
+func (idx *Index) expand(prefix string) []string {
+	var terms []string
+	for term := range idx.postings {
+		if strings.HasPrefix(term, prefix) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// countPhrase counts the positions in e where the slots match consecutively.
+func countPhrase(e *entry, slots [][]string) int {
+	sets := make([]map[int]bool, len(slots))
+	for i, slot := range slots {
+		sets[i] = make(map[int]bool)
+		for _, term := range slot {
+			for _, pos := range e.Terms[term] {
+				sets[i][pos] = true
+			}
+		}
+		if len(sets[i]) == 0 {
+			return 0
+		}
+	}
+
+	count := 0
+starts:
+	for start := range sets[0] {
+		for i := 1; i < len(sets); i++ {
+			if !sets[i][start+i] {
+				continue starts
+			}
+		}
+		count++
+	}
+	return count
+}
+
+func sortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].CreatedAt != hits[j].CreatedAt {
+			return hits[i].CreatedAt > hits[j].CreatedAt
+		}
+		return hits[i].ID > hits[j].ID
+	})
+}
+
+func (idx *Index) reset() {
+	idx.docs = make(map[docKey]*entry)
+	idx.postings = make(map[string]map[docKey][]int)
+	idx.totalLength = 0
+}
+
+func (idx *Index) add(e *entry) {
+	k := e.key()
+	idx.remove(k)
+
+	idx.docs[k] = e
+	idx.totalLength += e.Length
+	for term, positions := range e.Terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[docKey][]int)
+		}
+		idx.postings[term][k] = positions
+	}
+}
+
+func (idx *Index) remove(k docKey) {
+	e, ok := idx.docs[k]
+	if !ok {
+		return
+	}
+
+	delete(idx.docs, k)
+	idx.totalLength -= e.Length
+	for term := range e.Terms {
+		delete(idx.postings[term], k)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+}
+
+func (idx *Index) removeWhere(match func(docKey) bool) {
+	for k := range idx.docs {
+		if match(k) {
+			idx.remove(k)
+		}
+	}
+}
+
+func (idx *Index) apply(j journalEntry) error {
+	switch j.Op {
+	case opAdd:
+		if j.Doc == nil {
+			return errors.New("add without document")
+		}
+		idx.add(j.Doc)
+	case opRemove:
+		idx.remove(docKey{projectID: j.ProjectID, taskID: j.TaskID, id: j.ID})
+	case opRemoveTask:
+		idx.removeWhere(func(k docKey) bool { return k.projectID == j.ProjectID && k.taskID == j.TaskID })
+	case opRemoveProject:
+		idx.removeWhere(func(k docKey) bool { return k.projectID == j.ProjectID })
+	default:
+		return fmt.Errorf("unknown operation %q", j.Op)
+	}
+	return nil
+}
+
+// lockFiles takes the lock shared with other processes, if any.
+func (idx *Index) lockFiles(exclusive bool) (unlock func(), err error) {
+	if idx.lock == nil || idx.path == "" {
+		return func() {}, nil
+	}
+	return idx.lock(exclusive)
+}
+
+// update applies a change and records it in the journal. The changes other
+// processes persisted are applied first, so that none is lost by appending
+// to a journal another process has compacted away.
+func (idx *Index) update(j journalEntry) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.readOnly {
+		return idx.apply(j)
+	}
+
+	unlock, err := idx.lockFiles(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	clean, err := idx.sync()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		// Nothing can be appended after a torn line
+		if err := idx.compact(); err != nil {
+			return err
+		}
+	}
+
+	if err := idx.apply(j); err != nil {
+		return err
+	}
+	return idx.record(j)
+}
+
+// load replaces the documents with the persisted snapshot and journal.
+// found is false when nothing usable has been persisted; clean is false when
+// the journal ends with a line that cannot be applied.
+func (idx *Index) load() (found, clean bool, err error) {
+	idx.reset()
+	idx.snapshotInfo, idx.journalInfo = nil, nil
+	idx.journalSize, idx.journalOps = 0, 0
+
+	f, err := os.Open(idx.path)
+	switch {
+	case err == nil:
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return false, false, err
+		}
+		var snap snapshot
+		if err := json.NewDecoder(f).Decode(&snap); err != nil {
+			return false, false, fmt.Errorf("decoding search index: %w", err)
+		}
+		idx.snapshotInfo = info
+		if snap.Version < snapshotVersion {
+			// Written by an older version: start empty so the caller rebuilds it
+			return false, true, nil
+		}
+		if snap.Version != snapshotVersion {
+			return false, false, fmt.Errorf("unsupported search index version %d", snap.Version)
+		}
+		for _, e := range snap.Docs {
+			idx.add(e)
+		}
+		found = true
+	case !os.IsNotExist(err):
+		return false, false, err
+	}
+
+	clean, err = idx.replayJournal()
+	if err != nil {
+		return false, false, err
+	}
+	return found || idx.journalOps > 0, clean, nil
+}
+
+// sync applies the changes persisted since the index was loaded: the new
+// end of the journal, or everything when another process compacted it.
+func (idx *Index) sync() (clean bool, err error) {
+	if idx.path == "" {
+		return true, nil
+	}
+
+	snapshotInfo, err := statFile(idx.path)
+	if err != nil {
+		return false, err
+	}
+	journalInfo, err := statFile(idx.path + journalSuffix)
+	if err != nil {
+		return false, err
+	}
+
+	reload := !sameState(snapshotInfo, idx.snapshotInfo)
+	switch {
+	case reload:
+	case journalInfo == nil:
+		reload = idx.journalInfo != nil
+	case idx.journalInfo != nil && !os.SameFile(journalInfo, idx.journalInfo):
+		reload = true
+	case journalInfo.Size() < idx.journalSize:
+		reload = true
+	case journalInfo.Size() == idx.journalSize:
+		return true, nil
+	}
+
+	if reload {
+		_, clean, err := idx.load()
+		return clean, err
+	}
+	return idx.replayJournal()
+}
+
+// record appends a change to the journal, compacting when it grows too long.
+// The journal is opened for each change since another process may have
+// replaced it in between.
+func (idx *Index) record(j journalEntry) error {
+	if idx.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(idx.path+journalSuffix, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	var info os.FileInfo
+	if err == nil {
+		info, err = f.Stat()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	idx.journalInfo = info
+	idx.journalSize = info.Size()
+	idx.journalOps++
+	if idx.journalOps >= compactAfter {
+		return idx.compact()
+	}
+	return nil
+}
+
+// replayJournal applies the journal from where the last replay stopped.
+// clean is false when a line could not be decoded; replay stops there.
+func (idx *Index) replayJournal() (clean bool, err error) {
+	f, err := os.Open(idx.path + journalSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	idx.journalInfo = info
+	if _, err := f.Seek(idx.journalSize, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return true, nil
+		}
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+
+		// A line without its newline was torn by a crash mid-append
+		var j journalEntry
+		if err == io.EOF || json.Unmarshal(line, &j) != nil || idx.apply(j) != nil {
+			return false, nil
+		}
+		idx.journalSize += int64(len(line))
+		idx.journalOps++
+	}
+}
+
+// compact writes the current state as a new snapshot and removes the journal.
+func (idx *Index) compact() error {
+	if idx.path == "" {
+		return nil
+	}
+
+	snap := snapshot{Version: snapshotVersion, Docs: make([]*entry, 0, len(idx.docs))}
+	for _, e := range idx.docs {
+		snap.Docs = append(snap.Docs, e)
+	}
+	data, err := json.Marshal(&snap)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(idx.path), filepath.Base(idx.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), idx.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Remove(idx.path + journalSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	info, err := os.Stat(idx.path)
+	if err != nil {
+		return err
+	}
+	idx.snapshotInfo, idx.journalInfo = info, nil
+	idx.journalSize, idx.journalOps = 0, 0
+	return nil
+}
+
+// statFile returns the file's info, or nil when it does not exist.
+func statFile(path string) (os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return info, err
+}
+
+// sameState reports whether two infos describe the same unchanged file.
+func sameState(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
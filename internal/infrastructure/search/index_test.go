@@ -0,0 +1,271 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupTestIndex(t *testing.T) (string, func()) {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "search-index-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	cleanup := func() {
+		os.RemoveAll(tmpDir)
+	}
+
+	return filepath.Join(tmpDir, "index.json"), cleanup
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := make([]string, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+func addDocs(t *testing.T, idx *Index, docs ...Doc) {
+	t.Helper()
+	for _, d := range docs {
+		if err := idx.Add(d); err != nil {
+			t.Fatalf("Add(%s) error = %v", d.ID, err)
+		}
+	}
+}
+
+func TestIndex_Search_RanksByRelevance(t *testing.T) {
+	idx := NewIndex()
+	addDocs(t, idx,
+		Doc{ProjectID: "p", TaskID: "t", ID: "1", CreatedAt: 1, Text: "database migration notes for the release"},
+		Doc{ProjectID: "p", TaskID: "t", ID: "2", CreatedAt: 2, Text: "database database database schema"},
+		Doc{ProjectID: "p", TaskID: "t", ID: "3", CreatedAt: 3, Text: "frontend styling"},
+	)
+
+	hits := idx.Search(ParseQuery("database"), nil)
+	if got := hitIDs(hits); len(got) != 2 || got[0] != "2" || got[1] != "1" {
+		t.Fatalf("Search(database) = %v, want [2 1]", got)
+	}
+	if hits[0].Score <= hits[1].Score || hits[1].Score <= 0 {
+		t.Errorf("Search(database) scores = %v, %v, want descending and positive", hits[0].Score, hits[1].Score)
+	}
+
+	// Every clause must match
+	if got := hitIDs(idx.Search(ParseQuery("database frontend"), nil)); len(got) != 0 {
+		t.Errorf("Search(database frontend) = %v, want none", got)
+	}
+}
+
+func TestIndex_Search_PhraseAndPrefix(t *testing.T) {
+	idx := NewIndex()
+	addDocs(t, idx,
+		Doc{ProjectID: "p", TaskID: "t", ID: "1", CreatedAt: 1, Text: "refresh the token on expiry"},
+		Doc{ProjectID: "p", TaskID: "t", ID: "2", CreatedAt: 2, Text: "token refresh is broken"},
+		Doc{ProjectID: "p", TaskID: "t", ID: "3", CreatedAt: 3, Text: "authentication and authorization"},
+	)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: `"token refresh"`, expected: []string{"2"}},
+		{query: "token refresh", expected: []string{"2", "1"}},
+		{query: "auth*", expected: []string{"3"}},
+		{query: "auth", expected: []string{}},
+		{query: `"token ref"*`, expected: []string{"2"}},
+		{query: "ref*", expected: []string{"2", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := hitIDs(idx.Search(ParseQuery(tt.query), nil))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Search(%s) = %v, want %v", tt.query, got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.expected)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestIndex_Search_EmptyQueryAndFilter(t *testing.T) {
+	idx := NewIndex()
+	addDocs(t, idx,
+		Doc{ProjectID: "p1", TaskID: "t", ID: "1", CreatedAt: 1, Text: "alpha"},
+		Doc{ProjectID: "p2", TaskID: "t", ID: "2", CreatedAt: 2, Text: "alpha beta"},
+	)
+
+	// Empty query matches everything, newest first
+	if got := hitIDs(idx.Search(ParseQuery(""), nil)); len(got) != 2 || got[0] != "2" {
+		t.Errorf("Search(\"\") = %v, want [2 1]", got)
+	}
+
+	onlyP1 := func(d Doc) bool { return d.ProjectID == "p1" }
+	if got := hitIDs(idx.Search(ParseQuery("alpha"), onlyP1)); len(got) != 1 || got[0] != "1" {
+		t.Errorf("Search(alpha, p1) = %v, want [1]", got)
+	}
+}
+
+func TestIndex_Remove(t *testing.T) {
+	idx := NewIndex()
+	addDocs(t, idx,
+		Doc{ProjectID: "p1", TaskID: "t1", ID: "1", Text: "shared word"},
+		Doc{ProjectID: "p1", TaskID: "t2", ID: "2", Text: "shared word"},
+		Doc{ProjectID: "p2", TaskID: "t1", ID: "3", Text: "shared word"},
+		Doc{ProjectID: "p2", TaskID: "t1", ID: "4", Text: "shared word"},
+	)
+
+	if err := idx.Remove("p1", "t1", "1"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := idx.RemoveTask("p1", "t2"); err != nil {
+		t.Fatalf("RemoveTask() error = %v", err)
+	}
+	if got := hitIDs(idx.Search(ParseQuery("shared"), nil)); len(got) != 2 {
+		t.Errorf("Search() after removals = %v, want [4 3]", got)
+	}
+
+	if err := idx.RemoveProject("p2"); err != nil {
+		t.Fatalf("RemoveProject() error = %v", err)
+	}
+	if idx.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", idx.Len())
+	}
+
+	// Re-adding a document replaces it
+	addDocs(t, idx, Doc{ProjectID: "p", TaskID: "t", ID: "5", Text: "old text"})
+	addDocs(t, idx, Doc{ProjectID: "p", TaskID: "t", ID: "5", Text: "new text"})
+	if got := idx.Search(ParseQuery("old"), nil); len(got) != 0 {
+		t.Errorf("Search(old) after replace = %v, want none", hitIDs(got))
+	}
+	if idx.Len() != 1 {
+		t.Errorf("Len() after replace = %d, want 1", idx.Len())
+	}
+}
+
+func TestIndex_Persistence(t *testing.T) {
+	path, cleanup := setupTestIndex(t)
+	defer cleanup()
+
+	idx, found, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if found {
+		t.Error("Open() on empty dir found = true, want false")
+	}
+
+	if err := idx.Rebuild([]Doc{
+		{ProjectID: "p", TaskID: "t", ID: "1", Text: "snapshot document"},
+		{ProjectID: "p", TaskID: "t", ID: "2", Text: "removed document"},
+	}); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+
+	// Changes after the snapshot go to the journal
+	addDocs(t, idx, Doc{ProjectID: "p", TaskID: "t", ID: "3", Text: "journal document"})
+	if err := idx.Remove("p", "t", "2"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	idx.Close()
+
+	idx, found, err = Open(path, nil)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer idx.Close()
+
+	if !found {
+		t.Error("reopen found = false, want true")
+	}
+	if got := hitIDs(idx.Search(ParseQuery("document"), nil)); len(got) != 2 {
+		t.Errorf("Search() after reopen = %v, want [3 1]", got)
+	}
+	if got := idx.Search(ParseQuery(`"journal document"`), nil); len(got) != 1 {
+		t.Errorf("phrase Search() after reopen = %v, want [3]", hitIDs(got))
+	}
+}
+
+func TestIndex_TornJournal(t *testing.T) {
+	path, cleanup := setupTestIndex(t)
+	defer cleanup()
+
+	idx, _, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	addDocs(t, idx, Doc{ProjectID: "p", TaskID: "t", ID: "1", Text: "kept"})
+	idx.Close()
+
+	// Simulate a crash in the middle of appending
+	f, err := os.OpenFile(path+journalSuffix, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open journal: %v", err)
+	}
+	f.WriteString(`{"op":"add","doc":{"proj`)
+	f.Close()
+
+	idx, found, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open() with torn journal error = %v", err)
+	}
+	defer idx.Close()
+
+	if !found || idx.Len() != 1 {
+		t.Errorf("Open() with torn journal found = %v, Len() = %d, want true, 1", found, idx.Len())
+	}
+
+	// The torn entry was compacted away
+	data, _ := os.ReadFile(path + journalSuffix)
+	if len(data) != 0 {
+		t.Errorf("journal after recovery = %q, want empty", data)
+	}
+}
+
+func TestIndex_CompactsJournal(t *testing.T) {
+	path, cleanup := setupTestIndex(t)
+	defer cleanup()
+
+	idx, _, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer idx.Close()
+
+	for i := 0; i < compactAfter; i++ {
+		addDocs(t, idx, Doc{ProjectID: "p", TaskID: "t", ID: "1", Text: "same document"})
+	}
+
+	if idx.journalOps != 0 {
+		t.Errorf("journalOps = %d after %d writes, want 0", idx.journalOps, compactAfter)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("snapshot missing after compaction: %v", err)
+	}
+}
+
+func TestIndex_OlderSnapshotIsRebuilt(t *testing.T) {
+	path, cleanup := setupTestIndex(t)
+	defer cleanup()
+
+	os.WriteFile(path, []byte(`{"version":1,"docs":[{"project_id":"p","task_id":"t","id":"1","length":1,"terms":{"old":[0]}}]}`), 0644)
+	os.WriteFile(path+journalSuffix, []byte(`{"op":"remove","project_id":"p","task_id":"t","id":"1"}`+"\n"), 0644)
+
+	idx, found, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open() on older snapshot error = %v", err)
+	}
+	defer idx.Close()
+
+	if found || idx.Len() != 0 {
+		t.Errorf("Open() on older snapshot found = %v, Len() = %d, want false, 0", found, idx.Len())
+	}
+}
@@ -0,0 +1,107 @@
+// Package search implements the tokenizer, query parser and BM25-ranked
+// inverted index used for artifact full-text search.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits text into lowercase tokens. A token is a run of letters
+// and digits; everything else separates tokens.
+func Tokenize(text string) []string {
+	var tokens []string
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			tokens = append(tokens, strings.ToLower(text[start:i]))
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = append(tokens, strings.ToLower(text[start:]))
+	}
+	return tokens
+}
+
+// Clause is a single query element: a term, a prefix or a phrase.
+// Every clause of a query must match for a document to be returned.
+type Clause struct {
+	Terms  []string // One term, or several for a phrase
+	Prefix bool     // The last term matches any token it is a prefix of
+}
+
+// IsPhrase reports whether the clause matches a sequence of tokens.
+func (c Clause) IsPhrase() bool {
+	return len(c.Terms) > 1
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Clauses []Clause
+}
+
+// Empty reports whether the query has no clauses and therefore matches everything.
+func (q Query) Empty() bool {
+	return len(q.Clauses) == 0
+}
+
+// ParseQuery parses a search query.
+//
+//	auth token       both terms must appear
+//	"token refresh"  the tokens must appear next to each other, in order
+//	auth*            any token starting with "auth"
+//
+// A bare word that tokenizes into several tokens (e.g. file_path) is treated
+// as a phrase.
+func ParseQuery(input string) Query {
+	var q Query
+	add := func(text string, prefix bool) {
+		terms := Tokenize(text)
+		if len(terms) == 0 {
+			return
+		}
+		q.Clauses = append(q.Clauses, Clause{Terms: terms, Prefix: prefix})
+	}
+
+	rest := input
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end == -1 {
+				// Unterminated phrase runs to the end of the query
+				add(rest[1:], false)
+				break
+			}
+			phrase := rest[1 : end+1]
+			rest = rest[end+2:]
+			prefix := strings.HasPrefix(rest, "*")
+			if prefix {
+				rest = rest[1:]
+			}
+			add(phrase, prefix)
+			continue
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end == -1 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+		add(word, strings.HasSuffix(word, "*"))
+	}
+
+	return q
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "lowercases words",
+			input:    "Hello World",
+			expected: []string{"hello", "world"},
+		},
+		{
+			name:     "splits on punctuation",
+			input:    "file_path=/src/main.go",
+			expected: []string{"file", "path", "src", "main", "go"},
+		},
+		{
+			name:     "keeps digits",
+			input:    "HTTP 404 error",
+			expected: []string{"http", "404", "error"},
+		},
+		{
+			name:     "unicode letters",
+			input:    "Привет, мир",
+			expected: []string{"привет", "мир"},
+		},
+		{
+			name:     "empty",
+			input:    "  -- ",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tokenize(tt.input)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Tokenize(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Clause
+	}{
+		{
+			name:  "terms",
+			input: "Auth  token",
+			expected: []Clause{
+				{Terms: []string{"auth"}},
+				{Terms: []string{"token"}},
+			},
+		},
+		{
+			name:  "phrase",
+			input: `"token refresh" bug`,
+			expected: []Clause{
+				{Terms: []string{"token", "refresh"}},
+				{Terms: []string{"bug"}},
+			},
+		},
+		{
+			name:  "prefix",
+			input: "auth*",
+			expected: []Clause{
+				{Terms: []string{"auth"}, Prefix: true},
+			},
+		},
+		{
+			name:  "prefix phrase",
+			input: `"token ref"*`,
+			expected: []Clause{
+				{Terms: []string{"token", "ref"}, Prefix: true},
+			},
+		},
+		{
+			name:  "compound word becomes phrase",
+			input: "file_path",
+			expected: []Clause{
+				{Terms: []string{"file", "path"}},
+			},
+		},
+		{
+			name:  "unterminated phrase",
+			input: `"token refresh`,
+			expected: []Clause{
+				{Terms: []string{"token", "refresh"}},
+			},
+		},
+		{
+			name:     "only punctuation",
+			input:    `* "" --`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQuery(tt.input)
+			if !reflect.DeepEqual(got.Clauses, tt.expected) {
+				t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.input, got.Clauses, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package filesystem
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"agent-memory/internal/domain/task"
+)
+
+const frontmatterDelimiter = "---"
+
+// artifactFrontmatter is the YAML document at the top of every artifact file.
+type artifactFrontmatter struct {
+	ID           string               `yaml:"id"`
+	ProjectID    string               `yaml:"project_id"`
+	TaskID       string               `yaml:"task_id"`
+	Type         string               `yaml:"type"`
+	CreatedAt    string               `yaml:"created_at"`
+	Version      int                  `yaml:"version,omitempty"`    // Omitted for the original version
+	UpdatedAt    string               `yaml:"updated_at,omitempty"` // When this version was written
+	Tags         []string             `yaml:"tags,omitempty"`
+	Links        []frontmatterLink    `yaml:"links,omitempty"`
+	SupersededBy []frontmatterRef     `yaml:"superseded_by,omitempty"`
+	Metadata     map[string]yaml.Node `yaml:"metadata,omitempty"` // Nodes keep scalars as written
+}
+
+// frontmatterRef is a task.ArtifactRef in the frontmatter.
+type frontmatterRef struct {
+	ProjectID  string `yaml:"project_id"`
+	TaskID     string `yaml:"task_id"`
+	ArtifactID string `yaml:"artifact_id,omitempty"`
+}
+
+// frontmatterLink is a task.ArtifactLink in the frontmatter, with the target inlined.
+type frontmatterLink struct {
+	Relation       string `yaml:"relation"`
+	frontmatterRef `yaml:",inline"`
+}
+
+func toFrontmatterRef(r task.ArtifactRef) frontmatterRef {
+	return frontmatterRef{ProjectID: r.ProjectID.String(), TaskID: r.TaskID.String(), ArtifactID: r.ArtifactID}
+}
+
+func (r frontmatterRef) ref() task.ArtifactRef {
+	return task.ArtifactRef{ProjectID: task.ProjectID(r.ProjectID), TaskID: task.TaskID(r.TaskID), ArtifactID: r.ArtifactID}
+}
+
+// links converts the frontmatter links, dropping any with an unknown relation.
+func (fm *artifactFrontmatter) links() []task.ArtifactLink {
+	var links []task.ArtifactLink
+	for _, l := range fm.Links {
+		relation, err := task.ParseLinkRelation(l.Relation)
+		if err != nil {
+			continue
+		}
+		links = append(links, task.ArtifactLink{Relation: relation, Target: l.ref()})
+	}
+	return links
+}
+
+func (fm *artifactFrontmatter) supersededBy() []task.ArtifactRef {
+	var refs []task.ArtifactRef
+	for _, r := range fm.SupersededBy {
+		refs = append(refs, r.ref())
+	}
+	return refs
+}
+
+// encodeArtifactMarkdown renders an artifact as a markdown file with a YAML frontmatter.
+func encodeArtifactMarkdown(a *task.Artifact) (string, error) {
+	fm := artifactFrontmatter{
+		ID:        a.ID,
+		ProjectID: a.ProjectID.String(),
+		TaskID:    a.TaskID.String(),
+		Type:      string(a.Type),
+		CreatedAt: a.CreatedAt.Format(time.RFC3339),
+		Tags:      a.Tags,
+	}
+	if a.Version > 1 {
+		fm.Version = a.Version
+	}
+	for _, l := range a.Links {
+		fm.Links = append(fm.Links, frontmatterLink{Relation: string(l.Relation), frontmatterRef: toFrontmatterRef(l.Target)})
+	}
+	for _, r := range a.SupersededBy {
+		fm.SupersededBy = append(fm.SupersededBy, toFrontmatterRef(r))
+	}
+	if a.UpdatedAt != nil {
+		fm.UpdatedAt = a.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	if len(a.Metadata) > 0 {
+		fm.Metadata = make(map[string]yaml.Node, len(a.Metadata))
+		for k, v := range a.Metadata {
+			fm.Metadata[k] = stringNode(v)
+		}
+	}
+
+	return renderMarkdown(&fm, a.Content)
+}
+
+// renderMarkdown writes fm as a YAML frontmatter followed by the body.
+func renderMarkdown(fm any, body string) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(fm); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(frontmatterDelimiter + "\n")
+	sb.Write(buf.Bytes())
+	sb.WriteString(frontmatterDelimiter + "\n\n")
+	sb.WriteString(body)
+
+	return sb.String(), nil
+}
+
+// memoryFrontmatter is the YAML document at the top of every memory file.
+type memoryFrontmatter struct {
+	ID        string   `yaml:"id"`
+	ProjectID string   `yaml:"project_id,omitempty"` // Omitted for global memories
+	Title     string   `yaml:"title"`
+	Tags      []string `yaml:"tags,omitempty"`
+	Pinned    bool     `yaml:"pinned,omitempty"`
+	CreatedAt string   `yaml:"created_at"`
+	UpdatedAt string   `yaml:"updated_at"`
+}
+
+// encodeMemoryMarkdown renders a memory as a markdown file with a YAML frontmatter.
+func encodeMemoryMarkdown(m *task.Memory) (string, error) {
+	fm := memoryFrontmatter{
+		ID:        m.ID.String(),
+		ProjectID: m.ProjectID.String(),
+		Title:     m.Title,
+		Tags:      m.Tags,
+		Pinned:    m.Pinned,
+		CreatedAt: m.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt: m.UpdatedAt.Format(time.RFC3339Nano),
+	}
+	return renderMarkdown(&fm, m.Content)
+}
+
+// decodeMemoryMarkdown parses a memory file. The ID and project come from
+// where the file is, so a copied or renamed file cannot claim another memory.
+func decodeMemoryMarkdown(data string, projectID task.ProjectID, id task.MemoryID) (*task.Memory, error) {
+	m := &task.Memory{ID: id, ProjectID: projectID, Content: strings.TrimSpace(data)}
+
+	header, body, ok := splitFrontmatter(data)
+	if !ok {
+		m.Title = id.String()
+		return m, nil
+	}
+
+	var fm memoryFrontmatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return nil, err
+	}
+
+	m.Title = fm.Title
+	if m.Title == "" {
+		m.Title = id.String()
+	}
+	m.Content = strings.TrimSpace(body)
+	m.Tags = task.NormalizeTags(fm.Tags)
+	m.Pinned = fm.Pinned
+	m.CreatedAt, _ = time.Parse(time.RFC3339Nano, fm.CreatedAt)
+	m.UpdatedAt, _ = time.Parse(time.RFC3339Nano, fm.UpdatedAt)
+	return m, nil
+}
+
+// splitFrontmatter separates the frontmatter block from the markdown body.
+// ok is false when the file has no frontmatter.
+func splitFrontmatter(data string) (header, body string, ok bool) {
+	if !strings.HasPrefix(data, frontmatterDelimiter+"\n") {
+		return "", data, false
+	}
+
+	rest := data[len(frontmatterDelimiter)+1:]
+	if strings.HasPrefix(rest, frontmatterDelimiter+"\n") {
+		return "", rest[len(frontmatterDelimiter)+1:], true
+	}
+
+	endIdx := strings.Index(rest, "\n"+frontmatterDelimiter+"\n")
+	if endIdx == -1 {
+		return "", data, false
+	}
+
+	return rest[:endIdx+1], rest[endIdx+len(frontmatterDelimiter)+2:], true
+}
+
+// splitArtifactFrontmatter separates an artifact file into its frontmatter and
+// body. valid is false when the frontmatter was written by the hand-rolled
+// encoder and needs migrating. A file whose leading block has neither an id
+// nor a type has no frontmatter: the block is part of the content, as in a
+// legacy artifact whose content starts with a YAML document. Then fm is nil
+// and body is the whole file.
+func splitArtifactFrontmatter(data string) (fm *artifactFrontmatter, body string, valid bool) {
+	header, body, ok := splitFrontmatter(data)
+	if !ok {
+		return nil, data, false
+	}
+
+	if fm, err := parseFrontmatter(header); err == nil {
+		if fm.known() {
+			return fm, body, true
+		}
+		return nil, data, false
+	}
+
+	// Not migrated yet: written by the hand-rolled encoder
+	if fm := parseLegacyFrontmatter(header); fm.known() {
+		return fm, body, false
+	}
+	return nil, data, false
+}
+
+// parseFrontmatter decodes a YAML frontmatter block.
+func parseFrontmatter(header string) (*artifactFrontmatter, error) {
+	var fm artifactFrontmatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return nil, err
+	}
+	return &fm, nil
+}
+
+// known reports whether fm has the keys every artifact frontmatter is
+// written with, telling it apart from any other YAML.
+func (fm *artifactFrontmatter) known() bool {
+	return fm.ID != "" || fm.Type != ""
+}
+
+// parseLegacyFrontmatter reads the hand-rolled "key: value" frontmatter written
+// before artifacts used real YAML. Values were written unescaped, so a line that
+// does not look like a key belongs to the previous metadata value.
+func parseLegacyFrontmatter(header string) *artifactFrontmatter {
+	fm := &artifactFrontmatter{}
+	inMetadata := false
+	lastKey := ""
+
+	for _, line := range strings.Split(strings.TrimSuffix(header, "\n"), "\n") {
+		// metadata was always written last, so everything after it belongs to it
+		if inMetadata {
+			if strings.HasPrefix(line, "  ") {
+				if k, v, found := strings.Cut(line[2:], ": "); found {
+					fm.Metadata[k] = stringNode(v)
+					lastKey = k
+					continue
+				}
+			}
+			if lastKey != "" {
+				n := fm.Metadata[lastKey]
+				n.Value += "\n" + line
+				fm.Metadata[lastKey] = n
+			}
+			continue
+		}
+
+		k, v, _ := strings.Cut(strings.TrimSuffix(line, ":"), ": ")
+		switch k {
+		case "id":
+			fm.ID = v
+		case "project_id":
+			fm.ProjectID = v
+		case "task_id":
+			fm.TaskID = v
+		case "type":
+			fm.Type = v
+		case "created_at":
+			fm.CreatedAt = v
+		case "metadata":
+			inMetadata = true
+			fm.Metadata = make(map[string]yaml.Node)
+		}
+	}
+
+	return fm
+}
+
+// stringNode is a YAML string holding s, quoted when written if it would
+// otherwise read back as another type.
+func stringNode(s string) yaml.Node {
+	return yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// flattenMetadata converts decoded YAML metadata to the flat string map used by
+// task.Artifact. Nested maps become dotted keys and lists become comma-separated
+// values. Scalars keep the text they were written with, so 1.0 stays "1.0".
+func flattenMetadata(raw map[string]yaml.Node) map[string]string {
+	out := make(map[string]string, len(raw))
+	for k, n := range raw {
+		flattenInto(out, k, &n)
+	}
+	return out
+}
+
+func flattenInto(out map[string]string, key string, n *yaml.Node) {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			flattenInto(out, key+"."+n.Content[i].Value, n.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			items = append(items, scalarString(item))
+		}
+		out[key] = strings.Join(items, ",")
+	default:
+		out[key] = scalarString(n)
+	}
+}
+
+func scalarString(n *yaml.Node) string {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return ""
+		}
+		return n.Value
+	case yaml.MappingNode:
+		flat := make(map[string]string)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			flattenInto(flat, n.Content[i].Value, n.Content[i+1])
+		}
+		keys := make([]string, 0, len(flat))
+		for k := range flat {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, k+"="+flat[k])
+		}
+		return strings.Join(pairs, ",")
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			items = append(items, scalarString(item))
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	default:
+		return ""
+	}
+}
+
+// resolveAlias returns the node an alias refers to, or n itself.
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
@@ -0,0 +1,216 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+// Several server processes may share one store, so changes take advisory
+// locks kept in locksDir:
+//
+//	.locks/project-id.lock              (the project: shared to read it or change
+//	                                     one task or memory, exclusive to create,
+//	                                     update or delete it or its tasks)
+//	.locks/project-id/task-id.lock      (one task's artifacts and history)
+//	.locks/project-id/_memories.lock    (the project's memories)
+//	.locks/_search-index.lock           (the search index snapshot and journal)
+//
+// Lock files live outside the directories they protect and are never
+// removed, so deleting a project or task cannot pull a lock from under a
+// process waiting on it. Locks are always taken project first, and the
+// search index lock last.
+const (
+	locksDir        = ".locks"
+	memoriesLock    = "_memories"     // Not a valid task ID, so it cannot clash with one
+	searchIndexLock = "_search-index" // Not a valid project ID either
+)
+
+// lockRetryMax bounds the wait between two attempts to take a busy lock.
+const lockRetryMax = 50 * time.Millisecond
+
+// lockProject locks a project, exclusively or shared. It gives up when ctx
+// is done, returning an error that wraps ctx.Err().
+func (r *Repository) lockProject(ctx context.Context, projectID task.ProjectID, exclusive bool) (unlock func(), err error) {
+	unlock, err = r.lockFile(ctx, filepath.Join(r.basePath, locksDir, lockName(projectID)+".lock"), exclusive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: locking project: %w", task.ErrStorageFailed, err)
+	}
+	return unlock, nil
+}
+
+// lockSearchIndex locks the search index for the index package, which calls
+// it without a context: it waits as long as another process holds the lock.
+func (r *Repository) lockSearchIndex(exclusive bool) (unlock func(), err error) {
+	unlock, err = r.lockFile(context.Background(), filepath.Join(r.basePath, locksDir, searchIndexLock+".lock"), exclusive)
+	if err != nil {
+		return nil, fmt.Errorf("locking search index: %w", err)
+	}
+	return unlock, nil
+}
+
+// lockTask locks a project shared and one of its tasks, or its memories
+// with memoriesLock, exclusively.
+func (r *Repository) lockTask(ctx context.Context, projectID task.ProjectID, name string) (unlock func(), err error) {
+	unlockProject, err := r.lockProject(ctx, projectID, false)
+	if err != nil {
+		return nil, err
+	}
+	unlockTask, err := r.lockFile(ctx, filepath.Join(r.basePath, locksDir, lockName(projectID), name+".lock"), true)
+	if err != nil {
+		unlockProject()
+		return nil, fmt.Errorf("%w: locking task: %w", task.ErrStorageFailed, err)
+	}
+	return func() {
+		unlockTask()
+		unlockProject()
+	}, nil
+}
+
+// lockFile takes the lock file at path, creating it if needed. A read-only
+// repository creates nothing: it takes shared locks on the lock files
+// writers created and goes without where there are none yet.
+func (r *Repository) lockFile(ctx context.Context, path string, exclusive bool) (unlock func(), err error) {
+	if !r.readOnly {
+		f, err := openLockFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return lockOpenFile(ctx, f, exclusive)
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lockOpenFile(ctx, f, false)
+}
+
+// lockName is the name of a project's lock; global memories lock _global.
+func lockName(projectID task.ProjectID) string {
+	if projectID == task.GlobalScope {
+		return globalDir
+	}
+	return projectID.String()
+}
+
+// waitLock calls try until it takes a lock, waiting longer after each
+// failed attempt, and returns ctx.Err() once ctx is done.
+func waitLock(ctx context.Context, try func() (bool, error)) error {
+	delay := time.Millisecond
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		locked, err := try()
+		if locked || err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, lockRetryMax)
+	}
+}
+
+// openLockFile opens or creates a lock file and its directory.
+func openLockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	}
+	return f, err
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers and crashes see the old or the new content,
+// never part of it. Temporary names are hidden and do not end in .md, so
+// listings skip them.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// createDirAtomic creates dir with the contents fill writes into a hidden
+// temporary directory, which is then renamed into place, so dir never
+// exists half-made.
+func createDirAtomic(dir string, fill func(tmp string) error) error {
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp, 0755)
+	if err == nil {
+		err = fill(tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, dir)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+	}
+	return err
+}
+
+// removeDir renames dir to a hidden name and then removes it, so a crash
+// halfway leaves nothing that looks like a project or task.
+func removeDir(dir string) error {
+	trash := filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".deleted-"+strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := os.Rename(dir, trash); err != nil {
+		return err
+	}
+	return os.RemoveAll(trash)
+}
+
+// isHidden reports whether a directory entry is internal: locks, temporary
+// files and directories being created or removed.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// storageError wraps err in task.ErrStorageFailed unless it already is one.
+func storageError(err error) error {
+	if errors.Is(err, task.ErrStorageFailed) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+}
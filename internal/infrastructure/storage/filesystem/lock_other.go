@@ -0,0 +1,42 @@
+//go:build !unix
+
+package filesystem
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+var (
+	locksMu sync.Mutex
+	locks   = make(map[string]*sync.RWMutex) // Lock file path -> lock
+)
+
+// lockOpenFile locks the lock file f within this process only: without
+// flock, processes sharing a store are not excluded from each other, though
+// every write is still atomic. A busy lock is tried again until ctx is done.
+// f is only needed for its name and is closed right away.
+func lockOpenFile(ctx context.Context, f *os.File, exclusive bool) (unlock func(), err error) {
+	path := f.Name()
+	f.Close()
+
+	locksMu.Lock()
+	l := locks[path]
+	if l == nil {
+		l = new(sync.RWMutex)
+		locks[path] = l
+	}
+	locksMu.Unlock()
+
+	if exclusive {
+		if err := waitLock(ctx, func() (bool, error) { return l.TryLock(), nil }); err != nil {
+			return nil, err
+		}
+		return l.Unlock, nil
+	}
+	if err := waitLock(ctx, func() (bool, error) { return l.TryRLock(), nil }); err != nil {
+		return nil, err
+	}
+	return l.RUnlock, nil
+}
@@ -0,0 +1,293 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+// The stress test runs workers as goroutines sharing one repository and as
+// subprocesses with their own, all against one store.
+const (
+	stressProject    task.ProjectID = "stress"
+	stressTasks                     = 4
+	stressGoroutines                = 8
+	stressProcesses                 = 4
+	stressIterations                = 25
+
+	// Artifact IDs are nanosecond timestamps, which workers saving at the
+	// same moment can share; give every save its own
+	stressArtifactBase int64 = 1_700_000_000_000_000_000
+
+	stressDirEnv    = "AGENT_MEMORY_STRESS_DIR"
+	stressWorkerEnv = "AGENT_MEMORY_STRESS_WORKER"
+)
+
+func TestRepository_ConcurrentWriters(t *testing.T) {
+	if dir := os.Getenv(stressDirEnv); dir != "" {
+		runStressProcess(t, dir)
+		return
+	}
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := repo.CreateProject(ctx, task.NewProject(stressProject, "Stress")); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	for i := 0; i < stressTasks; i++ {
+		if err := repo.CreateTask(ctx, task.NewTask(stressProject, stressTaskID(i), "Task")); err != nil {
+			t.Fatalf("CreateTask() error = %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, stressGoroutines+stressProcesses)
+	for w := 0; w < stressGoroutines; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := stressWorker(repo, w); err != nil {
+				errs <- fmt.Errorf("goroutine %d: %w", w, err)
+			}
+		}()
+	}
+	for w := stressGoroutines; w < stressGoroutines+stressProcesses; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestRepository_ConcurrentWriters$")
+			cmd.Env = append(os.Environ(), stressDirEnv+"="+tmpDir, stressWorkerEnv+"="+strconv.Itoa(w))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("process %d: %v\n%s", w, err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// One directory per task, named after the status in its metadata, and
+	// no temporary files left behind
+	entries, err := os.ReadDir(repo.projectPath(stressProject))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	dirs := make(map[task.TaskID]int)
+	for _, entry := range entries {
+		if isHidden(entry.Name()) {
+			t.Errorf("left behind %s", entry.Name())
+			continue
+		}
+		if matches := taskDirPattern.FindStringSubmatch(entry.Name()); matches != nil {
+			dirs[task.TaskID(matches[2])]++
+		}
+	}
+
+	saves, changes, updates := 0, 0, 0
+	for i := 0; i < stressTasks; i++ {
+		id := stressTaskID(i)
+		if dirs[id] != 1 {
+			t.Errorf("task %s has %d directories, want 1", id, dirs[id])
+		}
+
+		tsk, err := repo.GetTask(ctx, stressProject, id)
+		if err != nil {
+			t.Fatalf("GetTask(%s) error = %v", id, err)
+		}
+		if dir := repo.findTaskDir(stressProject, id); filepath.Base(dir) != tsk.DirName() {
+			t.Errorf("task %s is in %s, want %s", id, filepath.Base(dir), tsk.DirName())
+		}
+		updates += tsk.Revision
+
+		artifacts, err := repo.ListArtifacts(ctx, stressProject, id, task.ListOptions{Limit: math.MaxInt32})
+		if err != nil {
+			t.Fatalf("ListArtifacts(%s) error = %v", id, err)
+		}
+		saves += len(artifacts.Items)
+		files, _ := os.ReadDir(filepath.Join(repo.findTaskDir(stressProject, id), artifactsDir))
+		for _, f := range files {
+			if isHidden(f.Name()) {
+				t.Errorf("left behind %s in %s", f.Name(), id)
+			}
+		}
+
+		history, err := repo.GetTaskHistory(ctx, stressProject, id)
+		if err != nil {
+			t.Fatalf("GetTaskHistory(%s) error = %v", id, err)
+		}
+		changes += len(history)
+	}
+
+	want := (stressGoroutines + stressProcesses) * stressIterations
+	if saves != want {
+		t.Errorf("saved %d artifacts, want %d", saves, want)
+	}
+	if changes != want {
+		t.Errorf("recorded %d status changes, want %d", changes, want)
+	}
+	if updates != want {
+		t.Errorf("revisions add up to %d updates, want %d", updates, want)
+	}
+
+	// Lock files are not projects
+	projects, err := repo.ListProjects(ctx, task.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if len(projects.Items) != 1 {
+		t.Errorf("ListProjects() returned %d projects, want 1", len(projects.Items))
+	}
+}
+
+// runStressProcess is the body of a stress test subprocess.
+func runStressProcess(t *testing.T, dir string) {
+	worker, err := strconv.Atoi(os.Getenv(stressWorkerEnv))
+	if err != nil {
+		t.Fatalf("invalid %s: %v", stressWorkerEnv, err)
+	}
+	repo, err := NewRepository(dir)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	if err := stressWorker(repo, worker); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stressWorker changes the status of the tasks in turn, saving an artifact
+// and recording the change each time. Status changes are read-modify-write,
+// retried on revision conflicts.
+func stressWorker(repo *Repository, worker int) error {
+	ctx := context.Background()
+	statuses := []task.TaskStatus{task.TaskStatusOpen, task.TaskStatusInProgress, task.TaskStatusCompleted}
+
+	for i := 0; i < stressIterations; i++ {
+		id := stressTaskID(worker + i)
+		// Re-read and retry when another worker updated the task meanwhile
+		var tsk *task.Task
+		var from task.TaskStatus
+		for {
+			var err error
+			tsk, err = repo.GetTask(ctx, stressProject, id)
+			if err != nil {
+				return fmt.Errorf("GetTask: %w", err)
+			}
+
+			from = tsk.Status
+			tsk.Status = statuses[(worker+i)%len(statuses)]
+			err = repo.UpdateTask(ctx, tsk)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, task.ErrConflict) {
+				return fmt.Errorf("UpdateTask: %w", err)
+			}
+		}
+
+		a := task.NewArtifact(stressProject, id, task.ArtifactTypeNote, fmt.Sprintf("worker %d, iteration %d", worker, i))
+		a.ID = strconv.FormatInt(stressArtifactBase+int64(worker)*1000+int64(i), 10)
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			return fmt.Errorf("SaveArtifact: %w", err)
+		}
+
+		change := task.StatusChange{From: from, To: tsk.Status, Actor: "worker " + strconv.Itoa(worker)}
+		if err := repo.AppendTaskHistory(ctx, stressProject, id, change); err != nil {
+			return fmt.Errorf("AppendTaskHistory: %w", err)
+		}
+	}
+	return nil
+}
+
+func stressTaskID(i int) task.TaskID {
+	return task.TaskID("task-" + strconv.Itoa(i%stressTasks))
+}
+
+func TestRepository_LockContext(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := repo.CreateProject(ctx, task.NewProject("backend", "Backend")); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	// Another writer holds the project while it is changed
+	unlock, err := repo.lockProject(ctx, "backend", true)
+	if err != nil {
+		t.Fatalf("lockProject() error = %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := repo.GetProject(timeout, "backend"); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, task.ErrStorageFailed) {
+		t.Errorf("GetProject() while locked error = %v, want a deadline exceeded storage failure", err)
+	}
+
+	// A waiting caller gets the lock once it is released
+	done := make(chan error, 1)
+	go func() {
+		_, err := repo.GetProject(ctx, "backend")
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	unlock()
+	if err := <-done; err != nil {
+		t.Errorf("GetProject() after release error = %v", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "task.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Errorf("file = %q, %v, want %q", data, err, content)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory holds %s, want only task.json", strings.Join(names, ", "))
+	}
+
+	// A missing directory fails without leaving anything behind
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "task.json"), []byte("x")); err == nil {
+		t.Error("writeFileAtomic() into a missing directory error = nil")
+	}
+}
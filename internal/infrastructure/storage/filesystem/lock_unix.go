@@ -0,0 +1,37 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"context"
+	"os"
+	"syscall"
+)
+
+// lockOpenFile takes an flock on the lock file f and returns the function
+// that releases it. flock locks belong to the open file, so they also
+// exclude other goroutines of this process. A busy lock is tried again until
+// ctx is done. f is closed when the lock is released or cannot be taken.
+func lockOpenFile(ctx context.Context, f *os.File, exclusive bool) (unlock func(), err error) {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	err = waitLock(ctx, func() (bool, error) {
+		switch err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB); err {
+		case nil:
+			return true, nil
+		case syscall.EWOULDBLOCK, syscall.EINTR:
+			return false, nil
+		default:
+			return false, err
+		}
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Closing the file releases the lock
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,245 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/search"
+)
+
+// memoryDocType is the search document type of memories, which unlike
+// artifacts have no task.
+const memoryDocType = "memory"
+
+// Memory operations
+
+// CreateMemory saves a new memory in its project or the global scope.
+func (r *Repository) CreateMemory(ctx context.Context, m *task.Memory) error {
+	unlock, err := r.lockTask(ctx, m.ProjectID, memoriesLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := r.requireMemoryScope(m.ProjectID); err != nil {
+		return err
+	}
+
+	filePath := r.memoryPath(m.ProjectID, m.ID)
+	if _, err := os.Stat(filePath); err == nil {
+		return task.ErrMemoryAlreadyExists
+	}
+
+	return r.writeMemory(m)
+}
+
+// GetMemory retrieves a memory by project ID and memory ID.
+func (r *Repository) GetMemory(ctx context.Context, projectID task.ProjectID, memoryID task.MemoryID) (*task.Memory, error) {
+	if err := r.requireMemoryScope(projectID); err != nil {
+		return nil, err
+	}
+
+	m, err := r.loadMemory(projectID, memoryID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, task.ErrMemoryNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return m, nil
+}
+
+// ListMemories returns the memories of a project, or the global ones, pinned
+// first and then most recently updated.
+func (r *Repository) ListMemories(ctx context.Context, projectID task.ProjectID, opts task.ListOptions) (*task.ListResult[*task.Memory], error) {
+	if err := r.requireMemoryScope(projectID); err != nil {
+		return nil, err
+	}
+
+	memories, err := r.loadMemories(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*task.Memory
+	for _, m := range memories {
+		if opts.MatchesTags(m.Tags) {
+			matched = append(matched, m)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Pinned != matched[j].Pinned {
+			return matched[i].Pinned
+		}
+		return matched[i].UpdatedAt.After(matched[j].UpdatedAt)
+	})
+
+	return applyPagination(matched, opts), nil
+}
+
+// UpdateMemory replaces an existing memory and sets its UpdatedAt.
+func (r *Repository) UpdateMemory(ctx context.Context, m *task.Memory) error {
+	unlock, err := r.lockTask(ctx, m.ProjectID, memoriesLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := r.GetMemory(ctx, m.ProjectID, m.ID); err != nil {
+		return err
+	}
+
+	m.UpdatedAt = time.Now().UTC()
+	return r.writeMemory(m)
+}
+
+// DeleteMemory removes a memory.
+func (r *Repository) DeleteMemory(ctx context.Context, projectID task.ProjectID, memoryID task.MemoryID) error {
+	unlock, err := r.lockTask(ctx, projectID, memoriesLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := r.GetMemory(ctx, projectID, memoryID); err != nil {
+		return err
+	}
+
+	if err := os.Remove(r.memoryPath(projectID, memoryID)); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := r.index.Remove(projectID.String(), "", memoryID.String()); err != nil {
+		return fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+	return nil
+}
+
+// SearchMemories searches memory titles and content in every scope or in one
+// project. Memories share the artifact search index, with no task.
+func (r *Repository) SearchMemories(ctx context.Context, query string, projectID *task.ProjectID, opts task.ListOptions) (*task.ListResult[*task.MemorySearchResult], error) {
+	if err := r.index.Refresh(); err != nil {
+		return nil, fmt.Errorf("%w: reading search index: %v", task.ErrStorageFailed, err)
+	}
+	hits := r.index.Search(search.ParseQuery(query), func(d search.Doc) bool {
+		if d.TaskID != "" || d.Type != memoryDocType || !opts.MatchesTags(d.Tags) {
+			return false
+		}
+		return projectID == nil || d.ProjectID == projectID.String()
+	})
+
+	page := applyPagination(hits, opts)
+
+	results := make([]*task.MemorySearchResult, 0, len(page.Items))
+	for _, h := range page.Items {
+		m, err := r.loadMemory(task.ProjectID(h.ProjectID), task.MemoryID(h.ID))
+		if err != nil {
+			continue // Removed outside the server
+		}
+		results = append(results, &task.MemorySearchResult{Memory: m, Score: h.Score})
+	}
+
+	return &task.ListResult[*task.MemorySearchResult]{
+		Items:   results,
+		Total:   page.Total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+		HasMore: page.HasMore,
+	}, nil
+}
+
+// requireMemoryScope checks that the project of a memory exists. The global
+// scope always does.
+func (r *Repository) requireMemoryScope(projectID task.ProjectID) error {
+	if projectID == task.GlobalScope {
+		return nil
+	}
+	if _, err := os.Stat(r.projectPath(projectID)); os.IsNotExist(err) {
+		return task.ErrProjectNotFound
+	}
+	return nil
+}
+
+// memoriesPath returns the directory holding the memories of a project, or
+// the global ones.
+func (r *Repository) memoriesPath(projectID task.ProjectID) string {
+	if projectID == task.GlobalScope {
+		return filepath.Join(r.basePath, globalDir, memoriesDir)
+	}
+	return filepath.Join(r.projectPath(projectID), memoriesDir)
+}
+
+func (r *Repository) memoryPath(projectID task.ProjectID, memoryID task.MemoryID) string {
+	return filepath.Join(r.memoriesPath(projectID), memoryID.String()+".md")
+}
+
+func (r *Repository) writeMemory(m *task.Memory) error {
+	if err := os.MkdirAll(r.memoriesPath(m.ProjectID), 0755); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	content, err := encodeMemoryMarkdown(m)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	if err := writeFileAtomic(r.memoryPath(m.ProjectID, m.ID), []byte(content)); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := r.index.Add(memorySearchDoc(m)); err != nil {
+		return fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+	return nil
+}
+
+func (r *Repository) loadMemory(projectID task.ProjectID, memoryID task.MemoryID) (*task.Memory, error) {
+	data, err := os.ReadFile(r.memoryPath(projectID, memoryID))
+	if err != nil {
+		return nil, err
+	}
+	return decodeMemoryMarkdown(string(data), projectID, memoryID)
+}
+
+// loadMemories reads every memory of a project, or the global ones.
+// Unreadable files are skipped.
+func (r *Repository) loadMemories(projectID task.ProjectID) ([]*task.Memory, error) {
+	entries, err := os.ReadDir(r.memoriesPath(projectID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	var memories []*task.Memory
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		m, err := r.loadMemory(projectID, task.MemoryID(strings.TrimSuffix(entry.Name(), ".md")))
+		if err != nil {
+			continue // Skip invalid memories
+		}
+		memories = append(memories, m)
+	}
+	return memories, nil
+}
+
+// memorySearchDoc describes a memory for the search index.
+func memorySearchDoc(m *task.Memory) search.Doc {
+	return search.Doc{
+		ProjectID: m.ProjectID.String(),
+		ID:        m.ID.String(),
+		Type:      memoryDocType,
+		Tags:      m.Tags,
+		CreatedAt: m.CreatedAt.UnixNano(),
+		Text:      m.Title + "\n" + m.Content,
+	}
+}
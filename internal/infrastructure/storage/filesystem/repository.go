@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -13,43 +14,127 @@ import (
 	"time"
 
 	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/search"
 )
 
 const (
 	projectMetadataFile = "project.json"
 	taskMetadataFile    = "task.json"
+	taskHistoryFile     = "history.jsonl"
 	artifactsDir        = "artifacts"
+	versionsDir         = "versions"
+	memoriesDir         = "memories"
+	globalDir           = "_global" // Not a valid project ID, so it cannot clash with one
+	searchIndexFile     = ".search-index.json"
 )
 
+// taskDirPattern matches kanban-style task directories: [status]-task-id.
+// Statuses come from the project workflow, so digits are allowed too.
+var taskDirPattern = regexp.MustCompile(`^\[([a-z][a-z0-9_]*)\]-(.+)$`)
+
 // Repository implements task.Repository using filesystem storage.
 // Directory structure:
 //
 //	/base_path/
 //	  /project-id/
 //	    project.json                    (project metadata)
+//	    /memories/                      (project memories)
+//	      coding-conventions.md
 //	    /[status]-task-id/              (kanban-style naming)
 //	      task.json                     (task metadata)
+//	      history.jsonl                 (status changes, one JSON object per line)
 //	      /artifacts/
 //	        note.1234567890.md
 //	        code.1234567891.md
+//	        /versions/                  (prior versions of updated artifacts)
+//	          note.1234567890.v1.md
+//	  /_global/
+//	    /memories/                      (memories shared by every project)
+//	  .search-index.json                (artifact search index snapshot)
+//	  .search-index.json.journal        (index changes since the snapshot)
+//	  /.locks/                          (advisory locks, see lock.go)
+//
+// Files are replaced through temporary files and directories are created
+// and removed through hidden temporary names, so other processes sharing
+// the store never see them half-written.
 type Repository struct {
 	basePath string
+	index    *search.Index
+	readOnly bool // Opened with OpenReadOnly
 }
 
 // NewRepository creates a new filesystem repository.
+// The search index is built from disk on first use.
 func NewRepository(basePath string) (*Repository, error) {
 	// Create base directory if it doesn't exist
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create base directory: %w", err)
 	}
 
-	return &Repository{basePath: basePath}, nil
+	r := &Repository{basePath: basePath}
+
+	indexPath := filepath.Join(basePath, searchIndexFile)
+	index, found, err := search.Open(indexPath, r.lockSearchIndex)
+	if err != nil {
+		// Unreadable index: start over from the artifact files
+		os.Remove(indexPath)
+		index, found, err = search.Open(indexPath, r.lockSearchIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open search index: %w", err)
+		}
+	}
+	r.index = index
+
+	if !found {
+		if _, err := r.RebuildSearchIndex(context.Background()); err != nil {
+			index.Close()
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// OpenReadOnly opens an existing store without writing to it: no directory,
+// lock file or search index file is created, and a missing or unreadable
+// index is built in memory. The repository does not reject changes itself;
+// wrap it with readonly.NewRepository.
+func OpenReadOnly(basePath string) (*Repository, error) {
+	info, err := os.Stat(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open base directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to open base directory: %s is not a directory", basePath)
+	}
+
+	r := &Repository{basePath: basePath, readOnly: true}
+
+	index, found, err := search.Load(filepath.Join(basePath, searchIndexFile))
+	if err != nil {
+		index, found = search.NewIndex(), false
+	}
+	r.index = index
+
+	if !found {
+		if _, err := r.RebuildSearchIndex(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
 }
 
 // Project operations
 
 // CreateProject creates a new project directory structure.
 func (r *Repository) CreateProject(ctx context.Context, p *task.Project) error {
+	unlock, err := r.lockProject(ctx, p.ID, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	projectDir := r.projectPath(p.ID)
 
 	// Check if project already exists
@@ -57,17 +142,24 @@ func (r *Repository) CreateProject(ctx context.Context, p *task.Project) error {
 		return task.ErrProjectAlreadyExists
 	}
 
-	// Create project directory
-	if err := os.MkdirAll(projectDir, 0755); err != nil {
-		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	// Create project directory with its metadata
+	err = createDirAtomic(projectDir, func(tmp string) error {
+		return r.saveProjectMetadataToDir(tmp, p)
+	})
+	if err != nil {
+		return storageError(err)
 	}
-
-	// Save project metadata
-	return r.saveProjectMetadata(p)
+	return nil
 }
 
 // GetProject retrieves a project by ID.
 func (r *Repository) GetProject(ctx context.Context, id task.ProjectID) (*task.Project, error) {
+	unlock, err := r.lockProject(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	projectDir := r.projectPath(id)
 
 	// Check if project exists
@@ -87,7 +179,7 @@ func (r *Repository) ListProjects(ctx context.Context, opts task.ListOptions) (*
 
 	var projects []*task.Project
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() || entry.Name() == globalDir || isHidden(entry.Name()) {
 			continue
 		}
 
@@ -109,6 +201,12 @@ func (r *Repository) ListProjects(ctx context.Context, opts task.ListOptions) (*
 
 // UpdateProject updates project metadata.
 func (r *Repository) UpdateProject(ctx context.Context, p *task.Project) error {
+	unlock, err := r.lockProject(ctx, p.ID, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	projectDir := r.projectPath(p.ID)
 
 	// Check if project exists
@@ -116,12 +214,31 @@ func (r *Repository) UpdateProject(ctx context.Context, p *task.Project) error {
 		return task.ErrProjectNotFound
 	}
 
+	current, err := r.loadProjectMetadata(p.ID)
+	if err != nil {
+		return err
+	}
+	if current.Revision != p.Revision {
+		return &task.ConflictError{Revision: p.Revision, Project: current}
+	}
+
+	p.Revision++
 	p.UpdatedAt = time.Now().UTC()
-	return r.saveProjectMetadata(p)
+	if err := r.saveProjectMetadata(p); err != nil {
+		p.Revision--
+		return err
+	}
+	return nil
 }
 
 // DeleteProject removes a project and all its tasks.
 func (r *Repository) DeleteProject(ctx context.Context, id task.ProjectID) error {
+	unlock, err := r.lockProject(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	projectDir := r.projectPath(id)
 
 	// Check if project exists
@@ -129,10 +246,14 @@ func (r *Repository) DeleteProject(ctx context.Context, id task.ProjectID) error
 		return task.ErrProjectNotFound
 	}
 
-	if err := os.RemoveAll(projectDir); err != nil {
+	if err := removeDir(projectDir); err != nil {
 		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
 	}
 
+	if err := r.index.RemoveProject(id.String()); err != nil {
+		return fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+
 	return nil
 }
 
@@ -140,6 +261,12 @@ func (r *Repository) DeleteProject(ctx context.Context, id task.ProjectID) error
 
 // CreateTask creates a new task directory structure within a project.
 func (r *Repository) CreateTask(ctx context.Context, t *task.Task) error {
+	unlock, err := r.lockProject(ctx, t.ProjectID, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Verify project exists
 	projectDir := r.projectPath(t.ProjectID)
 	if _, err := os.Stat(projectDir); os.IsNotExist(err) {
@@ -151,25 +278,28 @@ func (r *Repository) CreateTask(ctx context.Context, t *task.Task) error {
 		return task.ErrTaskAlreadyExists
 	}
 
-	taskDir := r.taskDirPath(t.ProjectID, t)
-
-	// Create task directory with kanban-style name
-	if err := os.MkdirAll(taskDir, 0755); err != nil {
-		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
-	}
-
-	// Create artifacts subdirectory
-	artifactsPath := filepath.Join(taskDir, artifactsDir)
-	if err := os.MkdirAll(artifactsPath, 0755); err != nil {
-		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	// Create task directory with kanban-style name, its artifacts
+	// subdirectory and metadata
+	err = createDirAtomic(r.taskDirPath(t.ProjectID, t), func(tmp string) error {
+		if err := os.Mkdir(filepath.Join(tmp, artifactsDir), 0755); err != nil {
+			return err
+		}
+		return r.saveTaskMetadataToDir(tmp, t)
+	})
+	if err != nil {
+		return storageError(err)
 	}
-
-	// Save task metadata
-	return r.saveTaskMetadata(t)
+	return nil
 }
 
 // GetTask retrieves a task by project ID and task ID.
 func (r *Repository) GetTask(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) (*task.Task, error) {
+	unlock, err := r.lockProject(ctx, projectID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	taskDir := r.findTaskDir(projectID, taskID)
 	if taskDir == "" {
 		return nil, task.ErrTaskNotFound
@@ -180,6 +310,12 @@ func (r *Repository) GetTask(ctx context.Context, projectID task.ProjectID, task
 
 // ListTasks returns tasks for a project with pagination.
 func (r *Repository) ListTasks(ctx context.Context, projectID task.ProjectID, opts task.ListOptions) (*task.ListResult[*task.Task], error) {
+	unlock, err := r.lockProject(ctx, projectID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	projectDir := r.projectPath(projectID)
 
 	// Check if project exists
@@ -194,7 +330,7 @@ func (r *Repository) ListTasks(ctx context.Context, projectID task.ProjectID, op
 
 	var tasks []*task.Task
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() || isHidden(entry.Name()) {
 			continue
 		}
 
@@ -214,14 +350,17 @@ func (r *Repository) ListTasks(ctx context.Context, projectID task.ProjectID, op
 		if opts.Status != "" && t.Status != opts.Status {
 			continue
 		}
+		if opts.Parent != "" && t.ParentID != opts.Parent {
+			continue
+		}
+		if !opts.MatchesTags(t.Tags) {
+			continue
+		}
 
 		tasks = append(tasks, t)
 	}
 
-	// Sort by updated time, newest first
-	sort.Slice(tasks, func(i, j int) bool {
-		return tasks[i].UpdatedAt.After(tasks[j].UpdatedAt)
-	})
+	task.SortTasks(tasks, opts.Sort)
 
 	return applyPagination(tasks, opts), nil
 }
@@ -229,7 +368,7 @@ func (r *Repository) ListTasks(ctx context.Context, projectID task.ProjectID, op
 // ListAllTasks returns tasks from all projects with pagination.
 func (r *Repository) ListAllTasks(ctx context.Context, opts task.ListOptions) (*task.ListResult[*task.Task], error) {
 	// Get all projects first
-	projectsResult, err := r.ListProjects(ctx, task.ListOptions{Limit: 0})
+	projectsResult, err := r.ListProjects(ctx, task.ListOptions{Limit: math.MaxInt32})
 	if err != nil {
 		return nil, err
 	}
@@ -237,29 +376,52 @@ func (r *Repository) ListAllTasks(ctx context.Context, opts task.ListOptions) (*
 	var allTasks []*task.Task
 	for _, p := range projectsResult.Items {
 		// List tasks for each project (without pagination to collect all)
-		tasksResult, err := r.ListTasks(ctx, p.ID, task.ListOptions{Limit: 0, Status: opts.Status})
+		tasksResult, err := r.ListTasks(ctx, p.ID, task.ListOptions{
+			Limit:    math.MaxInt32,
+			Status:   opts.Status,
+			Tags:     opts.Tags,
+			TagMatch: opts.TagMatch,
+		})
 		if err != nil {
 			continue // Skip projects with errors
 		}
 		allTasks = append(allTasks, tasksResult.Items...)
 	}
 
-	// Sort by updated time, newest first
-	sort.Slice(allTasks, func(i, j int) bool {
-		return allTasks[i].UpdatedAt.After(allTasks[j].UpdatedAt)
-	})
+	task.SortTasks(allTasks, opts.Sort)
 
 	return applyPagination(allTasks, opts), nil
 }
 
 // UpdateTask updates task metadata and renames directory if status changed.
+// The metadata is written first: it holds the status, so a crash before the
+// rename leaves a directory name that the next update corrects.
 func (r *Repository) UpdateTask(ctx context.Context, t *task.Task) error {
+	unlock, err := r.lockProject(ctx, t.ProjectID, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	oldDir := r.findTaskDir(t.ProjectID, t.ID)
 	if oldDir == "" {
 		return task.ErrTaskNotFound
 	}
 
+	current, err := r.loadTaskMetadataFromDir(oldDir)
+	if err != nil {
+		return err
+	}
+	if current.Revision != t.Revision {
+		return &task.ConflictError{Revision: t.Revision, Task: current}
+	}
+
+	t.Revision++
 	t.UpdatedAt = time.Now().UTC()
+	if err := r.saveTaskMetadataToDir(oldDir, t); err != nil {
+		t.Revision--
+		return err
+	}
 
 	// Check if we need to rename directory (status changed)
 	newDir := r.taskDirPath(t.ProjectID, t)
@@ -268,28 +430,117 @@ func (r *Repository) UpdateTask(ctx context.Context, t *task.Task) error {
 			return fmt.Errorf("%w: failed to rename task directory: %v", task.ErrStorageFailed, err)
 		}
 	}
-
-	return r.saveTaskMetadataToDir(newDir, t)
+	return nil
 }
 
 // DeleteTask removes a task and all its artifacts.
 func (r *Repository) DeleteTask(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) error {
+	unlock, err := r.lockProject(ctx, projectID, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	taskDir := r.findTaskDir(projectID, taskID)
+	if taskDir == "" {
+		return task.ErrTaskNotFound
+	}
+
+	if err := removeDir(taskDir); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := r.index.RemoveTask(projectID.String(), taskID.String()); err != nil {
+		return fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+
+	return nil
+}
+
+// AppendTaskHistory appends a status change to the task's history.jsonl.
+func (r *Repository) AppendTaskHistory(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, change task.StatusChange) error {
+	unlock, err := r.lockTask(ctx, projectID, taskID.String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	taskDir := r.findTaskDir(projectID, taskID)
 	if taskDir == "" {
 		return task.ErrTaskNotFound
 	}
 
-	if err := os.RemoveAll(taskDir); err != nil {
+	line, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(taskDir, taskHistoryFile), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
 		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
 	}
+	defer f.Close()
 
+	// Start a new line if a previous append was torn by a crash
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
 	return nil
 }
 
+// GetTaskHistory returns the task's status changes, oldest first. A line torn
+// by a crash while appending is skipped.
+func (r *Repository) GetTaskHistory(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) ([]task.StatusChange, error) {
+	unlock, err := r.lockProject(ctx, projectID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	taskDir := r.findTaskDir(projectID, taskID)
+	if taskDir == "" {
+		return nil, task.ErrTaskNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, taskHistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []task.StatusChange{}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	changes := []task.StatusChange{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var change task.StatusChange
+		if err := json.Unmarshal([]byte(line), &change); err != nil {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
 // Artifact operations
 
 // SaveArtifact saves an artifact to a task.
 func (r *Repository) SaveArtifact(ctx context.Context, a *task.Artifact) error {
+	unlock, err := r.lockTask(ctx, a.ProjectID, a.TaskID.String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	taskDir := r.findTaskDir(a.ProjectID, a.TaskID)
 	if taskDir == "" {
 		return task.ErrTaskNotFound
@@ -305,9 +556,12 @@ func (r *Repository) SaveArtifact(ctx context.Context, a *task.Artifact) error {
 	filePath := filepath.Join(artifactsPath, filename)
 
 	// Build markdown content with frontmatter
-	content := r.buildArtifactMarkdown(a)
+	content, err := r.buildArtifactMarkdown(a)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
 
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+	if err := writeFileAtomic(filePath, []byte(content)); err != nil {
 		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
 	}
 
@@ -318,27 +572,205 @@ func (r *Repository) SaveArtifact(ctx context.Context, a *task.Artifact) error {
 		r.saveTaskMetadataToDir(taskDir, t)
 	}
 
+	if err := r.index.Add(searchDoc(a)); err != nil {
+		return fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+
 	return nil
 }
 
 // GetArtifact retrieves an artifact by project ID, task ID, and artifact ID.
 func (r *Repository) GetArtifact(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string) (*task.Artifact, error) {
-	result, err := r.ListArtifacts(ctx, projectID, taskID, task.ListOptions{Limit: 0})
+	unlock, err := r.lockProject(ctx, projectID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return r.getArtifact(projectID, taskID, artifactID)
+}
+
+// getArtifact is GetArtifact for callers holding a lock on the project.
+func (r *Repository) getArtifact(projectID task.ProjectID, taskID task.TaskID, artifactID string) (*task.Artifact, error) {
+	taskDir := r.findTaskDir(projectID, taskID)
+	if taskDir == "" {
+		return nil, task.ErrTaskNotFound
+	}
+
+	filename := r.findArtifactFile(taskDir, artifactID)
+	if filename == "" {
+		return nil, task.ErrArtifactNotFound
+	}
+
+	return r.loadArtifact(projectID, taskID, taskDir, filename)
+}
+
+// UpdateArtifact replaces an artifact's content, tags and metadata. The file
+// being replaced is copied to the versions directory first.
+func (r *Repository) UpdateArtifact(ctx context.Context, a *task.Artifact) error {
+	unlock, err := r.lockTask(ctx, a.ProjectID, a.TaskID.String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	taskDir := r.findTaskDir(a.ProjectID, a.TaskID)
+	if taskDir == "" {
+		return task.ErrTaskNotFound
+	}
+
+	current, err := r.getArtifact(a.ProjectID, a.TaskID, a.ID)
+	if err != nil {
+		return err
+	}
+
+	artifactsPath := filepath.Join(taskDir, artifactsDir)
+	versionsPath := filepath.Join(artifactsPath, versionsDir)
+	if err := os.MkdirAll(versionsPath, 0755); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	// Keep the stored file byte for byte, so the old version reads back as it was
+	previous, err := os.ReadFile(filepath.Join(artifactsPath, current.Filename()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	if err := writeFileAtomic(filepath.Join(versionsPath, current.VersionFilename(current.Version)), previous); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	// Links change through UpdateArtifactLinks, maybe since a was read
+	now := time.Now().UTC()
+	a.Type = current.Type
+	a.CreatedAt = current.CreatedAt
+	a.Version = current.Version + 1
+	a.UpdatedAt = &now
+	a.Links = current.Links
+	a.SupersededBy = current.SupersededBy
+
+	content, err := r.buildArtifactMarkdown(a)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	if err := writeFileAtomic(filepath.Join(artifactsPath, a.Filename()), []byte(content)); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	// Update task's updated_at
+	t, err := r.loadTaskMetadataFromDir(taskDir)
+	if err == nil {
+		t.UpdatedAt = now
+		r.saveTaskMetadataToDir(taskDir, t)
+	}
+
+	if err := r.index.Add(searchDoc(a)); err != nil {
+		return fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+
+	return nil
+}
+
+// UpdateArtifactLinks rewrites the artifact file with the links change
+// makes to the stored artifact, holding the task's lock in between.
+// Links are not content, so no version is kept.
+func (r *Repository) UpdateArtifactLinks(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string, change func(*task.Artifact) bool) (*task.Artifact, error) {
+	unlock, err := r.lockTask(ctx, projectID, taskID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	taskDir := r.findTaskDir(projectID, taskID)
+	if taskDir == "" {
+		return nil, task.ErrTaskNotFound
+	}
+
+	a, err := r.getArtifact(projectID, taskID, artifactID)
+	if err != nil {
+		return nil, err
+	}
+	if !change(a) {
+		return a, nil
+	}
+
+	content, err := r.buildArtifactMarkdown(a)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	if err := writeFileAtomic(filepath.Join(taskDir, artifactsDir, a.Filename()), []byte(content)); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := r.index.Add(searchDoc(a)); err != nil {
+		return nil, fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+
+	return a, nil
+}
+
+// ListArtifactVersions returns every version of an artifact, oldest first.
+func (r *Repository) ListArtifactVersions(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string) ([]*task.Artifact, error) {
+	unlock, err := r.lockProject(ctx, projectID, false)
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
 
-	for _, a := range result.Items {
-		if a.ID == artifactID {
-			return a, nil
+	current, err := r.getArtifact(projectID, taskID, artifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*task.Artifact, 0, current.Version)
+	for v := 1; v < current.Version; v++ {
+		a, err := r.loadArtifactVersion(current, v)
+		if err != nil {
+			continue // Removed outside the server
 		}
+		versions = append(versions, a)
 	}
 
-	return nil, task.ErrArtifactNotFound
+	return append(versions, current), nil
+}
+
+// GetArtifactVersion retrieves a specific version of an artifact.
+func (r *Repository) GetArtifactVersion(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string, version int) (*task.Artifact, error) {
+	unlock, err := r.lockProject(ctx, projectID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	current, err := r.getArtifact(projectID, taskID, artifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == current.Version {
+		return current, nil
+	}
+	if version < 1 || version > current.Version {
+		return nil, task.ErrArtifactVersionNotFound
+	}
+
+	a, err := r.loadArtifactVersion(current, version)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, task.ErrArtifactVersionNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return a, nil
 }
 
 // ListArtifacts returns artifacts for a task with pagination.
 func (r *Repository) ListArtifacts(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, opts task.ListOptions) (*task.ListResult[*task.Artifact], error) {
+	unlock, err := r.lockProject(ctx, projectID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	taskDir := r.findTaskDir(projectID, taskID)
 	if taskDir == "" {
 		return nil, task.ErrTaskNotFound
@@ -369,6 +801,9 @@ func (r *Repository) ListArtifacts(ctx context.Context, projectID task.ProjectID
 		if err != nil {
 			continue // Skip invalid artifacts
 		}
+		if !opts.MatchesTags(a.Tags) || (a.IsSuperseded() && !opts.IncludeSuperseded) {
+			continue
+		}
 		artifacts = append(artifacts, a)
 	}
 
@@ -381,86 +816,220 @@ func (r *Repository) ListArtifacts(ctx context.Context, projectID task.ProjectID
 }
 
 // SearchArtifacts searches artifact content across all projects/tasks or specific project/task.
-func (r *Repository) SearchArtifacts(ctx context.Context, query string, projectID *task.ProjectID, taskID *task.TaskID, opts task.ListOptions) (*task.ListResult[*task.Artifact], error) {
-	queryLower := strings.ToLower(query)
-	var results []*task.Artifact
+// Results come from the search index, ranked by BM25.
+func (r *Repository) SearchArtifacts(ctx context.Context, query string, projectID *task.ProjectID, taskID *task.TaskID, opts task.ListOptions) (*task.ListResult[*task.SearchResult], error) {
+	if err := r.index.Refresh(); err != nil {
+		return nil, fmt.Errorf("%w: reading search index: %v", task.ErrStorageFailed, err)
+	}
+	hits := r.index.Search(search.ParseQuery(query), func(d search.Doc) bool {
+		if d.TaskID == "" {
+			return false // A memory
+		}
+		if !opts.MatchesTags(d.Tags) || (d.Superseded && !opts.IncludeSuperseded) {
+			return false
+		}
+		if projectID == nil {
+			return true
+		}
+		if d.ProjectID != projectID.String() {
+			return false
+		}
+		return taskID == nil || d.TaskID == taskID.String()
+	})
+
+	page := applyPagination(hits, opts)
+
+	results := make([]*task.SearchResult, 0, len(page.Items))
+	for _, h := range page.Items {
+		pid, tid := task.ProjectID(h.ProjectID), task.TaskID(h.TaskID)
+		taskDir := r.findTaskDir(pid, tid)
+		if taskDir == "" {
+			continue // Removed outside the server
+		}
+
+		a, err := r.loadArtifact(pid, tid, taskDir, fmt.Sprintf("%s.%s.md", h.Type, h.ID))
+		if err != nil {
+			continue // Removed outside the server
+		}
+		results = append(results, &task.SearchResult{Artifact: a, Score: h.Score})
+	}
 
-	// Determine which projects to search
-	var projectIDs []task.ProjectID
+	return &task.ListResult[*task.SearchResult]{
+		Items:   results,
+		Total:   page.Total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+		HasMore: page.HasMore,
+	}, nil
+}
+
+// CountTags returns how many tasks and artifacts carry each tag. Artifact
+// tags are read from the search index rather than from every artifact file.
+func (r *Repository) CountTags(ctx context.Context, projectID *task.ProjectID) ([]task.TagCount, error) {
+	all := task.ListOptions{Limit: math.MaxInt32}
+
+	var tasks *task.ListResult[*task.Task]
+	var err error
 	if projectID != nil {
-		projectIDs = []task.ProjectID{*projectID}
+		tasks, err = r.ListTasks(ctx, *projectID, all)
 	} else {
-		projectsResult, err := r.ListProjects(ctx, task.ListOptions{Limit: 0})
-		if err != nil {
-			return nil, err
+		tasks, err = r.ListAllTasks(ctx, all)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]*task.TagCount)
+	count := func(tag string) *task.TagCount {
+		c, ok := counts[tag]
+		if !ok {
+			c = &task.TagCount{Tag: tag}
+			counts[tag] = c
 		}
-		for _, p := range projectsResult.Items {
-			projectIDs = append(projectIDs, p.ID)
+		return c
+	}
+
+	for _, t := range tasks.Items {
+		for _, tag := range t.Tags {
+			count(tag).Tasks++
+		}
+	}
+	if err := r.index.Refresh(); err != nil {
+		return nil, fmt.Errorf("%w: reading search index: %v", task.ErrStorageFailed, err)
+	}
+	artifacts := r.index.Docs(func(d search.Doc) bool {
+		return d.TaskID != "" && (projectID == nil || d.ProjectID == projectID.String())
+	})
+	for _, d := range artifacts {
+		for _, tag := range d.Tags {
+			count(tag).Artifacts++
 		}
 	}
 
-	for _, pid := range projectIDs {
-		// Determine which tasks to search within this project
-		var taskIDs []task.TaskID
-		if taskID != nil && projectID != nil {
-			taskIDs = []task.TaskID{*taskID}
-		} else {
-			tasksResult, err := r.ListTasks(ctx, pid, task.ListOptions{Limit: 0})
-			if err != nil {
-				continue
+	result := make([]task.TagCount, 0, len(counts))
+	for _, c := range counts {
+		result = append(result, *c)
+	}
+	return result, nil
+}
+
+// RebuildSearchIndex re-indexes every artifact and memory on disk, picking up
+// files that were added, edited or removed outside the server.
+func (r *Repository) RebuildSearchIndex(ctx context.Context) (int, error) {
+	projectEntries, err := os.ReadDir(r.basePath)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	var docs []search.Doc
+	globalMemories, _ := r.loadMemories(task.GlobalScope)
+	for _, m := range globalMemories {
+		docs = append(docs, memorySearchDoc(m))
+	}
+
+	for _, projectEntry := range projectEntries {
+		if !projectEntry.IsDir() || projectEntry.Name() == globalDir || isHidden(projectEntry.Name()) {
+			continue
+		}
+
+		projectMemories, _ := r.loadMemories(task.ProjectID(projectEntry.Name()))
+		for _, m := range projectMemories {
+			docs = append(docs, memorySearchDoc(m))
+		}
+
+		projectDir := filepath.Join(r.basePath, projectEntry.Name())
+		taskEntries, err := os.ReadDir(projectDir)
+		if err != nil {
+			continue
+		}
+
+		for _, taskEntry := range taskEntries {
+			if err := ctx.Err(); err != nil {
+				return 0, err
 			}
-			for _, t := range tasksResult.Items {
-				taskIDs = append(taskIDs, t.ID)
+			if !taskEntry.IsDir() || taskEntry.Name() == memoriesDir || isHidden(taskEntry.Name()) {
+				continue
 			}
-		}
 
-		for _, tid := range taskIDs {
-			artifactsResult, err := r.ListArtifacts(ctx, pid, tid, task.ListOptions{Limit: 0})
+			taskDir := filepath.Join(projectDir, taskEntry.Name())
+			t, err := r.loadTaskMetadataFromDir(taskDir)
 			if err != nil {
-				continue
+				continue // Skip invalid tasks
 			}
 
-			for _, a := range artifactsResult.Items {
-				if strings.Contains(strings.ToLower(a.Content), queryLower) {
-					results = append(results, a)
+			entries, err := os.ReadDir(filepath.Join(taskDir, artifactsDir))
+			if err != nil {
+				continue // No artifacts directory
+			}
+			for _, entry := range entries {
+				if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+					continue
 				}
+				a, err := r.loadArtifact(t.ProjectID, t.ID, taskDir, entry.Name())
+				if err != nil {
+					continue // Skip invalid artifacts
+				}
+				docs = append(docs, searchDoc(a))
 			}
 		}
 	}
 
-	return applyPagination(results, opts), nil
+	if err := r.index.Rebuild(docs); err != nil {
+		return 0, fmt.Errorf("%w: writing search index: %v", task.ErrStorageFailed, err)
+	}
+
+	return len(docs), nil
 }
 
 // DeleteArtifact removes an artifact.
 func (r *Repository) DeleteArtifact(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string) error {
+	unlock, err := r.lockTask(ctx, projectID, taskID.String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	taskDir := r.findTaskDir(projectID, taskID)
 	if taskDir == "" {
 		return task.ErrTaskNotFound
 	}
 
-	artifactsResult, err := r.ListArtifacts(ctx, projectID, taskID, task.ListOptions{Limit: 0})
-	if err != nil {
-		return err
+	filename := r.findArtifactFile(taskDir, artifactID)
+	if filename == "" {
+		return task.ErrArtifactNotFound
 	}
 
-	for _, a := range artifactsResult.Items {
-		if a.ID == artifactID {
-			artifactsPath := filepath.Join(taskDir, artifactsDir)
-			filePath := filepath.Join(artifactsPath, a.Filename())
-
-			if err := os.Remove(filePath); err != nil {
-				return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
-			}
-			return nil
+	// Prior versions go first, so a failure leaves the artifact to delete
+	// again rather than versions without it. Not globbed: task directories
+	// contain brackets.
+	artifactsPath := filepath.Join(taskDir, artifactsDir)
+	versionsPath := filepath.Join(artifactsPath, versionsDir)
+	versions, err := os.ReadDir(versionsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	for _, entry := range versions {
+		if !strings.Contains(entry.Name(), "."+artifactID+".v") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(versionsPath, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%w: removing prior version: %v", task.ErrStorageFailed, err)
 		}
 	}
 
-	return task.ErrArtifactNotFound
+	if err := os.Remove(filepath.Join(artifactsPath, filename)); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := r.index.Remove(projectID.String(), taskID.String(), artifactID); err != nil {
+		return fmt.Errorf("%w: updating search index: %v", task.ErrStorageFailed, err)
+	}
+	return nil
 }
 
 // Close releases any resources.
 func (r *Repository) Close() error {
-	return nil
+	return r.index.Close()
 }
 
 // Helper methods
@@ -483,22 +1052,19 @@ func (r *Repository) findTaskDir(projectID task.ProjectID, taskID task.TaskID) s
 		return ""
 	}
 
-	// Pattern: [status]-taskid
-	pattern := regexp.MustCompile(`^\[([a-z_]+)\]-(.+)$`)
-
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
 
 		name := entry.Name()
-		matches := pattern.FindStringSubmatch(name)
+		matches := taskDirPattern.FindStringSubmatch(name)
 		if matches != nil && task.TaskID(matches[2]) == taskID {
 			return filepath.Join(projectDir, name)
 		}
 
 		// Also check for legacy directories without status prefix
-		if task.TaskID(name) == taskID {
+		if name != memoriesDir && task.TaskID(name) == taskID {
 			return filepath.Join(projectDir, name)
 		}
 	}
@@ -507,7 +1073,10 @@ func (r *Repository) findTaskDir(projectID task.ProjectID, taskID task.TaskID) s
 }
 
 func (r *Repository) saveProjectMetadata(p *task.Project) error {
-	projectDir := r.projectPath(p.ID)
+	return r.saveProjectMetadataToDir(r.projectPath(p.ID), p)
+}
+
+func (r *Repository) saveProjectMetadataToDir(projectDir string, p *task.Project) error {
 	metadataPath := filepath.Join(projectDir, projectMetadataFile)
 
 	data, err := json.MarshalIndent(p, "", "  ")
@@ -515,7 +1084,7 @@ func (r *Repository) saveProjectMetadata(p *task.Project) error {
 		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
 	}
 
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
+	if err := writeFileAtomic(metadataPath, data); err != nil {
 		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
 	}
 
@@ -529,6 +1098,11 @@ func (r *Repository) loadProjectMetadata(id task.ProjectID) (*task.Project, erro
 	data, err := os.ReadFile(metadataPath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			// Deleted meanwhile, not a legacy directory
+			if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+				return nil, task.ErrProjectNotFound
+			}
+
 			// Create default project metadata for legacy directories
 			return &task.Project{
 				ID:        id,
@@ -562,7 +1136,7 @@ func (r *Repository) saveTaskMetadataToDir(taskDir string, t *task.Task) error {
 		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
 	}
 
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
+	if err := writeFileAtomic(metadataPath, data); err != nil {
 		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
 	}
 
@@ -577,8 +1151,7 @@ func (r *Repository) loadTaskMetadataFromDir(taskDir string) (*task.Task, error)
 		if os.IsNotExist(err) {
 			// Try to extract task ID from directory name
 			dirName := filepath.Base(taskDir)
-			pattern := regexp.MustCompile(`^\[([a-z_]+)\]-(.+)$`)
-			matches := pattern.FindStringSubmatch(dirName)
+			matches := taskDirPattern.FindStringSubmatch(dirName)
 
 			var taskID task.TaskID
 			var status task.TaskStatus = task.TaskStatusOpen
@@ -613,43 +1186,69 @@ func (r *Repository) loadTaskMetadataFromDir(taskDir string) (*task.Task, error)
 	return &t, nil
 }
 
-func (r *Repository) buildArtifactMarkdown(a *task.Artifact) string {
-	var sb strings.Builder
+// searchDoc describes an artifact for the search index.
+func searchDoc(a *task.Artifact) search.Doc {
+	return search.Doc{
+		ProjectID:  a.ProjectID.String(),
+		TaskID:     a.TaskID.String(),
+		ID:         a.ID,
+		Type:       string(a.Type),
+		Tags:       a.Tags,
+		Superseded: a.IsSuperseded(),
+		CreatedAt:  a.CreatedAt.UnixNano(),
+		Text:       a.Content,
+	}
+}
 
-	// Frontmatter
-	sb.WriteString("---\n")
-	sb.WriteString(fmt.Sprintf("id: %s\n", a.ID))
-	sb.WriteString(fmt.Sprintf("project_id: %s\n", a.ProjectID))
-	sb.WriteString(fmt.Sprintf("task_id: %s\n", a.TaskID))
-	sb.WriteString(fmt.Sprintf("type: %s\n", a.Type))
-	sb.WriteString(fmt.Sprintf("created_at: %s\n", a.CreatedAt.Format(time.RFC3339)))
+func (r *Repository) buildArtifactMarkdown(a *task.Artifact) (string, error) {
+	return encodeArtifactMarkdown(a)
+}
 
-	if len(a.Metadata) > 0 {
-		sb.WriteString("metadata:\n")
-		for k, v := range a.Metadata {
-			sb.WriteString(fmt.Sprintf("  %s: %s\n", k, v))
-		}
+// findArtifactFile returns the filename of an artifact in the task directory,
+// or "" if there is none.
+func (r *Repository) findArtifactFile(taskDir, artifactID string) string {
+	entries, err := os.ReadDir(filepath.Join(taskDir, artifactsDir))
+	if err != nil {
+		return ""
 	}
 
-	sb.WriteString("---\n\n")
-	sb.WriteString(a.Content)
-
-	return sb.String()
+	suffix := "." + artifactID + ".md"
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), suffix) {
+			return entry.Name()
+		}
+	}
+	return ""
 }
 
 func (r *Repository) loadArtifact(projectID task.ProjectID, taskID task.TaskID, taskDir, filename string) (*task.Artifact, error) {
-	artifactsPath := filepath.Join(taskDir, artifactsDir)
-	filePath := filepath.Join(artifactsPath, filename)
+	return r.loadArtifactFile(projectID, taskID, filepath.Join(taskDir, artifactsDir, filename))
+}
 
+// loadArtifactVersion reads an older version of the current artifact from the versions directory.
+func (r *Repository) loadArtifactVersion(current *task.Artifact, version int) (*task.Artifact, error) {
+	taskDir := r.findTaskDir(current.ProjectID, current.TaskID)
+	path := filepath.Join(taskDir, artifactsDir, versionsDir, current.VersionFilename(version))
+
+	a, err := r.loadArtifactFile(current.ProjectID, current.TaskID, path)
+	if err != nil {
+		return nil, err
+	}
+	a.Version = version
+	return a, nil
+}
+
+func (r *Repository) loadArtifactFile(projectID task.ProjectID, taskID task.TaskID, filePath string) (*task.Artifact, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
 	content := string(data)
+	filename := filepath.Base(filePath)
 
 	// Parse filename to extract type and timestamp (nanoseconds)
-	// Format: type.timestamp_nano.md
+	// Format: type.timestamp_nano.md, or type.timestamp_nano.vN.md for a prior version
 	parts := strings.Split(strings.TrimSuffix(filename, ".md"), ".")
 	if len(parts) < 2 {
 		return nil, fmt.Errorf("invalid artifact filename: %s", filename)
@@ -664,26 +1263,154 @@ func (r *Repository) loadArtifact(projectID task.ProjectID, taskID task.TaskID,
 	// Convert nanoseconds to time
 	createdAt := time.Unix(0, timestampNano).UTC()
 
-	// Extract content from markdown (skip frontmatter)
+	// Split frontmatter from content
 	actualContent := content
-	if strings.HasPrefix(content, "---\n") {
-		endIdx := strings.Index(content[4:], "\n---\n")
-		if endIdx != -1 {
-			actualContent = strings.TrimSpace(content[4+endIdx+5:])
+	metadata := make(map[string]string)
+	var tags []string
+	var links []task.ArtifactLink
+	var supersededBy []task.ArtifactRef
+	version := 1
+	var updatedAt *time.Time
+	if fm, body, _ := splitArtifactFrontmatter(content); fm != nil {
+		actualContent = strings.TrimSpace(body)
+
+		if fm.Metadata != nil {
+			metadata = flattenMetadata(fm.Metadata)
+		}
+		tags = task.NormalizeTags(fm.Tags)
+		links = fm.links()
+		supersededBy = fm.supersededBy()
+		if fm.Version > 1 {
+			version = fm.Version
+		}
+		if at, err := time.Parse(time.RFC3339Nano, fm.UpdatedAt); err == nil {
+			updatedAt = &at
 		}
 	}
 
 	return &task.Artifact{
-		ID:        fmt.Sprintf("%d", timestampNano),
-		ProjectID: projectID,
-		TaskID:    taskID,
-		Type:      artifactType,
-		Content:   actualContent,
-		Metadata:  make(map[string]string),
-		CreatedAt: createdAt,
+		ID:           fmt.Sprintf("%d", timestampNano),
+		ProjectID:    projectID,
+		TaskID:       taskID,
+		Type:         artifactType,
+		Content:      actualContent,
+		Tags:         tags,
+		Metadata:     metadata,
+		Links:        links,
+		SupersededBy: supersededBy,
+		Version:      version,
+		CreatedAt:    createdAt,
+		UpdatedAt:    updatedAt,
 	}, nil
 }
 
+// MigrateArtifactFrontmatter rewrites artifact files whose frontmatter is not
+// valid YAML (written by older versions with unescaped metadata values), or
+// that have no frontmatter at all. It returns the number of files rewritten.
+func (r *Repository) MigrateArtifactFrontmatter(ctx context.Context) (int, error) {
+	projectEntries, err := os.ReadDir(r.basePath)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	migrated := 0
+	for _, projectEntry := range projectEntries {
+		if !projectEntry.IsDir() || projectEntry.Name() == globalDir || isHidden(projectEntry.Name()) {
+			continue
+		}
+
+		n, err := r.migrateProjectArtifacts(ctx, task.ProjectID(projectEntry.Name()))
+		migrated += n
+		if err != nil {
+			return migrated, err
+		}
+	}
+
+	return migrated, nil
+}
+
+// migrateProjectArtifacts migrates the artifacts of a project's tasks,
+// holding the project exclusively.
+func (r *Repository) migrateProjectArtifacts(ctx context.Context, projectID task.ProjectID) (int, error) {
+	unlock, err := r.lockProject(ctx, projectID, true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	projectDir := r.projectPath(projectID)
+	taskEntries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return 0, nil
+	}
+
+	migrated := 0
+	for _, taskEntry := range taskEntries {
+		if err := ctx.Err(); err != nil {
+			return migrated, err
+		}
+		// Only task directories; the project's memories have no frontmatter to migrate
+		if !taskEntry.IsDir() || !taskDirPattern.MatchString(taskEntry.Name()) {
+			continue
+		}
+
+		taskDir := filepath.Join(projectDir, taskEntry.Name())
+		n, err := r.migrateTaskArtifacts(taskDir)
+		migrated += n
+		if err != nil {
+			return migrated, err
+		}
+	}
+
+	return migrated, nil
+}
+
+func (r *Repository) migrateTaskArtifacts(taskDir string) (int, error) {
+	artifactsPath := filepath.Join(taskDir, artifactsDir)
+	entries, err := os.ReadDir(artifactsPath)
+	if err != nil {
+		return 0, nil // No artifacts directory
+	}
+
+	t, err := r.loadTaskMetadataFromDir(taskDir)
+	if err != nil {
+		return 0, nil // Skip invalid tasks
+	}
+
+	migrated := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+
+		filePath := filepath.Join(artifactsPath, entry.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			continue
+		}
+
+		if _, _, valid := splitArtifactFrontmatter(string(data)); valid {
+			continue // Already valid YAML
+		}
+
+		a, err := r.loadArtifact(t.ProjectID, t.ID, taskDir, entry.Name())
+		if err != nil {
+			continue // Skip invalid artifacts
+		}
+
+		content, err := r.buildArtifactMarkdown(a)
+		if err != nil {
+			return migrated, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		if err := writeFileAtomic(filePath, []byte(content)); err != nil {
+			return migrated, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
+
 // applyPagination applies pagination options to a slice and returns ListResult.
 func applyPagination[T any](items []T, opts task.ListOptions) *task.ListResult[T] {
 	total := len(items)
@@ -2,8 +2,14 @@ package filesystem
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -53,6 +59,77 @@ func TestNewRepository(t *testing.T) {
 	}
 }
 
+func TestOpenReadOnly(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if err := repo.SaveArtifact(ctx, task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "indexed keyword")); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+	if err := repo.CreateMemory(ctx, task.NewMemory(project.ID, "conventions", "Conventions", "Tabs, not spaces")); err != nil {
+		t.Fatalf("CreateMemory() error = %v", err)
+	}
+	repo.Close()
+
+	tests := []struct {
+		name   string
+		remove []string // Left out of the store before opening it
+	}{
+		{name: "persisted index"},
+		{name: "no index or locks", remove: []string{searchIndexFile, searchIndexFile + ".journal", locksDir}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range tt.remove {
+				os.RemoveAll(filepath.Join(tmpDir, name))
+			}
+			before := snapshotDir(t, tmpDir)
+
+			ro, err := OpenReadOnly(tmpDir)
+			if err != nil {
+				t.Fatalf("OpenReadOnly() error = %v", err)
+			}
+			defer ro.Close()
+
+			if _, err := ro.GetTask(ctx, project.ID, taskObj.ID); err != nil {
+				t.Errorf("GetTask() error = %v", err)
+			}
+			if tasks, err := ro.ListAllTasks(ctx, task.ListOptions{Limit: 10}); err != nil || tasks.Total != 1 {
+				t.Errorf("ListAllTasks() = %v, %v, want 1 task", tasks, err)
+			}
+			if result, err := ro.SearchArtifacts(ctx, "keyword", nil, nil, task.ListOptions{Limit: 10}); err != nil || result.Total != 1 {
+				t.Errorf("SearchArtifacts() = %v, %v, want 1 artifact", result, err)
+			}
+			if result, err := ro.SearchMemories(ctx, "tabs", nil, task.ListOptions{Limit: 10}); err != nil || result.Total != 1 {
+				t.Errorf("SearchMemories() = %v, %v, want 1 memory", result, err)
+			}
+
+			if after := snapshotDir(t, tmpDir); !maps.Equal(after, before) {
+				t.Errorf("OpenReadOnly() changed the store: %d entries before, %d after", len(before), len(after))
+			}
+		})
+	}
+
+	missing := filepath.Join(tmpDir, "missing")
+	if _, err := OpenReadOnly(missing); err == nil {
+		t.Error("OpenReadOnly() of a missing directory error = nil")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("OpenReadOnly() created %s", missing)
+	}
+}
+
 func TestRepository_CreateProject(t *testing.T) {
 	repo, _, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -222,6 +299,68 @@ func TestRepository_UpdateProject(t *testing.T) {
 	}
 }
 
+func TestRepository_Revisions(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := repo.CreateProject(ctx, task.NewProject("test-project", "Test Project")); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	taskObj := task.NewTask("test-project", "fix-bug", "Fix bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	// Each update bumps the revision
+	for want := 1; want <= 2; want++ {
+		taskObj.Description = fmt.Sprintf("update %d", want)
+		if err := repo.UpdateTask(ctx, taskObj); err != nil {
+			t.Fatalf("UpdateTask() error = %v", err)
+		}
+		if taskObj.Revision != want {
+			t.Errorf("UpdateTask() Revision = %d, want %d", taskObj.Revision, want)
+		}
+	}
+
+	// An update based on an older revision is rejected with the stored task
+	stale, err := repo.GetTask(ctx, taskObj.ProjectID, taskObj.ID)
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	stale.Revision = 1
+	stale.Description = "stale"
+	err = repo.UpdateTask(ctx, stale)
+	var conflict *task.ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("UpdateTask() stale error = %v, want ConflictError", err)
+	}
+	if conflict.Revision != 1 || conflict.Task == nil || conflict.Task.Revision != 2 || conflict.Task.Description != "update 2" {
+		t.Errorf("ConflictError = %+v, want revision 1 and the stored task at revision 2", conflict)
+	}
+	if stale.Revision != 1 {
+		t.Errorf("UpdateTask() stale Revision = %d, want it unchanged", stale.Revision)
+	}
+
+	// Projects too
+	project, err := repo.GetProject(ctx, taskObj.ProjectID)
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+	if project.Revision != 0 {
+		t.Errorf("GetProject() Revision = %d, want 0", project.Revision)
+	}
+	project.Name = "Renamed"
+	if err := repo.UpdateProject(ctx, project); err != nil {
+		t.Fatalf("UpdateProject() error = %v", err)
+	}
+	project.Revision = 0
+	err = repo.UpdateProject(ctx, project)
+	if !errors.As(err, &conflict) || conflict.Project == nil || conflict.Project.Revision != 1 {
+		t.Errorf("UpdateProject() stale error = %v, want ConflictError at revision 1", err)
+	}
+}
+
 func TestRepository_DeleteProject(t *testing.T) {
 	repo, _, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -393,6 +532,20 @@ func TestRepository_ListTasks_StatusFilter(t *testing.T) {
 	if result.Items[0].ID != task.TaskID("completed-task") {
 		t.Errorf("ListTasks() returned wrong task: %q", result.Items[0].ID)
 	}
+
+	// Filter by parent
+	subtask := task.NewTask(project.ID, task.TaskID("subtask"), "Subtask")
+	subtask.ParentID = taskOpen.ID
+	if err := repo.CreateTask(ctx, subtask); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	result, err = repo.ListTasks(ctx, project.ID, task.ListOptions{Parent: taskOpen.ID})
+	if err != nil {
+		t.Fatalf("ListTasks() with parent filter error = %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].ID != subtask.ID {
+		t.Errorf("ListTasks() with parent filter returned %d items, want subtask", len(result.Items))
+	}
 }
 
 func TestRepository_UpdateTask_StatusChange(t *testing.T) {
@@ -446,6 +599,216 @@ func TestRepository_UpdateTask_StatusChange(t *testing.T) {
 	}
 }
 
+func TestRepository_UpdateTask_CustomStatus(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	// Statuses from a project workflow, including digits
+	for _, status := range []task.TaskStatus{"review", "qa_2", "blocked"} {
+		taskObj.Status = status
+		if err := repo.UpdateTask(ctx, taskObj); err != nil {
+			t.Fatalf("UpdateTask(%s) error = %v", status, err)
+		}
+
+		dir := filepath.Join(tmpDir, "test-project", "["+string(status)+"]-fix-bug")
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("task directory for %s: %v", status, err)
+		}
+
+		got, err := repo.GetTask(ctx, project.ID, taskObj.ID)
+		if err != nil {
+			t.Fatalf("GetTask() with status %s error = %v", status, err)
+		}
+		if got.Status != status {
+			t.Errorf("GetTask().Status = %q, want %q", got.Status, status)
+		}
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(tmpDir, "test-project"))
+	dirs := 0
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs++
+		}
+	}
+	if dirs != 1 {
+		t.Errorf("project has %d task directories, want 1", dirs)
+	}
+}
+
+func TestRepository_TaskHistory(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	if got, err := repo.GetTaskHistory(ctx, project.ID, taskObj.ID); err != nil || len(got) != 0 {
+		t.Fatalf("GetTaskHistory() on new task = %v, %v, want empty", got, err)
+	}
+
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	if err := repo.AppendTaskHistory(ctx, project.ID, taskObj.ID, task.StatusChange{To: task.TaskStatusOpen, At: start}); err != nil {
+		t.Fatalf("AppendTaskHistory() error = %v", err)
+	}
+
+	// The history file moves with the task directory on a status change
+	taskObj.Status = task.TaskStatusInProgress
+	if err := repo.UpdateTask(ctx, taskObj); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	change := task.StatusChange{From: task.TaskStatusOpen, To: task.TaskStatusInProgress, At: start.Add(time.Hour), Reason: "started"}
+	if err := repo.AppendTaskHistory(ctx, project.ID, taskObj.ID, change); err != nil {
+		t.Fatalf("AppendTaskHistory() error = %v", err)
+	}
+
+	// Simulate a crash in the middle of appending
+	f, err := os.OpenFile(filepath.Join(repo.findTaskDir(project.ID, taskObj.ID), taskHistoryFile), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open history: %v", err)
+	}
+	f.WriteString(`{"from":"in_progress","to":`)
+	f.Close()
+
+	got, err := repo.GetTaskHistory(ctx, project.ID, taskObj.ID)
+	if err != nil {
+		t.Fatalf("GetTaskHistory() error = %v", err)
+	}
+	if len(got) != 2 || got[1].Reason != "started" || !got[1].At.Equal(change.At) {
+		t.Errorf("GetTaskHistory() = %+v, want 2 changes", got)
+	}
+
+	// Appending after a torn line starts a new line
+	if err := repo.AppendTaskHistory(ctx, project.ID, taskObj.ID, task.StatusChange{To: task.TaskStatusCompleted, At: start.Add(2 * time.Hour)}); err != nil {
+		t.Fatalf("AppendTaskHistory() after torn line error = %v", err)
+	}
+	got, _ = repo.GetTaskHistory(ctx, project.ID, taskObj.ID)
+	if len(got) != 3 || got[2].To != task.TaskStatusCompleted {
+		t.Errorf("GetTaskHistory() after torn line = %+v, want 3 changes", got)
+	}
+
+	if err := repo.AppendTaskHistory(ctx, project.ID, "missing", change); err != task.ErrTaskNotFound {
+		t.Errorf("AppendTaskHistory(missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestRepository_Tags(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	for id, tags := range map[task.TaskID][]string{
+		"login":  {"backend", "auth"},
+		"schema": {"backend"},
+		"docs":   nil,
+	} {
+		taskObj := task.NewTask(project.ID, id, id.String())
+		taskObj.Tags = tags
+		if err := repo.CreateTask(ctx, taskObj); err != nil {
+			t.Fatalf("CreateTask(%s) error = %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts task.ListOptions
+		want int
+	}{
+		{name: "any", opts: task.ListOptions{Tags: []string{"auth", "ui"}}, want: 1},
+		{name: "all", opts: task.ListOptions{Tags: []string{"backend", "auth"}, TagMatch: task.TagMatchAll}, want: 1},
+		{name: "shared", opts: task.ListOptions{Tags: []string{"backend"}}, want: 2},
+		{name: "none", opts: task.ListOptions{Tags: []string{"ui"}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.ListTasks(ctx, project.ID, tt.opts)
+			if err != nil || result.Total != tt.want {
+				t.Errorf("ListTasks() total = %v, %v, want %d", result, err, tt.want)
+			}
+			all, err := repo.ListAllTasks(ctx, tt.opts)
+			if err != nil || all.Total != tt.want {
+				t.Errorf("ListAllTasks() total = %v, %v, want %d", all, err, tt.want)
+			}
+		})
+	}
+
+	tagged := task.NewArtifact(project.ID, "login", task.ArtifactTypeDecision, "Use JWT tokens")
+	tagged.Tags = []string{"auth"}
+	plain := task.NewArtifact(project.ID, "login", task.ArtifactTypeNote, "JWT library notes")
+	plain.CreatedAt = tagged.CreatedAt.Add(time.Millisecond)
+	plain.ID = strconv.FormatInt(plain.CreatedAt.UnixNano(), 10)
+	for _, a := range []*task.Artifact{tagged, plain} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+	}
+
+	// Tags are stored in the frontmatter and survive a restart
+	repo.Close()
+	repo, err := NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	opts := task.ListOptions{Tags: []string{"auth"}}
+	artifacts, err := repo.ListArtifacts(ctx, project.ID, "login", opts)
+	if err != nil || artifacts.Total != 1 || artifacts.Items[0].ID != tagged.ID {
+		t.Fatalf("ListArtifacts(auth) = %v, %v, want the decision", artifacts, err)
+	}
+	if got := artifacts.Items[0].Tags; len(got) != 1 || got[0] != "auth" {
+		t.Errorf("artifact Tags = %v, want [auth]", got)
+	}
+
+	results, err := repo.SearchArtifacts(ctx, "jwt", nil, nil, opts)
+	if err != nil || results.Total != 1 || results.Items[0].Artifact.ID != tagged.ID {
+		t.Errorf("SearchArtifacts(jwt, auth) = %v, %v, want the decision", results, err)
+	}
+
+	for _, scope := range []*task.ProjectID{nil, &project.ID} {
+		counts, err := repo.CountTags(ctx, scope)
+		if err != nil {
+			t.Fatalf("CountTags() error = %v", err)
+		}
+		got := make(map[string]task.TagCount)
+		for _, c := range counts {
+			got[c.Tag] = c
+		}
+		if len(got) != 2 || got["backend"] != (task.TagCount{Tag: "backend", Tasks: 2}) || got["auth"] != (task.TagCount{Tag: "auth", Tasks: 1, Artifacts: 1}) {
+			t.Errorf("CountTags() = %+v, want backend on 2 tasks and auth on a task and an artifact", counts)
+		}
+	}
+	missing := task.ProjectID("missing")
+	if _, err := repo.CountTags(ctx, &missing); err != task.ErrProjectNotFound {
+		t.Errorf("CountTags(missing) error = %v, want ErrProjectNotFound", err)
+	}
+}
+
 func TestRepository_DeleteTask(t *testing.T) {
 	repo, _, cleanup := setupTestRepo(t)
 	defer cleanup()
@@ -662,106 +1025,992 @@ func TestRepository_SearchArtifacts(t *testing.T) {
 	}
 }
 
-func TestRepository_SearchArtifacts_WithProjectFilter(t *testing.T) {
+func TestRepository_SearchArtifacts_Ranking(t *testing.T) {
 	repo, _, cleanup := setupTestRepo(t)
 	defer cleanup()
 
 	ctx := context.Background()
 
-	// Create two projects
-	project1 := task.NewProject(task.ProjectID("project1"), "Project 1")
-	if err := repo.CreateProject(ctx, project1); err != nil {
-		t.Fatalf("CreateProject() error = %v", err)
-	}
-
-	project2 := task.NewProject(task.ProjectID("project2"), "Project 2")
-	if err := repo.CreateProject(ctx, project2); err != nil {
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
 		t.Fatalf("CreateProject() error = %v", err)
 	}
 
-	// Create tasks in each project
-	task1 := task.NewTask(project1.ID, task.TaskID("task1"), "Task 1")
-	if err := repo.CreateTask(ctx, task1); err != nil {
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
 		t.Fatalf("CreateTask() error = %v", err)
 	}
 
-	task2 := task.NewTask(project2.ID, task.TaskID("task2"), "Task 2")
-	if err := repo.CreateTask(ctx, task2); err != nil {
-		t.Fatalf("CreateTask() error = %v", err)
+	passing := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "Token refresh mentioned once among many other words about the release")
+	focused := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "Token refresh fails: refresh token expired before refresh")
+	for _, a := range []*task.Artifact{passing, focused} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
 
-	// Save artifacts with same keyword in both projects
-	artifact1 := task.NewArtifact(project1.ID, task1.ID, task.ArtifactTypeNote, "Important keyword here")
-	if err := repo.SaveArtifact(ctx, artifact1); err != nil {
-		t.Fatalf("SaveArtifact() error = %v", err)
+	result, err := repo.SearchArtifacts(ctx, "refresh", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() error = %v", err)
 	}
-
-	artifact2 := task.NewArtifact(project2.ID, task2.ID, task.ArtifactTypeNote, "Another important keyword")
-	if err := repo.SaveArtifact(ctx, artifact2); err != nil {
-		t.Fatalf("SaveArtifact() error = %v", err)
+	if len(result.Items) != 2 {
+		t.Fatalf("SearchArtifacts() returned %d items, want 2", len(result.Items))
+	}
+	if result.Items[0].Artifact.ID != focused.ID {
+		t.Errorf("SearchArtifacts() first result = %q, want most relevant %q", result.Items[0].Artifact.ID, focused.ID)
+	}
+	if result.Items[0].Score <= result.Items[1].Score {
+		t.Errorf("SearchArtifacts() scores = %v, %v, want descending", result.Items[0].Score, result.Items[1].Score)
+	}
+	if result.Items[0].Artifact.Content != focused.Content {
+		t.Errorf("SearchArtifacts() content = %q, want %q", result.Items[0].Artifact.Content, focused.Content)
 	}
 
-	// Search all projects
-	result, err := repo.SearchArtifacts(ctx, "keyword", nil, nil, task.ListOptions{Limit: 10})
+	// Phrase and prefix queries
+	result, err = repo.SearchArtifacts(ctx, `"refresh token"`, nil, nil, task.ListOptions{Limit: 10})
 	if err != nil {
-		t.Fatalf("SearchArtifacts() error = %v", err)
+		t.Fatalf("SearchArtifacts() phrase error = %v", err)
 	}
-	if len(result.Items) != 2 {
-		t.Errorf("SearchArtifacts() all projects returned %d items, want 2", len(result.Items))
+	if result.Total != 1 || result.Items[0].Artifact.ID != focused.ID {
+		t.Errorf("SearchArtifacts() phrase Total = %d, want 1", result.Total)
 	}
 
-	// Search only project1
-	pid := project1.ID
-	result2, err := repo.SearchArtifacts(ctx, "keyword", &pid, nil, task.ListOptions{Limit: 10})
+	result, err = repo.SearchArtifacts(ctx, "expir*", nil, nil, task.ListOptions{Limit: 10})
 	if err != nil {
-		t.Fatalf("SearchArtifacts() with project filter error = %v", err)
+		t.Fatalf("SearchArtifacts() prefix error = %v", err)
 	}
-	if len(result2.Items) != 1 {
-		t.Errorf("SearchArtifacts() with project filter returned %d items, want 1", len(result2.Items))
+	if result.Total != 1 {
+		t.Errorf("SearchArtifacts() prefix Total = %d, want 1", result.Total)
 	}
 }
 
-func TestRepository_DeleteArtifact(t *testing.T) {
-	repo, _, cleanup := setupTestRepo(t)
+func TestRepository_SearchIndex_Incremental(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 
 	ctx := context.Background()
 
-	// Create project and task
 	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
 	if err := repo.CreateProject(ctx, project); err != nil {
 		t.Fatalf("CreateProject() error = %v", err)
 	}
 
-	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
-	if err := repo.CreateTask(ctx, taskObj); err != nil {
-		t.Fatalf("CreateTask() error = %v", err)
+	task1 := task.NewTask(project.ID, task.TaskID("task-1"), "Task 1")
+	task2 := task.NewTask(project.ID, task.TaskID("task-2"), "Task 2")
+	for _, tk := range []*task.Task{task1, task2} {
+		if err := repo.CreateTask(ctx, tk); err != nil {
+			t.Fatalf("CreateTask() error = %v", err)
+		}
 	}
 
-	// Save artifact
-	artifact := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "This is a note")
-	if err := repo.SaveArtifact(ctx, artifact); err != nil {
-		t.Fatalf("SaveArtifact() error = %v", err)
+	a1 := task.NewArtifact(project.ID, task1.ID, task.ArtifactTypeNote, "indexed keyword one")
+	a2 := task.NewArtifact(project.ID, task1.ID, task.ArtifactTypeNote, "indexed keyword two")
+	a3 := task.NewArtifact(project.ID, task2.ID, task.ArtifactTypeNote, "indexed keyword three")
+	for _, a := range []*task.Artifact{a1, a2, a3} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
 	}
 
-	// Delete artifact
-	if err := repo.DeleteArtifact(ctx, project.ID, taskObj.ID, artifact.ID); err != nil {
+	search := func() int {
+		t.Helper()
+		result, err := repo.SearchArtifacts(ctx, "keyword", nil, nil, task.ListOptions{Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchArtifacts() error = %v", err)
+		}
+		return result.Total
+	}
+
+	if got := search(); got != 3 {
+		t.Fatalf("SearchArtifacts() Total = %d, want 3", got)
+	}
+
+	if err := repo.DeleteArtifact(ctx, project.ID, task1.ID, a1.ID); err != nil {
 		t.Fatalf("DeleteArtifact() error = %v", err)
 	}
+	if got := search(); got != 2 {
+		t.Errorf("SearchArtifacts() after DeleteArtifact Total = %d, want 2", got)
+	}
 
-	// Verify deletion
-	_, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, artifact.ID)
-	if err != task.ErrArtifactNotFound {
-		t.Errorf("GetArtifact() after delete error = %v, want ErrArtifactNotFound", err)
+	if err := repo.DeleteTask(ctx, project.ID, task1.ID); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+	if got := search(); got != 1 {
+		t.Errorf("SearchArtifacts() after DeleteTask Total = %d, want 1", got)
+	}
+
+	// Status change renames the task directory; results must still load
+	task2.Status = task.TaskStatusCompleted
+	if err := repo.UpdateTask(ctx, task2); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+
+	// Index survives a restart
+	repo.Close()
+	reopened, err := NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository() reopen error = %v", err)
+	}
+	defer reopened.Close()
+	repo = reopened
+
+	result, err := repo.SearchArtifacts(ctx, "keyword", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() after reopen error = %v", err)
+	}
+	if result.Total != 1 || len(result.Items) != 1 || result.Items[0].Artifact.ID != a3.ID {
+		t.Errorf("SearchArtifacts() after reopen Total = %d, want 1 (%s)", result.Total, a3.ID)
+	}
+
+	if err := repo.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if got := search(); got != 0 {
+		t.Errorf("SearchArtifacts() after DeleteProject Total = %d, want 0", got)
 	}
 }
 
-func TestRepository_Close(t *testing.T) {
-	repo, _, cleanup := setupTestRepo(t)
+func TestRepository_SearchIndex_Shared(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 
-	err := repo.Close()
+	// Another process on the same store
+	other, err := NewRepository(tmpDir)
 	if err != nil {
-		t.Errorf("Close() error = %v", err)
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer other.Close()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	taskObj := task.NewTask(project.ID, task.TaskID("task-1"), "Task 1")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	save := func(r *Repository, content string) {
+		t.Helper()
+		if err := r.SaveArtifact(ctx, task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, content)); err != nil {
+			t.Fatalf("SaveArtifact(%q) error = %v", content, err)
+		}
+	}
+	search := func(r *Repository, query string) int {
+		t.Helper()
+		result, err := r.SearchArtifacts(ctx, query, nil, nil, task.ListOptions{Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchArtifacts(%q) error = %v", query, err)
+		}
+		return result.Total
+	}
+
+	save(repo, "written by first")
+	save(other, "written by second")
+	if got := search(repo, "second"); got != 1 {
+		t.Errorf("first SearchArtifacts(second) Total = %d, want 1", got)
+	}
+	if got := search(other, "first"); got != 1 {
+		t.Errorf("second SearchArtifacts(first) Total = %d, want 1", got)
+	}
+
+	// One compacts the journal the other was appending to
+	if _, err := repo.RebuildSearchIndex(ctx); err != nil {
+		t.Fatalf("RebuildSearchIndex() error = %v", err)
+	}
+	save(other, "after compaction")
+	save(repo, "after rebuild")
+	for name, r := range map[string]*Repository{"first": repo, "second": other} {
+		if got := search(r, "after"); got != 2 {
+			t.Errorf("%s SearchArtifacts(after) Total = %d, want 2", name, got)
+		}
+	}
+
+	// Nothing was lost for a process started afterwards
+	reopened, err := NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository() reopen error = %v", err)
+	}
+	defer reopened.Close()
+	if got := search(reopened, "written"); got != 2 {
+		t.Errorf("reopened SearchArtifacts(written) Total = %d, want 2", got)
+	}
+	if got := search(reopened, "after"); got != 2 {
+		t.Errorf("reopened SearchArtifacts(after) Total = %d, want 2", got)
+	}
+}
+
+func TestRepository_RebuildSearchIndex(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	artifact := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "original wording")
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	// Edit the file by hand
+	path := filepath.Join(tmpDir, "test-project", "[open]-fix-bug", "artifacts", artifact.Filename())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read artifact: %v", err)
+	}
+	edited := strings.Replace(string(data), "original wording", "edited by hand", 1)
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatalf("failed to write artifact: %v", err)
+	}
+
+	result, _ := repo.SearchArtifacts(ctx, "edited", nil, nil, task.ListOptions{Limit: 10})
+	if result.Total != 0 {
+		t.Errorf("SearchArtifacts() before rebuild Total = %d, want 0", result.Total)
+	}
+
+	n, err := repo.RebuildSearchIndex(ctx)
+	if err != nil {
+		t.Fatalf("RebuildSearchIndex() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("RebuildSearchIndex() = %d, want 1", n)
+	}
+
+	result, _ = repo.SearchArtifacts(ctx, "edited", nil, nil, task.ListOptions{Limit: 10})
+	if result.Total != 1 {
+		t.Errorf("SearchArtifacts() after rebuild Total = %d, want 1", result.Total)
+	}
+	result, _ = repo.SearchArtifacts(ctx, "original", nil, nil, task.ListOptions{Limit: 10})
+	if result.Total != 0 {
+		t.Errorf("SearchArtifacts() for old wording after rebuild Total = %d, want 0", result.Total)
+	}
+}
+
+func TestNewRepository_BuildsMissingSearchIndex(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	artifact := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "existing artifact")
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+	repo.Close()
+
+	// Data from a version without an index
+	os.Remove(filepath.Join(tmpDir, searchIndexFile))
+	os.Remove(filepath.Join(tmpDir, searchIndexFile+".journal"))
+
+	repo, err := NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	result, err := repo.SearchArtifacts(ctx, "existing", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() error = %v", err)
+	}
+	if result.Total != 1 {
+		t.Errorf("SearchArtifacts() Total = %d, want 1", result.Total)
+	}
+
+	// Index files are not mistaken for projects
+	projects, err := repo.ListProjects(ctx, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if projects.Total != 1 {
+		t.Errorf("ListProjects() Total = %d, want 1", projects.Total)
+	}
+}
+
+func TestRepository_SearchArtifacts_WithProjectFilter(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Create two projects
+	project1 := task.NewProject(task.ProjectID("project1"), "Project 1")
+	if err := repo.CreateProject(ctx, project1); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	project2 := task.NewProject(task.ProjectID("project2"), "Project 2")
+	if err := repo.CreateProject(ctx, project2); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	// Create tasks in each project
+	task1 := task.NewTask(project1.ID, task.TaskID("task1"), "Task 1")
+	if err := repo.CreateTask(ctx, task1); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	task2 := task.NewTask(project2.ID, task.TaskID("task2"), "Task 2")
+	if err := repo.CreateTask(ctx, task2); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	// Save artifacts with same keyword in both projects
+	artifact1 := task.NewArtifact(project1.ID, task1.ID, task.ArtifactTypeNote, "Important keyword here")
+	if err := repo.SaveArtifact(ctx, artifact1); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	artifact2 := task.NewArtifact(project2.ID, task2.ID, task.ArtifactTypeNote, "Another important keyword")
+	if err := repo.SaveArtifact(ctx, artifact2); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	// Search all projects
+	result, err := repo.SearchArtifacts(ctx, "keyword", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() error = %v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("SearchArtifacts() all projects returned %d items, want 2", len(result.Items))
+	}
+
+	// Search only project1
+	pid := project1.ID
+	result2, err := repo.SearchArtifacts(ctx, "keyword", &pid, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() with project filter error = %v", err)
+	}
+	if len(result2.Items) != 1 {
+		t.Errorf("SearchArtifacts() with project filter returned %d items, want 1", len(result2.Items))
+	}
+}
+
+func TestRepository_ArtifactVersions(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	artifact := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeDecision, "Use sessions")
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	updated := *artifact
+	updated.Content = "Use JWT"
+	if err := repo.UpdateArtifact(ctx, &updated); err != nil {
+		t.Fatalf("UpdateArtifact() error = %v", err)
+	}
+	if updated.Version != 2 || updated.UpdatedAt == nil {
+		t.Errorf("UpdateArtifact() version = %d, updated_at = %v, want 2 and set", updated.Version, updated.UpdatedAt)
+	}
+
+	// The prior version is kept in the versions directory, the listing is unchanged
+	versionFile := filepath.Join(tmpDir, "test-project", "[open]-fix-bug", "artifacts", "versions", artifact.VersionFilename(1))
+	if _, err := os.Stat(versionFile); err != nil {
+		t.Errorf("version file not written: %v", err)
+	}
+	list, _ := repo.ListArtifacts(ctx, project.ID, taskObj.ID, task.ListOptions{})
+	if list.Total != 1 || list.Items[0].Content != "Use JWT" || list.Items[0].Version != 2 {
+		t.Errorf("ListArtifacts() = %+v, want only the latest version", list.Items)
+	}
+
+	// Versions survive a reload from disk
+	repo.Close()
+	repo, err := NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	versions, err := repo.ListArtifactVersions(ctx, project.ID, taskObj.ID, artifact.ID)
+	if err != nil {
+		t.Fatalf("ListArtifactVersions() error = %v", err)
+	}
+	if len(versions) != 2 || versions[0].Content != "Use sessions" || versions[0].Version != 1 || versions[1].Content != "Use JWT" || versions[1].UpdatedAt == nil {
+		t.Errorf("ListArtifactVersions() = %+v, want v1 then v2", versions)
+	}
+
+	if _, err := repo.GetArtifactVersion(ctx, project.ID, taskObj.ID, artifact.ID, 5); err != task.ErrArtifactVersionNotFound {
+		t.Errorf("GetArtifactVersion(5) error = %v, want ErrArtifactVersionNotFound", err)
+	}
+
+	hits, _ := repo.SearchArtifacts(ctx, "sessions", nil, nil, task.ListOptions{})
+	if hits.Total != 0 {
+		t.Errorf("SearchArtifacts(sessions) total = %d, want 0 (only the latest version is indexed)", hits.Total)
+	}
+
+	// A version that cannot be removed fails the delete and keeps the artifact
+	stuck := filepath.Join(filepath.Dir(versionFile), artifact.VersionFilename(9))
+	if err := os.MkdirAll(filepath.Join(stuck, "content"), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := repo.DeleteArtifact(ctx, project.ID, taskObj.ID, artifact.ID); !errors.Is(err, task.ErrStorageFailed) {
+		t.Errorf("DeleteArtifact() with a stuck version error = %v, want ErrStorageFailed", err)
+	}
+	if _, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, artifact.ID); err != nil {
+		t.Errorf("GetArtifact() after failed delete error = %v", err)
+	}
+	os.RemoveAll(stuck)
+
+	if err := repo.DeleteArtifact(ctx, project.ID, taskObj.ID, artifact.ID); err != nil {
+		t.Fatalf("DeleteArtifact() error = %v", err)
+	}
+	if _, err := os.Stat(versionFile); !os.IsNotExist(err) {
+		t.Errorf("version file still exists after delete: %v", err)
+	}
+}
+
+func TestRepository_ArtifactLinks(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	repo.CreateProject(ctx, project)
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	repo.CreateTask(ctx, taskObj)
+
+	old := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeDecision, "Retry three times")
+	decision := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeDecision, "Retry with backoff")
+	decision.ID = strconv.FormatInt(old.CreatedAt.UnixNano()+1, 10)
+	for _, a := range []*task.Artifact{old, decision} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+	}
+
+	decision.Links = []task.ArtifactLink{
+		{Relation: task.RelationSupersedes, Target: old.Ref()},
+		{Relation: task.RelationDerivedFrom, Target: task.ArtifactRef{ProjectID: project.ID, TaskID: "other"}},
+	}
+	old.SupersededBy = []task.ArtifactRef{decision.Ref()}
+	stale := *decision
+	for _, a := range []*task.Artifact{decision, old} {
+		_, err := repo.UpdateArtifactLinks(ctx, a.ProjectID, a.TaskID, a.ID, func(stored *task.Artifact) bool {
+			stored.Links, stored.SupersededBy = a.Links, a.SupersededBy
+			return true
+		})
+		if err != nil {
+			t.Fatalf("UpdateArtifactLinks() error = %v", err)
+		}
+	}
+
+	// Links survive a reload from disk and keep the content and version
+	repo.Close()
+	repo, err := NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	got, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, decision.ID)
+	if err != nil {
+		t.Fatalf("GetArtifact() error = %v", err)
+	}
+	if len(got.Links) != 2 || got.Links[0] != decision.Links[0] || got.Links[1] != decision.Links[1] || got.Content != "Retry with backoff" || got.Version != 1 {
+		t.Errorf("GetArtifact() = %+v, want the links, content and version 1", got)
+	}
+
+	list, _ := repo.ListArtifacts(ctx, project.ID, taskObj.ID, task.ListOptions{})
+	if list.Total != 1 || list.Items[0].ID != decision.ID {
+		t.Errorf("ListArtifacts() total = %d, want only the superseding decision", list.Total)
+	}
+	list, _ = repo.ListArtifacts(ctx, project.ID, taskObj.ID, task.ListOptions{IncludeSuperseded: true})
+	if list.Total != 2 {
+		t.Errorf("ListArtifacts(include superseded) total = %d, want 2", list.Total)
+	}
+
+	hits, _ := repo.SearchArtifacts(ctx, "retry", nil, nil, task.ListOptions{})
+	if hits.Total != 1 {
+		t.Errorf("SearchArtifacts(retry) total = %d, want 1", hits.Total)
+	}
+	hits, _ = repo.SearchArtifacts(ctx, "retry", nil, nil, task.ListOptions{IncludeSuperseded: true})
+	if hits.Total != 2 {
+		t.Errorf("SearchArtifacts(retry, include superseded) total = %d, want 2", hits.Total)
+	}
+
+	// An update from a copy read before the links were made keeps them
+	stale.Links = nil
+	stale.Content = "Retry with backoff"
+	if err := repo.UpdateArtifact(ctx, &stale); err != nil {
+		t.Fatalf("UpdateArtifact() error = %v", err)
+	}
+	if len(stale.Links) != len(decision.Links) {
+		t.Errorf("UpdateArtifact() links = %v, want %v", stale.Links, decision.Links)
+	}
+	if got, _ := repo.GetArtifact(ctx, project.ID, taskObj.ID, decision.ID); len(got.Links) != 2 {
+		t.Errorf("GetArtifact() after UpdateArtifact links = %v, want %v", got.Links, decision.Links)
+	}
+}
+
+func TestRepository_Memories(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	conventions := task.NewMemory(project.ID, "conventions", "Conventions", "Tabs, not spaces")
+	conventions.Pinned = true
+	conventions.Tags = []string{"style"}
+	global := task.NewMemory(task.GlobalScope, "preferences", "Preferences", "Short answers, tabs everywhere")
+	for _, m := range []*task.Memory{conventions, global} {
+		if err := repo.CreateMemory(ctx, m); err != nil {
+			t.Fatalf("CreateMemory(%s) error = %v", m.ID, err)
+		}
+	}
+	if err := repo.CreateMemory(ctx, conventions); err != task.ErrMemoryAlreadyExists {
+		t.Errorf("CreateMemory(duplicate) error = %v, want ErrMemoryAlreadyExists", err)
+	}
+	if err := repo.CreateMemory(ctx, task.NewMemory("missing", "x", "X", "")); err != task.ErrProjectNotFound {
+		t.Errorf("CreateMemory(missing project) error = %v, want ErrProjectNotFound", err)
+	}
+
+	// Stored as markdown next to the tasks, global ones in their own directory
+	for _, path := range []string{
+		filepath.Join(tmpDir, "test-project", "memories", "conventions.md"),
+		filepath.Join(tmpDir, "_global", "memories", "preferences.md"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("memory file %s: %v", path, err)
+		}
+	}
+
+	got, err := repo.GetMemory(ctx, project.ID, "conventions")
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if got.Title != "Conventions" || got.Content != "Tabs, not spaces" || !got.Pinned || len(got.Tags) != 1 || !got.CreatedAt.Equal(conventions.CreatedAt) {
+		t.Errorf("GetMemory() = %+v, want the saved memory", got)
+	}
+	if _, err := repo.GetMemory(ctx, task.GlobalScope, "conventions"); err != task.ErrMemoryNotFound {
+		t.Errorf("GetMemory(global conventions) error = %v, want ErrMemoryNotFound", err)
+	}
+
+	// The memories directories are neither projects nor tasks
+	projects, _ := repo.ListProjects(ctx, task.ListOptions{})
+	if projects.Total != 1 {
+		t.Errorf("ListProjects() total = %d, want 1", projects.Total)
+	}
+	if err := repo.CreateTask(ctx, task.NewTask(project.ID, "memories", "Memories")); err != nil {
+		t.Errorf("CreateTask(memories) error = %v", err)
+	}
+
+	list, _ := repo.ListMemories(ctx, project.ID, task.ListOptions{Tags: []string{"style"}})
+	if list.Total != 1 || list.Items[0].ID != "conventions" {
+		t.Errorf("ListMemories(tag style) total = %d, want the conventions", list.Total)
+	}
+
+	hits, _ := repo.SearchMemories(ctx, "tabs", nil, task.ListOptions{})
+	if hits.Total != 2 {
+		t.Errorf("SearchMemories(tabs) total = %d, want 2", hits.Total)
+	}
+	scope := task.GlobalScope
+	hits, _ = repo.SearchMemories(ctx, "tabs", &scope, task.ListOptions{})
+	if hits.Total != 1 || hits.Items[0].Memory.ID != "preferences" {
+		t.Errorf("SearchMemories(tabs, global) total = %d, want the preferences", hits.Total)
+	}
+	if artifacts, _ := repo.SearchArtifacts(ctx, "tabs", nil, nil, task.ListOptions{}); artifacts.Total != 0 {
+		t.Errorf("SearchArtifacts(tabs) total = %d, want memories excluded", artifacts.Total)
+	}
+
+	got.Content = "Spaces, not tabs"
+	if err := repo.UpdateMemory(ctx, got); err != nil {
+		t.Fatalf("UpdateMemory() error = %v", err)
+	}
+	if hits, _ := repo.SearchMemories(ctx, "spaces", nil, task.ListOptions{}); hits.Total != 1 {
+		t.Errorf("SearchMemories(spaces) after update total = %d, want 1", hits.Total)
+	}
+
+	// Rebuilding the index picks up memories
+	n, err := repo.RebuildSearchIndex(ctx)
+	if err != nil || n != 2 {
+		t.Errorf("RebuildSearchIndex() = %d, %v, want 2 memories", n, err)
+	}
+	if hits, _ := repo.SearchMemories(ctx, "spaces", nil, task.ListOptions{}); hits.Total != 1 {
+		t.Errorf("SearchMemories(spaces) after rebuild total = %d, want 1", hits.Total)
+	}
+
+	if err := repo.DeleteMemory(ctx, task.GlobalScope, "preferences"); err != nil {
+		t.Fatalf("DeleteMemory() error = %v", err)
+	}
+	if err := repo.DeleteMemory(ctx, task.GlobalScope, "preferences"); err != task.ErrMemoryNotFound {
+		t.Errorf("DeleteMemory(twice) error = %v, want ErrMemoryNotFound", err)
+	}
+	if hits, _ := repo.SearchMemories(ctx, "tabs", nil, task.ListOptions{}); hits.Total != 1 {
+		t.Errorf("SearchMemories(tabs) after delete total = %d, want 1", hits.Total)
+	}
+
+	if err := repo.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if hits, _ := repo.SearchMemories(ctx, "spaces", nil, task.ListOptions{}); hits.Total != 0 {
+		t.Errorf("SearchMemories(spaces) after deleting the project total = %d, want 0", hits.Total)
+	}
+}
+
+func TestRepository_DeleteArtifact(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Create project and task
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	// Save artifact
+	artifact := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "This is a note")
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	// Delete artifact
+	if err := repo.DeleteArtifact(ctx, project.ID, taskObj.ID, artifact.ID); err != nil {
+		t.Fatalf("DeleteArtifact() error = %v", err)
+	}
+
+	// Verify deletion
+	_, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, artifact.ID)
+	if err != task.ErrArtifactNotFound {
+		t.Errorf("GetArtifact() after delete error = %v, want ErrArtifactNotFound", err)
+	}
+}
+
+func TestRepository_Close(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	err := repo.Close()
+	if err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestRepository_ArtifactMetadata_RoundTrip(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	metadata := map[string]string{
+		"file_path":   "/src/main.go",
+		"query":       "key: value # not a comment",
+		"multi_line":  "first line\nsecond line\n---\nthird line",
+		"needs: esc":  "'quoted' \"value\"",
+		"numeric":     "00123",
+		"empty_value": "",
+	}
+
+	artifact := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "Body text")
+	artifact.Metadata = metadata
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	got, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, artifact.ID)
+	if err != nil {
+		t.Fatalf("GetArtifact() error = %v", err)
+	}
+
+	if got.Content != "Body text" {
+		t.Errorf("GetArtifact().Content = %q, want %q", got.Content, "Body text")
+	}
+	if len(got.Metadata) != len(metadata) {
+		t.Errorf("GetArtifact().Metadata has %d keys, want %d: %v", len(got.Metadata), len(metadata), got.Metadata)
+	}
+	for k, want := range metadata {
+		if got.Metadata[k] != want {
+			t.Errorf("GetArtifact().Metadata[%q] = %q, want %q", k, got.Metadata[k], want)
+		}
+	}
+}
+
+func TestRepository_ArtifactMetadata_NestedYAML(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	// Hand-edited file with nested maps and lists
+	content := `---
+id: "1733312000123456789"
+project_id: test-project
+task_id: fix-bug
+type: note
+created_at: 2024-12-04T11:33:20Z
+metadata:
+  source:
+    file: main.go
+    line: 42
+  reviewers: [alice, bob]
+  version: 1.0
+  budget: 1e3
+  mask: 0x1F
+  due: 2024-12-04
+  owner: ~
+---
+
+Hand written note`
+	path := filepath.Join(tmpDir, "test-project", "[open]-fix-bug", "artifacts", "note.1733312000123456789.md")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write artifact: %v", err)
+	}
+
+	got, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, "1733312000123456789")
+	if err != nil {
+		t.Fatalf("GetArtifact() error = %v", err)
+	}
+
+	want := map[string]string{
+		"source.file": "main.go",
+		"source.line": "42",
+		"reviewers":   "alice,bob",
+		// Scalars keep the text they were written with
+		"version": "1.0",
+		"budget":  "1e3",
+		"mask":    "0x1F",
+		"due":     "2024-12-04",
+		"owner":   "",
+	}
+	for k, v := range want {
+		if got.Metadata[k] != v {
+			t.Errorf("GetArtifact().Metadata[%q] = %q, want %q", k, got.Metadata[k], v)
+		}
+	}
+	if got.Content != "Hand written note" {
+		t.Errorf("GetArtifact().Content = %q, want %q", got.Content, "Hand written note")
+	}
+
+	// Writing the artifact back quotes the values so they read back the same
+	if err := repo.UpdateArtifact(ctx, got); err != nil {
+		t.Fatalf("UpdateArtifact() error = %v", err)
+	}
+	updated, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, "1733312000123456789")
+	if err != nil {
+		t.Fatalf("GetArtifact() after update error = %v", err)
+	}
+	for k, v := range want {
+		if updated.Metadata[k] != v {
+			t.Errorf("GetArtifact().Metadata[%q] after update = %q, want %q", k, updated.Metadata[k], v)
+		}
+	}
+}
+
+func TestRepository_MigrateArtifactFrontmatter(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	taskObj := task.NewTask(project.ID, task.TaskID("fix-bug"), "Fix Bug")
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	artifactsPath := filepath.Join(tmpDir, "test-project", "[open]-fix-bug", "artifacts")
+
+	// Written by the old hand-rolled encoder: unescaped values, not valid YAML
+	legacy := "---\nid: 1733312000000000001\nproject_id: test-project\ntask_id: fix-bug\ntype: search\ncreated_at: 2024-12-04T11:33:20Z\nmetadata:\n  query: foo: [bar\n  pattern: *.go\n---\n\nSearch results"
+	legacyPath := filepath.Join(artifactsPath, "search.1733312000000000001.md")
+	if err := os.WriteFile(legacyPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write legacy artifact: %v", err)
+	}
+
+	// Written before artifacts had a frontmatter, with content that starts
+	// with a YAML document: not a frontmatter, and migrated to get one
+	yamlBody := "---\nowner: alice\nstatus: done\n---\n\nRelease checklist"
+	yamlBodyPath := filepath.Join(artifactsPath, "note.1733312000000000002.md")
+	if err := os.WriteFile(yamlBodyPath, []byte(yamlBody), 0644); err != nil {
+		t.Fatalf("failed to write legacy artifact: %v", err)
+	}
+
+	// Already valid YAML, must be left untouched
+	valid := task.NewArtifact(project.ID, taskObj.ID, task.ArtifactTypeNote, "Valid note")
+	valid.Metadata["file_path"] = "main.go"
+	if err := repo.SaveArtifact(ctx, valid); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+	validPath := filepath.Join(artifactsPath, valid.Filename())
+	validBefore, _ := os.ReadFile(validPath)
+
+	// Legacy files are readable before migration
+	got, err := repo.GetArtifact(ctx, project.ID, taskObj.ID, "1733312000000000001")
+	if err != nil {
+		t.Fatalf("GetArtifact() before migration error = %v", err)
+	}
+	if got.Metadata["query"] != "foo: [bar" {
+		t.Errorf("legacy Metadata[query] = %q, want %q", got.Metadata["query"], "foo: [bar")
+	}
+	got, err = repo.GetArtifact(ctx, project.ID, taskObj.ID, "1733312000000000002")
+	if err != nil {
+		t.Fatalf("GetArtifact() before migration error = %v", err)
+	}
+	if got.Content != yamlBody || len(got.Metadata) != 0 {
+		t.Errorf("legacy YAML body read as Content = %q, Metadata = %v", got.Content, got.Metadata)
+	}
+
+	// Memories are not artifacts, and _global is not a project even when it
+	// holds something that looks like a task
+	for _, scope := range []task.ProjectID{project.ID, task.GlobalScope} {
+		if err := repo.CreateMemory(ctx, task.NewMemory(scope, "style", "Style", "Tabs")); err != nil {
+			t.Fatalf("CreateMemory() error = %v", err)
+		}
+	}
+	strayDir := filepath.Join(tmpDir, globalDir, "[open]-stray")
+	os.MkdirAll(filepath.Join(strayDir, artifactsDir), 0755)
+	taskJSON, _ := os.ReadFile(filepath.Join(tmpDir, "test-project", "[open]-fix-bug", taskMetadataFile))
+	os.WriteFile(filepath.Join(strayDir, taskMetadataFile), taskJSON, 0644)
+	os.WriteFile(filepath.Join(strayDir, artifactsDir, "note.1733312000000000003.md"), []byte("Not an artifact"), 0644)
+	globalBefore := snapshotDir(t, filepath.Join(tmpDir, globalDir))
+	memoriesBefore := snapshotDir(t, filepath.Join(tmpDir, "test-project", memoriesDir))
+
+	n, err := repo.MigrateArtifactFrontmatter(ctx)
+	if err != nil {
+		t.Fatalf("MigrateArtifactFrontmatter() error = %v", err)
+	}
+	if !maps.Equal(snapshotDir(t, filepath.Join(tmpDir, globalDir)), globalBefore) {
+		t.Error("MigrateArtifactFrontmatter() changed files in _global")
+	}
+	if !maps.Equal(snapshotDir(t, filepath.Join(tmpDir, "test-project", memoriesDir)), memoriesBefore) {
+		t.Error("MigrateArtifactFrontmatter() changed the project's memories")
+	}
+	if n != 2 {
+		t.Errorf("MigrateArtifactFrontmatter() migrated %d files, want 2", n)
+	}
+
+	// Rewritten file is valid YAML and keeps its metadata and content
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		t.Fatalf("failed to read migrated artifact: %v", err)
+	}
+	header, _, ok := splitFrontmatter(string(data))
+	if !ok {
+		t.Fatal("migrated artifact has no frontmatter")
+	}
+	if _, err := parseFrontmatter(header); err != nil {
+		t.Errorf("migrated frontmatter is not valid YAML: %v", err)
+	}
+
+	got, err = repo.GetArtifact(ctx, project.ID, taskObj.ID, "1733312000000000001")
+	if err != nil {
+		t.Fatalf("GetArtifact() after migration error = %v", err)
+	}
+	if got.Metadata["query"] != "foo: [bar" || got.Metadata["pattern"] != "*.go" {
+		t.Errorf("migrated Metadata = %v", got.Metadata)
+	}
+	if got.Content != "Search results" {
+		t.Errorf("migrated Content = %q, want %q", got.Content, "Search results")
+	}
+
+	got, err = repo.GetArtifact(ctx, project.ID, taskObj.ID, "1733312000000000002")
+	if err != nil {
+		t.Fatalf("GetArtifact() after migration error = %v", err)
+	}
+	if got.Content != yamlBody || got.Type != task.ArtifactTypeNote || len(got.Metadata) != 0 {
+		t.Errorf("migrated YAML body = %q (%s), Metadata = %v", got.Content, got.Type, got.Metadata)
+	}
+
+	validAfter, _ := os.ReadFile(validPath)
+	if string(validBefore) != string(validAfter) {
+		t.Error("MigrateArtifactFrontmatter() should not rewrite valid files")
+	}
+
+	// Second run is a no-op
+	if n, _ := repo.MigrateArtifactFrontmatter(ctx); n != 0 {
+		t.Errorf("second MigrateArtifactFrontmatter() migrated %d files, want 0", n)
+	}
+}
+
+// snapshotDir returns the contents of every file below dir by path, and
+// every directory with a trailing separator and no content.
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			files[path+string(filepath.Separator)] = ""
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		files[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", dir, err)
 	}
+	return files
 }
@@ -0,0 +1,90 @@
+// Package readonly provides a task.Repository decorator that rejects every
+// change, for servers that share memory with agents that must not rewrite it.
+package readonly
+
+import (
+	"context"
+
+	"agent-memory/internal/domain/task"
+)
+
+// Repository passes reads to the wrapped repository and fails every change
+// with task.ErrReadOnly. Methods added to task.Repository that change the
+// store must be overridden here.
+type Repository struct {
+	task.Repository
+}
+
+// NewRepository wraps repo so it can only be read.
+func NewRepository(repo task.Repository) *Repository {
+	return &Repository{Repository: repo}
+}
+
+// ReadOnly implements task.ReadOnly.
+func (r *Repository) ReadOnly() bool {
+	return true
+}
+
+// Project operations
+
+func (r *Repository) CreateProject(ctx context.Context, project *task.Project) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) UpdateProject(ctx context.Context, project *task.Project) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) DeleteProject(ctx context.Context, id task.ProjectID) error {
+	return task.ErrReadOnly
+}
+
+// Task operations
+
+func (r *Repository) CreateTask(ctx context.Context, t *task.Task) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) UpdateTask(ctx context.Context, t *task.Task) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) DeleteTask(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) AppendTaskHistory(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, change task.StatusChange) error {
+	return task.ErrReadOnly
+}
+
+// Artifact operations
+
+func (r *Repository) SaveArtifact(ctx context.Context, artifact *task.Artifact) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) UpdateArtifact(ctx context.Context, artifact *task.Artifact) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) UpdateArtifactLinks(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string, change func(*task.Artifact) bool) (*task.Artifact, error) {
+	return nil, task.ErrReadOnly
+}
+
+func (r *Repository) DeleteArtifact(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string) error {
+	return task.ErrReadOnly
+}
+
+// Memory operations
+
+func (r *Repository) CreateMemory(ctx context.Context, memory *task.Memory) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) UpdateMemory(ctx context.Context, memory *task.Memory) error {
+	return task.ErrReadOnly
+}
+
+func (r *Repository) DeleteMemory(ctx context.Context, projectID task.ProjectID, memoryID task.MemoryID) error {
+	return task.ErrReadOnly
+}
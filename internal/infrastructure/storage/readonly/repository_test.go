@@ -0,0 +1,84 @@
+package readonly
+
+import (
+	"context"
+	"testing"
+
+	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/storage/filesystem"
+)
+
+func TestRepository(t *testing.T) {
+	inner, err := filesystem.NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer inner.Close()
+
+	ctx := context.Background()
+	project := task.NewProject("backend", "Backend")
+	if err := inner.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	tsk := task.NewTask("backend", "auth", "Auth")
+	if err := inner.CreateTask(ctx, tsk); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	artifact := task.NewArtifact("backend", "auth", task.ArtifactTypeNote, "notes")
+	if err := inner.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+	memory := task.NewMemory("backend", "conventions", "Conventions", "tabs")
+	if err := inner.CreateMemory(ctx, memory); err != nil {
+		t.Fatalf("CreateMemory() error = %v", err)
+	}
+
+	repo := NewRepository(inner)
+	if !task.IsReadOnly(repo) {
+		t.Error("IsReadOnly() = false, want true")
+	}
+	if task.IsReadOnly(inner) {
+		t.Error("IsReadOnly(filesystem) = true, want false")
+	}
+
+	// Reads pass through
+	if _, err := repo.GetTask(ctx, "backend", "auth"); err != nil {
+		t.Errorf("GetTask() error = %v", err)
+	}
+	if result, err := repo.ListArtifacts(ctx, "backend", "auth", task.ListOptions{}); err != nil || len(result.Items) != 1 {
+		t.Errorf("ListArtifacts() = %v, %v, want 1 artifact", result, err)
+	}
+
+	changes := map[string]error{
+		"CreateProject":     repo.CreateProject(ctx, task.NewProject("frontend", "Frontend")),
+		"UpdateProject":     repo.UpdateProject(ctx, project),
+		"DeleteProject":     repo.DeleteProject(ctx, "backend"),
+		"CreateTask":        repo.CreateTask(ctx, task.NewTask("backend", "ui", "UI")),
+		"UpdateTask":        repo.UpdateTask(ctx, tsk),
+		"DeleteTask":        repo.DeleteTask(ctx, "backend", "auth"),
+		"AppendTaskHistory": repo.AppendTaskHistory(ctx, "backend", "auth", task.StatusChange{}),
+		"SaveArtifact":      repo.SaveArtifact(ctx, task.NewArtifact("backend", "auth", task.ArtifactTypeNote, "more")),
+		"UpdateArtifact":    repo.UpdateArtifact(ctx, artifact),
+		"UpdateArtifactLinks": func() error {
+			_, err := repo.UpdateArtifactLinks(ctx, "backend", "auth", artifact.ID, func(*task.Artifact) bool { return true })
+			return err
+		}(),
+		"DeleteArtifact": repo.DeleteArtifact(ctx, "backend", "auth", artifact.ID),
+		"CreateMemory":   repo.CreateMemory(ctx, task.NewMemory("backend", "other", "Other", "x")),
+		"UpdateMemory":   repo.UpdateMemory(ctx, memory),
+		"DeleteMemory":   repo.DeleteMemory(ctx, "backend", "conventions"),
+	}
+	for method, err := range changes {
+		if err != task.ErrReadOnly {
+			t.Errorf("%s() error = %v, want ErrReadOnly", method, err)
+		}
+	}
+
+	// The store is unchanged
+	if _, err := inner.GetProject(ctx, "frontend"); err != task.ErrProjectNotFound {
+		t.Errorf("GetProject(frontend) error = %v, want ErrProjectNotFound", err)
+	}
+	if result, _ := inner.ListArtifacts(ctx, "backend", "auth", task.ListOptions{}); len(result.Items) != 1 {
+		t.Errorf("ListArtifacts() returned %d artifacts, want 1", len(result.Items))
+	}
+}
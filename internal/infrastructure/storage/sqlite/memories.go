@@ -0,0 +1,239 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/search"
+)
+
+// Memory operations
+
+// CreateMemory saves a new memory in its project or the global scope.
+func (r *Repository) CreateMemory(ctx context.Context, m *task.Memory) error {
+	if err := r.requireMemoryScope(ctx, m.ProjectID); err != nil {
+		return err
+	}
+
+	data, err := encodeMemory(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO memories (project_id, id, title, content, data, pinned, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		m.ProjectID.String(), m.ID.String(), m.Title, m.Content, data, m.Pinned, m.UpdatedAt.UnixNano(),
+	)
+	if err != nil {
+		if isConstraintError(err) {
+			return task.ErrMemoryAlreadyExists
+		}
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return nil
+}
+
+// GetMemory retrieves a memory by project ID and memory ID.
+func (r *Repository) GetMemory(ctx context.Context, projectID task.ProjectID, memoryID task.MemoryID) (*task.Memory, error) {
+	if err := r.requireMemoryScope(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	var content, data string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT content, data FROM memories WHERE project_id = ? AND id = ?`,
+		projectID.String(), memoryID.String(),
+	).Scan(&content, &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, task.ErrMemoryNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return decodeMemory(content, data)
+}
+
+// ListMemories returns the memories of a project, or the global ones, pinned
+// first and then most recently updated.
+func (r *Repository) ListMemories(ctx context.Context, projectID task.ProjectID, opts task.ListOptions) (*task.ListResult[*task.Memory], error) {
+	if err := r.requireMemoryScope(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	where := newFilter()
+	where.add("project_id = ?", projectID.String())
+	where.addTags("data", opts)
+
+	page := newPage(opts)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM memories`+where.sql(), where.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	args := append(append([]any{}, where.args...), page.limit, page.offset)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT content, data, 0.0 FROM memories`+where.sql()+` ORDER BY pinned DESC, updated_at DESC, id LIMIT ? OFFSET ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	results, err := scanMemories(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	memories := make([]*task.Memory, 0, len(results))
+	for _, res := range results {
+		memories = append(memories, res.Memory)
+	}
+	return pageResult(page, memories, total), nil
+}
+
+// UpdateMemory replaces an existing memory and sets its UpdatedAt.
+func (r *Repository) UpdateMemory(ctx context.Context, m *task.Memory) error {
+	if err := r.requireMemoryScope(ctx, m.ProjectID); err != nil {
+		return err
+	}
+
+	m.UpdatedAt = time.Now().UTC()
+
+	data, err := encodeMemory(m)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE memories SET title = ?, content = ?, data = ?, pinned = ?, updated_at = ? WHERE project_id = ? AND id = ?`,
+		m.Title, m.Content, data, m.Pinned, m.UpdatedAt.UnixNano(), m.ProjectID.String(), m.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return requireAffected(res, task.ErrMemoryNotFound)
+}
+
+// DeleteMemory removes a memory.
+func (r *Repository) DeleteMemory(ctx context.Context, projectID task.ProjectID, memoryID task.MemoryID) error {
+	if err := r.requireMemoryScope(ctx, projectID); err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM memories WHERE project_id = ? AND id = ?`,
+		projectID.String(), memoryID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return requireAffected(res, task.ErrMemoryNotFound)
+}
+
+// SearchMemories searches memory titles and content in every scope or in one
+// project. Matching and BM25 ranking are done by the memories_fts index.
+func (r *Repository) SearchMemories(ctx context.Context, query string, projectID *task.ProjectID, opts task.ListOptions) (*task.ListResult[*task.MemorySearchResult], error) {
+	q := search.ParseQuery(query)
+
+	where := newFilter()
+	if projectID != nil {
+		where.add("m.project_id = ?", projectID.String())
+	}
+	where.addTags("m.data", opts)
+
+	// bm25() is negative, lower is better; it is only available with MATCH
+	from := `memories m`
+	score := `0.0`
+	if !q.Empty() {
+		from = `memories_fts JOIN memories m ON m.seq = memories_fts.rowid`
+		score = `-bm25(memories_fts)`
+		where.add("memories_fts MATCH ?", matchExpression(q))
+	}
+
+	page := newPage(opts)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+from+where.sql(), where.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	args := append(append([]any{}, where.args...), page.limit, page.offset)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT m.content, m.data, `+score+` AS score FROM `+from+where.sql()+
+			` ORDER BY score DESC, m.pinned DESC, m.updated_at DESC LIMIT ? OFFSET ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	results, err := scanMemories(rows)
+	if err != nil {
+		return nil, err
+	}
+	return pageResult(page, results, total), nil
+}
+
+// requireMemoryScope checks that the project of a memory exists. The global
+// scope always does.
+func (r *Repository) requireMemoryScope(ctx context.Context, projectID task.ProjectID) error {
+	if projectID == task.GlobalScope {
+		return nil
+	}
+	return r.requireProject(ctx, r.db, projectID)
+}
+
+// scanMemories reads (content, data, score) rows.
+func scanMemories(rows *sql.Rows) ([]*task.MemorySearchResult, error) {
+	defer rows.Close()
+
+	results := []*task.MemorySearchResult{}
+	for rows.Next() {
+		var content, data string
+		var score float64
+		if err := rows.Scan(&content, &data, &score); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		m, err := decodeMemory(content, data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &task.MemorySearchResult{Memory: m, Score: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return results, nil
+}
+
+// encodeMemory serializes everything except the content, like encodeArtifact.
+func encodeMemory(m *task.Memory) (string, error) {
+	stored := *m
+	stored.Content = ""
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return string(data), nil
+}
+
+func decodeMemory(content, data string) (*task.Memory, error) {
+	var m task.Memory
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	m.Content = content
+	return &m, nil
+}
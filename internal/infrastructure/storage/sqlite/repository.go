@@ -0,0 +1,1224 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+
+	"agent-memory/internal/domain/task"
+	"agent-memory/internal/infrastructure/search"
+)
+
+// DatabaseFile is the default database filename inside the tasks directory.
+const DatabaseFile = "agent-memory.db"
+
+// schemaVersion is stored in PRAGMA user_version and bumped whenever
+// migrations are appended below.
+const schemaVersion = 5
+
+// migrations are applied in order; index i upgrades the schema to version i+1.
+var migrations = []string{
+	`
+	CREATE TABLE projects (
+		id         TEXT PRIMARY KEY,
+		data       TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE tasks (
+		project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		id         TEXT NOT NULL,
+		status     TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (project_id, id)
+	);
+	CREATE INDEX tasks_updated_at ON tasks(updated_at DESC);
+	CREATE INDEX tasks_status ON tasks(status);
+
+	CREATE TABLE artifacts (
+		project_id TEXT NOT NULL,
+		task_id    TEXT NOT NULL,
+		id         TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		content    TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (project_id, task_id, id),
+		FOREIGN KEY (project_id, task_id) REFERENCES tasks(project_id, id) ON DELETE CASCADE
+	);
+	CREATE INDEX artifacts_created_at ON artifacts(created_at DESC);
+	`,
+	// Full-text search: artifacts gets a stable integer rowid for the
+	// external-content FTS5 table, which triggers keep in sync.
+	`
+	CREATE TABLE artifacts_v2 (
+		seq        INTEGER PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		task_id    TEXT NOT NULL,
+		id         TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		content    TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		UNIQUE (project_id, task_id, id),
+		FOREIGN KEY (project_id, task_id) REFERENCES tasks(project_id, id) ON DELETE CASCADE
+	);
+	INSERT INTO artifacts_v2 (project_id, task_id, id, type, content, data, created_at)
+		SELECT project_id, task_id, id, type, content, data, created_at FROM artifacts ORDER BY created_at;
+	DROP TABLE artifacts;
+	ALTER TABLE artifacts_v2 RENAME TO artifacts;
+	CREATE INDEX artifacts_created_at ON artifacts(created_at DESC);
+
+	CREATE VIRTUAL TABLE artifacts_fts USING fts5(
+		content,
+		content = 'artifacts',
+		content_rowid = 'seq',
+		tokenize = 'unicode61 remove_diacritics 0'
+	);
+	CREATE TRIGGER artifacts_fts_insert AFTER INSERT ON artifacts BEGIN
+		INSERT INTO artifacts_fts (rowid, content) VALUES (new.seq, new.content);
+	END;
+	CREATE TRIGGER artifacts_fts_delete AFTER DELETE ON artifacts BEGIN
+		INSERT INTO artifacts_fts (artifacts_fts, rowid, content) VALUES ('delete', old.seq, old.content);
+	END;
+	CREATE TRIGGER artifacts_fts_update AFTER UPDATE ON artifacts BEGIN
+		INSERT INTO artifacts_fts (artifacts_fts, rowid, content) VALUES ('delete', old.seq, old.content);
+		INSERT INTO artifacts_fts (rowid, content) VALUES (new.seq, new.content);
+	END;
+	INSERT INTO artifacts_fts (artifacts_fts) VALUES ('rebuild');
+	`,
+	// Append-only task status history.
+	`
+	CREATE TABLE task_history (
+		seq        INTEGER PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		task_id    TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		at         INTEGER NOT NULL,
+		FOREIGN KEY (project_id, task_id) REFERENCES tasks(project_id, id) ON DELETE CASCADE
+	);
+	CREATE INDEX task_history_task ON task_history(project_id, task_id, seq);
+	`,
+	// Prior versions of updated artifacts; the current one stays in artifacts.
+	`
+	CREATE TABLE artifact_versions (
+		project_id  TEXT NOT NULL,
+		task_id     TEXT NOT NULL,
+		artifact_id TEXT NOT NULL,
+		version     INTEGER NOT NULL,
+		content     TEXT NOT NULL,
+		data        TEXT NOT NULL,
+		PRIMARY KEY (project_id, task_id, artifact_id, version),
+		FOREIGN KEY (project_id, task_id, artifact_id) REFERENCES artifacts(project_id, task_id, id) ON DELETE CASCADE
+	);
+	`,
+	// Project and global memories. Global ones have an empty project_id, so
+	// there is no foreign key; a trigger removes a deleted project's memories.
+	`
+	CREATE TABLE memories (
+		seq        INTEGER PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		id         TEXT NOT NULL,
+		title      TEXT NOT NULL,
+		content    TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		pinned     INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		UNIQUE (project_id, id)
+	);
+	CREATE TRIGGER projects_delete_memories AFTER DELETE ON projects BEGIN
+		DELETE FROM memories WHERE project_id = old.id;
+	END;
+
+	CREATE VIRTUAL TABLE memories_fts USING fts5(
+		title,
+		content,
+		content = 'memories',
+		content_rowid = 'seq',
+		tokenize = 'unicode61 remove_diacritics 0'
+	);
+	CREATE TRIGGER memories_fts_insert AFTER INSERT ON memories BEGIN
+		INSERT INTO memories_fts (rowid, title, content) VALUES (new.seq, new.title, new.content);
+	END;
+	CREATE TRIGGER memories_fts_delete AFTER DELETE ON memories BEGIN
+		INSERT INTO memories_fts (memories_fts, rowid, title, content) VALUES ('delete', old.seq, old.title, old.content);
+	END;
+	CREATE TRIGGER memories_fts_update AFTER UPDATE ON memories BEGIN
+		INSERT INTO memories_fts (memories_fts, rowid, title, content) VALUES ('delete', old.seq, old.title, old.content);
+		INSERT INTO memories_fts (rowid, title, content) VALUES (new.seq, new.title, new.content);
+	END;
+	`,
+}
+
+// Repository implements task.Repository using a SQLite database.
+// Entities are stored as JSON documents in a data column; the columns next
+// to it only exist for lookups, filtering and ordering.
+//
+//	projects  (id, data, created_at, updated_at)
+//	tasks     (project_id, id, status, data, created_at, updated_at)
+//	artifacts (seq, project_id, task_id, id, type, content, data, created_at)
+//	artifacts_fts (FTS5 index over artifacts.content)
+//	task_history  (seq, project_id, task_id, data, at)
+//	artifact_versions (project_id, task_id, artifact_id, version, content, data)
+//	memories      (seq, project_id, id, title, content, data, pinned, updated_at)
+//	memories_fts  (FTS5 index over memories.title and memories.content)
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository opens (or creates) the SQLite database at dbPath and applies
+// pending schema migrations.
+func NewRepository(dbPath string) (*Repository, error) {
+	// Create parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	r := &Repository{db: db}
+	if err := r.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return r, nil
+}
+
+// OpenReadOnly opens the existing SQLite database at dbPath without writing
+// to it. Migrating would change the database, so one whose schema is behind
+// this version's is refused rather than upgraded. SQLite still creates the
+// -wal and -shm files it coordinates connections with when they are missing.
+func OpenReadOnly(dbPath string) (*Repository, error) {
+	// SQLite reports a missing file in read-only mode less clearly
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", dbPath)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if version < schemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("database schema version %d is behind %d: open it once without read-only mode to migrate it", version, schemaVersion)
+	}
+
+	return &Repository{db: db}, nil
+}
+
+// Project operations
+
+// CreateProject creates a new project.
+func (r *Repository) CreateProject(ctx context.Context, p *task.Project) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO projects (id, data, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		p.ID.String(), string(data), p.CreatedAt.UnixNano(), p.UpdatedAt.UnixNano(),
+	)
+	if err != nil {
+		if isConstraintError(err) {
+			return task.ErrProjectAlreadyExists
+		}
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return nil
+}
+
+// GetProject retrieves a project by ID.
+func (r *Repository) GetProject(ctx context.Context, id task.ProjectID) (*task.Project, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, `SELECT data FROM projects WHERE id = ?`, id.String()).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, task.ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return decodeProject(data)
+}
+
+// ListProjects returns projects with pagination.
+func (r *Repository) ListProjects(ctx context.Context, opts task.ListOptions) (*task.ListResult[*task.Project], error) {
+	page := newPage(opts)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM projects`).Scan(&total); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT data FROM projects ORDER BY updated_at DESC LIMIT ? OFFSET ?`,
+		page.limit, page.offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	projects, err := scanAll(rows, decodeProject)
+	if err != nil {
+		return nil, err
+	}
+
+	return pageResult(page, projects, total), nil
+}
+
+// UpdateProject updates project metadata if the stored revision is still
+// p.Revision, and bumps it.
+func (r *Repository) UpdateProject(ctx context.Context, p *task.Project) error {
+	updated := *p
+	updated.Revision++
+	updated.UpdatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(&updated)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE projects SET data = ?, updated_at = ? WHERE id = ? AND `+revisionIs,
+		string(data), updated.UpdatedAt.UnixNano(), p.ID.String(), p.Revision,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := requireAffected(res, errNoMatch); err == errNoMatch {
+		current, err := r.GetProject(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		return &task.ConflictError{Revision: p.Revision, Project: current}
+	} else if err != nil {
+		return err
+	}
+
+	*p = updated
+	return nil
+}
+
+// DeleteProject removes a project and all its tasks.
+func (r *Repository) DeleteProject(ctx context.Context, id task.ProjectID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM projects WHERE id = ?`, id.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return requireAffected(res, task.ErrProjectNotFound)
+}
+
+// Task operations
+
+// CreateTask creates a new task within a project.
+func (r *Repository) CreateTask(ctx context.Context, t *task.Task) error {
+	if err := r.requireProject(ctx, r.db, t.ProjectID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO tasks (project_id, id, status, data, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		t.ProjectID.String(), t.ID.String(), string(t.Status), string(data), t.CreatedAt.UnixNano(), t.UpdatedAt.UnixNano(),
+	)
+	if err != nil {
+		if isConstraintError(err) {
+			return task.ErrTaskAlreadyExists
+		}
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return nil
+}
+
+// GetTask retrieves a task by project ID and task ID.
+func (r *Repository) GetTask(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) (*task.Task, error) {
+	return r.getTask(ctx, r.db, projectID, taskID)
+}
+
+// ListTasks returns tasks for a project with pagination.
+func (r *Repository) ListTasks(ctx context.Context, projectID task.ProjectID, opts task.ListOptions) (*task.ListResult[*task.Task], error) {
+	if err := r.requireProject(ctx, r.db, projectID); err != nil {
+		return nil, err
+	}
+
+	where := newFilter()
+	where.add("project_id = ?", projectID.String())
+	if opts.Status != "" {
+		where.add("status = ?", string(opts.Status))
+	}
+	if opts.Parent != "" {
+		where.add("json_extract(data, '$.parent_id') = ?", opts.Parent.String())
+	}
+	where.addTags("data", opts)
+
+	return r.listTasks(ctx, where, opts)
+}
+
+// ListAllTasks returns tasks from all projects with pagination.
+func (r *Repository) ListAllTasks(ctx context.Context, opts task.ListOptions) (*task.ListResult[*task.Task], error) {
+	where := newFilter()
+	if opts.Status != "" {
+		where.add("status = ?", string(opts.Status))
+	}
+	where.addTags("data", opts)
+
+	return r.listTasks(ctx, where, opts)
+}
+
+// UpdateTask updates task metadata if the stored revision is still
+// t.Revision, and bumps it.
+func (r *Repository) UpdateTask(ctx context.Context, t *task.Task) error {
+	updated := *t
+	updated.Revision++
+	updated.UpdatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(&updated)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE tasks SET status = ?, data = ?, updated_at = ? WHERE project_id = ? AND id = ? AND `+revisionIs,
+		string(updated.Status), string(data), updated.UpdatedAt.UnixNano(), t.ProjectID.String(), t.ID.String(), t.Revision,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := requireAffected(res, errNoMatch); err == errNoMatch {
+		current, err := r.getTask(ctx, r.db, t.ProjectID, t.ID)
+		if err != nil {
+			return err
+		}
+		return &task.ConflictError{Revision: t.Revision, Task: current}
+	} else if err != nil {
+		return err
+	}
+
+	*t = updated
+	return nil
+}
+
+// DeleteTask removes a task and all its artifacts.
+func (r *Repository) DeleteTask(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) error {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM tasks WHERE project_id = ? AND id = ?`,
+		projectID.String(), taskID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return requireAffected(res, task.ErrTaskNotFound)
+}
+
+// AppendTaskHistory appends a status change to the task's history.
+func (r *Repository) AppendTaskHistory(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, change task.StatusChange) error {
+	if _, err := r.getTask(ctx, r.db, projectID, taskID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO task_history (project_id, task_id, data, at) VALUES (?, ?, ?, ?)`,
+		projectID.String(), taskID.String(), string(data), change.At.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return nil
+}
+
+// GetTaskHistory returns the task's status changes, oldest first.
+func (r *Repository) GetTaskHistory(ctx context.Context, projectID task.ProjectID, taskID task.TaskID) ([]task.StatusChange, error) {
+	if _, err := r.getTask(ctx, r.db, projectID, taskID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT data FROM task_history WHERE project_id = ? AND task_id = ? ORDER BY seq`,
+		projectID.String(), taskID.String(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	defer rows.Close()
+
+	changes := []task.StatusChange{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		var change task.StatusChange
+		if err := json.Unmarshal([]byte(data), &change); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return changes, nil
+}
+
+// Artifact operations
+
+// SaveArtifact saves an artifact to a task and bumps the task's updated_at.
+func (r *Repository) SaveArtifact(ctx context.Context, a *task.Artifact) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		t, err := r.getTask(ctx, tx, a.ProjectID, a.TaskID)
+		if err != nil {
+			return err
+		}
+
+		data, err := encodeArtifact(a)
+		if err != nil {
+			return err
+		}
+
+		// Upsert rather than INSERT OR REPLACE: REPLACE deletes without
+		// firing the delete trigger, which would leave stale FTS entries.
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO artifacts (project_id, task_id, id, type, content, data, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (project_id, task_id, id) DO UPDATE SET
+				type = excluded.type, content = excluded.content, data = excluded.data, created_at = excluded.created_at`,
+			a.ProjectID.String(), a.TaskID.String(), a.ID, string(a.Type), a.Content, data, a.CreatedAt.UnixNano(),
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+
+		t.UpdatedAt = time.Now().UTC()
+		return r.saveTask(ctx, tx, t)
+	})
+}
+
+// GetArtifact retrieves an artifact by project ID, task ID, and artifact ID.
+func (r *Repository) GetArtifact(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string) (*task.Artifact, error) {
+	if _, err := r.getTask(ctx, r.db, projectID, taskID); err != nil {
+		return nil, err
+	}
+
+	return r.getArtifact(ctx, r.db, projectID, taskID, artifactID)
+}
+
+// UpdateArtifact replaces an artifact's content, tags and metadata, moving the
+// stored row to artifact_versions first.
+func (r *Repository) UpdateArtifact(ctx context.Context, a *task.Artifact) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		t, err := r.getTask(ctx, tx, a.ProjectID, a.TaskID)
+		if err != nil {
+			return err
+		}
+
+		current, err := r.getArtifact(ctx, tx, a.ProjectID, a.TaskID, a.ID)
+		if err != nil {
+			return err
+		}
+
+		previous, err := encodeArtifact(current)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO artifact_versions (project_id, task_id, artifact_id, version, content, data) VALUES (?, ?, ?, ?, ?, ?)`,
+			a.ProjectID.String(), a.TaskID.String(), a.ID, current.Version, current.Content, previous,
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+
+		// Links change through UpdateArtifactLinks, maybe since a was read
+		now := time.Now().UTC()
+		a.Type = current.Type
+		a.CreatedAt = current.CreatedAt
+		a.Version = current.Version + 1
+		a.UpdatedAt = &now
+		a.Links = current.Links
+		a.SupersededBy = current.SupersededBy
+
+		data, err := encodeArtifact(a)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE artifacts SET content = ?, data = ? WHERE project_id = ? AND task_id = ? AND id = ?`,
+			a.Content, data, a.ProjectID.String(), a.TaskID.String(), a.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+
+		t.UpdatedAt = now
+		return r.saveTask(ctx, tx, t)
+	})
+}
+
+// UpdateArtifactLinks stores the links change makes to the stored artifact
+// in its data column, in one transaction. Links are not content, so no
+// version is kept.
+func (r *Repository) UpdateArtifactLinks(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string, change func(*task.Artifact) bool) (*task.Artifact, error) {
+	var a *task.Artifact
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := r.getTask(ctx, tx, projectID, taskID); err != nil {
+			return err
+		}
+
+		var err error
+		a, err = r.getArtifact(ctx, tx, projectID, taskID, artifactID)
+		if err != nil {
+			return err
+		}
+		if !change(a) {
+			return nil
+		}
+
+		data, err := encodeArtifact(a)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE artifacts SET data = ? WHERE project_id = ? AND task_id = ? AND id = ?`,
+			data, projectID.String(), taskID.String(), artifactID,
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ListArtifactVersions returns every version of an artifact, oldest first.
+func (r *Repository) ListArtifactVersions(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string) ([]*task.Artifact, error) {
+	current, err := r.GetArtifact(ctx, projectID, taskID, artifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT version, content, data FROM artifact_versions WHERE project_id = ? AND task_id = ? AND artifact_id = ? ORDER BY version`,
+		projectID.String(), taskID.String(), artifactID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	defer rows.Close()
+
+	versions := []*task.Artifact{}
+	for rows.Next() {
+		var version int
+		var content, data string
+		if err := rows.Scan(&version, &content, &data); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		a, err := decodeArtifact(content, data)
+		if err != nil {
+			return nil, err
+		}
+		a.Version = version
+		versions = append(versions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return append(versions, current), nil
+}
+
+// GetArtifactVersion retrieves a specific version of an artifact.
+func (r *Repository) GetArtifactVersion(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string, version int) (*task.Artifact, error) {
+	current, err := r.GetArtifact(ctx, projectID, taskID, artifactID)
+	if err != nil {
+		return nil, err
+	}
+	if version == current.Version {
+		return current, nil
+	}
+
+	var content, data string
+	err = r.db.QueryRowContext(ctx,
+		`SELECT content, data FROM artifact_versions WHERE project_id = ? AND task_id = ? AND artifact_id = ? AND version = ?`,
+		projectID.String(), taskID.String(), artifactID, version,
+	).Scan(&content, &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, task.ErrArtifactVersionNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	a, err := decodeArtifact(content, data)
+	if err != nil {
+		return nil, err
+	}
+	a.Version = version
+	return a, nil
+}
+
+// ListArtifacts returns artifacts for a task with pagination.
+func (r *Repository) ListArtifacts(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, opts task.ListOptions) (*task.ListResult[*task.Artifact], error) {
+	if _, err := r.getTask(ctx, r.db, projectID, taskID); err != nil {
+		return nil, err
+	}
+
+	where := newFilter()
+	where.add("project_id = ?", projectID.String())
+	where.add("task_id = ?", taskID.String())
+	where.addTags("data", opts)
+	where.addSuperseded("data", opts)
+
+	return r.listArtifacts(ctx, where, opts)
+}
+
+// SearchArtifacts searches artifact content across all projects/tasks or specific project/task.
+// Matching and BM25 ranking are done by the artifacts_fts index.
+func (r *Repository) SearchArtifacts(ctx context.Context, query string, projectID *task.ProjectID, taskID *task.TaskID, opts task.ListOptions) (*task.ListResult[*task.SearchResult], error) {
+	q := search.ParseQuery(query)
+
+	where := newFilter()
+	if projectID != nil {
+		where.add("a.project_id = ?", projectID.String())
+		if taskID != nil {
+			where.add("a.task_id = ?", taskID.String())
+		}
+	}
+	where.addTags("a.data", opts)
+	where.addSuperseded("a.data", opts)
+
+	// bm25() is negative, lower is better; it is only available with MATCH
+	from := `artifacts a`
+	score := `0.0`
+	if !q.Empty() {
+		from = `artifacts_fts JOIN artifacts a ON a.seq = artifacts_fts.rowid`
+		score = `-bm25(artifacts_fts)`
+		where.add("artifacts_fts MATCH ?", matchExpression(q))
+	}
+
+	page := newPage(opts)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+from+where.sql(), where.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	args := append(append([]any{}, where.args...), page.limit, page.offset)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT a.content, a.data, `+score+` AS score FROM `+from+where.sql()+
+			` ORDER BY score DESC, a.created_at DESC, a.seq DESC LIMIT ? OFFSET ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	defer rows.Close()
+
+	results := []*task.SearchResult{}
+	for rows.Next() {
+		var content, data string
+		var score float64
+		if err := rows.Scan(&content, &data, &score); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		a, err := decodeArtifact(content, data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &task.SearchResult{Artifact: a, Score: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return pageResult(page, results, total), nil
+}
+
+// CountTags returns how many tasks and artifacts carry each tag, counted by
+// a single query over the tags arrays of both tables.
+func (r *Repository) CountTags(ctx context.Context, projectID *task.ProjectID) ([]task.TagCount, error) {
+	tasks, artifacts := newFilter(), newFilter()
+	if projectID != nil {
+		if err := r.requireProject(ctx, r.db, *projectID); err != nil {
+			return nil, err
+		}
+		tasks.add("t.project_id = ?", projectID.String())
+		artifacts.add("a.project_id = ?", projectID.String())
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT tag, SUM(tasks), SUM(artifacts) FROM (
+			SELECT tags.value AS tag, 1 AS tasks, 0 AS artifacts FROM tasks t, json_each(t.data, '$.tags') tags`+tasks.sql()+`
+			UNION ALL
+			SELECT tags.value, 0, 1 FROM artifacts a, json_each(a.data, '$.tags') tags`+artifacts.sql()+`
+		) GROUP BY tag`,
+		append(tasks.args, artifacts.args...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	defer rows.Close()
+
+	counts := []task.TagCount{}
+	for rows.Next() {
+		var c task.TagCount
+		if err := rows.Scan(&c.Tag, &c.Tasks, &c.Artifacts); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return counts, nil
+}
+
+// RebuildSearchIndex rebuilds the FTS indexes from the artifacts and memories tables.
+func (r *Repository) RebuildSearchIndex(ctx context.Context) (int, error) {
+	for _, table := range []string{"artifacts_fts", "memories_fts"} {
+		if _, err := r.db.ExecContext(ctx, `INSERT INTO `+table+` (`+table+`) VALUES ('rebuild')`); err != nil {
+			return 0, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+	}
+
+	var n int
+	if err := r.db.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM artifacts) + (SELECT COUNT(*) FROM memories)`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return n, nil
+}
+
+// DeleteArtifact removes an artifact.
+func (r *Repository) DeleteArtifact(ctx context.Context, projectID task.ProjectID, taskID task.TaskID, artifactID string) error {
+	if _, err := r.getTask(ctx, r.db, projectID, taskID); err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM artifacts WHERE project_id = ? AND task_id = ? AND id = ?`,
+		projectID.String(), taskID.String(), artifactID,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return requireAffected(res, task.ErrArtifactNotFound)
+}
+
+// Close releases the database handle.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// Helper methods
+
+// querier is the subset of *sql.DB and *sql.Tx used by helpers that run
+// both inside and outside transactions.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *Repository) migrate(ctx context.Context) error {
+	var version int
+	if err := r.db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < schemaVersion; i++ {
+		err := r.withTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+				return err
+			}
+			// PRAGMA does not accept bound parameters
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) getArtifact(ctx context.Context, q querier, projectID task.ProjectID, taskID task.TaskID, artifactID string) (*task.Artifact, error) {
+	var content, data string
+	err := q.QueryRowContext(ctx,
+		`SELECT content, data FROM artifacts WHERE project_id = ? AND task_id = ? AND id = ?`,
+		projectID.String(), taskID.String(), artifactID,
+	).Scan(&content, &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, task.ErrArtifactNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return decodeArtifact(content, data)
+}
+
+func (r *Repository) requireProject(ctx context.Context, q querier, id task.ProjectID) error {
+	var exists int
+	err := q.QueryRowContext(ctx, `SELECT 1 FROM projects WHERE id = ?`, id.String()).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return task.ErrProjectNotFound
+		}
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) getTask(ctx context.Context, q querier, projectID task.ProjectID, taskID task.TaskID) (*task.Task, error) {
+	var data string
+	err := q.QueryRowContext(ctx,
+		`SELECT data FROM tasks WHERE project_id = ? AND id = ?`,
+		projectID.String(), taskID.String(),
+	).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, task.ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return decodeTask(data)
+}
+
+func (r *Repository) saveTask(ctx context.Context, q querier, t *task.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	res, err := q.ExecContext(ctx,
+		`UPDATE tasks SET status = ?, data = ?, updated_at = ? WHERE project_id = ? AND id = ?`,
+		string(t.Status), string(data), t.UpdatedAt.UnixNano(), t.ProjectID.String(), t.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return requireAffected(res, task.ErrTaskNotFound)
+}
+
+func (r *Repository) listTasks(ctx context.Context, where *filter, opts task.ListOptions) (*task.ListResult[*task.Task], error) {
+	page := newPage(opts)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks`+where.sql(), where.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	args := append(append([]any{}, where.args...), page.limit, page.offset)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT data FROM tasks`+where.sql()+` ORDER BY `+taskOrder(opts.Sort)+` LIMIT ? OFFSET ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	tasks, err := scanAll(rows, decodeTask)
+	if err != nil {
+		return nil, err
+	}
+
+	return pageResult(page, tasks, total), nil
+}
+
+func (r *Repository) listArtifacts(ctx context.Context, where *filter, opts task.ListOptions) (*task.ListResult[*task.Artifact], error) {
+	page := newPage(opts)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM artifacts`+where.sql(), where.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	args := append(append([]any{}, where.args...), page.limit, page.offset)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT content, data FROM artifacts`+where.sql()+` ORDER BY created_at DESC LIMIT ? OFFSET ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	defer rows.Close()
+
+	artifacts := []*task.Artifact{}
+	for rows.Next() {
+		var content, data string
+		if err := rows.Scan(&content, &data); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		a, err := decodeArtifact(content, data)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return pageResult(page, artifacts, total), nil
+}
+
+// taskOrder returns the ORDER BY clause matching task.SortTasks.
+func taskOrder(order task.TaskSort) string {
+	priority := fmt.Sprintf(`COALESCE(NULLIF(json_extract(data, '$.priority'), ''), '%s')`, task.DefaultPriority)
+	due := `json_extract(data, '$.due_date')`
+
+	switch order {
+	case task.SortPriority:
+		return priority + `, updated_at DESC, id`
+	case task.SortDueDate:
+		return due + ` IS NULL, julianday(` + due + `), ` + priority + `, updated_at DESC, id`
+	case task.SortStale:
+		return `updated_at, id`
+	default:
+		return `updated_at DESC, id`
+	}
+}
+
+// filter accumulates AND-ed WHERE conditions with their bound arguments.
+type filter struct {
+	conds []string
+	args  []any
+}
+
+func newFilter() *filter {
+	return &filter{}
+}
+
+func (f *filter) add(cond string, args ...any) {
+	f.conds = append(f.conds, cond)
+	f.args = append(f.args, args...)
+}
+
+// addTags applies the tag filter of opts to the tags array of a JSON data column.
+func (f *filter) addTags(column string, opts task.ListOptions) {
+	if len(opts.Tags) == 0 {
+		return
+	}
+
+	args := make([]any, 0, len(opts.Tags)+1)
+	for _, tag := range opts.Tags {
+		args = append(args, tag)
+	}
+	in := strings.TrimSuffix(strings.Repeat("?, ", len(opts.Tags)), ", ")
+
+	if opts.TagMatch == task.TagMatchAll {
+		args = append(args, len(opts.Tags))
+		f.add(fmt.Sprintf("(SELECT COUNT(DISTINCT value) FROM json_each(%s, '$.tags') WHERE value IN (%s)) = ?", column, in), args...)
+		return
+	}
+	f.add(fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s, '$.tags') WHERE value IN (%s))", column, in), args...)
+}
+
+// addSuperseded hides artifacts that another artifact supersedes, unless opts includes them.
+func (f *filter) addSuperseded(column string, opts task.ListOptions) {
+	if !opts.IncludeSuperseded {
+		f.add(fmt.Sprintf("json_extract(%s, '$.superseded_by') IS NULL", column))
+	}
+}
+
+func (f *filter) sql() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// page normalizes ListOptions the same way the filesystem repository does.
+type page struct {
+	limit  int
+	offset int
+}
+
+func newPage(opts task.ListOptions) page {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	offset := opts.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	return page{limit: limit, offset: offset}
+}
+
+func pageResult[T any](p page, items []T, total int) *task.ListResult[T] {
+	return &task.ListResult[T]{
+		Items:   items,
+		Total:   total,
+		Limit:   p.limit,
+		Offset:  p.offset,
+		HasMore: p.offset+len(items) < total,
+	}
+}
+
+func scanAll[T any](rows *sql.Rows, decode func(string) (T, error)) ([]T, error) {
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+		}
+		item, err := decode(data)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+
+	return items, nil
+}
+
+func decodeProject(data string) (*task.Project, error) {
+	var p task.Project
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return &p, nil
+}
+
+func decodeTask(data string) (*task.Task, error) {
+	var t task.Task
+	if err := json.Unmarshal([]byte(data), &t); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return &t, nil
+}
+
+// encodeArtifact serializes everything except the content, which lives in
+// its own column so it can be searched without decoding JSON.
+func encodeArtifact(a *task.Artifact) (string, error) {
+	stored := *a
+	stored.Content = ""
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	return string(data), nil
+}
+
+func decodeArtifact(content, data string) (*task.Artifact, error) {
+	var a task.Artifact
+	if err := json.Unmarshal([]byte(data), &a); err != nil {
+		return nil, fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	a.Content = content
+	if a.Metadata == nil {
+		a.Metadata = make(map[string]string)
+	}
+	if a.Version < 1 {
+		a.Version = 1 // Saved before artifacts had versions
+	}
+	return &a, nil
+}
+
+// matchExpression renders a parsed query as an FTS5 MATCH expression.
+// Every term is quoted so FTS5 keywords (AND, OR, NOT, NEAR) stay literal.
+func matchExpression(q search.Query) string {
+	parts := make([]string, 0, len(q.Clauses))
+	for _, c := range q.Clauses {
+		part := `"` + strings.Join(c.Terms, " ") + `"`
+		if c.Prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// revisionIs matches the rows whose data holds the revision given as its
+// argument. Rows written before revisions existed hold none, which is 0.
+const revisionIs = `COALESCE(json_extract(data, '$.revision'), 0) = ?`
+
+// errNoMatch is the requireAffected error of an update guarded by
+// revisionIs, which matches no row if the row is gone or has moved on.
+var errNoMatch = errors.New("no row matched")
+
+func requireAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", task.ErrStorageFailed, err)
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+func isConstraintError(err error) bool {
+	return strings.Contains(err.Error(), "constraint failed")
+}
@@ -0,0 +1,1280 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-memory/internal/domain/task"
+)
+
+func setupTestRepo(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "agent-memory-sqlite-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	repo, err := NewRepository(filepath.Join(tmpDir, DatabaseFile))
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	cleanup := func() {
+		repo.Close()
+		os.RemoveAll(tmpDir)
+	}
+
+	return repo, tmpDir, cleanup
+}
+
+func createTestTask(t *testing.T, repo *Repository, projectID task.ProjectID, taskID task.TaskID) *task.Task {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := repo.GetProject(ctx, projectID); err == task.ErrProjectNotFound {
+		if err := repo.CreateProject(ctx, task.NewProject(projectID, projectID.String())); err != nil {
+			t.Fatalf("CreateProject() error = %v", err)
+		}
+	}
+
+	taskObj := task.NewTask(projectID, taskID, taskID.String())
+	if err := repo.CreateTask(ctx, taskObj); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	return taskObj
+}
+
+func TestNewRepository(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agent-memory-sqlite-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "nested", DatabaseFile)
+
+	repo, err := NewRepository(dbPath)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	// Verify database file was created
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		t.Error("NewRepository() should create database file")
+	}
+}
+
+func TestNewRepository_Reopen(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createTestTask(t, repo, "test-project", "fix-bug")
+	repo.Close()
+
+	// Reopening must not re-run migrations or lose data
+	reopened, err := NewRepository(filepath.Join(tmpDir, DatabaseFile))
+	if err != nil {
+		t.Fatalf("NewRepository() reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if _, err := reopened.GetTask(ctx, "test-project", "fix-bug"); err != nil {
+		t.Errorf("GetTask() after reopen error = %v", err)
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	repo, tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+	if err := repo.SaveArtifact(ctx, task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "indexed keyword")); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+	repo.Close()
+
+	dbPath := filepath.Join(tmpDir, DatabaseFile)
+	before := readDir(t, tmpDir)
+
+	ro, err := OpenReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("OpenReadOnly() error = %v", err)
+	}
+	if _, err := ro.GetTask(ctx, "test-project", "fix-bug"); err != nil {
+		t.Errorf("GetTask() error = %v", err)
+	}
+	if result, err := ro.SearchArtifacts(ctx, "keyword", nil, nil, task.ListOptions{Limit: 10}); err != nil || result.Total != 1 {
+		t.Errorf("SearchArtifacts() = %v, %v, want 1 artifact", result, err)
+	}
+	if err := ro.CreateProject(ctx, task.NewProject("other", "Other")); err == nil {
+		t.Error("CreateProject() on a read-only database error = nil")
+	}
+	ro.Close()
+
+	if after := readDir(t, tmpDir); !maps.Equal(after, before) {
+		t.Errorf("OpenReadOnly() changed the database directory: %v, was %v", slices.Sorted(maps.Keys(after)), slices.Sorted(maps.Keys(before)))
+	}
+
+	// A database behind the schema is refused, not migrated
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion-1)); err != nil {
+		t.Fatalf("failed to set user_version: %v", err)
+	}
+	db.Close()
+	before = readDir(t, tmpDir)
+
+	if _, err := OpenReadOnly(dbPath); err == nil {
+		t.Error("OpenReadOnly() of an older schema error = nil")
+	}
+	if after := readDir(t, tmpDir); !maps.Equal(after, before) {
+		t.Error("OpenReadOnly() of an older schema changed the database directory")
+	}
+
+	if _, err := OpenReadOnly(filepath.Join(tmpDir, "missing", DatabaseFile)); err == nil {
+		t.Error("OpenReadOnly() of a missing database error = nil")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "missing")); !os.IsNotExist(err) {
+		t.Error("OpenReadOnly() of a missing database created its directory")
+	}
+}
+
+// readDir returns the contents of every file in dir by name, leaving out
+// the -wal and -shm files SQLite shares between connections, which it
+// creates even for read-only ones.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", dir, err)
+	}
+	files := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), "-wal") || strings.HasSuffix(e.Name(), "-shm") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", e.Name(), err)
+		}
+		files[e.Name()] = string(data)
+	}
+	return files
+}
+
+func TestRepository_CreateProject(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+	project.Description = "A test project"
+	project.Metadata["team"] = "backend"
+
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	got, err := repo.GetProject(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+
+	if got.Name != project.Name {
+		t.Errorf("GetProject().Name = %q, want %q", got.Name, project.Name)
+	}
+	if got.Description != project.Description {
+		t.Errorf("GetProject().Description = %q, want %q", got.Description, project.Description)
+	}
+	if got.Metadata["team"] != "backend" {
+		t.Errorf("GetProject().Metadata[team] = %q, want %q", got.Metadata["team"], "backend")
+	}
+}
+
+func TestRepository_CreateProject_AlreadyExists(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() first call error = %v", err)
+	}
+
+	err := repo.CreateProject(ctx, project)
+	if err != task.ErrProjectAlreadyExists {
+		t.Errorf("CreateProject() error = %v, want ErrProjectAlreadyExists", err)
+	}
+}
+
+func TestRepository_GetProject_NotFound(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	_, err := repo.GetProject(context.Background(), task.ProjectID("nonexistent"))
+	if err != task.ErrProjectNotFound {
+		t.Errorf("GetProject() error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestRepository_ListProjects_Pagination(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Create 10 projects
+	for i := 0; i < 10; i++ {
+		id := task.ProjectID(string(rune('a' + i)))
+		project := task.NewProject(id, "Project "+string(rune('A'+i)))
+		if err := repo.CreateProject(ctx, project); err != nil {
+			t.Fatalf("CreateProject() error = %v", err)
+		}
+	}
+
+	result, err := repo.ListProjects(ctx, task.ListOptions{Limit: 3, Offset: 0})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+
+	if len(result.Items) != 3 {
+		t.Errorf("ListProjects() returned %d items, want 3", len(result.Items))
+	}
+	if result.Total != 10 {
+		t.Errorf("ListProjects().Total = %d, want 10", result.Total)
+	}
+	if !result.HasMore {
+		t.Error("ListProjects().HasMore should be true")
+	}
+
+	// Newest first
+	if result.Items[0].ID != task.ProjectID("j") {
+		t.Errorf("ListProjects() first item = %q, want %q", result.Items[0].ID, "j")
+	}
+
+	// Last page
+	result2, err := repo.ListProjects(ctx, task.ListOptions{Limit: 3, Offset: 9})
+	if err != nil {
+		t.Fatalf("ListProjects() with offset error = %v", err)
+	}
+
+	if len(result2.Items) != 1 {
+		t.Errorf("ListProjects() with offset returned %d items, want 1", len(result2.Items))
+	}
+	if result2.HasMore {
+		t.Error("ListProjects().HasMore should be false on last page")
+	}
+}
+
+func TestRepository_UpdateProject(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	project := task.NewProject(task.ProjectID("test-project"), "Test Project")
+
+	if err := repo.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	project.Name = "Updated Name"
+	if err := repo.UpdateProject(ctx, project); err != nil {
+		t.Fatalf("UpdateProject() error = %v", err)
+	}
+
+	got, err := repo.GetProject(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+	if got.Name != "Updated Name" {
+		t.Errorf("GetProject().Name = %q, want %q", got.Name, "Updated Name")
+	}
+
+	missing := task.NewProject(task.ProjectID("missing"), "Missing")
+	if err := repo.UpdateProject(ctx, missing); err != task.ErrProjectNotFound {
+		t.Errorf("UpdateProject() missing error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestRepository_DeleteProject_Cascades(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	artifact := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "orphan candidate")
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	if err := repo.DeleteProject(ctx, taskObj.ProjectID); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+
+	if _, err := repo.GetProject(ctx, taskObj.ProjectID); err != task.ErrProjectNotFound {
+		t.Errorf("GetProject() after delete error = %v, want ErrProjectNotFound", err)
+	}
+
+	// Tasks and artifacts go with the project
+	result, err := repo.SearchArtifacts(ctx, "orphan", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() error = %v", err)
+	}
+	if result.Total != 0 {
+		t.Errorf("SearchArtifacts() after project delete Total = %d, want 0", result.Total)
+	}
+
+	if err := repo.DeleteProject(ctx, taskObj.ProjectID); err != task.ErrProjectNotFound {
+		t.Errorf("DeleteProject() twice error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestRepository_CreateTask_NoProject(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	taskObj := task.NewTask(task.ProjectID("nonexistent"), task.TaskID("fix-bug"), "Fix Bug")
+
+	err := repo.CreateTask(context.Background(), taskObj)
+	if err != task.ErrProjectNotFound {
+		t.Errorf("CreateTask() error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestRepository_CreateTask_AlreadyExists(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	err := repo.CreateTask(context.Background(), taskObj)
+	if err != task.ErrTaskAlreadyExists {
+		t.Errorf("CreateTask() error = %v, want ErrTaskAlreadyExists", err)
+	}
+}
+
+func TestRepository_ListTasks_StatusFilter(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createTestTask(t, repo, "test-project", "open-task")
+	completed := createTestTask(t, repo, "test-project", "completed-task")
+
+	completed.Status = task.TaskStatusCompleted
+	if err := repo.UpdateTask(ctx, completed); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+
+	result, err := repo.ListTasks(ctx, "test-project", task.ListOptions{
+		Limit:  10,
+		Status: task.TaskStatusCompleted,
+	})
+	if err != nil {
+		t.Fatalf("ListTasks() error = %v", err)
+	}
+
+	if len(result.Items) != 1 {
+		t.Fatalf("ListTasks() with status filter returned %d items, want 1", len(result.Items))
+	}
+	if result.Items[0].ID != task.TaskID("completed-task") {
+		t.Errorf("ListTasks() returned wrong task: %q", result.Items[0].ID)
+	}
+
+	if _, err := repo.ListTasks(ctx, "nonexistent", task.ListOptions{}); err != task.ErrProjectNotFound {
+		t.Errorf("ListTasks() missing project error = %v, want ErrProjectNotFound", err)
+	}
+
+	subtask := task.NewTask("test-project", "subtask", "Subtask")
+	subtask.ParentID = "open-task"
+	if err := repo.CreateTask(ctx, subtask); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	result, err = repo.ListTasks(ctx, "test-project", task.ListOptions{Parent: "open-task"})
+	if err != nil {
+		t.Fatalf("ListTasks() with parent filter error = %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].ID != "subtask" {
+		t.Errorf("ListTasks() with parent filter returned %d items, want subtask", len(result.Items))
+	}
+}
+
+func TestRepository_ListTasks_Sort(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	soon := time.Now().UTC().AddDate(0, 0, 1)
+	later := soon.AddDate(0, 0, 4)
+
+	// Updated in this order, so "default" is the most recent
+	for _, tt := range []struct {
+		id       task.TaskID
+		priority task.Priority
+		due      *time.Time
+	}{
+		{id: "someday", priority: task.PriorityP3},
+		{id: "urgent", priority: task.PriorityP0, due: &later},
+		{id: "default", due: &soon},
+	} {
+		taskObj := createTestTask(t, repo, "test-project", tt.id)
+		taskObj.Priority = tt.priority
+		taskObj.DueDate = tt.due
+		time.Sleep(2 * time.Millisecond)
+		if err := repo.UpdateTask(ctx, taskObj); err != nil {
+			t.Fatalf("UpdateTask(%s) error = %v", tt.id, err)
+		}
+	}
+
+	tests := []struct {
+		order task.TaskSort
+		want  []task.TaskID
+	}{
+		{order: "", want: []task.TaskID{"default", "urgent", "someday"}},
+		{order: task.SortPriority, want: []task.TaskID{"urgent", "default", "someday"}},
+		{order: task.SortDueDate, want: []task.TaskID{"default", "urgent", "someday"}},
+		{order: task.SortStale, want: []task.TaskID{"someday", "urgent", "default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			result, err := repo.ListTasks(ctx, "test-project", task.ListOptions{Sort: tt.order})
+			if err != nil {
+				t.Fatalf("ListTasks() error = %v", err)
+			}
+			for i, id := range tt.want {
+				if result.Items[i].ID != id {
+					t.Fatalf("ListTasks(sort=%s)[%d] = %s, want order %v", tt.order, i, result.Items[i].ID, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRepository_ListAllTasks(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createTestTask(t, repo, "project1", "task1")
+	time.Sleep(10 * time.Millisecond)
+	createTestTask(t, repo, "project2", "task2")
+
+	result, err := repo.ListAllTasks(ctx, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListAllTasks() error = %v", err)
+	}
+
+	if result.Total != 2 {
+		t.Fatalf("ListAllTasks().Total = %d, want 2", result.Total)
+	}
+	if result.Items[0].ID != task.TaskID("task2") {
+		t.Errorf("ListAllTasks() first item = %q, want most recently updated %q", result.Items[0].ID, "task2")
+	}
+}
+
+func TestRepository_UpdateTask(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	taskObj.Status = task.TaskStatusInProgress
+	taskObj.Description = "Working on it"
+	if err := repo.UpdateTask(ctx, taskObj); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+
+	got, err := repo.GetTask(ctx, taskObj.ProjectID, taskObj.ID)
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	if got.Status != task.TaskStatusInProgress {
+		t.Errorf("GetTask().Status = %q, want %q", got.Status, task.TaskStatusInProgress)
+	}
+	if got.Description != "Working on it" {
+		t.Errorf("GetTask().Description = %q, want %q", got.Description, "Working on it")
+	}
+
+	missing := task.NewTask(taskObj.ProjectID, task.TaskID("missing"), "Missing")
+	if err := repo.UpdateTask(ctx, missing); err != task.ErrTaskNotFound {
+		t.Errorf("UpdateTask() missing error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestRepository_Revisions(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	// Each update bumps the revision
+	for want := 1; want <= 2; want++ {
+		taskObj.Description = fmt.Sprintf("update %d", want)
+		if err := repo.UpdateTask(ctx, taskObj); err != nil {
+			t.Fatalf("UpdateTask() error = %v", err)
+		}
+		if taskObj.Revision != want {
+			t.Errorf("UpdateTask() Revision = %d, want %d", taskObj.Revision, want)
+		}
+	}
+
+	// An update based on an older revision is rejected with the stored task
+	stale, err := repo.GetTask(ctx, taskObj.ProjectID, taskObj.ID)
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	stale.Revision = 1
+	stale.Description = "stale"
+	err = repo.UpdateTask(ctx, stale)
+	var conflict *task.ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("UpdateTask() stale error = %v, want ConflictError", err)
+	}
+	if conflict.Revision != 1 || conflict.Task == nil || conflict.Task.Revision != 2 || conflict.Task.Description != "update 2" {
+		t.Errorf("ConflictError = %+v, want revision 1 and the stored task at revision 2", conflict)
+	}
+	if stale.Revision != 1 {
+		t.Errorf("UpdateTask() stale Revision = %d, want it unchanged", stale.Revision)
+	}
+
+	// Projects too
+	project, err := repo.GetProject(ctx, taskObj.ProjectID)
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+	if project.Revision != 0 {
+		t.Errorf("GetProject() Revision = %d, want 0", project.Revision)
+	}
+	project.Name = "Renamed"
+	if err := repo.UpdateProject(ctx, project); err != nil {
+		t.Fatalf("UpdateProject() error = %v", err)
+	}
+	project.Revision = 0
+	err = repo.UpdateProject(ctx, project)
+	if !errors.As(err, &conflict) || conflict.Project == nil || conflict.Project.Revision != 1 {
+		t.Errorf("UpdateProject() stale error = %v, want ConflictError at revision 1", err)
+	}
+}
+
+func TestRepository_UpdateTask_WithoutRevision(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	// Rows written before revisions existed count as revision 0
+	if _, err := repo.db.Exec(`UPDATE tasks SET data = json_remove(data, '$.revision')`); err != nil {
+		t.Fatalf("removing revision: %v", err)
+	}
+	taskObj.Description = "updated"
+	if err := repo.UpdateTask(ctx, taskObj); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if taskObj.Revision != 1 {
+		t.Errorf("UpdateTask() Revision = %d, want 1", taskObj.Revision)
+	}
+}
+
+func TestRepository_DeleteTask(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	if err := repo.DeleteTask(ctx, taskObj.ProjectID, taskObj.ID); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+
+	_, err := repo.GetTask(ctx, taskObj.ProjectID, taskObj.ID)
+	if err != task.ErrTaskNotFound {
+		t.Errorf("GetTask() after delete error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestRepository_TaskHistory(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	changes := []task.StatusChange{
+		{To: task.TaskStatusOpen, At: start},
+		{From: task.TaskStatusOpen, To: task.TaskStatusInProgress, At: start.Add(time.Hour), Reason: "started", Actor: "agent"},
+	}
+	for _, c := range changes {
+		if err := repo.AppendTaskHistory(ctx, taskObj.ProjectID, taskObj.ID, c); err != nil {
+			t.Fatalf("AppendTaskHistory() error = %v", err)
+		}
+	}
+
+	got, err := repo.GetTaskHistory(ctx, taskObj.ProjectID, taskObj.ID)
+	if err != nil {
+		t.Fatalf("GetTaskHistory() error = %v", err)
+	}
+	if len(got) != 2 || got[1].To != task.TaskStatusInProgress || got[1].Reason != "started" || !got[1].At.Equal(changes[1].At) {
+		t.Errorf("GetTaskHistory() = %+v, want the appended changes in order", got)
+	}
+
+	if err := repo.AppendTaskHistory(ctx, taskObj.ProjectID, "missing", changes[0]); err != task.ErrTaskNotFound {
+		t.Errorf("AppendTaskHistory(missing) error = %v, want ErrTaskNotFound", err)
+	}
+
+	// History is removed with the task
+	if err := repo.DeleteTask(ctx, taskObj.ProjectID, taskObj.ID); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+	var count int
+	repo.db.QueryRow(`SELECT COUNT(*) FROM task_history`).Scan(&count)
+	if count != 0 {
+		t.Errorf("task_history rows after DeleteTask = %d, want 0", count)
+	}
+}
+
+func TestRepository_Tags(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for id, tags := range map[task.TaskID][]string{
+		"login":  {"backend", "auth"},
+		"schema": {"backend"},
+		"docs":   nil,
+	} {
+		taskObj := createTestTask(t, repo, "test-project", id)
+		taskObj.Tags = tags
+		if err := repo.UpdateTask(ctx, taskObj); err != nil {
+			t.Fatalf("UpdateTask(%s) error = %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts task.ListOptions
+		want int
+	}{
+		{name: "any", opts: task.ListOptions{Tags: []string{"auth", "ui"}}, want: 1},
+		{name: "all", opts: task.ListOptions{Tags: []string{"backend", "auth"}, TagMatch: task.TagMatchAll}, want: 1},
+		{name: "shared", opts: task.ListOptions{Tags: []string{"backend"}}, want: 2},
+		{name: "none", opts: task.ListOptions{Tags: []string{"ui"}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.ListTasks(ctx, "test-project", tt.opts)
+			if err != nil || result.Total != tt.want {
+				t.Errorf("ListTasks() total = %v, %v, want %d", result, err, tt.want)
+			}
+			all, err := repo.ListAllTasks(ctx, tt.opts)
+			if err != nil || all.Total != tt.want {
+				t.Errorf("ListAllTasks() total = %v, %v, want %d", all, err, tt.want)
+			}
+		})
+	}
+
+	tagged := task.NewArtifact("test-project", "login", task.ArtifactTypeDecision, "Use JWT tokens")
+	tagged.Tags = []string{"auth"}
+	plain := task.NewArtifact("test-project", "login", task.ArtifactTypeNote, "JWT library notes")
+	plain.ID = tagged.ID + "1"
+	for _, a := range []*task.Artifact{tagged, plain} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+	}
+
+	opts := task.ListOptions{Tags: []string{"auth"}}
+	artifacts, err := repo.ListArtifacts(ctx, "test-project", "login", opts)
+	if err != nil || artifacts.Total != 1 || artifacts.Items[0].ID != tagged.ID {
+		t.Errorf("ListArtifacts(auth) = %v, %v, want the decision", artifacts, err)
+	}
+
+	results, err := repo.SearchArtifacts(ctx, "jwt", nil, nil, opts)
+	if err != nil || results.Total != 1 || results.Items[0].Artifact.ID != tagged.ID {
+		t.Errorf("SearchArtifacts(jwt, auth) = %v, %v, want the decision", results, err)
+	}
+
+	project := task.ProjectID("test-project")
+	for _, scope := range []*task.ProjectID{nil, &project} {
+		counts, err := repo.CountTags(ctx, scope)
+		if err != nil {
+			t.Fatalf("CountTags() error = %v", err)
+		}
+		got := make(map[string]task.TagCount)
+		for _, c := range counts {
+			got[c.Tag] = c
+		}
+		if len(got) != 2 || got["backend"] != (task.TagCount{Tag: "backend", Tasks: 2}) || got["auth"] != (task.TagCount{Tag: "auth", Tasks: 1, Artifacts: 1}) {
+			t.Errorf("CountTags() = %+v, want backend on 2 tasks and auth on a task and an artifact", counts)
+		}
+	}
+	missing := task.ProjectID("missing")
+	if _, err := repo.CountTags(ctx, &missing); err != task.ErrProjectNotFound {
+		t.Errorf("CountTags(missing) error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestRepository_SaveArtifact(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+	before := taskObj.UpdatedAt
+
+	time.Sleep(10 * time.Millisecond)
+
+	artifact := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "This is a note")
+	artifact.Metadata["file_path"] = "main.go"
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	got, err := repo.GetArtifact(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID)
+	if err != nil {
+		t.Fatalf("GetArtifact() error = %v", err)
+	}
+	if got.Content != "This is a note" {
+		t.Errorf("GetArtifact().Content = %q, want %q", got.Content, "This is a note")
+	}
+	if got.Metadata["file_path"] != "main.go" {
+		t.Errorf("GetArtifact().Metadata[file_path] = %q, want %q", got.Metadata["file_path"], "main.go")
+	}
+	if !got.CreatedAt.Equal(artifact.CreatedAt) {
+		t.Errorf("GetArtifact().CreatedAt = %v, want %v", got.CreatedAt, artifact.CreatedAt)
+	}
+
+	// Saving an artifact touches the task
+	updated, err := repo.GetTask(ctx, taskObj.ProjectID, taskObj.ID)
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	if !updated.UpdatedAt.After(before) {
+		t.Error("SaveArtifact() should bump task UpdatedAt")
+	}
+}
+
+func TestRepository_SaveArtifact_NoTask(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	artifact := task.NewArtifact("test-project", "missing", task.ArtifactTypeNote, "content")
+	if err := repo.SaveArtifact(context.Background(), artifact); err != task.ErrTaskNotFound {
+		t.Errorf("SaveArtifact() error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestRepository_GetArtifact_NotFound(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	_, err := repo.GetArtifact(context.Background(), taskObj.ProjectID, taskObj.ID, "nonexistent")
+	if err != task.ErrArtifactNotFound {
+		t.Errorf("GetArtifact() error = %v, want ErrArtifactNotFound", err)
+	}
+}
+
+func TestRepository_ListArtifacts_Pagination(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	var last *task.Artifact
+	for i := 0; i < 10; i++ {
+		last = task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "Note content")
+		if err := repo.SaveArtifact(ctx, last); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+		time.Sleep(time.Millisecond) // Ensure different timestamps
+	}
+
+	result, err := repo.ListArtifacts(ctx, taskObj.ProjectID, taskObj.ID, task.ListOptions{Limit: 3})
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+
+	if len(result.Items) != 3 {
+		t.Errorf("ListArtifacts() returned %d items, want 3", len(result.Items))
+	}
+	if result.Total != 10 {
+		t.Errorf("ListArtifacts().Total = %d, want 10", result.Total)
+	}
+	if !result.HasMore {
+		t.Error("ListArtifacts().HasMore should be true")
+	}
+	if result.Items[0].ID != last.ID {
+		t.Errorf("ListArtifacts() first item = %q, want newest %q", result.Items[0].ID, last.ID)
+	}
+}
+
+func TestRepository_SearchArtifacts(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	task1 := createTestTask(t, repo, "project1", "task1")
+	task2 := createTestTask(t, repo, "project2", "task2")
+
+	for _, a := range []*task.Artifact{
+		task.NewArtifact(task1.ProjectID, task1.ID, task.ArtifactTypeNote, "Important KEYWORD here"),
+		task.NewArtifact(task2.ProjectID, task2.ID, task.ArtifactTypeNote, "Another important keyword"),
+		task.NewArtifact(task2.ProjectID, task2.ID, task.ArtifactTypeNote, "Unrelated content"),
+	} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+	}
+
+	// Case-insensitive across all projects
+	result, err := repo.SearchArtifacts(ctx, "keyword", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() error = %v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("SearchArtifacts() all projects returned %d items, want 2", len(result.Items))
+	}
+
+	// Restricted to one project
+	pid := task1.ProjectID
+	result2, err := repo.SearchArtifacts(ctx, "keyword", &pid, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() with project filter error = %v", err)
+	}
+	if len(result2.Items) != 1 {
+		t.Errorf("SearchArtifacts() with project filter returned %d items, want 1", len(result2.Items))
+	}
+
+	// Restricted to one task
+	pid2, tid2 := task2.ProjectID, task2.ID
+	result3, err := repo.SearchArtifacts(ctx, "content", &pid2, &tid2, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() with task filter error = %v", err)
+	}
+	if len(result3.Items) != 1 {
+		t.Errorf("SearchArtifacts() with task filter returned %d items, want 1", len(result3.Items))
+	}
+}
+
+func TestRepository_SearchArtifacts_Ranking(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "project1", "task1")
+
+	passing := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "Token refresh mentioned once among many other words about the release")
+	focused := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "Token refresh fails: refresh token expired before refresh")
+	other := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "Unrelated text")
+	for _, a := range []*task.Artifact{passing, focused, other} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+	}
+
+	result, err := repo.SearchArtifacts(ctx, "refresh", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() error = %v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("SearchArtifacts() returned %d items, want 2", len(result.Items))
+	}
+	if result.Items[0].Artifact.ID != focused.ID {
+		t.Errorf("SearchArtifacts() first result = %q, want most relevant %q", result.Items[0].Artifact.ID, focused.ID)
+	}
+	if result.Items[0].Score <= result.Items[1].Score {
+		t.Errorf("SearchArtifacts() scores = %v, %v, want descending", result.Items[0].Score, result.Items[1].Score)
+	}
+
+	tests := []struct {
+		query string
+		total int
+	}{
+		{query: `"refresh token"`, total: 1},
+		{query: "expir*", total: 1},
+		{query: "token unrelated", total: 0},
+		{query: "AND", total: 0}, // FTS5 keywords are plain words
+		{query: "", total: 3},
+	}
+	for _, tt := range tests {
+		result, err := repo.SearchArtifacts(ctx, tt.query, nil, nil, task.ListOptions{Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchArtifacts(%q) error = %v", tt.query, err)
+		}
+		if result.Total != tt.total {
+			t.Errorf("SearchArtifacts(%q) Total = %d, want %d", tt.query, result.Total, tt.total)
+		}
+	}
+}
+
+func TestRepository_SearchIndex_StaysInSync(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "project1", "task1")
+
+	a := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "first version")
+	if err := repo.SaveArtifact(ctx, a); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	// Saving the same artifact again replaces its indexed content
+	a.Content = "second version"
+	if err := repo.SaveArtifact(ctx, a); err != nil {
+		t.Fatalf("SaveArtifact() overwrite error = %v", err)
+	}
+
+	count := func(query string) int {
+		t.Helper()
+		result, err := repo.SearchArtifacts(ctx, query, nil, nil, task.ListOptions{Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchArtifacts(%q) error = %v", query, err)
+		}
+		return result.Total
+	}
+
+	if got := count("first"); got != 0 {
+		t.Errorf("SearchArtifacts(first) after overwrite Total = %d, want 0", got)
+	}
+	if got := count("second"); got != 1 {
+		t.Errorf("SearchArtifacts(second) after overwrite Total = %d, want 1", got)
+	}
+
+	// Cascading deletes go through the FTS triggers too
+	if err := repo.DeleteTask(ctx, taskObj.ProjectID, taskObj.ID); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+	if got := count("second"); got != 0 {
+		t.Errorf("SearchArtifacts() after DeleteTask Total = %d, want 0", got)
+	}
+
+	n, err := repo.RebuildSearchIndex(ctx)
+	if err != nil {
+		t.Fatalf("RebuildSearchIndex() error = %v", err)
+	}
+	if n != 0 {
+		t.Errorf("RebuildSearchIndex() = %d, want 0", n)
+	}
+}
+
+func TestRepository_MigratesVersion1(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agent-memory-sqlite-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, DatabaseFile)
+	ctx := context.Background()
+
+	// Database as created by schema version 1
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	stmts := []string{
+		migrations[0],
+		`PRAGMA user_version = 1`,
+		`INSERT INTO projects (id, data, created_at, updated_at) VALUES ('p', '{"id":"p","name":"p"}', 1, 1)`,
+		`INSERT INTO tasks (project_id, id, status, data, created_at, updated_at) VALUES ('p', 't', 'open', '{"id":"t","project_id":"p","status":"open"}', 1, 1)`,
+		`INSERT INTO artifacts (project_id, task_id, id, type, content, data, created_at) VALUES ('p', 't', '1', 'note', 'legacy searchable note', '{"id":"1","project_id":"p","task_id":"t","type":"note"}', 1)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("setting up version 1 database: %v", err)
+		}
+	}
+	db.Close()
+
+	repo, err := NewRepository(dbPath)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	result, err := repo.SearchArtifacts(ctx, "searchable", nil, nil, task.ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchArtifacts() error = %v", err)
+	}
+	if result.Total != 1 || result.Items[0].Artifact.Content != "legacy searchable note" {
+		t.Errorf("SearchArtifacts() after migration Total = %d, want 1", result.Total)
+	}
+}
+
+func TestRepository_ArtifactVersions(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	artifact := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeDecision, "Use sessions")
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	for _, content := range []string{"Use JWT", "Use JWT with RS256"} {
+		updated, _ := repo.GetArtifact(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID)
+		updated.Content = content
+		if err := repo.UpdateArtifact(ctx, updated); err != nil {
+			t.Fatalf("UpdateArtifact(%q) error = %v", content, err)
+		}
+	}
+
+	latest, err := repo.GetArtifact(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID)
+	if err != nil || latest.Version != 3 || latest.Content != "Use JWT with RS256" || latest.UpdatedAt == nil {
+		t.Errorf("GetArtifact() = %+v, %v, want version 3", latest, err)
+	}
+
+	versions, err := repo.ListArtifactVersions(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID)
+	if err != nil {
+		t.Fatalf("ListArtifactVersions() error = %v", err)
+	}
+	if len(versions) != 3 || versions[0].Content != "Use sessions" || versions[1].Version != 2 || versions[2].Version != 3 {
+		t.Errorf("ListArtifactVersions() = %+v, want v1..v3", versions)
+	}
+
+	v2, err := repo.GetArtifactVersion(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID, 2)
+	if err != nil || v2.Content != "Use JWT" {
+		t.Errorf("GetArtifactVersion(2) = %+v, %v", v2, err)
+	}
+	if _, err := repo.GetArtifactVersion(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID, 4); err != task.ErrArtifactVersionNotFound {
+		t.Errorf("GetArtifactVersion(4) error = %v, want ErrArtifactVersionNotFound", err)
+	}
+
+	// Old versions are not searchable
+	hits, _ := repo.SearchArtifacts(ctx, "sessions", nil, nil, task.ListOptions{})
+	if hits.Total != 0 {
+		t.Errorf("SearchArtifacts(sessions) total = %d, want 0", hits.Total)
+	}
+
+	if err := repo.UpdateArtifact(ctx, task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "x")); err != task.ErrArtifactNotFound {
+		t.Errorf("UpdateArtifact(missing) error = %v, want ErrArtifactNotFound", err)
+	}
+
+	// Deleting the artifact removes its versions
+	if err := repo.DeleteArtifact(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID); err != nil {
+		t.Fatalf("DeleteArtifact() error = %v", err)
+	}
+	var n int
+	repo.db.QueryRow(`SELECT COUNT(*) FROM artifact_versions`).Scan(&n)
+	if n != 0 {
+		t.Errorf("artifact_versions has %d rows after delete, want 0", n)
+	}
+}
+
+func TestRepository_ArtifactLinks(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	old := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeDecision, "Retry three times")
+	decision := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeDecision, "Retry with backoff")
+	decision.ID = old.ID + "-2"
+	for _, a := range []*task.Artifact{old, decision} {
+		if err := repo.SaveArtifact(ctx, a); err != nil {
+			t.Fatalf("SaveArtifact() error = %v", err)
+		}
+	}
+
+	decision.Links = []task.ArtifactLink{{Relation: task.RelationSupersedes, Target: old.Ref()}}
+	old.SupersededBy = []task.ArtifactRef{decision.Ref()}
+	stale := *decision
+	for _, a := range []*task.Artifact{decision, old} {
+		_, err := repo.UpdateArtifactLinks(ctx, a.ProjectID, a.TaskID, a.ID, func(stored *task.Artifact) bool {
+			stored.Links, stored.SupersededBy = a.Links, a.SupersededBy
+			return true
+		})
+		if err != nil {
+			t.Fatalf("UpdateArtifactLinks() error = %v", err)
+		}
+	}
+
+	// An update from a copy read before the links were made keeps them
+	stale.Links = nil
+	stale.Content = "Retry with backoff"
+	if err := repo.UpdateArtifact(ctx, &stale); err != nil {
+		t.Fatalf("UpdateArtifact() error = %v", err)
+	}
+	if len(stale.Links) != len(decision.Links) {
+		t.Errorf("UpdateArtifact() links = %v, want %v", stale.Links, decision.Links)
+	}
+
+	got, err := repo.GetArtifact(ctx, taskObj.ProjectID, taskObj.ID, decision.ID)
+	if err != nil || len(got.Links) != 1 || got.Links[0] != decision.Links[0] || got.Content != "Retry with backoff" {
+		t.Errorf("GetArtifact() = %+v, %v, want the link and unchanged content", got, err)
+	}
+
+	list, _ := repo.ListArtifacts(ctx, taskObj.ProjectID, taskObj.ID, task.ListOptions{})
+	if list.Total != 1 || list.Items[0].ID != decision.ID {
+		t.Errorf("ListArtifacts() total = %d, want only the superseding decision", list.Total)
+	}
+	list, _ = repo.ListArtifacts(ctx, taskObj.ProjectID, taskObj.ID, task.ListOptions{IncludeSuperseded: true})
+	if list.Total != 2 {
+		t.Errorf("ListArtifacts(include superseded) total = %d, want 2", list.Total)
+	}
+
+	// The FTS index still matches the content after the data-only update
+	hits, _ := repo.SearchArtifacts(ctx, "retry", nil, nil, task.ListOptions{})
+	if hits.Total != 1 {
+		t.Errorf("SearchArtifacts(retry) total = %d, want 1", hits.Total)
+	}
+	hits, _ = repo.SearchArtifacts(ctx, "retry", nil, nil, task.ListOptions{IncludeSuperseded: true})
+	if hits.Total != 2 {
+		t.Errorf("SearchArtifacts(retry, include superseded) total = %d, want 2", hits.Total)
+	}
+
+	if _, err := repo.UpdateArtifactLinks(ctx, taskObj.ProjectID, taskObj.ID, "missing", func(*task.Artifact) bool { return true }); err != task.ErrArtifactNotFound {
+		t.Errorf("UpdateArtifactLinks(missing) error = %v, want ErrArtifactNotFound", err)
+	}
+}
+
+func TestRepository_Memories(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	conventions := task.NewMemory(taskObj.ProjectID, "conventions", "Conventions", "Tabs, not spaces")
+	conventions.Pinned = true
+	conventions.Tags = []string{"style"}
+	notes := task.NewMemory(taskObj.ProjectID, "notes", "Notes", "Deploys on Fridays")
+	global := task.NewMemory(task.GlobalScope, "preferences", "Preferences", "Short answers, tabs everywhere")
+	for _, m := range []*task.Memory{conventions, notes, global} {
+		if err := repo.CreateMemory(ctx, m); err != nil {
+			t.Fatalf("CreateMemory(%s) error = %v", m.ID, err)
+		}
+	}
+	if err := repo.CreateMemory(ctx, conventions); err != task.ErrMemoryAlreadyExists {
+		t.Errorf("CreateMemory(duplicate) error = %v, want ErrMemoryAlreadyExists", err)
+	}
+	if err := repo.CreateMemory(ctx, task.NewMemory("missing", "x", "X", "")); err != task.ErrProjectNotFound {
+		t.Errorf("CreateMemory(missing project) error = %v, want ErrProjectNotFound", err)
+	}
+
+	got, err := repo.GetMemory(ctx, taskObj.ProjectID, "conventions")
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if got.Title != "Conventions" || got.Content != "Tabs, not spaces" || !got.Pinned || len(got.Tags) != 1 {
+		t.Errorf("GetMemory() = %+v, want the saved memory", got)
+	}
+
+	// Pinned first, then most recently updated
+	list, _ := repo.ListMemories(ctx, taskObj.ProjectID, task.ListOptions{})
+	if list.Total != 2 || list.Items[0].ID != "conventions" {
+		t.Errorf("ListMemories() total = %d, want 2 with the pinned one first", list.Total)
+	}
+	list, _ = repo.ListMemories(ctx, task.GlobalScope, task.ListOptions{})
+	if list.Total != 1 || list.Items[0].ID != "preferences" {
+		t.Errorf("ListMemories(global) total = %d, want the preferences", list.Total)
+	}
+
+	hits, _ := repo.SearchMemories(ctx, "tabs", nil, task.ListOptions{})
+	if hits.Total != 2 {
+		t.Errorf("SearchMemories(tabs) total = %d, want 2", hits.Total)
+	}
+	hits, _ = repo.SearchMemories(ctx, "tabs", &taskObj.ProjectID, task.ListOptions{Tags: []string{"style"}})
+	if hits.Total != 1 || hits.Items[0].Memory.ID != "conventions" || hits.Items[0].Score <= 0 {
+		t.Errorf("SearchMemories(tabs, project, tag style) = %+v, want the conventions", hits.Items)
+	}
+	if hits, _ := repo.SearchMemories(ctx, "preferences", nil, task.ListOptions{}); hits.Total != 1 {
+		t.Errorf("SearchMemories(preferences) total = %d, want the title matched", hits.Total)
+	}
+
+	got.Content = "Spaces, not tabs"
+	if err := repo.UpdateMemory(ctx, got); err != nil {
+		t.Fatalf("UpdateMemory() error = %v", err)
+	}
+	if hits, _ := repo.SearchMemories(ctx, "spaces", nil, task.ListOptions{}); hits.Total != 1 {
+		t.Errorf("SearchMemories(spaces) after update total = %d, want 1", hits.Total)
+	}
+	if err := repo.UpdateMemory(ctx, task.NewMemory(task.GlobalScope, "missing", "", "")); err != task.ErrMemoryNotFound {
+		t.Errorf("UpdateMemory(missing) error = %v, want ErrMemoryNotFound", err)
+	}
+
+	if n, err := repo.RebuildSearchIndex(ctx); err != nil || n != 3 {
+		t.Errorf("RebuildSearchIndex() = %d, %v, want 3 memories", n, err)
+	}
+
+	if err := repo.DeleteMemory(ctx, task.GlobalScope, "preferences"); err != nil {
+		t.Fatalf("DeleteMemory() error = %v", err)
+	}
+	if err := repo.DeleteMemory(ctx, task.GlobalScope, "preferences"); err != task.ErrMemoryNotFound {
+		t.Errorf("DeleteMemory(twice) error = %v, want ErrMemoryNotFound", err)
+	}
+
+	if err := repo.DeleteProject(ctx, taskObj.ProjectID); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if hits, _ := repo.SearchMemories(ctx, "", nil, task.ListOptions{}); hits.Total != 0 {
+		t.Errorf("SearchMemories() after deleting the project total = %d, want 0", hits.Total)
+	}
+}
+
+func TestRepository_DeleteArtifact(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	taskObj := createTestTask(t, repo, "test-project", "fix-bug")
+
+	artifact := task.NewArtifact(taskObj.ProjectID, taskObj.ID, task.ArtifactTypeNote, "This is a note")
+	if err := repo.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatalf("SaveArtifact() error = %v", err)
+	}
+
+	if err := repo.DeleteArtifact(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID); err != nil {
+		t.Fatalf("DeleteArtifact() error = %v", err)
+	}
+
+	_, err := repo.GetArtifact(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID)
+	if err != task.ErrArtifactNotFound {
+		t.Errorf("GetArtifact() after delete error = %v, want ErrArtifactNotFound", err)
+	}
+
+	err = repo.DeleteArtifact(ctx, taskObj.ProjectID, taskObj.ID, artifact.ID)
+	if err != task.ErrArtifactNotFound {
+		t.Errorf("DeleteArtifact() twice error = %v, want ErrArtifactNotFound", err)
+	}
+}
+
+func TestRepository_Close(t *testing.T) {
+	repo, _, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	if err := repo.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
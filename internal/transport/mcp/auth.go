@@ -0,0 +1,415 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"agent-memory/internal/application/service"
+	"agent-memory/internal/domain/task"
+)
+
+// Scope is a permission a token grants.
+type Scope string
+
+const (
+	ScopeRead      Scope = "read"      // Get, list, search and resume
+	ScopeWrite     Scope = "write"     // Create, update, link and compact
+	ScopeDelete    Scope = "delete"    // Delete projects, tasks, artifacts and memories
+	ScopeWorkspace Scope = "workspace" // Read and search the files of project workspaces
+)
+
+// scopes lists the valid scopes.
+var scopes = []Scope{ScopeRead, ScopeWrite, ScopeDelete, ScopeWorkspace}
+
+// toolScopes is the scope each tool needs. A tool missing here is denied to
+// every token.
+var toolScopes = map[string]Scope{
+	"create_project": ScopeWrite,
+	"get_project":    ScopeRead,
+	"list_projects":  ScopeRead,
+	"update_project": ScopeWrite,
+	"delete_project": ScopeDelete,
+
+	"create_task":       ScopeWrite,
+	"get_task":          ScopeRead,
+	"list_tasks":        ScopeRead,
+	"list_all_tasks":    ScopeRead,
+	"update_task":       ScopeWrite,
+	"delete_task":       ScopeDelete,
+	"get_task_tree":     ScopeRead,
+	"get_task_history":  ScopeRead,
+	"add_dependency":    ScopeWrite,
+	"remove_dependency": ScopeWrite,
+	"list_ready_tasks":  ScopeRead,
+	"suggest_next_task": ScopeRead,
+	"claim_task":        ScopeWrite,
+	"renew_claim":       ScopeWrite,
+	"release_task":      ScopeWrite,
+	"resume_task":       ScopeRead,
+	"list_tags":         ScopeRead,
+
+	"save_artifact":          ScopeWrite,
+	"get_artifact":           ScopeRead,
+	"update_artifact":        ScopeWrite,
+	"list_artifact_versions": ScopeRead,
+	"get_artifact_version":   ScopeRead,
+	"diff_artifact_versions": ScopeRead,
+	"link_artifact":          ScopeWrite,
+	"unlink_artifact":        ScopeWrite,
+	"list_artifacts":         ScopeRead,
+	"search_artifacts":       ScopeRead,
+	"rebuild_search_index":   ScopeWrite,
+	"compact_artifacts":      ScopeWrite,
+	"delete_artifact":        ScopeDelete,
+
+	"create_memory": ScopeWrite,
+	"get_memory":    ScopeRead,
+	"list_memories": ScopeRead,
+	"update_memory": ScopeWrite,
+	"delete_memory": ScopeDelete,
+
+	"read_file":    ScopeWorkspace,
+	"list_files":   ScopeWorkspace,
+	"search_files": ScopeWorkspace,
+}
+
+// mutates reports whether a tool changes the store.
+func mutates(tool string) bool {
+	scope, ok := toolScopes[tool]
+	return !ok || scope == ScopeWrite || scope == ScopeDelete
+}
+
+// Token is a static bearer token of the sse and http transports.
+type Token struct {
+	Name     string   // Identifies the token in logs
+	Secret   string   // What clients send as "Authorization: Bearer <secret>"
+	Scopes   []Scope  // What the token may do
+	Projects []string // Projects the token may touch (empty = all)
+}
+
+// allows reports whether the token grants scope.
+func (t *Token) allows(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// restricted reports whether the token is limited to some projects.
+func (t *Token) restricted() bool {
+	return len(t.Projects) > 0
+}
+
+// allowsProject reports whether the token may touch a project.
+func (t *Token) allowsProject(projectID string) bool {
+	return !t.restricted() || slices.Contains(t.Projects, projectID)
+}
+
+// Codes of AuthError, also used as JSON-RPC error codes outside tool calls.
+const (
+	codeUnauthorized = -32001 // No valid token
+	codeForbidden    = -32003 // The token does not allow the call
+)
+
+// AuthError is the structured error returned when a caller may not do
+// something: the JSON-RPC error data of a rejected request, and the
+// structured content of a denied tool call.
+type AuthError struct {
+	Code          int    `json:"code"`
+	Reason        string `json:"reason"` // unauthorized or forbidden
+	Message       string `json:"message"`
+	Token         string `json:"token,omitempty"`
+	RequiredScope Scope  `json:"required_scope,omitempty"`
+	ProjectID     string `json:"project_id,omitempty"`
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+func unauthorized(message string) *AuthError {
+	return &AuthError{Code: codeUnauthorized, Reason: "unauthorized", Message: message}
+}
+
+func forbidden(caller *Token, message string) *AuthError {
+	return &AuthError{Code: codeForbidden, Reason: "forbidden", Message: message, Token: caller.Name}
+}
+
+// SetTokens turns on bearer-token authentication for the sse and http
+// transports. The stdio transport is trusted: its client started the server.
+func (s *Server) SetTokens(tokens []Token) error {
+	secrets := make(map[string]bool, len(tokens))
+	normalized := make([]Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Name == "" {
+			return errors.New("auth token without a name")
+		}
+		if t.Secret == "" {
+			return fmt.Errorf("auth token %q has no secret", t.Name)
+		}
+		if secrets[t.Secret] {
+			return fmt.Errorf("auth token %q reuses the secret of another token", t.Name)
+		}
+		secrets[t.Secret] = true
+		for _, scope := range t.Scopes {
+			if !slices.Contains(scopes, scope) {
+				return fmt.Errorf("auth token %q: unknown scope %q (want read, write, delete or workspace)", t.Name, scope)
+			}
+		}
+
+		// Compared with project IDs as the service normalizes them
+		projects := make([]string, len(t.Projects))
+		for i, projectID := range t.Projects {
+			projects[i] = task.NewProjectID(projectID).String()
+		}
+		t.Projects = projects
+		normalized = append(normalized, t)
+	}
+	s.tokens = normalized
+	return nil
+}
+
+type callerKey struct{}
+
+// withCaller returns ctx carrying the token of the caller.
+func withCaller(ctx context.Context, caller *Token) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFromContext returns the token of the caller; nil means no
+// authentication, which allows everything.
+func callerFromContext(ctx context.Context) *Token {
+	caller, _ := ctx.Value(callerKey{}).(*Token)
+	return caller
+}
+
+// authenticate rejects the requests without a valid bearer token and passes
+// the others to next with the token in their context. Without tokens every
+// request passes.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if len(s.tokens) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || secret == "" {
+			writeUnauthorized(w, unauthorized("missing bearer token"))
+			return
+		}
+		caller := s.tokenBySecret(secret)
+		if caller == nil {
+			s.logger.Warn("rejected request with an unknown token", "remote", r.RemoteAddr)
+			writeUnauthorized(w, unauthorized("invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withCaller(r.Context(), caller)))
+	})
+}
+
+// tokenBySecret finds a token, comparing every secret in constant time.
+func (s *Server) tokenBySecret(secret string) *Token {
+	var found *Token
+	for i := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(s.tokens[i].Secret), []byte(secret)) == 1 {
+			found = &s.tokens[i]
+		}
+	}
+	return found
+}
+
+func writeUnauthorized(w http.ResponseWriter, authErr *AuthError) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="agent-memory"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(mcp.NewJSONRPCError(mcp.NewRequestId(nil), authErr.Code, authErr.Message, authErr))
+}
+
+// authorizeTools is the tool middleware that checks every call against the
+// scopes and projects of the caller before the handler runs.
+func authorizeTools(logger *slog.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			caller := callerFromContext(ctx)
+			if err := authorizeToolCall(caller, request.Params.Name, request.GetArguments()); err != nil {
+				logger.Warn("tool call denied", "token", caller.Name, "tool", request.Params.Name, "reason", err.Message)
+				return deniedResult(err), nil
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// authorizeToolCall checks a tool call of caller. Tokens limited to some
+// projects may only make calls that name one of them: list_projects is
+// filtered instead, and calls across projects or on global memories are
+// denied. The calls returning a project's memories leave out the global
+// ones for these tokens, see hidesGlobalMemories.
+func authorizeToolCall(caller *Token, tool string, args map[string]any) *AuthError {
+	if caller == nil {
+		return nil
+	}
+
+	scope, ok := toolScopes[tool]
+	if !ok {
+		return forbidden(caller, fmt.Sprintf("tool %s is not available to tokens", tool))
+	}
+	if !caller.allows(scope) {
+		authErr := forbidden(caller, fmt.Sprintf("tool %s needs the %s scope", tool, scope))
+		authErr.RequiredScope = scope
+		return authErr
+	}
+
+	if !caller.restricted() || tool == "list_projects" {
+		return nil
+	}
+	projectIDs := toolProjects(tool, args)
+	if len(projectIDs) == 0 {
+		return forbidden(caller, fmt.Sprintf("token %s is limited to projects %s; %s must name one of them",
+			caller.Name, strings.Join(caller.Projects, ", "), tool))
+	}
+	for _, projectID := range projectIDs {
+		if !caller.allowsProject(projectID) {
+			return projectForbidden(caller, projectID)
+		}
+	}
+	return nil
+}
+
+// toolProjects returns the projects a tool call names: project_id, the
+// *_project_id arguments, and the id of the project tools, normalized like
+// the service normalizes them.
+func toolProjects(tool string, args map[string]any) []string {
+	var projectIDs []string
+	for name, value := range args {
+		isProject := name == "project_id" || strings.HasSuffix(name, "_project_id") ||
+			(name == "id" && strings.HasSuffix(tool, "_project"))
+		if id, ok := value.(string); ok && isProject && id != "" {
+			projectIDs = append(projectIDs, task.NewProjectID(id).String())
+		}
+	}
+	return projectIDs
+}
+
+func projectForbidden(caller *Token, projectID string) *AuthError {
+	authErr := forbidden(caller, fmt.Sprintf("token %s may not access project %s", caller.Name, projectID))
+	authErr.ProjectID = projectID
+	return authErr
+}
+
+// hidesGlobalMemories reports whether the caller in ctx may not see global
+// memories: tokens limited to some projects cannot read them directly, so
+// search_artifacts, get_project, resume_task and the prompts leave them out.
+func hidesGlobalMemories(ctx context.Context) bool {
+	caller := callerFromContext(ctx)
+	return caller != nil && caller.restricted()
+}
+
+// visibleMemories removes the global memories the caller in ctx may not see.
+func visibleMemories(ctx context.Context, memories []*task.Memory) []*task.Memory {
+	if !hidesGlobalMemories(ctx) {
+		return memories
+	}
+	return slices.DeleteFunc(memories, (*task.Memory).IsGlobal)
+}
+
+// visibleLinkedItems removes the linked items in projects the caller in ctx
+// may not access: links are only checked when they are made, so they can
+// lead to any project.
+func visibleLinkedItems(ctx context.Context, items []*service.LinkedItem) []*service.LinkedItem {
+	caller := callerFromContext(ctx)
+	if caller == nil || !caller.restricted() {
+		return items
+	}
+	return slices.DeleteFunc(items, func(item *service.LinkedItem) bool {
+		return !caller.allowsProject(item.Ref.ProjectID.String())
+	})
+}
+
+// deniedResult is the result of a denied tool call: the error as structured
+// content, and as JSON text for clients that only read text.
+func deniedResult(authErr *AuthError) *mcp.CallToolResult {
+	content := map[string]any{"error": authErr}
+	text, _ := json.Marshal(content)
+	result := mcp.NewToolResultStructured(content, string(text))
+	result.IsError = true
+	return result
+}
+
+// authorizeResources is the resource middleware that checks every read
+// against the read scope and the projects of the caller.
+func authorizeResources(logger *slog.Logger) server.ResourceHandlerMiddleware {
+	return func(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+		return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			caller := callerFromContext(ctx)
+			if err := authorizeResource(caller, request.Params.URI); err != nil {
+				logger.Warn("resource read denied", "token", caller.Name, "uri", request.Params.URI, "reason", err.Message)
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// authorizeResource checks that caller may read or subscribe to a resource.
+func authorizeResource(caller *Token, uri string) *AuthError {
+	if caller == nil {
+		return nil
+	}
+	if !caller.allows(ScopeRead) {
+		authErr := forbidden(caller, "resources need the read scope")
+		authErr.RequiredScope = ScopeRead
+		return authErr
+	}
+	if ref, ok := parseResourceURI(uri); ok && !caller.allowsProject(ref.ProjectID.String()) {
+		return projectForbidden(caller, ref.ProjectID.String())
+	}
+	return nil
+}
+
+// filterResources removes from a resources/list result what the caller may
+// not read.
+func filterResources(ctx context.Context, result *mcp.ListResourcesResult) {
+	caller := callerFromContext(ctx)
+	if caller == nil {
+		return
+	}
+	result.Resources = slices.DeleteFunc(result.Resources, func(r mcp.Resource) bool {
+		return authorizeResource(caller, r.URI) != nil
+	})
+}
+
+// authorizePrompt wraps a prompt handler with the check of the read scope
+// and of the project_id argument.
+func (s *Server) authorizePrompt(next server.PromptHandlerFunc) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		caller := callerFromContext(ctx)
+		if caller == nil {
+			return next(ctx, request)
+		}
+
+		var authErr *AuthError
+		projectID := task.NewProjectID(request.Params.Arguments["project_id"]).String()
+		switch {
+		case !caller.allows(ScopeRead):
+			authErr = forbidden(caller, fmt.Sprintf("prompt %s needs the read scope", request.Params.Name))
+			authErr.RequiredScope = ScopeRead
+		case caller.restricted() && projectID == "":
+			authErr = forbidden(caller, fmt.Sprintf("token %s is limited to projects %s; pass project_id",
+				caller.Name, strings.Join(caller.Projects, ", ")))
+		case !caller.allowsProject(projectID):
+			authErr = projectForbidden(caller, projectID)
+		}
+		if authErr != nil {
+			s.logger.Warn("prompt denied", "token", caller.Name, "prompt", request.Params.Name, "reason", authErr.Message)
+			return nil, authErr
+		}
+		return next(ctx, request)
+	}
+}
@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Transports the server can be reached over.
+const (
+	TransportStdio = "stdio" // One client, the process that started the server
+	TransportSSE   = "sse"   // HTTP with server-sent events: GET {base}/sse, POST {base}/message
+	TransportHTTP  = "http"  // Streamable HTTP: {base}/mcp
+)
+
+// shutdownTimeout bounds how long a graceful shutdown waits for the requests
+// in flight.
+const shutdownTimeout = 10 * time.Second
+
+// ListenAndServe serves the sse or http transport on addr, with its endpoints
+// under basePath, until ctx is done. It then stops accepting connections,
+// closes the event streams and waits for the requests in flight.
+func (s *Server) ListenAndServe(ctx context.Context, transport, addr, basePath string) error {
+	srv := &http.Server{Addr: addr}
+	handler, shutdown, err := s.httpHandler(transport, basePath, srv)
+	if err != nil {
+		return err
+	}
+	srv.Handler = handler
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("listening", "transport", transport, "address", ln.Addr().String(), "base_path", cleanBasePath(basePath))
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// httpHandler creates the handler of the sse or http transport, and the
+// function that shuts it and srv down. srv must not be started yet. With
+// tokens set, every request needs one of them.
+func (s *Server) httpHandler(transport, basePath string, srv *http.Server) (http.Handler, func(context.Context) error, error) {
+	basePath = cleanBasePath(basePath)
+
+	// Event streams only end when the client leaves, so end them on shutdown
+	streams, closeStreams := context.WithCancel(context.Background())
+	srv.RegisterOnShutdown(closeStreams)
+
+	switch transport {
+	case TransportSSE:
+		sse := server.NewSSEServer(s.mcpServer,
+			server.WithStaticBasePath(basePath),
+			server.WithHTTPServer(srv),
+		)
+		sessionID := func(r *http.Request) string {
+			return r.URL.Query().Get("sessionId")
+		}
+		// Responses travel over the event stream, as mcp-go sends them
+		reply := func(w http.ResponseWriter, sessionID string, response mcp.JSONRPCMessage) {
+			if err := sse.SendEventToSession(sessionID, response); err != nil {
+				s.dropSubscriptions(sessionID)
+				http.Error(w, "Invalid session ID", http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}
+		return s.authenticate(s.withSubscriptions(endOnShutdown(sse, streams), sessionID, reply)), sse.Shutdown, nil
+
+	case TransportHTTP:
+		endpoint := path.Join(basePath, "mcp")
+		streamable := server.NewStreamableHTTPServer(s.mcpServer,
+			server.WithEndpointPath(endpoint),
+			server.WithStateful(true), // Notifications need the session of the client's GET stream
+			server.WithStreamableHTTPServer(srv),
+		)
+		sessionID := func(r *http.Request) string {
+			return r.Header.Get(server.HeaderKeySessionID)
+		}
+		reply := func(w http.ResponseWriter, sessionID string, response mcp.JSONRPCMessage) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(server.HeaderKeySessionID, sessionID)
+			json.NewEncoder(w).Encode(response)
+		}
+		mux := http.NewServeMux()
+		mux.Handle(endpoint, s.authenticate(s.withSubscriptions(endOnShutdown(streamable, streams), sessionID, reply)))
+		return mux, streamable.Shutdown, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown transport %q (want %s, %s or %s)", transport, TransportStdio, TransportSSE, TransportHTTP)
+}
+
+// withSubscriptions answers the subscription requests posted to next, which
+// mcp-go does not implement, and passes everything else on. sessionID finds
+// the session of a request and reply sends the response.
+func (s *Server) withSubscriptions(next http.Handler, sessionID func(*http.Request) string, reply func(http.ResponseWriter, string, mcp.JSONRPCMessage)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request", http.StatusBadRequest)
+			return
+		}
+		id := sessionID(r)
+		if response, ok := s.handleSubscription(r.Context(), id, body); ok {
+			reply(w, id, response)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// endOnShutdown cancels the requests of event streams (GET) when streams is
+// done; other requests run to completion.
+func endOnShutdown(next http.Handler, streams context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			stop := context.AfterFunc(streams, cancel)
+			defer stop()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cleanBasePath returns basePath with a leading slash and no trailing one;
+// empty is the root.
+func cleanBasePath(basePath string) string {
+	return path.Join("/", basePath)
+}
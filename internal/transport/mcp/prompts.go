@@ -0,0 +1,349 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"agent-memory/internal/application/service"
+	"agent-memory/internal/domain/task"
+)
+
+// promptTaskLimit is the number of active tasks start_session lists.
+const promptTaskLimit = 20
+
+// registerPrompts registers the prompts for the usual memory workflows. Each
+// expands to a user message filled in from the store.
+func (s *Server) registerPrompts() {
+	s.mcpServer.AddPrompt(
+		mcp.NewPrompt("start_session",
+			mcp.WithPromptDescription("Start a work session: pinned memories and the active tasks, best candidates first"),
+			mcp.WithArgument("project_id", mcp.ArgumentDescription("Project to work on (empty = all projects)")),
+		),
+		s.authorizePrompt(s.handleStartSessionPrompt),
+	)
+	s.mcpServer.AddPrompt(
+		mcp.NewPrompt("resume_task",
+			mcp.WithPromptDescription("Continue a task: its details, pinned memories, decisions, open questions and recent artifacts"),
+			mcp.WithArgument("project_id", mcp.ArgumentDescription("Project ID"), mcp.RequiredArgument()),
+			mcp.WithArgument("task_id", mcp.ArgumentDescription("Task ID"), mcp.RequiredArgument()),
+			mcp.WithArgument("token_budget", mcp.ArgumentDescription("Approximate size of the context in tokens (default 4000)")),
+		),
+		s.authorizePrompt(s.handleResumeTaskPrompt),
+	)
+	// The others end with saving an artifact, which a read-only store rejects
+	if s.readOnly {
+		return
+	}
+	s.mcpServer.AddPrompt(
+		mcp.NewPrompt("record_decision",
+			mcp.WithPromptDescription("Record an architecture decision on a task from an ADR template"),
+			mcp.WithArgument("project_id", mcp.ArgumentDescription("Project ID"), mcp.RequiredArgument()),
+			mcp.WithArgument("task_id", mcp.ArgumentDescription("Task ID"), mcp.RequiredArgument()),
+			mcp.WithArgument("title", mcp.ArgumentDescription("What was decided, in a few words")),
+			mcp.WithArgument("context", mcp.ArgumentDescription("The problem and constraints behind the decision")),
+		),
+		s.authorizePrompt(s.handleRecordDecisionPrompt),
+	)
+	s.mcpServer.AddPrompt(
+		mcp.NewPrompt("handoff",
+			mcp.WithPromptDescription("Write a handoff note so another agent can take over a task"),
+			mcp.WithArgument("project_id", mcp.ArgumentDescription("Project ID"), mcp.RequiredArgument()),
+			mcp.WithArgument("task_id", mcp.ArgumentDescription("Task ID"), mcp.RequiredArgument()),
+			mcp.WithArgument("recipient", mcp.ArgumentDescription("Who takes over (default: another agent)")),
+		),
+		s.authorizePrompt(s.handleHandoffPrompt),
+	)
+}
+
+func (s *Server) handleStartSessionPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	projectID := request.Params.Arguments["project_id"]
+
+	var sb strings.Builder
+	if projectID != "" {
+		p, err := s.taskService.GetProject(ctx, projectID)
+		if err != nil {
+			return nil, promptError(err, projectID, "")
+		}
+		fmt.Fprintf(&sb, "# Work session: %s\n\n", p.Name)
+		if p.Description != "" {
+			fmt.Fprintf(&sb, "%s\n\n", p.Description)
+		}
+		if p.WorkspacePath != "" {
+			fmt.Fprintf(&sb, "Workspace: %s\n\n", p.WorkspacePath)
+		}
+	} else {
+		sb.WriteString("# Work session\n\n")
+	}
+
+	memories, err := s.taskService.PinnedMemories(ctx, projectID)
+	if err != nil {
+		return nil, promptError(err, projectID, "")
+	}
+	memories = visibleMemories(ctx, memories)
+	if len(memories) > 0 {
+		sb.WriteString("## Pinned memories\n")
+		for _, m := range memories {
+			fmt.Fprintf(&sb, "\n### %s\n\n%s\n", m.Title, strings.TrimSpace(m.Content))
+		}
+		sb.WriteString("\n")
+	}
+
+	suggestions, err := s.taskService.SuggestNextTask(ctx, service.SuggestNextTaskRequest{ProjectID: projectID, Limit: promptTaskLimit})
+	if err != nil {
+		return nil, promptError(err, projectID, "")
+	}
+	sb.WriteString("## Active tasks\n\n")
+	if len(suggestions) == 0 {
+		sb.WriteString("There are no active tasks. Create one with `create_task(project_id, id)` for the work you are about to do.\n")
+	} else {
+		for _, suggestion := range suggestions {
+			sb.WriteString("- " + taskLine(suggestion.Task))
+			if suggestion.Blocked {
+				sb.WriteString(" (blocked)")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\nThe best candidates come first. Pick the task to work on, or ask which one, and call `resume_task(project_id, task_id)` to load its context.\n")
+	}
+	sb.WriteString("\nSave progress as you go with `save_artifact`: findings, decisions, blockers and patterns.\n")
+
+	return promptResult("Start a work session", sb.String()), nil
+}
+
+func (s *Server) handleResumeTaskPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := request.Params.Arguments
+	if err := requireArguments(args, "project_id", "task_id"); err != nil {
+		return nil, err
+	}
+	budget, err := optionalInt(args, "token_budget")
+	if err != nil {
+		return nil, err
+	}
+
+	pack, err := s.taskService.ResumeTask(ctx, service.ResumeTaskRequest{
+		ProjectID:             args["project_id"],
+		TaskID:                args["task_id"],
+		TokenBudget:           budget,
+		ExcludeGlobalMemories: hidesGlobalMemories(ctx),
+	})
+	if err != nil {
+		return nil, promptError(err, args["project_id"], args["task_id"])
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Resume task %s\n\n", pack.Task.Ref())
+	writeContextPack(&sb, pack)
+	sb.WriteString("\nContinue the task from here. Save new findings and decisions with `save_artifact`, and update the status with `update_task` when it changes.\n")
+
+	return promptResult("Resume task "+pack.Task.Ref().String(), sb.String()), nil
+}
+
+func (s *Server) handleRecordDecisionPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := request.Params.Arguments
+	if err := requireArguments(args, "project_id", "task_id"); err != nil {
+		return nil, err
+	}
+	projectID, taskID := args["project_id"], args["task_id"]
+
+	t, err := s.taskService.GetTask(ctx, projectID, taskID)
+	if err != nil {
+		return nil, promptError(err, projectID, taskID)
+	}
+	artifacts, err := s.taskService.ListArtifacts(ctx, service.ListArtifactsRequest{ProjectID: projectID, TaskID: taskID, Limit: math.MaxInt32})
+	if err != nil {
+		return nil, promptError(err, projectID, taskID)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Record an architecture decision for task %s: %s.\n", t.Ref(), t.Name)
+	if t.Description != "" {
+		fmt.Fprintf(&sb, "\nTask description: %s\n", t.Description)
+	}
+
+	var decisions []*task.Artifact
+	for _, a := range artifacts.Items {
+		if a.Type == task.ArtifactTypeDecision {
+			decisions = append(decisions, a)
+		}
+	}
+	if len(decisions) > 0 {
+		sb.WriteString("\nEarlier decisions on this task:\n")
+		for _, a := range decisions {
+			fmt.Fprintf(&sb, "- %s (`%s`, %s)\n", firstLine(a.Content), a.ID, a.CreatedAt.Format("2006-01-02"))
+		}
+		sb.WriteString("\nIf the new decision replaces one of them, link it with `link_artifact(..., relation=\"supersedes\")` after saving.\n")
+	}
+
+	title := args["title"]
+	if title == "" {
+		title = "<Decision title>"
+	}
+	decisionContext := args["context"]
+	if decisionContext == "" {
+		decisionContext = "<The problem, the constraints and the options considered>"
+	}
+
+	fmt.Fprintf(&sb, "\nFill in the template and save it with `save_artifact(project_id=%q, task_id=%q, type=\"decision\", content=...)`:\n\n", projectID, taskID)
+	fmt.Fprintf(&sb, "# %s\n\n## Status\n\nAccepted\n\n## Context\n\n%s\n\n## Decision\n\n<What was decided>\n\n## Consequences\n\n<What becomes easier or harder, and the follow-up work>\n", title, decisionContext)
+
+	return promptResult("Record a decision on "+t.Ref().String(), sb.String()), nil
+}
+
+func (s *Server) handleHandoffPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := request.Params.Arguments
+	if err := requireArguments(args, "project_id", "task_id"); err != nil {
+		return nil, err
+	}
+	projectID, taskID := args["project_id"], args["task_id"]
+	recipient := args["recipient"]
+	if recipient == "" {
+		recipient = "another agent"
+	}
+
+	pack, err := s.taskService.ResumeTask(ctx, service.ResumeTaskRequest{ProjectID: projectID, TaskID: taskID, ExcludeGlobalMemories: hidesGlobalMemories(ctx)})
+	if err != nil {
+		return nil, promptError(err, projectID, taskID)
+	}
+	history, err := s.taskService.GetTaskHistory(ctx, projectID, taskID)
+	if err != nil {
+		return nil, promptError(err, projectID, taskID)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Write a handoff note so that %s can take over task %s without asking questions.\n\n", recipient, pack.Task.Ref())
+	writeContextPack(&sb, pack)
+
+	if len(history.Changes) > 0 {
+		sb.WriteString("\n## Status history\n\n")
+		for _, c := range history.Changes {
+			fmt.Fprintf(&sb, "- %s: %s", c.At.Format("2006-01-02 15:04"), c.To)
+			if c.From != "" {
+				fmt.Fprintf(&sb, " (from %s)", c.From)
+			}
+			if c.Reason != "" {
+				fmt.Fprintf(&sb, ": %s", c.Reason)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString(`
+Write the note with these sections: Goal, Current state, Decisions, Open questions, Next steps and Pointers (files, commands, artifact IDs).
+Keep it short and concrete; leave out what the reader can find in the artifacts listed above.
+`)
+	fmt.Fprintf(&sb, "Save it with `save_artifact(project_id=%q, task_id=%q, type=\"note\", tags=[\"handoff\"], content=...)`.\n", projectID, taskID)
+
+	return promptResult("Hand off "+pack.Task.Ref().String(), sb.String()), nil
+}
+
+// writeContextPack writes a task's context pack as Markdown sections.
+func writeContextPack(sb *strings.Builder, pack *service.ContextPack) {
+	t := pack.Task
+	fmt.Fprintf(sb, "Task: %s\n", taskLine(t))
+	if t.Description != "" {
+		fmt.Fprintf(sb, "\n%s\n", t.Description)
+	}
+	if t.WorkspacePath != "" {
+		fmt.Fprintf(sb, "\nWorkspace: %s\n", t.WorkspacePath)
+	}
+	fmt.Fprintf(sb, "\nProject: %s", pack.Project.Name)
+	if pack.Project.Description != "" {
+		fmt.Fprintf(sb, ": %s", pack.Project.Description)
+	}
+	sb.WriteString("\n")
+
+	sections := []struct {
+		section service.ContextSection
+		heading string
+	}{
+		{service.SectionMemory, "Pinned memories"},
+		{service.SectionDecision, "Decisions"},
+		{service.SectionQuestion, "Open questions"},
+		{service.SectionNote, "Notes"},
+		{service.SectionOther, "Other artifacts"},
+	}
+	for _, sec := range sections {
+		items := pack.Section(sec.section)
+		if len(items) == 0 {
+			continue
+		}
+		fmt.Fprintf(sb, "\n## %s\n", sec.heading)
+		for _, item := range items {
+			if m := item.Memory; m != nil {
+				fmt.Fprintf(sb, "\n### %s\n\n", m.Title)
+			} else {
+				a := item.Artifact
+				fmt.Fprintf(sb, "\n### %s, %s (`%s`)", a.Type, versionTime(a).Format("2006-01-02"), a.ID)
+				if len(a.Tags) > 0 {
+					fmt.Fprintf(sb, " [%s]", strings.Join(a.Tags, ", "))
+				}
+				sb.WriteString("\n\n")
+			}
+			sb.WriteString(strings.TrimSpace(item.Content))
+			if item.Truncated {
+				sb.WriteString("\n\n(truncated)")
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if n := len(pack.Omitted); n > 0 {
+		fmt.Fprintf(sb, "\n%d older or less relevant items did not fit; get them with `list_artifacts` and `get_artifact` when needed.\n", n)
+	}
+}
+
+// taskLine describes a task in one line: ref, name, status, priority and due date.
+func taskLine(t *task.Task) string {
+	line := fmt.Sprintf("`%s` %s [%s, %s", t.Ref(), t.Name, t.Status, t.EffectivePriority())
+	if t.DueDate != nil {
+		line += ", due " + t.DueDate.Format("2006-01-02")
+	}
+	return line + "]"
+}
+
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return mcp.NewGetPromptResult(description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	})
+}
+
+// requireArguments checks that the named prompt arguments are set; mcp-go
+// does not check required arguments itself.
+func requireArguments(args map[string]string, names ...string) error {
+	for _, name := range names {
+		if args[name] == "" {
+			return fmt.Errorf("argument %s is required", name)
+		}
+	}
+	return nil
+}
+
+// optionalInt parses an integer prompt argument, which clients send as a
+// string; a missing argument is 0.
+func optionalInt(args map[string]string, name string) (int, error) {
+	if args[name] == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(args[name])
+	if err != nil {
+		return 0, fmt.Errorf("argument %s must be a number", name)
+	}
+	return n, nil
+}
+
+// promptError turns a not-found error into a message naming what is missing.
+func promptError(err error, projectID, taskID string) error {
+	switch {
+	case errors.Is(err, task.ErrProjectNotFound):
+		return fmt.Errorf("project '%s' not found", projectID)
+	case errors.Is(err, task.ErrTaskNotFound):
+		return fmt.Errorf("task '%s' not found in project '%s'", taskID, projectID)
+	}
+	return err
+}
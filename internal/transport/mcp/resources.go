@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"agent-memory/internal/application/service"
+	"agent-memory/internal/domain/task"
+)
+
+// Resources expose the store to clients that attach context without a tool call:
+//
+//	memory://projects/{project}                              project JSON with its tasks
+//	memory://projects/{project}/tasks/{task}                 task JSON with its artifacts
+//	memory://projects/{project}/tasks/{task}/artifacts/{id}  artifact content as Markdown
+//
+// resources/list returns the projects and tasks; artifacts are reached
+// through the templates and the artifact list of their task.
+const (
+	resourceScheme   = "memory://"
+	jsonMIMEType     = "application/json"
+	markdownMIMEType = "text/markdown"
+
+	// resourcePageSize is the page size of resources/list. mcp-go applies
+	// it to tools/list too, which stays well below it.
+	resourcePageSize = 100
+)
+
+// registerResources registers the resource templates. The listed resources
+// are registered by refreshResources.
+func (s *Server) registerResources() {
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(resourceScheme+"projects/{project}", "project",
+			mcp.WithTemplateDescription("A project with its workflow and tasks"),
+			mcp.WithTemplateMIMEType(jsonMIMEType),
+		),
+		s.handleReadResource,
+	)
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(resourceScheme+"projects/{project}/tasks/{task}", "task",
+			mcp.WithTemplateDescription("A task with the list of its artifacts"),
+			mcp.WithTemplateMIMEType(jsonMIMEType),
+		),
+		s.handleReadResource,
+	)
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(resourceScheme+"projects/{project}/tasks/{task}/artifacts/{id}", "artifact",
+			mcp.WithTemplateDescription("The latest content of an artifact"),
+			mcp.WithTemplateMIMEType(markdownMIMEType),
+		),
+		s.handleReadResource,
+	)
+}
+
+// refreshResources registers a resource for every project and task and
+// removes those that no longer exist. It runs before each resources/list, so
+// the list reflects the store even when another process changed it, and
+// through scheduleRefresh when a project or task is created or deleted.
+func (s *Server) refreshResources(ctx context.Context) {
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+
+	projects, err := s.taskService.ListProjects(ctx, service.ListProjectsRequest{Limit: math.MaxInt32})
+	if err != nil {
+		s.logger.Error("failed to list projects for resources", "error", err)
+		return
+	}
+	tasks, err := s.taskService.ListAllTasks(ctx, service.ListAllTasksRequest{Limit: math.MaxInt32})
+	if err != nil {
+		s.logger.Error("failed to list tasks for resources", "error", err)
+		return
+	}
+
+	resources := make([]server.ServerResource, 0, len(projects.Items)+len(tasks.Items))
+	for _, p := range projects.Items {
+		description := p.Name
+		if p.Description != "" {
+			description += ": " + p.Description
+		}
+		resources = append(resources, server.ServerResource{
+			Resource: mcp.NewResource(projectURI(p.ID), p.ID.String(),
+				mcp.WithResourceDescription(description),
+				mcp.WithMIMEType(jsonMIMEType),
+			),
+			Handler: s.handleReadResource,
+		})
+	}
+	for _, t := range tasks.Items {
+		resources = append(resources, server.ServerResource{
+			Resource: mcp.NewResource(taskURI(t.ProjectID, t.ID), t.Ref().String(),
+				mcp.WithResourceDescription(fmt.Sprintf("%s [%s]", t.Name, t.Status)),
+				mcp.WithMIMEType(jsonMIMEType),
+			),
+			Handler: s.handleReadResource,
+		})
+	}
+
+	// mcp-go cannot tell which resources are registered, so remember them.
+	// Both calls announce a list change, so make them only when it changed.
+	current := make(map[string]string, len(resources))
+	var changed []server.ServerResource
+	for _, r := range resources {
+		current[r.Resource.URI] = r.Resource.Description
+		if description, ok := s.resourceURIs[r.Resource.URI]; !ok || description != r.Resource.Description {
+			changed = append(changed, r)
+		}
+	}
+	var stale []string
+	for uri := range s.resourceURIs {
+		if _, ok := current[uri]; !ok {
+			stale = append(stale, uri)
+		}
+	}
+
+	if len(stale) > 0 {
+		s.mcpServer.DeleteResources(stale...)
+	}
+	if len(changed) > 0 {
+		s.mcpServer.AddResources(changed...)
+	}
+	s.resourceURIs = current
+}
+
+// scheduleRefresh runs refreshResources in the background, so a change does
+// not wait for the whole store to be listed. Changes made while it runs are
+// coalesced into one more refresh.
+func (s *Server) scheduleRefresh() {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.refreshPending = true
+	if s.refreshing {
+		return
+	}
+	s.refreshing = true
+	s.refreshes.Add(1)
+
+	go func() {
+		defer s.refreshes.Done()
+		for {
+			s.refreshMu.Lock()
+			if !s.refreshPending {
+				s.refreshing = false
+				s.refreshMu.Unlock()
+				return
+			}
+			s.refreshPending = false
+			s.refreshMu.Unlock()
+
+			s.refreshResources(context.Background())
+		}
+	}()
+}
+
+// waitForRefresh waits until the refreshes scheduled so far are done.
+func (s *Server) waitForRefresh() {
+	s.refreshes.Wait()
+}
+
+// handleReadResource serves resources/read for every resource and template.
+func (s *Server) handleReadResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := request.Params.URI
+	ref, ok := parseResourceURI(uri)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", server.ErrResourceNotFound, uri)
+	}
+
+	var contents mcp.ResourceContents
+	var err error
+	switch {
+	case ref.ArtifactID != "":
+		contents, err = s.readArtifactResource(ctx, uri, ref)
+	case ref.TaskID != "":
+		contents, err = s.readTaskResource(ctx, uri, ref)
+	default:
+		contents, err = s.readProjectResource(ctx, uri, ref)
+	}
+	if err != nil {
+		if errors.Is(err, task.ErrProjectNotFound) || errors.Is(err, task.ErrTaskNotFound) || errors.Is(err, task.ErrArtifactNotFound) {
+			return nil, fmt.Errorf("%w: %s (%v)", server.ErrResourceNotFound, uri, err)
+		}
+		return nil, err
+	}
+	return []mcp.ResourceContents{contents}, nil
+}
+
+func (s *Server) readProjectResource(ctx context.Context, uri string, ref task.ArtifactRef) (mcp.ResourceContents, error) {
+	p, err := s.taskService.GetProject(ctx, ref.ProjectID.String())
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.taskService.ListTasks(ctx, service.ListTasksRequest{ProjectID: p.ID.String(), Limit: math.MaxInt32})
+	if err != nil {
+		return nil, err
+	}
+
+	taskMaps := make([]map[string]interface{}, 0, len(tasks.Items))
+	for _, t := range tasks.Items {
+		taskMaps = append(taskMaps, map[string]interface{}{
+			"uri":       taskURI(t.ProjectID, t.ID),
+			"id":        t.ID,
+			"parent_id": t.ParentID,
+			"name":      t.Name,
+			"status":    t.Status,
+			"priority":  t.EffectivePriority(),
+		})
+	}
+
+	data := projectToMap(p)
+	data["uri"] = uri
+	data["tasks"] = taskMaps
+	return jsonResourceContents(uri, data)
+}
+
+func (s *Server) readTaskResource(ctx context.Context, uri string, ref task.ArtifactRef) (mcp.ResourceContents, error) {
+	t, err := s.taskService.GetTask(ctx, ref.ProjectID.String(), ref.TaskID.String())
+	if err != nil {
+		return nil, err
+	}
+	artifacts, err := s.taskService.ListArtifacts(ctx, service.ListArtifactsRequest{
+		ProjectID: t.ProjectID.String(),
+		TaskID:    t.ID.String(),
+		Limit:     math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	artifactMaps := make([]map[string]interface{}, 0, len(artifacts.Items))
+	for _, a := range artifacts.Items {
+		artifactMaps = append(artifactMaps, map[string]interface{}{
+			"uri":        artifactURI(a),
+			"id":         a.ID,
+			"type":       a.Type,
+			"tags":       tagList(a.Tags),
+			"created_at": a.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			"summary":    firstLine(a.Content),
+		})
+	}
+
+	data := taskToMap(t)
+	data["uri"] = uri
+	data["project_uri"] = projectURI(t.ProjectID)
+	data["artifacts"] = artifactMaps
+	return jsonResourceContents(uri, data)
+}
+
+func (s *Server) readArtifactResource(ctx context.Context, uri string, ref task.ArtifactRef) (mcp.ResourceContents, error) {
+	a, err := s.taskService.GetArtifact(ctx, ref.ProjectID.String(), ref.TaskID.String(), ref.ArtifactID)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]any{
+		"type":       a.Type,
+		"tags":       tagList(a.Tags),
+		"version":    max(a.Version, 1),
+		"created_at": a.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"task_uri":   taskURI(a.ProjectID, a.TaskID),
+	}
+	if len(a.Metadata) > 0 {
+		meta["metadata"] = a.Metadata
+	}
+
+	return mcp.TextResourceContents{
+		Meta:     meta,
+		URI:      uri,
+		MIMEType: markdownMIMEType,
+		Text:     a.Content,
+	}, nil
+}
+
+func jsonResourceContents(uri string, data interface{}) (mcp.ResourceContents, error) {
+	text, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.TextResourceContents{URI: uri, MIMEType: jsonMIMEType, Text: string(text)}, nil
+}
+
+// parseResourceURI splits a resource URI into the project, task and
+// artifact it names; the parts below the resource are empty.
+func parseResourceURI(uri string) (task.ArtifactRef, bool) {
+	path, ok := strings.CutPrefix(uri, resourceScheme)
+	if !ok {
+		return task.ArtifactRef{}, false
+	}
+
+	parts := strings.Split(path, "/")
+	for _, part := range parts {
+		if part == "" {
+			return task.ArtifactRef{}, false
+		}
+	}
+
+	var ref task.ArtifactRef
+	switch {
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "tasks" && parts[4] == "artifacts":
+		ref.ArtifactID = parts[5]
+		fallthrough
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "tasks":
+		ref.TaskID = task.TaskID(parts[3])
+		fallthrough
+	case len(parts) == 2 && parts[0] == "projects":
+		ref.ProjectID = task.ProjectID(parts[1])
+		return ref, true
+	}
+	return task.ArtifactRef{}, false
+}
+
+func projectURI(projectID task.ProjectID) string {
+	return resourceScheme + "projects/" + projectID.String()
+}
+
+func taskURI(projectID task.ProjectID, taskID task.TaskID) string {
+	return projectURI(projectID) + "/tasks/" + taskID.String()
+}
+
+func artifactURI(a *task.Artifact) string {
+	return taskURI(a.ProjectID, a.TaskID) + "/artifacts/" + a.ID
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"agent-memory/internal/domain/task"
+)
+
+// Limits of what is sent to the client's model to summarize compacted artifacts.
+const (
+	samplingArtifactChars = 2000  // Per artifact
+	samplingPromptChars   = 60000 // In total; the remaining artifacts are left out
+	samplingMaxTokens     = 1024
+)
+
+const samplingSystemPrompt = `You summarize the work log of a software task for the agent that will continue it.
+Keep what is still useful: files and code areas looked at, what was found, decisions and open problems.
+Drop repetition and raw file contents. Answer with the summary only, as short Markdown.`
+
+// samplingSummarizer writes compaction summaries with the client's model,
+// through MCP sampling.
+type samplingSummarizer struct {
+	mcpServer *server.MCPServer
+}
+
+func (samplingSummarizer) Name() string {
+	return "sampling"
+}
+
+func (s samplingSummarizer) Summarize(ctx context.Context, t *task.Task, artifacts []*task.Artifact) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Task %s: %s\n", t.Ref(), t.Name)
+	if t.Description != "" {
+		fmt.Fprintf(&sb, "%s\n", t.Description)
+	}
+	fmt.Fprintf(&sb, "\n%d artifacts to summarize, oldest first:\n", len(artifacts))
+
+	for i, a := range artifacts {
+		if sb.Len() > samplingPromptChars {
+			fmt.Fprintf(&sb, "\n(%d more artifacts left out)\n", len(artifacts)-i)
+			break
+		}
+		content := a.Content
+		if r := []rune(content); len(r) > samplingArtifactChars {
+			content = string(r[:samplingArtifactChars]) + "..."
+		}
+		fmt.Fprintf(&sb, "\n--- %s, %s", a.Type, versionTime(a).Format(time.RFC3339))
+		if len(a.Metadata) > 0 {
+			fmt.Fprintf(&sb, " %v", a.Metadata)
+		}
+		fmt.Fprintf(&sb, "\n%s\n", content)
+	}
+
+	result, err := s.mcpServer.RequestSampling(ctx, mcp.CreateMessageRequest{
+		CreateMessageParams: mcp.CreateMessageParams{
+			Messages: []mcp.SamplingMessage{
+				{Role: mcp.RoleUser, Content: mcp.NewTextContent(sb.String())},
+			},
+			SystemPrompt: samplingSystemPrompt,
+			MaxTokens:    samplingMaxTokens,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	summary := strings.TrimSpace(mcp.GetTextFromContent(result.Content))
+	if summary == "" {
+		return "", errors.New("the client returned an empty summary")
+	}
+	return summary, nil
+}
+
+// clientSupportsSampling reports whether the client of the current session
+// declared the sampling capability when it connected.
+func clientSupportsSampling(ctx context.Context) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	return ok && session.GetClientCapabilities().Sampling != nil
+}
@@ -1,7 +1,12 @@
 package mcp
 
 import (
+	"context"
 	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -9,37 +14,88 @@ import (
 	"agent-memory/internal/application/service"
 )
 
-// Server wraps the MCP server with project/task/artifact/workspace tools.
+// Server wraps the MCP server with project/task/artifact/workspace tools,
+// resources for projects, tasks and artifacts, and prompts for the usual
+// memory workflows.
 type Server struct {
 	mcpServer        *server.MCPServer
 	taskService      *service.TaskService
 	workspaceService *service.WorkspaceService
 	logger           *slog.Logger
+	readOnly         bool // Only the tools and prompts that do not change the store are registered
+
+	resourcesMu  sync.Mutex
+	resourceURIs map[string]string // URI -> description of the resources registered by refreshResources
+
+	refreshMu      sync.Mutex
+	refreshing     bool           // A scheduleRefresh goroutine is running
+	refreshPending bool           // Changes came in since it last listed the store
+	refreshes      sync.WaitGroup // The scheduleRefresh goroutine
+
+	subscriptionsMu sync.Mutex
+	subscribers     map[string]map[string]bool // URI -> IDs of the sessions subscribed to it
+
+	tokens []Token // Bearer tokens of the sse and http transports (none = no authentication)
 }
 
-// NewServer creates a new MCP server with all tools.
+// NewServer creates a new MCP server with all tools, or only the read tools
+// when the task service's store is read-only.
 func NewServer(taskService *service.TaskService, workspaceService *service.WorkspaceService, logger *slog.Logger) *Server {
+	hooks := &server.Hooks{}
 	mcpServer := server.NewMCPServer(
 		"agent-memory",
 		"1.0.0",
 		server.WithLogging(),
+		server.WithHooks(hooks),
+		server.WithResourceCapabilities(true, true),
+		server.WithPromptCapabilities(false),
+		server.WithPaginationLimit(resourcePageSize),
+		server.WithToolHandlerMiddleware(authorizeTools(logger)),
+		server.WithResourceHandlerMiddleware(authorizeResources(logger)),
 	)
+	// Lets compact_artifacts summarize with the client's model
+	mcpServer.EnableSampling()
 
 	s := &Server{
 		mcpServer:        mcpServer,
 		taskService:      taskService,
 		workspaceService: workspaceService,
 		logger:           logger,
+		readOnly:         taskService.ReadOnly(),
+		subscribers:      make(map[string]map[string]bool),
 	}
 
 	s.registerTools()
+	s.registerResources()
+	s.registerPrompts()
+	hooks.AddBeforeListResources(func(ctx context.Context, _ any, _ *mcp.ListResourcesRequest) {
+		s.refreshResources(ctx)
+	})
+	hooks.AddAfterListResources(func(ctx context.Context, _ any, _ *mcp.ListResourcesRequest, result *mcp.ListResourcesResult) {
+		filterResources(ctx, result)
+	})
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		s.dropSubscriptions(session.SessionID())
+	})
+	taskService.Subscribe(s.handleEvent)
 
 	return s
 }
 
-// ServeStdio starts the MCP server using stdio transport.
+// ServeStdio starts the MCP server using stdio transport. It stops on
+// SIGTERM or SIGINT.
 func (s *Server) ServeStdio() error {
-	return server.ServeStdio(s.mcpServer)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	stdout := &syncWriter{w: os.Stdout}
+	sessionCtx := make(chan context.Context, 1)
+	stdio := server.NewStdioServer(s.mcpServer)
+	stdio.SetContextFunc(func(ctx context.Context) context.Context {
+		sessionCtx <- ctx
+		return ctx
+	})
+	return stdio.Listen(ctx, s.interceptSubscriptions(os.Stdin, stdout, sessionCtx), stdout)
 }
 
 // registerTools registers all tools with the MCP server.
@@ -58,20 +114,55 @@ func (s *Server) registerTools() {
 	s.registerListAllTasks()
 	s.registerUpdateTask()
 	s.registerDeleteTask()
+	s.registerGetTaskTree()
+	s.registerGetTaskHistory()
+	s.registerAddDependency()
+	s.registerRemoveDependency()
+	s.registerListReadyTasks()
+	s.registerSuggestNextTask()
+	s.registerClaimTask()
+	s.registerRenewClaim()
+	s.registerReleaseTask()
+	s.registerResumeTask()
+	s.registerListTags()
 
 	// Artifact management
 	s.registerSaveArtifact()
 	s.registerGetArtifact()
+	s.registerUpdateArtifact()
+	s.registerListArtifactVersions()
+	s.registerGetArtifactVersion()
+	s.registerDiffArtifactVersions()
+	s.registerLinkArtifact()
+	s.registerUnlinkArtifact()
 	s.registerListArtifacts()
 	s.registerSearchArtifacts()
+	s.registerRebuildSearchIndex()
+	s.registerCompactArtifacts()
 	s.registerDeleteArtifact()
 
+	// Memory management
+	s.registerCreateMemory()
+	s.registerGetMemory()
+	s.registerListMemories()
+	s.registerUpdateMemory()
+	s.registerDeleteMemory()
+
 	// Workspace/File operations
 	s.registerReadFile()
 	s.registerListFiles()
 	s.registerSearchFiles()
 }
 
+// addTool registers a tool, unless it changes the store and the store is
+// read-only.
+func (s *Server) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if s.readOnly && mutates(tool.Name) {
+		return
+	}
+	s.mcpServer.AddTool(tool, handler)
+}
+
 // Project tool registrations
 
 func (s *Server) registerCreateProject() {
@@ -82,7 +173,8 @@ WORKFLOW GUIDANCE:
 - Create a project for each repository/codebase you work with
 - Set workspace_path to the repository root for file operations
 - Use meaningful IDs like 'myapp-backend' or 'frontend-v2'
-- Projects persist between sessions - reuse existing ones`),
+- Projects persist between sessions - reuse existing ones
+- Set workflow for custom task statuses (e.g. review, blocked); update_task enforces its transitions`),
 		mcp.WithString("id",
 			mcp.Required(),
 			mcp.Description("Unique project identifier, like 'myapp' or 'backend-api'. Will be normalized to lowercase with dashes."),
@@ -96,24 +188,33 @@ WORKFLOW GUIDANCE:
 		mcp.WithString("workspace_path",
 			mcp.Description("Absolute path to the workspace/repository root directory for file operations."),
 		),
+		mcp.WithObject("workflow",
+			mcp.Description(`Task workflow: {"statuses": [{"name": "todo", "transitions": ["doing"]}, {"name": "doing", "transitions": ["review", "todo"]}, {"name": "review", "transitions": ["done", "doing"]}, {"name": "done", "terminal": true}]}. The first status is given to new tasks; empty transitions allow any status; terminal statuses are final. Default: open, in_progress, completed, archived with any transition.`),
+		),
 		mcp.WithObject("metadata",
 			mcp.Description("Additional key-value metadata for the project."),
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleCreateProject)
+	s.addTool(tool, s.handleCreateProject)
 }
 
 func (s *Server) registerGetProject() {
 	tool := mcp.NewTool("get_project",
-		mcp.WithDescription("Get details about a specific project including workspace path and task count."),
+		mcp.WithDescription(`Get details about a specific project including workspace path and task count.
+
+Call it with include_memories=true at the start of a session: the response then carries the project's
+pinned memories and the pinned global ones (conventions, architecture notes, user preferences).`),
 		mcp.WithString("id",
 			mcp.Required(),
 			mcp.Description("The project identifier."),
 		),
+		mcp.WithBoolean("include_memories",
+			mcp.Description("Include the pinned project and global memories (default: false)."),
+		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleGetProject)
+	s.addTool(tool, s.handleGetProject)
 }
 
 func (s *Server) registerListProjects() {
@@ -131,12 +232,12 @@ PAGINATION: Use limit/offset for large project lists. Response includes total co
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleListProjects)
+	s.addTool(tool, s.handleListProjects)
 }
 
 func (s *Server) registerUpdateProject() {
 	tool := mcp.NewTool("update_project",
-		mcp.WithDescription("Update project details like name, description, or workspace."),
+		mcp.WithDescription("Update project details like name, description, workspace, or task workflow. Changing the workflow does not change existing tasks; a task whose status is no longer in the workflow can move to any of its statuses."),
 		mcp.WithString("id",
 			mcp.Required(),
 			mcp.Description("The project identifier."),
@@ -150,12 +251,18 @@ func (s *Server) registerUpdateProject() {
 		mcp.WithString("workspace_path",
 			mcp.Description("New workspace path for file operations."),
 		),
+		mcp.WithObject("workflow",
+			mcp.Description(`Task workflow: {"statuses": [{"name": "todo", "transitions": ["doing"]}, {"name": "doing", "transitions": ["review", "todo"]}, {"name": "review", "transitions": ["done", "doing"]}, {"name": "done", "terminal": true}]}. The first status is given to new tasks; empty transitions allow any status; terminal statuses are final. Default: open, in_progress, completed, archived with any transition.`),
+		),
 		mcp.WithObject("metadata",
 			mcp.Description("New metadata for the project."),
 		),
+		mcp.WithNumber("expected_revision",
+			mcp.Description("The revision the change is based on, as returned by get_project. If the project has changed since, nothing is updated and the current project is returned to merge with."),
+		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleUpdateProject)
+	s.addTool(tool, s.handleUpdateProject)
 }
 
 func (s *Server) registerDeleteProject() {
@@ -167,7 +274,7 @@ func (s *Server) registerDeleteProject() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleDeleteProject)
+	s.addTool(tool, s.handleDeleteProject)
 }
 
 // Task tool registrations
@@ -180,7 +287,8 @@ WORKFLOW GUIDANCE:
 - Create one task per user request/feature/bug
 - Use descriptive IDs: 'add-auth-middleware', 'fix-login-bug', 'investigate-perf-issue'
 - Task persists all work artifacts - use it to restore context later
-- When resuming work, list_artifacts to see what was done before`),
+- When resuming work, list_artifacts to see what was done before
+- Split large work into subtasks with parent_id; get_task_tree shows progress`),
 		mcp.WithString("project_id",
 			mcp.Required(),
 			mcp.Description("The project identifier to create the task in."),
@@ -198,12 +306,26 @@ WORKFLOW GUIDANCE:
 		mcp.WithString("workspace_path",
 			mcp.Description("Absolute path to workspace (overrides project workspace)."),
 		),
+		mcp.WithString("parent_id",
+			mcp.Description("Make this a subtask of another task in the same project."),
+		),
+		mcp.WithString("priority",
+			mcp.Description("Priority from 'p0' (most urgent) to 'p3'. Default: 'p2'."),
+			mcp.Enum("p0", "p1", "p2", "p3"),
+		),
+		mcp.WithString("due_date",
+			mcp.Description("Due date as YYYY-MM-DD (end of that day, UTC) or RFC 3339."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Labels like 'auth' or 'tech-debt' for filtering. Lowercased; spaces become dashes."),
+			mcp.WithStringItems(),
+		),
 		mcp.WithObject("metadata",
 			mcp.Description("Additional key-value metadata for the task."),
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleCreateTask)
+	s.addTool(tool, s.handleCreateTask)
 }
 
 func (s *Server) registerGetTask() {
@@ -219,16 +341,20 @@ func (s *Server) registerGetTask() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleGetTask)
+	s.addTool(tool, s.handleGetTask)
 }
 
 func (s *Server) registerListTasks() {
 	tool := mcp.NewTool("list_tasks",
-		mcp.WithDescription(`List all tasks in a project, sorted by most recently updated.
+		mcp.WithDescription(`List all tasks in a project, sorted by most recently updated unless sort is set.
 
 Use this to find previous work and restore context. The most recently updated task is likely the one to continue.
 
 FILTERING: Use status parameter to filter by task status (open, in_progress, completed, archived).
+SUBTASKS: Use parent_id for direct subtasks of a task, descendants_of for subtasks at any depth.
+TAGS: Use tags with tag_match 'any' (default) or 'all'; list_tags shows the tags in use.
+SORTING: Use sort for 'priority', 'due_date' or 'stale' order instead of most recently updated.
+AVAILABILITY: Use available to leave out tasks another agent has claimed (claim_task) and not released.
 PAGINATION: Use limit/offset for large task lists. Response includes total count and has_more flag.
 
 NOTE: Task directories use kanban-style naming like [completed]-fix-login-bug for visual organization.`),
@@ -239,6 +365,27 @@ NOTE: Task directories use kanban-style naming like [completed]-fix-login-bug fo
 		mcp.WithString("status",
 			mcp.Description("Filter by status: 'open', 'in_progress', 'completed', 'archived'. Empty = all."),
 		),
+		mcp.WithString("parent_id",
+			mcp.Description("Only direct subtasks of this task."),
+		),
+		mcp.WithString("descendants_of",
+			mcp.Description("Only subtasks of this task, at any depth."),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Order: 'updated' (default, most recent first), 'priority' (p0 first), 'due_date' (earliest first, none last) or 'stale' (least recently updated first)."),
+			mcp.Enum("updated", "priority", "due_date", "stale"),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Only tasks with these tags."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("tag_match",
+			mcp.Description("How tags are matched: 'any' (default) or 'all'."),
+			mcp.Enum("any", "all"),
+		),
+		mcp.WithBoolean("available",
+			mcp.Description("Only tasks nobody holds an unexpired claim on (default: false)."),
+		),
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum number of tasks to return (default: 50)."),
 		),
@@ -247,23 +394,41 @@ NOTE: Task directories use kanban-style naming like [completed]-fix-login-bug fo
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleListTasks)
+	s.addTool(tool, s.handleListTasks)
 }
 
 func (s *Server) registerListAllTasks() {
 	tool := mcp.NewTool("list_all_tasks",
-		mcp.WithDescription(`List all tasks from ALL projects, sorted by most recently updated.
+		mcp.WithDescription(`List all tasks from ALL projects, sorted by most recently updated unless sort is set.
 
 Use this to get a global view of all work across all projects. Helpful for:
 - Finding recent work across multiple projects
 - Getting an overview of all ongoing tasks
 - Discovering tasks you may have forgotten about
 
-FILTERING: Use status parameter to filter by task status (open, in_progress, completed, archived).
+FILTERING: Use status parameter to filter by task status (open, in_progress, completed, archived),
+and tags with tag_match 'any' (default) or 'all'.
+SORTING: Use sort for 'priority', 'due_date' or 'stale' order instead of most recently updated.
+AVAILABILITY: Use available to leave out tasks another agent has claimed (claim_task) and not released.
 PAGINATION: Use limit/offset for large task lists. Response includes total count and has_more flag.`),
 		mcp.WithString("status",
 			mcp.Description("Filter by status: 'open', 'in_progress', 'completed', 'archived'. Empty = all."),
 		),
+		mcp.WithString("sort",
+			mcp.Description("Order: 'updated' (default, most recent first), 'priority' (p0 first), 'due_date' (earliest first, none last) or 'stale' (least recently updated first)."),
+			mcp.Enum("updated", "priority", "due_date", "stale"),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Only tasks with these tags."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("tag_match",
+			mcp.Description("How tags are matched: 'any' (default) or 'all'."),
+			mcp.Enum("any", "all"),
+		),
+		mcp.WithBoolean("available",
+			mcp.Description("Only tasks nobody holds an unexpired claim on (default: false)."),
+		),
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum number of tasks to return (default: 50)."),
 		),
@@ -272,16 +437,16 @@ PAGINATION: Use limit/offset for large task lists. Response includes total count
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleListAllTasks)
+	s.addTool(tool, s.handleListAllTasks)
 }
 
 func (s *Server) registerUpdateTask() {
 	tool := mcp.NewTool("update_task",
 		mcp.WithDescription(`Update task details like name, description, workspace, or status.
 
-STATUS WORKFLOW:
+STATUS WORKFLOW (default; projects can define their own statuses and transitions, see get_project):
 - 'open': Task created, not started
-- 'in_progress': Actively working on it (set when you start)
+- 'in_progress': Actively working on it (set when you start; refused while blockers are not completed)
 - 'completed': Work finished successfully (save final summary artifact first!)
 - 'archived': Old task, kept for reference
 
@@ -307,14 +472,37 @@ IMPORTANT: Before marking 'completed', save a summary artifact with:
 			mcp.Description("New workspace path for file operations."),
 		),
 		mcp.WithString("status",
-			mcp.Description("New status: 'open', 'in_progress', 'completed', or 'archived'."),
+			mcp.Description("New status: 'open', 'in_progress', 'completed', or 'archived', or a status of the project's workflow. Disallowed transitions are rejected."),
+		),
+		mcp.WithString("parent_id",
+			mcp.Description("Move the task under another task in the same project. Empty string makes it top level."),
+		),
+		mcp.WithString("priority",
+			mcp.Description("New priority from 'p0' (most urgent) to 'p3'."),
+			mcp.Enum("p0", "p1", "p2", "p3"),
+		),
+		mcp.WithString("due_date",
+			mcp.Description("New due date as YYYY-MM-DD or RFC 3339. Empty string removes it."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Replaces the task's tags. An empty list removes them."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("reason",
+			mcp.Description("Why the status is changing, e.g. 'waiting for API review'. Recorded in the task history."),
+		),
+		mcp.WithString("actor",
+			mcp.Description("Who is making the change. Defaults to the MCP client name."),
 		),
 		mcp.WithObject("metadata",
 			mcp.Description("New metadata for the task."),
 		),
+		mcp.WithNumber("expected_revision",
+			mcp.Description("The revision the change is based on, as returned by get_task. If the task has changed since, e.g. by another agent, nothing is updated and the current task is returned to merge with."),
+		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleUpdateTask)
+	s.addTool(tool, s.handleUpdateTask)
 }
 
 func (s *Server) registerDeleteTask() {
@@ -330,7 +518,262 @@ func (s *Server) registerDeleteTask() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleDeleteTask)
+	s.addTool(tool, s.handleDeleteTask)
+}
+
+func (s *Server) registerGetTaskTree() {
+	tool := mcp.NewTool("get_task_tree",
+		mcp.WithDescription(`Get the subtask hierarchy of a task, or of the whole project.
+
+Each node includes its children and a rollup of the statuses of every task below it,
+e.g. "3/5 subtasks completed". Children are ordered by creation time.
+
+Use this to see how far a large piece of work has progressed and which subtasks remain.`),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Description("Root task of the tree. Empty = every top-level task in the project."),
+		),
+	)
+
+	s.addTool(tool, s.handleGetTaskTree)
+}
+
+func (s *Server) registerGetTaskHistory() {
+	tool := mcp.NewTool("get_task_history",
+		mcp.WithDescription(`Get the status history of a task: every transition with its time, reason and actor,
+plus the total time spent in each status (e.g. how long it sat in 'in_progress' across sessions).
+
+History is append-only and recorded from task creation. Pass reason to update_task to explain a change.`),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+	)
+
+	s.addTool(tool, s.handleGetTaskHistory)
+}
+
+func (s *Server) registerAddDependency() {
+	tool := mcp.NewTool("add_dependency",
+		mcp.WithDescription(`Record that a task cannot start until another task is completed.
+
+The blocking task may be in another project (set blocked_by_project_id).
+Both tasks are updated: the task lists it in blocked_by, the blocker lists the task in blocks.
+Dependencies that would form a cycle are rejected.
+
+WORKFLOW GUIDANCE:
+- Use list_ready_tasks to pick the next unblocked piece of work
+- update_task refuses 'in_progress', or any other status that starts work in a custom workflow, while any blocker is not completed`),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("Project of the blocked task."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The blocked task."),
+		),
+		mcp.WithString("blocked_by_task_id",
+			mcp.Required(),
+			mcp.Description("The task that must be completed first."),
+		),
+		mcp.WithString("blocked_by_project_id",
+			mcp.Description("Project of the blocking task (default: same project)."),
+		),
+	)
+
+	s.addTool(tool, s.handleAddDependency)
+}
+
+func (s *Server) registerRemoveDependency() {
+	tool := mcp.NewTool("remove_dependency",
+		mcp.WithDescription("Remove a dependency added with add_dependency. Removing a dependency that does not exist is a no-op."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("Project of the blocked task."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The blocked task."),
+		),
+		mcp.WithString("blocked_by_task_id",
+			mcp.Required(),
+			mcp.Description("The blocking task."),
+		),
+		mcp.WithString("blocked_by_project_id",
+			mcp.Description("Project of the blocking task (default: same project)."),
+		),
+	)
+
+	s.addTool(tool, s.handleRemoveDependency)
+}
+
+func (s *Server) registerListReadyTasks() {
+	tool := mcp.NewTool("list_ready_tasks",
+		mcp.WithDescription(`List open tasks whose blockers are all completed, sorted by most recently updated.
+
+Use this to pick the next piece of work without triaging: every task returned can be
+moved to 'in_progress' right away. Blockers that were deleted no longer count, and tasks
+another agent has claimed (claim_task) are left out until the claim is released or expires.
+
+PAGINATION: Use limit/offset for large task lists. Response includes total count and has_more flag.`),
+		mcp.WithString("project_id",
+			mcp.Description("Only tasks in this project. Empty = all projects."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of tasks to return (default: 50)."),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of tasks to skip for pagination (default: 0)."),
+		),
+	)
+
+	s.addTool(tool, s.handleListReadyTasks)
+}
+
+func (s *Server) registerSuggestNextTask() {
+	tool := mcp.NewTool("suggest_next_task",
+		mcp.WithDescription(`Suggest what to work on next: ranks open and in-progress tasks across projects and explains each score. Tasks another agent has claimed are left out.
+
+Score = priority (p0 +40, p1 +30, p2 +20, p3 +10)
+      + due date (overdue +30, within a day +25, 3 days +20, a week +10)
+      + readiness (no open blockers +10, blocked -50)
+      + already started +15
+      + recency (updated today +10, this week +5)
+
+WORKFLOW GUIDANCE:
+- Call this at the start of a session instead of grabbing the most recently touched task
+- Set priority and due_date on tasks (create_task/update_task) so the ranking reflects what matters
+- Claim the task you pick with claim_task when other agents share the project
+- Then resume the suggested task with resume_task`),
+		mcp.WithString("project_id",
+			mcp.Description("Only tasks in this project. Empty = all projects."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of suggestions to return (default: 5)."),
+		),
+	)
+
+	s.addTool(tool, s.handleSuggestNextTask)
+}
+
+func (s *Server) registerClaimTask() {
+	tool := mcp.NewTool("claim_task",
+		mcp.WithDescription(`Claim a task so other agents sharing the project leave it alone.
+
+The claim records who works on the task (owner) and lasts for a lease. While it holds,
+claim_task by anyone else fails, and list_tasks/list_all_tasks with available, list_ready_tasks
+and suggest_next_task leave the task out. A claim that is not renewed before it expires
+lapses, and the task can be claimed again, so a crashed agent does not hold it forever.
+
+WORKFLOW GUIDANCE:
+- Claim a task before starting on it when several agents work on the same project
+- Call renew_claim while still working, well before expires_at
+- Call release_task when done or when giving the task up`),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithString("owner",
+			mcp.Description("Who claims the task, unique among the agents sharing the store, e.g. 'claude-code@laptop:4711'. Defaults to the MCP client name, which instances of the same client share."),
+		),
+		mcp.WithNumber("lease_minutes",
+			mcp.Description("How long the claim lasts without renewal (default: 30, at most 1440)."),
+		),
+	)
+
+	s.addTool(tool, s.handleClaimTask)
+}
+
+func (s *Server) registerRenewClaim() {
+	tool := mcp.NewTool("renew_claim",
+		mcp.WithDescription("Extend your claim on a task by another lease, counted from now. An expired claim can be renewed as long as nobody else claimed the task meanwhile."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithString("owner",
+			mcp.Description("The owner the task was claimed with. Defaults to the MCP client name."),
+		),
+		mcp.WithNumber("lease_minutes",
+			mcp.Description("How long the claim lasts from now (default: 30, at most 1440)."),
+		),
+	)
+
+	s.addTool(tool, s.handleRenewClaim)
+}
+
+func (s *Server) registerReleaseTask() {
+	tool := mcp.NewTool("release_task",
+		mcp.WithDescription("Release your claim on a task so other agents can pick it up. Releasing a task without a claim is a no-op; another agent's unexpired claim cannot be released."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithString("owner",
+			mcp.Description("The owner the task was claimed with. Defaults to the MCP client name."),
+		),
+	)
+
+	s.addTool(tool, s.handleReleaseTask)
+}
+
+func (s *Server) registerResumeTask() {
+	tool := mcp.NewTool("resume_task",
+		mcp.WithDescription(`Load everything needed to continue a task in one call, cut to a token budget.
+
+Returns the task, its project, the pinned project and global memories, and the task's artifacts grouped as decisions, open questions (artifacts tagged "question" or "open-question" that nothing supersedes), notes and other. Items are ranked by weight (pinned memories, then decisions, questions, notes, code and references, with file logs last) times recency, and added best first. An item larger than a quarter of the budget is cut short ("truncated": true); items that no longer fit are listed under "omitted" with their size.
+
+WORKFLOW GUIDANCE:
+- Call this instead of get_task + list_artifacts when picking up a task
+- Fetch truncated or omitted artifacts with get_artifact, memories with get_memory
+- Token counts are estimates (about 4 characters per token)`),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithNumber("token_budget",
+			mcp.Description("Approximate size of the response in tokens (default: 4000, minimum: 500)."),
+		),
+	)
+
+	s.addTool(tool, s.handleResumeTask)
+}
+
+func (s *Server) registerListTags() {
+	tool := mcp.NewTool("list_tags",
+		mcp.WithDescription(`List the tags used on tasks and artifacts, with how many of each carry the tag, most used first.
+
+Use this to discover the vocabulary of a project before filtering list_tasks, list_all_tasks,
+list_artifacts or search_artifacts by tags, and to reuse existing tags instead of inventing new ones.`),
+		mcp.WithString("project_id",
+			mcp.Description("Only tags used in this project. Empty = all projects."),
+		),
+	)
+
+	s.addTool(tool, s.handleListTags)
 }
 
 // Artifact tool registrations
@@ -391,17 +834,51 @@ When continuing work, reference previous artifacts to create a traceable chain o
 		mcp.WithString("type",
 			mcp.Description("Artifact type: 'note', 'code', 'decision', 'discussion', 'reference', or 'artifact' (default)."),
 		),
+		mcp.WithArray("tags",
+			mcp.Description("Labels like 'auth' or 'tech-debt' for filtering. Lowercased; spaces become dashes."),
+			mcp.WithStringItems(),
+		),
 		mcp.WithObject("metadata",
 			mcp.Description("Additional metadata for the artifact."),
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSaveArtifact)
+	s.addTool(tool, s.handleSaveArtifact)
 }
 
 func (s *Server) registerGetArtifact() {
 	tool := mcp.NewTool("get_artifact",
-		mcp.WithDescription("Get a specific artifact from a task."),
+		mcp.WithDescription("Get a specific artifact from a task. Returns the latest version; use get_artifact_version for older ones."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithString("artifact_id",
+			mcp.Required(),
+			mcp.Description("The artifact identifier (timestamp)."),
+		),
+		mcp.WithBoolean("include_links",
+			mcp.Description("Also return the artifacts and tasks it links to, and the artifacts superseding it, one hop away (default: false)."),
+		),
+	)
+
+	s.addTool(tool, s.handleGetArtifact)
+}
+
+func (s *Server) registerUpdateArtifact() {
+	tool := mcp.NewTool("update_artifact",
+		mcp.WithDescription(`Update an existing artifact instead of saving a new one. The previous content is kept as an older version.
+
+WHEN TO USE:
+- A decision was refined: update the decision artifact so there is one current answer
+- A progress note or summary is kept up to date over the course of a task
+
+Omitted fields are left unchanged. The artifact keeps its ID and type; its version number goes up by one.
+Use list_artifact_versions and diff_artifact_versions to see how it changed.`),
 		mcp.WithString("project_id",
 			mcp.Required(),
 			mcp.Description("The project identifier."),
@@ -414,9 +891,166 @@ func (s *Server) registerGetArtifact() {
 			mcp.Required(),
 			mcp.Description("The artifact identifier (timestamp)."),
 		),
+		mcp.WithString("content",
+			mcp.Description("New content (markdown supported). Replaces the whole content."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Replace the tags. Empty array removes them."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithObject("metadata",
+			mcp.Description("Replace the metadata."),
+		),
+	)
+
+	s.addTool(tool, s.handleUpdateArtifact)
+}
+
+func (s *Server) registerListArtifactVersions() {
+	tool := mcp.NewTool("list_artifact_versions",
+		mcp.WithDescription("List the versions of an artifact, oldest first. The last one is the current version returned by get_artifact."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithString("artifact_id",
+			mcp.Required(),
+			mcp.Description("The artifact identifier (timestamp)."),
+		),
+	)
+
+	s.addTool(tool, s.handleListArtifactVersions)
+}
+
+func (s *Server) registerGetArtifactVersion() {
+	tool := mcp.NewTool("get_artifact_version",
+		mcp.WithDescription("Get a specific version of an artifact, e.g. to see what a decision said before it was revised."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithString("artifact_id",
+			mcp.Required(),
+			mcp.Description("The artifact identifier (timestamp)."),
+		),
+		mcp.WithNumber("version",
+			mcp.Required(),
+			mcp.Description("Version number, 1 being the original content."),
+		),
+	)
+
+	s.addTool(tool, s.handleGetArtifactVersion)
+}
+
+func (s *Server) registerDiffArtifactVersions() {
+	tool := mcp.NewTool("diff_artifact_versions",
+		mcp.WithDescription("Show how an artifact's content changed between two versions, as a unified diff. By default compares the current version with the one before it."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project identifier."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task identifier."),
+		),
+		mcp.WithString("artifact_id",
+			mcp.Required(),
+			mcp.Description("The artifact identifier (timestamp)."),
+		),
+		mcp.WithNumber("from",
+			mcp.Description("Older version (default: the version before 'to')."),
+		),
+		mcp.WithNumber("to",
+			mcp.Description("Newer version (default: the current version)."),
+		),
+	)
+
+	s.addTool(tool, s.handleDiffArtifactVersions)
+}
+
+func (s *Server) registerLinkArtifact() {
+	tool := mcp.NewTool("link_artifact",
+		mcp.WithDescription(`Link an artifact to another artifact or to a task, in any project. Links are stored in the artifact's frontmatter.
+
+RELATIONS:
+- 'implements': a code artifact implementing a decision
+- 'derived_from': a reference or note that came out of another task or artifact
+- 'relates_to': loosely related context
+- 'supersedes': this artifact replaces the target artifact. The target is hidden from list_artifacts and
+  search_artifacts unless include_superseded=true, so outdated decisions stop resurfacing
+
+Use get_artifact with include_links=true to follow links one hop.`),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project of the artifact the link starts from."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task of the artifact the link starts from."),
+		),
+		mcp.WithString("artifact_id",
+			mcp.Required(),
+			mcp.Description("The artifact the link starts from."),
+		),
+		mcp.WithString("relation",
+			mcp.Required(),
+			mcp.Description("Link type."),
+			mcp.Enum("supersedes", "implements", "relates_to", "derived_from"),
+		),
+		mcp.WithString("target_project_id",
+			mcp.Description("Project of the target (default: same project)."),
+		),
+		mcp.WithString("target_task_id",
+			mcp.Description("Task of the target (default: same task)."),
+		),
+		mcp.WithString("target_artifact_id",
+			mcp.Description("Target artifact. Leave empty to link to the target task itself."),
+		),
+	)
+
+	s.addTool(tool, s.handleLinkArtifact)
+}
+
+func (s *Server) registerUnlinkArtifact() {
+	tool := mcp.NewTool("unlink_artifact",
+		mcp.WithDescription("Remove a link added with link_artifact. Removing a 'supersedes' link shows the target artifact in listings again."),
+		mcp.WithString("project_id",
+			mcp.Required(),
+			mcp.Description("The project of the artifact the link starts from."),
+		),
+		mcp.WithString("task_id",
+			mcp.Required(),
+			mcp.Description("The task of the artifact the link starts from."),
+		),
+		mcp.WithString("artifact_id",
+			mcp.Required(),
+			mcp.Description("The artifact the link starts from."),
+		),
+		mcp.WithString("relation",
+			mcp.Required(),
+			mcp.Description("Link type."),
+			mcp.Enum("supersedes", "implements", "relates_to", "derived_from"),
+		),
+		mcp.WithString("target_project_id",
+			mcp.Description("Project of the target (default: same project)."),
+		),
+		mcp.WithString("target_task_id",
+			mcp.Description("Task of the target (default: same task)."),
+		),
+		mcp.WithString("target_artifact_id",
+			mcp.Description("Target artifact. Leave empty to link to the target task itself."),
+		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleGetArtifact)
+	s.addTool(tool, s.handleUnlinkArtifact)
 }
 
 func (s *Server) registerListArtifacts() {
@@ -431,6 +1065,7 @@ CONTEXT RESTORATION WORKFLOW:
 
 The artifact chain tells the story of the work - use it to resume exactly where you left off.
 
+FILTERING: Use tags with tag_match 'any' (default) or 'all'. Superseded artifacts are hidden unless include_superseded=true.
 PAGINATION: Use limit/offset for tasks with many artifacts. Response includes total count and has_more flag.`),
 		mcp.WithString("project_id",
 			mcp.Required(),
@@ -440,6 +1075,17 @@ PAGINATION: Use limit/offset for tasks with many artifacts. Response includes to
 			mcp.Required(),
 			mcp.Description("The task identifier."),
 		),
+		mcp.WithArray("tags",
+			mcp.Description("Only artifacts with these tags."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("tag_match",
+			mcp.Description("How tags are matched: 'any' (default) or 'all'."),
+			mcp.Enum("any", "all"),
+		),
+		mcp.WithBoolean("include_superseded",
+			mcp.Description("Include artifacts superseded by another artifact (default: false)."),
+		),
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum number of artifacts to return (default: 50)."),
 		),
@@ -448,7 +1094,7 @@ PAGINATION: Use limit/offset for tasks with many artifacts. Response includes to
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleListArtifacts)
+	s.addTool(tool, s.handleListArtifacts)
 }
 
 func (s *Server) registerSearchArtifacts() {
@@ -461,10 +1107,32 @@ Use this to find relevant past work:
 - Locate code patterns you've implemented
 - Discover related work across different tasks
 
+QUERY SYNTAX:
+- auth token: artifacts containing both words
+- "token refresh": the exact phrase
+- auth*: any word starting with "auth"
+- -word or -"phrase": exclude artifacts containing it
+- type:decision or type:note,code: artifact type
+- project:backend, task:fix-login: owning project / task
+- status:in_progress: status of the owning task
+- tag:auth,security: artifact tags (any of them; repeat tag: to require several)
+- created:>2026-01-01 (also >=, <, <=, or an exact day): creation date, YYYY-MM-DD or RFC 3339
+- meta.file_path:*.go: metadata value, * and ? are wildcards
+Any filter can be negated with "-", e.g. -type:search. Combine filters to pull only the context you need.
+
+Results are ranked by relevance (BM25), best first; each result has a score and up to 3 snippets
+centered on the matched text. Each snippet has its line number, byte offset in the content, and
+highlights as byte ranges within the snippet text - use them to judge relevance before calling get_artifact.
+Artifacts superseded by another artifact are hidden unless include_superseded=true.
+
+MEMORIES: The first page also lists up to 5 matching memories (the project's and the global ones) in
+a separate "memories" field. Only free text, project: and tag: apply to memories; a search limited to
+a task or filtered on anything else finds none. Set include_memories=false to skip them.
+
 PAGINATION: Use limit/offset for large result sets. Response includes total count and has_more flag.`),
 		mcp.WithString("query",
 			mcp.Required(),
-			mcp.Description("Search query to find in artifact content."),
+			mcp.Description("Search query: free text (\"phrases\", prefix*, -excluded) and filters like type:decision status:in_progress created:>2026-01-01 meta.file_path:*.go."),
 		),
 		mcp.WithString("project_id",
 			mcp.Description("Optional: limit search to a specific project."),
@@ -472,6 +1140,20 @@ PAGINATION: Use limit/offset for large result sets. Response includes total coun
 		mcp.WithString("task_id",
 			mcp.Description("Optional: limit search to a specific task (requires project_id)."),
 		),
+		mcp.WithArray("tags",
+			mcp.Description("Only artifacts with these tags."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("tag_match",
+			mcp.Description("How tags are matched: 'any' (default) or 'all'."),
+			mcp.Enum("any", "all"),
+		),
+		mcp.WithBoolean("include_superseded",
+			mcp.Description("Include artifacts superseded by another artifact (default: false)."),
+		),
+		mcp.WithBoolean("include_memories",
+			mcp.Description("List matching memories on the first page (default: true)."),
+		),
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum number of results to return (default: 50)."),
 		),
@@ -480,7 +1162,52 @@ PAGINATION: Use limit/offset for large result sets. Response includes total coun
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSearchArtifacts)
+	s.addTool(tool, s.handleSearchArtifacts)
+}
+
+func (s *Server) registerRebuildSearchIndex() {
+	tool := mcp.NewTool("rebuild_search_index",
+		mcp.WithDescription(`Rebuild the artifact and memory search index from storage.
+
+The index is kept up to date automatically. Only use this when artifact or memory files were added, edited or removed outside the server and search_artifacts returns stale results.`),
+	)
+
+	s.addTool(tool, s.handleRebuildSearchIndex)
+}
+
+func (s *Server) registerCompactArtifacts() {
+	tool := mcp.NewTool("compact_artifacts",
+		mcp.WithDescription(`Roll old artifacts of each task into a single "summary" artifact.
+
+By default only the file_read, file_list and search logs saved by the workspace tools are compacted, once they are older than 7 days. The summary is written by the client's model through MCP sampling when the client supports it, otherwise (or if sampling fails) it lists the files read, directories listed and searches run, with counts.
+
+The originals are not deleted: the summary supersedes them, so they disappear from list_artifacts and search_artifacts but get_artifact still returns them, and unlink_artifact on the summary restores them.
+
+WORKFLOW GUIDANCE:
+- Run with dry_run=true first to see what would be compacted
+- Use on long-running tasks whose artifact lists are dominated by logs`),
+		mcp.WithString("project_id",
+			mcp.Description("Only tasks in this project. Empty = all projects."),
+		),
+		mcp.WithString("task_id",
+			mcp.Description("Only this task (requires project_id)."),
+		),
+		mcp.WithNumber("older_than_days",
+			mcp.Description("Only artifacts last written more than this many days ago (default: 7)."),
+		),
+		mcp.WithArray("types",
+			mcp.Description("Artifact types to compact (default: file_read, file_list, search). Summaries are never compacted."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Report what would be compacted, with an extractive preview of each summary, without saving anything (default: false)."),
+		),
+		mcp.WithBoolean("use_sampling",
+			mcp.Description("Summarize with the client's model when it supports sampling (default: true)."),
+		),
+	)
+
+	s.addTool(tool, s.handleCompactArtifacts)
 }
 
 func (s *Server) registerDeleteArtifact() {
@@ -500,7 +1227,131 @@ func (s *Server) registerDeleteArtifact() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleDeleteArtifact)
+	s.addTool(tool, s.handleDeleteArtifact)
+}
+
+// Memory tool registrations
+
+func (s *Server) registerCreateMemory() {
+	tool := mcp.NewTool("create_memory",
+		mcp.WithDescription(`Save long-lived knowledge that does not belong to a single task.
+
+WHEN TO USE:
+- Coding conventions, architecture notes, how to run the tests: a project memory
+- User preferences that apply everywhere: a global memory (omit project_id)
+
+Pin the memories every session should start with; get_project with include_memories=true returns them.
+Memories are found by search_artifacts. Use update_memory to change one instead of creating another.`),
+		mcp.WithString("project_id",
+			mcp.Description("The project the memory belongs to. Omit for a global memory shared by every project."),
+		),
+		mcp.WithString("id",
+			mcp.Description("Memory identifier like 'coding-conventions'. Defaults to the title, normalized to lowercase with dashes."),
+		),
+		mcp.WithString("title",
+			mcp.Required(),
+			mcp.Description("Short title, e.g. 'Coding conventions'."),
+		),
+		mcp.WithString("content",
+			mcp.Required(),
+			mcp.Description("The memory content (markdown supported)."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Tags for filtering, e.g. ['style', 'testing']."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("pinned",
+			mcp.Description("Load the memory at session start (default: false)."),
+		),
+	)
+
+	s.addTool(tool, s.handleCreateMemory)
+}
+
+func (s *Server) registerGetMemory() {
+	tool := mcp.NewTool("get_memory",
+		mcp.WithDescription("Get a project or global memory."),
+		mcp.WithString("project_id",
+			mcp.Description("The project identifier. Omit for a global memory."),
+		),
+		mcp.WithString("memory_id",
+			mcp.Required(),
+			mcp.Description("The memory identifier."),
+		),
+	)
+
+	s.addTool(tool, s.handleGetMemory)
+}
+
+func (s *Server) registerListMemories() {
+	tool := mcp.NewTool("list_memories",
+		mcp.WithDescription(`List the memories of a project, or the global memories when project_id is omitted.
+Pinned memories come first, then the most recently updated.
+
+PAGINATION: Use limit/offset for large result sets. Response includes total count and has_more flag.`),
+		mcp.WithString("project_id",
+			mcp.Description("The project identifier. Omit to list global memories."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Only memories with these tags."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("tag_match",
+			mcp.Description("How tags are matched: 'any' (default) or 'all'."),
+			mcp.Enum("any", "all"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of memories to return (default: 50)."),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of memories to skip for pagination (default: 0)."),
+		),
+	)
+
+	s.addTool(tool, s.handleListMemories)
+}
+
+func (s *Server) registerUpdateMemory() {
+	tool := mcp.NewTool("update_memory",
+		mcp.WithDescription(`Update a memory in place. Omitted fields are left unchanged; memories keep no version history.`),
+		mcp.WithString("project_id",
+			mcp.Description("The project identifier. Omit for a global memory."),
+		),
+		mcp.WithString("memory_id",
+			mcp.Required(),
+			mcp.Description("The memory identifier."),
+		),
+		mcp.WithString("title",
+			mcp.Description("New title."),
+		),
+		mcp.WithString("content",
+			mcp.Description("New content (markdown supported). Replaces the whole content."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Replace the tags. Empty array removes them."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("pinned",
+			mcp.Description("Pin or unpin the memory."),
+		),
+	)
+
+	s.addTool(tool, s.handleUpdateMemory)
+}
+
+func (s *Server) registerDeleteMemory() {
+	tool := mcp.NewTool("delete_memory",
+		mcp.WithDescription("Delete a project or global memory."),
+		mcp.WithString("project_id",
+			mcp.Description("The project identifier. Omit for a global memory."),
+		),
+		mcp.WithString("memory_id",
+			mcp.Required(),
+			mcp.Description("The memory identifier."),
+		),
+	)
+
+	s.addTool(tool, s.handleDeleteMemory)
 }
 
 // Workspace/File operation registrations
@@ -533,7 +1384,7 @@ Use log_read=true for important files you're analyzing, false for quick lookups.
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleReadFile)
+	s.addTool(tool, s.handleReadFile)
 }
 
 func (s *Server) registerListFiles() {
@@ -564,13 +1415,16 @@ func (s *Server) registerListFiles() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleListFiles)
+	s.addTool(tool, s.handleListFiles)
 }
 
 func (s *Server) registerSearchFiles() {
 	tool := mcp.NewTool("search_files",
 		mcp.WithDescription(`Search for text in files within the task's workspace. The search can be logged as an artifact.
 
+Each match has the line number, the line (a window centered on the match for long lines), its byte
+offset in the file, and highlights as byte ranges within the line.
+
 WHY LOG SEARCHES:
 When log_search=true, the search query and results are saved. This helps:
 - Remember what you were looking for
@@ -604,5 +1458,5 @@ Log important searches that reveal codebase understanding.`),
 		),
 	)
 
-	s.mcpServer.AddTool(tool, s.handleSearchFiles)
+	s.addTool(tool, s.handleSearchFiles)
 }
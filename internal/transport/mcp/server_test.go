@@ -2,17 +2,28 @@ package mcp
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/mark3labs/mcp-go/client"
+	clienttransport "github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 
 	"agent-memory/internal/application/service"
 	"agent-memory/internal/domain/task"
 	"agent-memory/internal/infrastructure/storage/filesystem"
+	"agent-memory/internal/infrastructure/storage/readonly"
 )
 
 func setupTestServer(t *testing.T) (*Server, func()) {
@@ -36,6 +47,7 @@ func setupTestServer(t *testing.T) (*Server, func()) {
 	server := NewServer(taskSvc, workspaceSvc, logger)
 
 	cleanup := func() {
+		server.waitForRefresh()
 		repo.Close()
 		os.RemoveAll(tmpDir)
 	}
@@ -433,6 +445,1077 @@ func TestServer_UpdateTask(t *testing.T) {
 	}
 }
 
+func TestServer_UpdateTask_Conflict(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "fix-bug"}))
+
+	update := func(description string, revision float64) *mcp.CallToolResult {
+		t.Helper()
+		result, err := server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{
+			"project_id":        "test-project",
+			"task_id":           "fix-bug",
+			"description":       description,
+			"expected_revision": revision,
+		}))
+		if err != nil {
+			t.Fatalf("handleUpdateTask() error = %v", err)
+		}
+		return result
+	}
+
+	// Both agents read revision 0
+	if result := update("first agent", 0); result.IsError {
+		t.Fatalf("handleUpdateTask() = %v", result.Content)
+	}
+	result := update("second agent", 0)
+	if !result.IsError {
+		t.Fatal("handleUpdateTask() with a stale revision succeeded")
+	}
+
+	var response struct {
+		Error           string                 `json:"error"`
+		CurrentRevision int                    `json:"current_revision"`
+		Current         map[string]interface{} `json:"current"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+		t.Fatalf("conflict result is not JSON: %v", err)
+	}
+	if response.CurrentRevision != 1 || response.Current["description"] != "first agent" || response.Error == "" {
+		t.Errorf("conflict result = %+v, want the first agent's task at revision 1", response)
+	}
+
+	// Projects report their revision too
+	result, _ = server.handleGetProject(ctx, createCallToolRequest("get_project", map[string]interface{}{"id": "test-project"}))
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"revision": 0`) {
+		t.Errorf("get_project = %s, want revision 0", text)
+	}
+}
+
+func TestServer_ClaimTask(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "fix-bug"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "add-tests"}))
+
+	claim := func(owner string) *mcp.CallToolResult {
+		t.Helper()
+		result, err := server.handleClaimTask(ctx, createCallToolRequest("claim_task", map[string]interface{}{
+			"project_id":    "test-project",
+			"task_id":       "fix-bug",
+			"owner":         owner,
+			"lease_minutes": float64(10),
+		}))
+		if err != nil {
+			t.Fatalf("handleClaimTask() error = %v", err)
+		}
+		return result
+	}
+
+	result := claim("agent-a")
+	if result.IsError {
+		t.Fatalf("handleClaimTask() = %v", result.Content)
+	}
+	var response struct {
+		Claim struct {
+			Owner  string `json:"owner"`
+			Active bool   `json:"active"`
+		} `json:"claim"`
+	}
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response)
+	if response.Claim.Owner != "agent-a" || !response.Claim.Active {
+		t.Errorf("claim = %+v, want an active claim of agent-a", response.Claim)
+	}
+
+	if result := claim("agent-b"); !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "agent-a") {
+		t.Errorf("handleClaimTask() by agent-b = %v, want an error naming agent-a", result.Content)
+	}
+
+	// The available view leaves the claimed task out
+	result, _ = server.handleListTasks(ctx, createCallToolRequest("list_tasks", map[string]interface{}{"project_id": "test-project", "available": true}))
+	var list struct {
+		Tasks []map[string]interface{} `json:"tasks"`
+	}
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &list)
+	if len(list.Tasks) != 1 || list.Tasks[0]["id"] != "add-tests" {
+		t.Errorf("list_tasks available = %v, want only add-tests", list.Tasks)
+	}
+
+	result, _ = server.handleRenewClaim(ctx, createCallToolRequest("renew_claim", map[string]interface{}{"project_id": "test-project", "task_id": "fix-bug", "owner": "agent-a"}))
+	if result.IsError {
+		t.Errorf("handleRenewClaim() = %v", result.Content)
+	}
+	result, _ = server.handleReleaseTask(ctx, createCallToolRequest("release_task", map[string]interface{}{"project_id": "test-project", "task_id": "fix-bug", "owner": "agent-a"}))
+	if result.IsError {
+		t.Errorf("handleReleaseTask() = %v", result.Content)
+	}
+	if result := claim("agent-b"); result.IsError {
+		t.Errorf("handleClaimTask() after release = %v", result.Content)
+	}
+
+	// Without an owner or a client name there is nobody to claim for
+	result, _ = server.handleClaimTask(ctx, createCallToolRequest("claim_task", map[string]interface{}{"project_id": "test-project", "task_id": "add-tests"}))
+	if !result.IsError {
+		t.Error("handleClaimTask() without an owner succeeded")
+	}
+}
+
+func TestServer_Workflow(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	result, err := server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{
+		"id": "test-project",
+		"workflow": map[string]interface{}{
+			"statuses": []interface{}{
+				map[string]interface{}{"name": "todo", "transitions": []interface{}{"review"}},
+				map[string]interface{}{"name": "review", "transitions": []interface{}{"done"}},
+				map[string]interface{}{"name": "done", "terminal": true},
+			},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("handleCreateProject() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("handleCreateProject() returned error: %v", result.Content)
+	}
+
+	result, _ = server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{
+		"project_id": "test-project",
+		"id":         "fix-bug",
+	}))
+	var created map[string]interface{}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &created)
+	}
+	if created["status"] != "todo" {
+		t.Errorf("created task status = %v, want todo", created["status"])
+	}
+
+	result, _ = server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "fix-bug",
+		"status":     "done",
+	}))
+	if !result.IsError {
+		t.Error("handleUpdateTask(todo -> done) should fail")
+	}
+
+	result, _ = server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "fix-bug",
+		"status":     "review",
+	}))
+	if result.IsError {
+		t.Errorf("handleUpdateTask(todo -> review) returned error: %v", result.Content)
+	}
+
+	result, _ = server.handleUpdateProject(ctx, createCallToolRequest("update_project", map[string]interface{}{
+		"id":       "test-project",
+		"workflow": map[string]interface{}{"statuses": []interface{}{}},
+	}))
+	if !result.IsError {
+		t.Error("handleUpdateProject() with an empty workflow should fail")
+	}
+}
+
+func TestServer_GetTaskHistory(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{
+		"project_id": "test-project",
+		"id":         "fix-bug",
+	}))
+	server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "fix-bug",
+		"status":     "in_progress",
+		"reason":     "starting work",
+		"actor":      "agent-1",
+	}))
+
+	result, err := server.handleGetTaskHistory(ctx, createCallToolRequest("get_task_history", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "fix-bug",
+	}))
+	if err != nil {
+		t.Fatalf("handleGetTaskHistory() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("handleGetTaskHistory() returned error: %v", result.Content)
+	}
+
+	var response struct {
+		Status  string `json:"status"`
+		History []struct {
+			From   string `json:"from"`
+			To     string `json:"to"`
+			Reason string `json:"reason"`
+			Actor  string `json:"actor"`
+		} `json:"history"`
+		TimeInStatus []struct {
+			Status  string `json:"status"`
+			Seconds int64  `json:"seconds"`
+			Current bool   `json:"current"`
+		} `json:"time_in_status"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &response)
+	}
+
+	if len(response.History) != 2 {
+		t.Fatalf("history = %+v, want 2 entries", response.History)
+	}
+	if h := response.History[1]; h.From != "open" || h.To != "in_progress" || h.Reason != "starting work" || h.Actor != "agent-1" {
+		t.Errorf("history[1] = %+v, want open -> in_progress by agent-1", h)
+	}
+	if len(response.TimeInStatus) != 2 || response.TimeInStatus[1].Status != "in_progress" || !response.TimeInStatus[1].Current {
+		t.Errorf("time_in_status = %+v, want open then current in_progress", response.TimeInStatus)
+	}
+}
+
+func TestServer_Tags(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	for id, tags := range map[string][]interface{}{
+		"login":  {"Auth", "backend"},
+		"schema": {"backend"},
+	} {
+		server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{
+			"project_id": "test-project",
+			"id":         id,
+			"tags":       tags,
+		}))
+	}
+	server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "login",
+		"content":    "Use JWT",
+		"tags":       []interface{}{"auth"},
+	}))
+
+	result, err := server.handleListTasks(ctx, createCallToolRequest("list_tasks", map[string]interface{}{
+		"project_id": "test-project",
+		"tags":       []interface{}{"auth", "backend"},
+		"tag_match":  "all",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleListTasks() = %v, %v", result.Content, err)
+	}
+
+	var tasks struct {
+		Tasks []struct {
+			ID   string   `json:"id"`
+			Tags []string `json:"tags"`
+		} `json:"tasks"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &tasks)
+	}
+	if len(tasks.Tasks) != 1 || tasks.Tasks[0].ID != "login" || len(tasks.Tasks[0].Tags) != 2 || tasks.Tasks[0].Tags[0] != "auth" {
+		t.Errorf("list_tasks(auth AND backend) = %+v, want login tagged [auth backend]", tasks.Tasks)
+	}
+
+	result, _ = server.handleListTasks(ctx, createCallToolRequest("list_tasks", map[string]interface{}{
+		"project_id": "test-project",
+		"tags":       []interface{}{"auth"},
+		"tag_match":  "some",
+	}))
+	if !result.IsError {
+		t.Error("list_tasks(tag_match=some) should return an error")
+	}
+
+	result, err = server.handleListTags(ctx, createCallToolRequest("list_tags", map[string]interface{}{
+		"project_id": "test-project",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleListTags() = %v, %v", result.Content, err)
+	}
+
+	var tags struct {
+		Tags []struct {
+			Tag       string `json:"tag"`
+			Tasks     int    `json:"tasks"`
+			Artifacts int    `json:"artifacts"`
+		} `json:"tags"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &tags)
+	}
+	if len(tags.Tags) != 2 || tags.Tags[0].Tag != "auth" || tags.Tags[0].Tasks != 1 || tags.Tags[0].Artifacts != 1 {
+		t.Errorf("list_tags = %+v, want auth (1 task, 1 artifact) then backend", tags.Tags)
+	}
+}
+
+func TestServer_SuggestNextTask(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	for _, args := range []map[string]interface{}{
+		{"id": "polish", "priority": "p3"},
+		{"id": "outage", "priority": "p0", "due_date": "2020-01-01"},
+	} {
+		args["project_id"] = "test-project"
+		result, _ := server.handleCreateTask(ctx, createCallToolRequest("create_task", args))
+		if result.IsError {
+			t.Fatalf("create_task(%v) failed: %v", args["id"], result.Content)
+		}
+	}
+
+	result, _ := server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{
+		"project_id": "test-project",
+		"id":         "bad",
+		"priority":   "urgent",
+	}))
+	if !result.IsError {
+		t.Error("create_task(priority=urgent) should return an error")
+	}
+
+	result, err := server.handleListTasks(ctx, createCallToolRequest("list_tasks", map[string]interface{}{
+		"project_id": "test-project",
+		"sort":       "priority",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleListTasks(sort=priority) = %v, %v", result.Content, err)
+	}
+
+	var tasks struct {
+		Tasks []struct {
+			ID       string `json:"id"`
+			Priority string `json:"priority"`
+			DueDate  string `json:"due_date"`
+		} `json:"tasks"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &tasks)
+	}
+	if len(tasks.Tasks) != 2 || tasks.Tasks[0].ID != "outage" || tasks.Tasks[0].DueDate != "2020-01-01T23:59:59Z" {
+		t.Errorf("list_tasks(sort=priority) = %+v, want outage (due 2020-01-01) first", tasks.Tasks)
+	}
+
+	result, err = server.handleSuggestNextTask(ctx, createCallToolRequest("suggest_next_task", map[string]interface{}{}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleSuggestNextTask() = %v, %v", result.Content, err)
+	}
+
+	var suggestions struct {
+		Suggestions []struct {
+			ID          string  `json:"id"`
+			Score       float64 `json:"score"`
+			Explanation string  `json:"explanation"`
+		} `json:"suggestions"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &suggestions)
+	}
+	if len(suggestions.Suggestions) != 2 || suggestions.Suggestions[0].ID != "outage" {
+		t.Fatalf("suggest_next_task = %+v, want outage first", suggestions.Suggestions)
+	}
+	if !strings.Contains(suggestions.Suggestions[0].Explanation, "overdue") {
+		t.Errorf("explanation = %q, want it to mention the overdue date", suggestions.Suggestions[0].Explanation)
+	}
+}
+
+func TestServer_ArtifactVersions(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "auth"}))
+	result, _ := server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "auth",
+		"type":       "decision",
+		"content":    "Use sessions",
+	}))
+
+	var saved struct {
+		ID string `json:"id"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &saved)
+	}
+
+	ids := map[string]interface{}{"project_id": "test-project", "task_id": "auth", "artifact_id": saved.ID}
+	withIDs := func(args map[string]interface{}) map[string]interface{} {
+		for k, v := range ids {
+			args[k] = v
+		}
+		return args
+	}
+
+	result, err := server.handleUpdateArtifact(ctx, createCallToolRequest("update_artifact", withIDs(map[string]interface{}{"content": "Use JWT"})))
+	if err != nil || result.IsError {
+		t.Fatalf("handleUpdateArtifact() = %v, %v", result.Content, err)
+	}
+
+	result, _ = server.handleGetArtifact(ctx, createCallToolRequest("get_artifact", withIDs(map[string]interface{}{})))
+	var latest struct {
+		Content string `json:"content"`
+		Version int    `json:"version"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &latest)
+	}
+	if latest.Content != "Use JWT" || latest.Version != 2 {
+		t.Errorf("get_artifact = %+v, want version 2 'Use JWT'", latest)
+	}
+
+	result, _ = server.handleListArtifactVersions(ctx, createCallToolRequest("list_artifact_versions", withIDs(map[string]interface{}{})))
+	var versions struct {
+		Versions []struct {
+			Version int    `json:"version"`
+			Summary string `json:"summary"`
+		} `json:"versions"`
+		Current int `json:"current"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &versions)
+	}
+	if len(versions.Versions) != 2 || versions.Versions[0].Summary != "Use sessions" || versions.Current != 2 {
+		t.Errorf("list_artifact_versions = %+v, want v1 'Use sessions' and current 2", versions)
+	}
+
+	result, _ = server.handleGetArtifactVersion(ctx, createCallToolRequest("get_artifact_version", withIDs(map[string]interface{}{"version": float64(1)})))
+	if result.IsError {
+		t.Fatalf("get_artifact_version(1) failed: %v", result.Content)
+	}
+	result, _ = server.handleGetArtifactVersion(ctx, createCallToolRequest("get_artifact_version", withIDs(map[string]interface{}{"version": float64(7)})))
+	if !result.IsError {
+		t.Error("get_artifact_version(7) should return an error")
+	}
+
+	result, _ = server.handleDiffArtifactVersions(ctx, createCallToolRequest("diff_artifact_versions", withIDs(map[string]interface{}{})))
+	var diff struct {
+		From int    `json:"from"`
+		To   int    `json:"to"`
+		Diff string `json:"diff"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &diff)
+	}
+	if diff.From != 1 || diff.To != 2 || !strings.Contains(diff.Diff, "-Use sessions\n+Use JWT") {
+		t.Errorf("diff_artifact_versions = %+v, want v1..v2 replacing the line", diff)
+	}
+}
+
+func TestServer_ArtifactLinks(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "auth"}))
+
+	save := func(artifactType, content string) string {
+		result, _ := server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
+			"project_id": "test-project",
+			"task_id":    "auth",
+			"type":       artifactType,
+			"content":    content,
+		}))
+		var saved struct {
+			ID string `json:"id"`
+		}
+		if text, ok := result.Content[0].(mcp.TextContent); ok {
+			json.Unmarshal([]byte(text.Text), &saved)
+		}
+		return saved.ID
+	}
+	oldID := save("decision", "Use sessions")
+	newID := save("decision", "Use JWT")
+
+	result, err := server.handleLinkArtifact(ctx, createCallToolRequest("link_artifact", map[string]interface{}{
+		"project_id":         "test-project",
+		"task_id":            "auth",
+		"artifact_id":        newID,
+		"relation":           "supersedes",
+		"target_artifact_id": oldID,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleLinkArtifact() = %v, %v", result.Content, err)
+	}
+
+	result, _ = server.handleLinkArtifact(ctx, createCallToolRequest("link_artifact", map[string]interface{}{
+		"project_id":  "test-project",
+		"task_id":     "auth",
+		"artifact_id": newID,
+		"relation":    "blocks",
+	}))
+	if !result.IsError {
+		t.Error("link_artifact(relation=blocks) should return an error")
+	}
+
+	var list struct {
+		Total int `json:"total"`
+	}
+	result, _ = server.handleListArtifacts(ctx, createCallToolRequest("list_artifacts", map[string]interface{}{"project_id": "test-project", "task_id": "auth"}))
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &list)
+	}
+	if list.Total != 1 {
+		t.Errorf("list_artifacts total = %d, want 1 (superseded hidden)", list.Total)
+	}
+
+	result, _ = server.handleGetArtifact(ctx, createCallToolRequest("get_artifact", map[string]interface{}{
+		"project_id":    "test-project",
+		"task_id":       "auth",
+		"artifact_id":   newID,
+		"include_links": true,
+	}))
+	var got struct {
+		Links  []map[string]interface{} `json:"links"`
+		Linked []struct {
+			Relation  string `json:"relation"`
+			Direction string `json:"direction"`
+			Artifact  struct {
+				Content string `json:"content"`
+			} `json:"artifact"`
+		} `json:"linked"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &got)
+	}
+	if len(got.Links) != 1 || len(got.Linked) != 1 || got.Linked[0].Direction != "outgoing" || got.Linked[0].Artifact.Content != "Use sessions" {
+		t.Errorf("get_artifact(include_links) = %+v, want the superseded decision inlined", got)
+	}
+
+	result, err = server.handleUnlinkArtifact(ctx, createCallToolRequest("unlink_artifact", map[string]interface{}{
+		"project_id":         "test-project",
+		"task_id":            "auth",
+		"artifact_id":        newID,
+		"relation":           "supersedes",
+		"target_artifact_id": oldID,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleUnlinkArtifact() = %v, %v", result.Content, err)
+	}
+	result, _ = server.handleListArtifacts(ctx, createCallToolRequest("list_artifacts", map[string]interface{}{"project_id": "test-project", "task_id": "auth"}))
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &list)
+	}
+	if list.Total != 2 {
+		t.Errorf("list_artifacts after unlink total = %d, want 2", list.Total)
+	}
+}
+
+func TestServer_Memories(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "auth"}))
+	server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "auth",
+		"type":       "note",
+		"content":    "Tests run against a local postgres",
+	}))
+
+	for _, args := range []map[string]interface{}{
+		{"project_id": "test-project", "title": "Testing", "content": "Run tests with make test, postgres must be up", "pinned": true},
+		{"project_id": "test-project", "id": "layout", "title": "Layout", "content": "Handlers live in internal/transport"},
+		{"title": "User preferences", "content": "Prefers table-driven tests", "pinned": true},
+	} {
+		result, err := server.handleCreateMemory(ctx, createCallToolRequest("create_memory", args))
+		if err != nil || result.IsError {
+			t.Fatalf("handleCreateMemory(%v) = %v, %v", args["title"], result.Content, err)
+		}
+	}
+
+	result, _ := server.handleCreateMemory(ctx, createCallToolRequest("create_memory", map[string]interface{}{"project_id": "test-project", "title": "Testing", "content": "again"}))
+	if !result.IsError {
+		t.Error("create_memory(duplicate) should return an error")
+	}
+
+	result, _ = server.handleGetProject(ctx, createCallToolRequest("get_project", map[string]interface{}{"id": "test-project", "include_memories": true}))
+	var project struct {
+		Memories []struct {
+			ID     string `json:"id"`
+			Global bool   `json:"global"`
+		} `json:"memories"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &project)
+	}
+	if len(project.Memories) != 2 || project.Memories[0].ID != "testing" || !project.Memories[1].Global {
+		t.Errorf("get_project(include_memories) memories = %+v, want the pinned project and global ones", project.Memories)
+	}
+
+	result, _ = server.handleSearchArtifacts(ctx, createCallToolRequest("search_artifacts", map[string]interface{}{"query": "tests", "project_id": "test-project"}))
+	var search struct {
+		Total         int `json:"total"`
+		MemoriesTotal int `json:"memories_total"`
+		Memories      []struct {
+			ID       string        `json:"id"`
+			Snippets []interface{} `json:"snippets"`
+		} `json:"memories"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &search)
+	}
+	if search.Total != 1 || search.MemoriesTotal != 2 || len(search.Memories) != 2 || len(search.Memories[0].Snippets) == 0 {
+		t.Errorf("search_artifacts(tests) = %+v, want 1 artifact and 2 memories with snippets", search)
+	}
+
+	search.Memories = nil
+	result, _ = server.handleSearchArtifacts(ctx, createCallToolRequest("search_artifacts", map[string]interface{}{"query": "tests", "include_memories": false}))
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &search)
+	}
+	if search.Memories != nil {
+		t.Errorf("search_artifacts(include_memories=false) memories = %+v, want none", search.Memories)
+	}
+
+	result, err := server.handleUpdateMemory(ctx, createCallToolRequest("update_memory", map[string]interface{}{
+		"project_id": "test-project",
+		"memory_id":  "layout",
+		"pinned":     true,
+		"tags":       []interface{}{"architecture"},
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleUpdateMemory() = %v, %v", result.Content, err)
+	}
+
+	result, _ = server.handleListMemories(ctx, createCallToolRequest("list_memories", map[string]interface{}{"project_id": "test-project", "tags": []interface{}{"architecture"}}))
+	var list struct {
+		Total    int `json:"total"`
+		Memories []struct {
+			ID     string `json:"id"`
+			Pinned bool   `json:"pinned"`
+		} `json:"memories"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &list)
+	}
+	if list.Total != 1 || list.Memories[0].ID != "layout" || !list.Memories[0].Pinned {
+		t.Errorf("list_memories(tag architecture) = %+v, want the pinned layout memory", list)
+	}
+
+	result, err = server.handleDeleteMemory(ctx, createCallToolRequest("delete_memory", map[string]interface{}{"memory_id": "user-preferences"}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleDeleteMemory() = %v, %v", result.Content, err)
+	}
+	result, _ = server.handleGetMemory(ctx, createCallToolRequest("get_memory", map[string]interface{}{"memory_id": "user-preferences"}))
+	if !result.IsError {
+		t.Error("get_memory(deleted) should return an error")
+	}
+	result, _ = server.handleGetMemory(ctx, createCallToolRequest("get_memory", map[string]interface{}{"project_id": "missing", "memory_id": "x"}))
+	if text, ok := result.Content[0].(mcp.TextContent); !result.IsError || !ok || !strings.Contains(text.Text, "Project 'missing' not found") {
+		t.Errorf("get_memory(missing project) = %v, want project not found", result.Content)
+	}
+}
+
+func TestServer_ResumeTask(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "auth"}))
+	server.handleCreateMemory(ctx, createCallToolRequest("create_memory", map[string]interface{}{"project_id": "test-project", "title": "Testing", "content": "Run make test", "pinned": true}))
+	for _, args := range []map[string]interface{}{
+		{"type": "decision", "content": "Use RS256"},
+		{"type": "note", "content": "How long do refresh tokens live?", "tags": []interface{}{"question"}},
+		{"type": "note", "content": "Login endpoint done"},
+		{"type": "file_read", "content": strings.Repeat("x", 4000)},
+	} {
+		args["project_id"] = "test-project"
+		args["task_id"] = "auth"
+		server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", args))
+	}
+
+	result, err := server.handleResumeTask(ctx, createCallToolRequest("resume_task", map[string]interface{}{
+		"project_id":   "test-project",
+		"task_id":      "auth",
+		"token_budget": float64(500),
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("handleResumeTask() = %v, %v", result.Content, err)
+	}
+
+	type item struct {
+		ArtifactID string `json:"artifact_id"`
+		MemoryID   string `json:"memory_id"`
+		Content    string `json:"content"`
+		Truncated  bool   `json:"truncated"`
+		Tokens     int    `json:"tokens"`
+	}
+	var pack struct {
+		Task struct {
+			ID string `json:"id"`
+		} `json:"task"`
+		Memories     []item `json:"memories"`
+		Decisions    []item `json:"decisions"`
+		Questions    []item `json:"questions"`
+		Notes        []item `json:"notes"`
+		Other        []item `json:"other"`
+		OmittedTotal int    `json:"omitted_total"`
+		TokenBudget  int    `json:"token_budget"`
+		TokensUsed   int    `json:"tokens_used"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &pack)
+	}
+
+	if pack.Task.ID != "auth" || len(pack.Memories) != 1 || len(pack.Decisions) != 1 || len(pack.Questions) != 1 || len(pack.Notes) != 1 {
+		t.Errorf("resume_task = %+v, want the task, memory, decision, question and note", pack)
+	}
+	if len(pack.Other) != 1 || !pack.Other[0].Truncated || pack.Other[0].Tokens != 1000 {
+		t.Errorf("resume_task other = %+v, want the file log truncated", pack.Other)
+	}
+	if pack.TokenBudget != 500 || pack.TokensUsed > pack.TokenBudget {
+		t.Errorf("resume_task used %d of %d tokens", pack.TokensUsed, pack.TokenBudget)
+	}
+
+	result, _ = server.handleResumeTask(ctx, createCallToolRequest("resume_task", map[string]interface{}{"project_id": "test-project", "task_id": "missing"}))
+	if text, ok := result.Content[0].(mcp.TextContent); !result.IsError || !ok || !strings.Contains(text.Text, "Task 'missing' not found") {
+		t.Errorf("resume_task(missing) = %v, want task not found", result.Content)
+	}
+}
+
+// handleMessage sends a JSON-RPC request to the MCP server and decodes its
+// result into result, returning the error message if it failed.
+func handleMessage(t *testing.T, server *Server, method string, params map[string]interface{}, result interface{}) string {
+	t.Helper()
+	return handleSessionMessage(t, context.Background(), server, method, params, result)
+}
+
+// handleSessionMessage is handleMessage in the context of a client session.
+func handleSessionMessage(t *testing.T, ctx context.Context, server *Server, method string, params map[string]interface{}, result interface{}) string {
+	t.Helper()
+
+	request, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	response, _ := json.Marshal(server.HandleMessage(ctx, request))
+
+	var decoded struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(response, &decoded); err != nil {
+		t.Fatalf("%s: invalid response %s", method, response)
+	}
+	if decoded.Error != nil {
+		return decoded.Error.Message
+	}
+	if err := json.Unmarshal(decoded.Result, result); err != nil {
+		t.Fatalf("%s: invalid result %s", method, decoded.Result)
+	}
+	return ""
+}
+
+func TestServer_Resources(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project", "name": "Test"}))
+	for i := 0; i < resourcePageSize; i++ {
+		server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": fmt.Sprintf("task-%03d", i)}))
+	}
+	result, _ := server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "task-000",
+		"type":       "decision",
+		"content":    "# Use RS256\n\nKeys rotate monthly",
+	}))
+	var artifact struct {
+		ID string `json:"id"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &artifact)
+	}
+
+	// The project and its tasks are listed over two pages
+	var uris []string
+	cursor := ""
+	for page := 0; page < 3; page++ {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var list mcp.ListResourcesResult
+		if msg := handleMessage(t, server, "resources/list", params, &list); msg != "" {
+			t.Fatalf("resources/list error = %s", msg)
+		}
+		for _, r := range list.Resources {
+			uris = append(uris, r.URI)
+		}
+		if cursor = string(list.NextCursor); cursor == "" {
+			break
+		}
+	}
+	if len(uris) != resourcePageSize+1 || !slices.Contains(uris, "memory://projects/test-project") || !slices.Contains(uris, "memory://projects/test-project/tasks/task-099") {
+		t.Errorf("resources/list = %d resources, want the project and its %d tasks", len(uris), resourcePageSize)
+	}
+
+	var templates mcp.ListResourceTemplatesResult
+	handleMessage(t, server, "resources/templates/list", nil, &templates)
+	if len(templates.ResourceTemplates) != 3 {
+		t.Errorf("resources/templates/list = %d templates, want 3", len(templates.ResourceTemplates))
+	}
+
+	type contents struct {
+		Contents []struct {
+			URI      string                 `json:"uri"`
+			MIMEType string                 `json:"mimeType"`
+			Text     string                 `json:"text"`
+			Meta     map[string]interface{} `json:"_meta"`
+		} `json:"contents"`
+	}
+
+	var project contents
+	handleMessage(t, server, "resources/read", map[string]interface{}{"uri": "memory://projects/test-project"}, &project)
+	if len(project.Contents) != 1 || project.Contents[0].MIMEType != "application/json" || !strings.Contains(project.Contents[0].Text, `"uri": "memory://projects/test-project/tasks/task-042"`) {
+		t.Errorf("resources/read(project) = %+v, want JSON listing the tasks", project)
+	}
+
+	artifactURI := "memory://projects/test-project/tasks/task-000/artifacts/" + artifact.ID
+	var taskContents contents
+	handleMessage(t, server, "resources/read", map[string]interface{}{"uri": "memory://projects/test-project/tasks/task-000"}, &taskContents)
+	if len(taskContents.Contents) != 1 || !strings.Contains(taskContents.Contents[0].Text, artifactURI) {
+		t.Errorf("resources/read(task) = %+v, want JSON listing the artifact", taskContents)
+	}
+
+	// Artifacts are only reached through the template
+	var artifactContents contents
+	if msg := handleMessage(t, server, "resources/read", map[string]interface{}{"uri": artifactURI}, &artifactContents); msg != "" {
+		t.Fatalf("resources/read(artifact) error = %s", msg)
+	}
+	got := artifactContents.Contents[0]
+	if got.MIMEType != "text/markdown" || got.Text != "# Use RS256\n\nKeys rotate monthly" || got.Meta["type"] != "decision" {
+		t.Errorf("resources/read(artifact) = %+v, want the Markdown content", got)
+	}
+
+	for _, uri := range []string{
+		"memory://projects/test-project/tasks/missing",
+		"memory://projects/test-project/tasks/task-000/artifacts/1",
+		"memory://projects/test-project/other/x",
+	} {
+		if msg := handleMessage(t, server, "resources/read", map[string]interface{}{"uri": uri}, &contents{}); msg == "" {
+			t.Errorf("resources/read(%s) succeeded, want an error", uri)
+		}
+	}
+
+	// Deleted tasks leave the list
+	server.handleDeleteTask(ctx, createCallToolRequest("delete_task", map[string]interface{}{"project_id": "test-project", "task_id": "task-099"}))
+	var list mcp.ListResourcesResult
+	handleMessage(t, server, "resources/list", map[string]interface{}{"cursor": base64.StdEncoding.EncodeToString([]byte("test-project/task-050"))}, &list)
+	for _, r := range list.Resources {
+		if strings.HasSuffix(r.URI, "task-099") {
+			t.Errorf("resources/list lists deleted task %s", r.URI)
+		}
+	}
+}
+
+func TestParseResourceURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want task.ArtifactRef
+		ok   bool
+	}{
+		{uri: "memory://projects/backend", want: task.ArtifactRef{ProjectID: "backend"}, ok: true},
+		{uri: "memory://projects/backend/tasks/auth", want: task.ArtifactRef{ProjectID: "backend", TaskID: "auth"}, ok: true},
+		{uri: "memory://projects/backend/tasks/auth/artifacts/123", want: task.ArtifactRef{ProjectID: "backend", TaskID: "auth", ArtifactID: "123"}, ok: true},
+		{uri: "memory://projects/", ok: false},
+		{uri: "memory://projects/backend/tasks", ok: false},
+		{uri: "file://projects/backend", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseResourceURI(tt.uri)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseResourceURI(%q) = %+v, %v, want %+v, %v", tt.uri, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestServer_GetTaskTree(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{
+		"id": "test-project",
+	}))
+	for _, args := range []map[string]interface{}{
+		{"id": "epic"},
+		{"id": "step-1", "parent_id": "epic"},
+		{"id": "step-2", "parent_id": "epic"},
+	} {
+		args["project_id"] = "test-project"
+		result, _ := server.handleCreateTask(ctx, createCallToolRequest("create_task", args))
+		if result.IsError {
+			t.Fatalf("handleCreateTask(%v) returned error: %v", args["id"], result.Content)
+		}
+	}
+	server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "step-1",
+		"status":     "completed",
+	}))
+
+	result, err := server.handleGetTaskTree(ctx, createCallToolRequest("get_task_tree", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "epic",
+	}))
+	if err != nil {
+		t.Fatalf("handleGetTaskTree() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("handleGetTaskTree() returned error: %v", result.Content)
+	}
+
+	var response struct {
+		Tasks []struct {
+			ID       string `json:"id"`
+			Progress string `json:"progress"`
+			Rollup   struct {
+				Total     int `json:"total"`
+				Completed int `json:"completed"`
+			} `json:"rollup"`
+			Children []struct {
+				ID       string `json:"id"`
+				ParentID string `json:"parent_id"`
+			} `json:"children"`
+		} `json:"tasks"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &response)
+	}
+
+	if len(response.Tasks) != 1 || response.Tasks[0].ID != "epic" {
+		t.Fatalf("tasks = %+v, want the epic tree", response.Tasks)
+	}
+	epic := response.Tasks[0]
+	if epic.Rollup.Total != 2 || epic.Rollup.Completed != 1 || epic.Progress != "1/2 subtasks completed" {
+		t.Errorf("epic rollup = %+v, progress = %q, want 1/2 completed", epic.Rollup, epic.Progress)
+	}
+	if len(epic.Children) != 2 || epic.Children[0].ParentID != "epic" {
+		t.Errorf("epic children = %+v, want step-1 and step-2", epic.Children)
+	}
+
+	// A cycle is rejected
+	result, _ = server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "epic",
+		"parent_id":  "step-2",
+	}))
+	if !result.IsError {
+		t.Error("handleUpdateTask() making epic a subtask of its own subtask should fail")
+	}
+}
+
+func TestServer_Dependencies(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	for _, id := range []string{"schema", "api"} {
+		server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{
+			"project_id": "test-project",
+			"id":         id,
+		}))
+	}
+
+	result, err := server.handleAddDependency(ctx, createCallToolRequest("add_dependency", map[string]interface{}{
+		"project_id":         "test-project",
+		"task_id":            "api",
+		"blocked_by_task_id": "schema",
+	}))
+	if err != nil {
+		t.Fatalf("handleAddDependency() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("handleAddDependency() returned error: %v", result.Content)
+	}
+
+	// The reverse dependency would be a cycle
+	result, _ = server.handleAddDependency(ctx, createCallToolRequest("add_dependency", map[string]interface{}{
+		"project_id":         "test-project",
+		"task_id":            "schema",
+		"blocked_by_task_id": "api",
+	}))
+	if !result.IsError {
+		t.Error("handleAddDependency() creating a cycle should fail")
+	}
+
+	result, _ = server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "api",
+		"status":     "in_progress",
+	}))
+	if !result.IsError {
+		t.Error("handleUpdateTask() starting a blocked task should fail")
+	}
+
+	result, err = server.handleListReadyTasks(ctx, createCallToolRequest("list_ready_tasks", map[string]interface{}{
+		"project_id": "test-project",
+	}))
+	if err != nil {
+		t.Fatalf("handleListReadyTasks() error = %v", err)
+	}
+
+	var response struct {
+		Tasks []struct {
+			ID     string         `json:"id"`
+			Blocks []task.TaskRef `json:"blocks"`
+		} `json:"tasks"`
+		Total int `json:"total"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &response)
+	}
+
+	if response.Total != 1 || response.Tasks[0].ID != "schema" {
+		t.Fatalf("list_ready_tasks = %+v, want [schema]", response.Tasks)
+	}
+	if len(response.Tasks[0].Blocks) != 1 || response.Tasks[0].Blocks[0].TaskID != "api" {
+		t.Errorf("schema blocks = %v, want [test-project/api]", response.Tasks[0].Blocks)
+	}
+
+	result, _ = server.handleRemoveDependency(ctx, createCallToolRequest("remove_dependency", map[string]interface{}{
+		"project_id":         "test-project",
+		"task_id":            "api",
+		"blocked_by_task_id": "schema",
+	}))
+	if result.IsError {
+		t.Errorf("handleRemoveDependency() returned error: %v", result.Content)
+	}
+}
+
 func TestServer_SaveArtifact(t *testing.T) {
 	server, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -563,32 +1646,106 @@ func TestServer_SearchArtifacts(t *testing.T) {
 	})
 	server.handleSaveArtifact(ctx, saveReq1)
 
-	saveReq2 := createCallToolRequest("save_artifact", map[string]interface{}{
+	saveReq2 := createCallToolRequest("save_artifact", map[string]interface{}{
+		"project_id": "test-project",
+		"task_id":    "fix-bug",
+		"content":    "Database connection fixed",
+		"type":       "note",
+	})
+	server.handleSaveArtifact(ctx, saveReq2)
+
+	// Search
+	searchReq := createCallToolRequest("search_artifacts", map[string]interface{}{
+		"query": "authentication",
+	})
+
+	result, err := server.handleSearchArtifacts(ctx, searchReq)
+	if err != nil {
+		t.Fatalf("handleSearchArtifacts() error = %v", err)
+	}
+
+	// Parse response
+	var response map[string]interface{}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &response)
+	}
+
+	if response["total"].(float64) != 1 {
+		t.Errorf("response total = %v, want 1", response["total"])
+	}
+
+	artifacts := response["artifacts"].([]interface{})
+	score, ok := artifacts[0].(map[string]interface{})["score"].(float64)
+	if !ok || score <= 0 {
+		t.Errorf("artifact score = %v, want positive number", artifacts[0].(map[string]interface{})["score"])
+	}
+
+	snippets, _ := artifacts[0].(map[string]interface{})["snippets"].([]interface{})
+	if len(snippets) != 1 {
+		t.Fatalf("artifact snippets = %v, want 1", artifacts[0].(map[string]interface{})["snippets"])
+	}
+	snippet := snippets[0].(map[string]interface{})
+	if snippet["text"] != "Authentication error found" || snippet["line"].(float64) != 1 {
+		t.Errorf("snippet = %v", snippet)
+	}
+
+	// Filters
+	filterReq := createCallToolRequest("search_artifacts", map[string]interface{}{
+		"query": "type:note -authentication",
+	})
+	result, _ = server.handleSearchArtifacts(ctx, filterReq)
+	response = nil
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &response)
+	}
+	if response["total"].(float64) != 1 {
+		t.Errorf("filtered response total = %v, want 1", response["total"])
+	}
+
+	// Invalid filter value
+	invalidReq := createCallToolRequest("search_artifacts", map[string]interface{}{
+		"query": "created:soon",
+	})
+	result, _ = server.handleSearchArtifacts(ctx, invalidReq)
+	if !result.IsError {
+		t.Error("handleSearchArtifacts() with invalid query should return error result")
+	}
+}
+
+func TestServer_RebuildSearchIndex(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{
+		"id": "test-project",
+	}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{
+		"project_id": "test-project",
+		"id":         "fix-bug",
+	}))
+	server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
 		"project_id": "test-project",
 		"task_id":    "fix-bug",
-		"content":    "Database connection fixed",
-		"type":       "note",
-	})
-	server.handleSaveArtifact(ctx, saveReq2)
+		"content":    "Indexed note",
+	}))
 
-	// Search
-	searchReq := createCallToolRequest("search_artifacts", map[string]interface{}{
-		"query": "authentication",
-	})
-
-	result, err := server.handleSearchArtifacts(ctx, searchReq)
+	result, err := server.handleRebuildSearchIndex(ctx, createCallToolRequest("rebuild_search_index", map[string]interface{}{}))
 	if err != nil {
-		t.Fatalf("handleSearchArtifacts() error = %v", err)
+		t.Fatalf("handleRebuildSearchIndex() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("handleRebuildSearchIndex() returned error result: %v", result.Content)
 	}
 
-	// Parse response
 	var response map[string]interface{}
 	if text, ok := result.Content[0].(mcp.TextContent); ok {
 		json.Unmarshal([]byte(text.Text), &response)
 	}
 
-	if response["total"].(float64) != 1 {
-		t.Errorf("response total = %v, want 1", response["total"])
+	if response["indexed"].(float64) != 1 {
+		t.Errorf("response indexed = %v, want 1", response["indexed"])
 	}
 }
 
@@ -843,6 +2000,80 @@ func TestServer_DeleteTask(t *testing.T) {
 	}
 }
 
+func TestServer_CompactArtifacts(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "auth"}))
+	for _, content := range []string{"one", "two", "three"} {
+		server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
+			"project_id": "test-project",
+			"task_id":    "auth",
+			"type":       "search",
+			"content":    content,
+		}))
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	args := map[string]interface{}{
+		"project_id":      "test-project",
+		"older_than_days": 1e-7, // About 9ms
+		"dry_run":         true,
+	}
+
+	var response struct {
+		Compacted   int  `json:"compacted"`
+		DryRun      bool `json:"dry_run"`
+		Compactions []struct {
+			TaskID      string   `json:"task_id"`
+			ArtifactIDs []string `json:"artifact_ids"`
+			Summary     struct {
+				ID      string `json:"id"`
+				Content string `json:"content"`
+			} `json:"summary"`
+		} `json:"compactions"`
+	}
+	result, err := server.handleCompactArtifacts(ctx, createCallToolRequest("compact_artifacts", args))
+	if err != nil || result.IsError {
+		t.Fatalf("handleCompactArtifacts(dry run) = %v, %v", result.Content, err)
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &response)
+	}
+	if !response.DryRun || response.Compacted != 3 || len(response.Compactions) != 1 || response.Compactions[0].Summary.ID != "" {
+		t.Errorf("compact_artifacts(dry run) = %+v, want 3 artifacts and no saved summary", response)
+	}
+
+	args["dry_run"] = false
+	result, _ = server.handleCompactArtifacts(ctx, createCallToolRequest("compact_artifacts", args))
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &response)
+	}
+	summaryID := response.Compactions[0].Summary.ID
+	if response.Compacted != 3 || summaryID == "" || !strings.Contains(response.Compactions[0].Summary.Content, "## Searches (3)") {
+		t.Errorf("compact_artifacts() = %+v, want 3 artifacts in a saved summary", response)
+	}
+
+	result, _ = server.handleListArtifacts(ctx, createCallToolRequest("list_artifacts", map[string]interface{}{"project_id": "test-project", "task_id": "auth"}))
+	var list struct {
+		Total int `json:"total"`
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); ok {
+		json.Unmarshal([]byte(text.Text), &list)
+	}
+	if list.Total != 1 {
+		t.Errorf("list_artifacts after compaction total = %d, want only the summary", list.Total)
+	}
+
+	result, _ = server.handleCompactArtifacts(ctx, createCallToolRequest("compact_artifacts", map[string]interface{}{"project_id": "test-project", "task_id": "missing"}))
+	if text, ok := result.Content[0].(mcp.TextContent); !result.IsError || !ok || !strings.Contains(text.Text, "Task 'missing' not found") {
+		t.Errorf("compact_artifacts(missing task) = %v, want task not found", result.Content)
+	}
+}
+
 func TestServer_DeleteArtifact(t *testing.T) {
 	server, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -990,3 +2221,763 @@ func TestArtifactToMap(t *testing.T) {
 		t.Errorf("artifactToMap content = %v, want Content here", m["content"])
 	}
 }
+
+// testSession is a client session that keeps the notifications sent to it.
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) SessionID() string                                   { return "test-session" }
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+
+// received returns the notifications sent so far as "method uri".
+func (s *testSession) received() []string {
+	var got []string
+	for {
+		select {
+		case n := <-s.notifications:
+			if uri, ok := n.Params.AdditionalFields["uri"]; ok {
+				got = append(got, fmt.Sprintf("%s %v", n.Method, uri))
+			} else {
+				got = append(got, n.Method)
+			}
+		default:
+			return got
+		}
+	}
+}
+
+func TestServer_ResourceSubscriptions(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 100)}
+	if err := server.mcpServer.RegisterSession(ctx, session); err != nil {
+		t.Fatalf("RegisterSession() error = %v", err)
+	}
+	sessionCtx := server.mcpServer.WithContext(ctx, session)
+
+	const (
+		projectURI = "memory://projects/test-project"
+		taskURI    = projectURI + "/tasks/task-1"
+	)
+	listChanged := string(mcp.MethodNotificationResourcesListChanged)
+	updated := func(uri string) string { return mcp.MethodNotificationResourceUpdated + " " + uri }
+
+	// New projects and tasks change the list, once the store was listed in the background
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project"}))
+	server.waitForRefresh()
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "task-1"}))
+	server.waitForRefresh()
+	if got := session.received(); !slices.Equal(got, []string{listChanged, listChanged}) {
+		t.Errorf("notifications after creating = %v, want two list changes", got)
+	}
+
+	var result map[string]interface{}
+	if msg := handleSessionMessage(t, sessionCtx, server, "resources/subscribe", map[string]interface{}{"uri": taskURI}, &result); msg != "" {
+		t.Fatalf("resources/subscribe error = %s", msg)
+	}
+	if msg := handleSessionMessage(t, sessionCtx, server, "resources/subscribe", map[string]interface{}{"uri": "memory://projects/test-project/tasks/task-1/artifacts"}, &result); !strings.Contains(msg, "resource not found") {
+		t.Errorf("resources/subscribe(bad URI) error = %q, want resource not found", msg)
+	}
+	if msg := handleMessage(t, server, "resources/subscribe", map[string]interface{}{"uri": taskURI}, &result); !strings.Contains(msg, "session") {
+		t.Errorf("resources/subscribe(no session) error = %q, want a session error", msg)
+	}
+
+	// Changes to the task and its artifacts update it; unrelated changes send nothing
+	server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{"project_id": "test-project", "task_id": "task-1", "description": "changed"}))
+	server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{"project_id": "test-project", "task_id": "task-1", "content": "note"}))
+	server.handleUpdateProject(ctx, createCallToolRequest("update_project", map[string]interface{}{"id": "test-project", "description": "changed"}))
+	if got := session.received(); !slices.Equal(got, []string{updated(taskURI), updated(taskURI)}) {
+		t.Errorf("notifications after updates = %v, want two updates of the task", got)
+	}
+
+	// Unsubscribed resources are not updated; subscribers of the project see its new task
+	handleSessionMessage(t, sessionCtx, server, "resources/unsubscribe", map[string]interface{}{"uri": taskURI}, &result)
+	handleSessionMessage(t, sessionCtx, server, "resources/subscribe", map[string]interface{}{"uri": projectURI}, &result)
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "task-2"}))
+	server.waitForRefresh()
+	if got := session.received(); !slices.Equal(got, []string{updated(projectURI), listChanged}) {
+		t.Errorf("notifications after creating a task = %v, want an update of the project and a list change", got)
+	}
+
+	// Deleting a task updates the project and the subscribed resources below the task
+	handleSessionMessage(t, sessionCtx, server, "resources/subscribe", map[string]interface{}{"uri": taskURI + "/artifacts/1"}, &result)
+	server.handleDeleteTask(ctx, createCallToolRequest("delete_task", map[string]interface{}{"project_id": "test-project", "task_id": "task-1"}))
+	server.waitForRefresh()
+	if got := session.received(); !slices.Equal(got, []string{updated(taskURI + "/artifacts/1"), updated(projectURI), listChanged}) {
+		t.Errorf("notifications after deleting a task = %v", got)
+	}
+
+	// Listing without changes announces nothing
+	var list mcp.ListResourcesResult
+	handleSessionMessage(t, sessionCtx, server, "resources/list", nil, &list)
+	if got := session.received(); len(got) != 0 {
+		t.Errorf("notifications after listing = %v, want none", got)
+	}
+
+	server.mcpServer.UnregisterSession(ctx, session.SessionID())
+	if len(server.subscribers) != 0 {
+		t.Errorf("subscribers after the session closed = %v, want none", server.subscribers)
+	}
+}
+
+func TestServer_InterceptSubscriptions(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	server.mcpServer.RegisterSession(ctx, session)
+	sessionCtx := make(chan context.Context, 1)
+	sessionCtx <- server.mcpServer.WithContext(ctx, session)
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}
+{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"memory://projects/p"}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+`
+	var out strings.Builder
+	passed, err := io.ReadAll(server.interceptSubscriptions(strings.NewReader(input), &out, sessionCtx))
+	if err != nil {
+		t.Fatalf("reading intercepted input: %v", err)
+	}
+
+	if strings.Contains(string(passed), "resources/subscribe") || strings.Count(string(passed), "\n") != 2 {
+		t.Errorf("passed input = %q, want the other two messages", passed)
+	}
+	if want := `{"jsonrpc":"2.0","id":2,"result":{}}` + "\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if len(server.subscribers["memory://projects/p"]) != 1 {
+		t.Errorf("subscribers = %v, want the session subscribed to the project", server.subscribers)
+	}
+}
+
+func TestServer_Prompts(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "test-project", "name": "Test", "description": "Billing API"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "auth", "name": "Token auth", "priority": "p1"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "test-project", "id": "old"}))
+	server.handleUpdateTask(ctx, createCallToolRequest("update_task", map[string]interface{}{"project_id": "test-project", "task_id": "old", "status": "completed", "reason": "shipped"}))
+	server.handleCreateMemory(ctx, createCallToolRequest("create_memory", map[string]interface{}{"project_id": "test-project", "id": "conventions", "title": "Conventions", "content": "Wrap errors with %w", "pinned": true}))
+	server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{"project_id": "test-project", "task_id": "auth", "type": "decision", "content": "Use RS256 for tokens"}))
+
+	var list mcp.ListPromptsResult
+	if msg := handleMessage(t, server, "prompts/list", nil, &list); msg != "" {
+		t.Fatalf("prompts/list error = %s", msg)
+	}
+	var names []string
+	for _, p := range list.Prompts {
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"handoff", "record_decision", "resume_task", "start_session"}) {
+		t.Errorf("prompts/list = %v", names)
+	}
+
+	getPrompt := func(name string, args map[string]interface{}) (string, string) {
+		t.Helper()
+		var result struct {
+			Messages []struct {
+				Role    mcp.Role        `json:"role"`
+				Content mcp.TextContent `json:"content"`
+			} `json:"messages"`
+		}
+		if msg := handleMessage(t, server, "prompts/get", map[string]interface{}{"name": name, "arguments": args}, &result); msg != "" {
+			return "", msg
+		}
+		if len(result.Messages) != 1 || result.Messages[0].Role != mcp.RoleUser {
+			t.Fatalf("prompts/get %s messages = %+v, want one user message", name, result.Messages)
+		}
+		return result.Messages[0].Content.Text, ""
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []string
+		skip []string // Must not appear
+	}{
+		{
+			name: "start_session",
+			args: map[string]interface{}{"project_id": "test-project"},
+			want: []string{"# Work session: Test", "Billing API", "### Conventions", "Wrap errors with %w", "`test-project/auth` Token auth [open, p1]", "resume_task(project_id, task_id)"},
+			skip: []string{"test-project/old"},
+		},
+		{
+			name: "resume_task",
+			args: map[string]interface{}{"project_id": "test-project", "task_id": "auth", "token_budget": "1000"},
+			want: []string{"# Resume task test-project/auth", "## Pinned memories", "## Decisions", "Use RS256 for tokens"},
+		},
+		{
+			name: "record_decision",
+			args: map[string]interface{}{"project_id": "test-project", "task_id": "auth", "title": "Rotate keys monthly"},
+			want: []string{"Earlier decisions on this task:\n- Use RS256 for tokens", "# Rotate keys monthly", "## Context\n\n<The problem", "## Consequences", `type="decision"`},
+		},
+		{
+			name: "handoff",
+			args: map[string]interface{}{"project_id": "test-project", "task_id": "old", "recipient": "the reviewer"},
+			want: []string{"so that the reviewer can take over task test-project/old", "## Status history", "completed (from open): shipped", `tags=["handoff"]`},
+		},
+	}
+	for _, tt := range tests {
+		text, msg := getPrompt(tt.name, tt.args)
+		if msg != "" {
+			t.Errorf("prompts/get %s error = %s", tt.name, msg)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("prompts/get %s = %q, want it to contain %q", tt.name, text, want)
+			}
+		}
+		for _, skip := range tt.skip {
+			if strings.Contains(text, skip) {
+				t.Errorf("prompts/get %s = %q, want it without %q", tt.name, text, skip)
+			}
+		}
+	}
+
+	if _, msg := getPrompt("resume_task", map[string]interface{}{"project_id": "test-project"}); !strings.Contains(msg, "task_id is required") {
+		t.Errorf("prompts/get resume_task without task_id error = %q", msg)
+	}
+	if _, msg := getPrompt("handoff", map[string]interface{}{"project_id": "test-project", "task_id": "missing"}); !strings.Contains(msg, "task 'missing' not found") {
+		t.Errorf("prompts/get handoff of a missing task error = %q", msg)
+	}
+	if _, msg := getPrompt("resume_task", map[string]interface{}{"project_id": "test-project", "task_id": "auth", "token_budget": "lots"}); !strings.Contains(msg, "must be a number") {
+		t.Errorf("prompts/get resume_task with a bad budget error = %q", msg)
+	}
+}
+
+func TestServer_HTTPTransports(t *testing.T) {
+	for _, transport := range []string{TransportHTTP, TransportSSE} {
+		t.Run(transport, func(t *testing.T) {
+			server, cleanup := setupTestServer(t)
+			defer cleanup()
+
+			ts := httptest.NewUnstartedServer(nil)
+			handler, shutdown, err := server.httpHandler(transport, "/agent/", ts.Config)
+			if err != nil {
+				t.Fatalf("httpHandler() error = %v", err)
+			}
+			ts.Config.Handler = handler
+			ts.Start()
+			defer ts.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			var c *client.Client
+			if transport == TransportHTTP {
+				c, err = client.NewStreamableHttpClient(ts.URL+"/agent/mcp", clienttransport.WithContinuousListening())
+			} else {
+				c, err = client.NewSSEMCPClient(ts.URL + "/agent/sse")
+			}
+			if err != nil {
+				t.Fatalf("creating client: %v", err)
+			}
+			defer c.Close()
+
+			updates := make(chan string, 100)
+			c.OnNotification(func(n mcp.JSONRPCNotification) {
+				if n.Method == mcp.MethodNotificationResourceUpdated {
+					updates <- fmt.Sprint(n.Params.AdditionalFields["uri"])
+				}
+			})
+
+			if err := c.Start(ctx); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+
+			result, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+				Name:      "create_project",
+				Arguments: map[string]interface{}{"id": "test-project"},
+			}})
+			if err != nil || result.IsError {
+				t.Fatalf("CallTool(create_project) = %+v, %v", result, err)
+			}
+
+			uri := "memory://projects/test-project"
+			if err := c.Subscribe(ctx, mcp.SubscribeRequest{Params: mcp.SubscribeParams{URI: uri}}); err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+
+			// The client may still be opening its event stream, so update until a notification arrives
+			received := false
+			for i := 0; i < 50 && !received; i++ {
+				c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+					Name:      "update_project",
+					Arguments: map[string]interface{}{"id": "test-project", "description": fmt.Sprint(i)},
+				}})
+				select {
+				case got := <-updates:
+					if got != uri {
+						t.Fatalf("resources/updated uri = %s, want %s", got, uri)
+					}
+					received = true
+				case <-time.After(100 * time.Millisecond):
+				}
+			}
+			if !received {
+				t.Fatalf("no resources/updated notification over %s", transport)
+			}
+
+			// Only the base path is served
+			if resp, err := http.Get(ts.URL + "/mcp"); err == nil {
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusNotFound {
+					t.Errorf("GET /mcp status = %d, want 404", resp.StatusCode)
+				}
+			}
+
+			// Shutdown ends the open event stream instead of waiting for the client
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelShutdown()
+			if err := shutdown(shutdownCtx); err != nil {
+				t.Errorf("shutdown() error = %v", err)
+			}
+		})
+	}
+
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	if _, _, err := server.httpHandler("websocket", "/", &http.Server{}); err == nil || !strings.Contains(err.Error(), "unknown transport") {
+		t.Errorf("httpHandler(websocket) error = %v, want unknown transport", err)
+	}
+}
+
+func TestServer_ListenAndServe(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe(ctx, TransportHTTP, "127.0.0.1:0", "/")
+	}()
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("ListenAndServe() after cancel error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe() did not stop when its context was canceled")
+	}
+
+	if err := server.ListenAndServe(context.Background(), TransportHTTP, "not an address", "/"); err == nil {
+		t.Error("ListenAndServe(bad address) error = nil")
+	}
+}
+
+func TestToolScopes(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	tools := server.mcpServer.ListTools()
+	for name := range tools {
+		if _, ok := toolScopes[name]; !ok {
+			t.Errorf("tool %s has no scope", name)
+		}
+	}
+	for name := range toolScopes {
+		if _, ok := tools[name]; !ok {
+			t.Errorf("scope of unknown tool %s", name)
+		}
+	}
+}
+
+func TestServer_Authorization(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, id := range []string{"backend", "frontend"} {
+		server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": id}))
+		server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": id, "id": "task-1"}))
+	}
+
+	reader := &Token{Name: "reader", Scopes: []Scope{ScopeRead}}
+	backend := &Token{Name: "backend-agent", Scopes: []Scope{ScopeRead, ScopeWrite}, Projects: []string{"backend"}}
+
+	type denial struct {
+		IsError           bool `json:"isError"`
+		StructuredContent struct {
+			Error *AuthError `json:"error"`
+		} `json:"structuredContent"`
+	}
+	call := func(caller *Token, tool string, args map[string]interface{}) denial {
+		t.Helper()
+		var result denial
+		if msg := handleSessionMessage(t, withCaller(ctx, caller), server, "tools/call",
+			map[string]interface{}{"name": tool, "arguments": args}, &result); msg != "" {
+			t.Fatalf("%s: %s", tool, msg)
+		}
+		return result
+	}
+
+	// Scopes
+	if got := call(reader, "get_task", map[string]interface{}{"project_id": "frontend", "task_id": "task-1"}); got.IsError {
+		t.Errorf("get_task with the read scope denied: %+v", got.StructuredContent.Error)
+	}
+	got := call(reader, "update_task", map[string]interface{}{"project_id": "frontend", "task_id": "task-1", "name": "x"})
+	if err := got.StructuredContent.Error; !got.IsError || err == nil || err.Code != codeForbidden || err.RequiredScope != ScopeWrite || err.Token != "reader" {
+		t.Errorf("update_task with the read scope = %+v, want forbidden needing write", err)
+	}
+	if got := call(backend, "delete_task", map[string]interface{}{"project_id": "backend", "task_id": "task-1"}); got.StructuredContent.Error == nil {
+		t.Error("delete_task without the delete scope was not denied")
+	}
+	if got := call(reader, "read_file", map[string]interface{}{"project_id": "backend", "path": "x"}); got.StructuredContent.Error == nil {
+		t.Error("read_file without the workspace scope was not denied")
+	}
+
+	// Project allow-list
+	if got := call(backend, "save_artifact", map[string]interface{}{"project_id": "backend", "task_id": "task-1", "content": "notes"}); got.IsError {
+		t.Errorf("save_artifact in an allowed project denied: %+v", got.StructuredContent.Error)
+	}
+	got = call(backend, "get_task", map[string]interface{}{"project_id": "frontend", "task_id": "task-1"})
+	if err := got.StructuredContent.Error; err == nil || err.ProjectID != "frontend" {
+		t.Errorf("get_task in another project = %+v, want forbidden for frontend", err)
+	}
+	got = call(backend, "add_dependency", map[string]interface{}{"project_id": "backend", "task_id": "task-1", "blocked_by_project_id": "frontend", "blocked_by_task_id": "task-1"})
+	if err := got.StructuredContent.Error; err == nil || err.ProjectID != "frontend" {
+		t.Errorf("add_dependency on another project = %+v, want forbidden for frontend", err)
+	}
+	if got := call(backend, "update_project", map[string]interface{}{"id": "frontend", "name": "x"}); got.StructuredContent.Error == nil {
+		t.Error("update_project of another project was not denied")
+	}
+	for _, tool := range []string{"list_all_tasks", "create_memory"} {
+		if got := call(backend, tool, map[string]interface{}{"content": "global"}); got.StructuredContent.Error == nil {
+			t.Errorf("%s without a project was not denied", tool)
+		}
+	}
+
+	var projects struct {
+		Content []mcp.TextContent `json:"content"`
+	}
+	handleSessionMessage(t, withCaller(ctx, backend), server, "tools/call", map[string]interface{}{"name": "list_projects"}, &projects)
+	if text := projects.Content[0].Text; !strings.Contains(text, `"backend"`) || strings.Contains(text, "frontend") {
+		t.Errorf("list_projects = %s, want only backend", text)
+	}
+
+	// Resources
+	var resources mcp.ListResourcesResult
+	handleSessionMessage(t, withCaller(ctx, backend), server, "resources/list", nil, &resources)
+	for _, r := range resources.Resources {
+		if !strings.HasPrefix(r.URI, "memory://projects/backend") {
+			t.Errorf("resources/list returned %s", r.URI)
+		}
+	}
+	if len(resources.Resources) != 2 {
+		t.Errorf("resources/list returned %d resources, want 2", len(resources.Resources))
+	}
+	var contents mcp.ReadResourceResult
+	if msg := handleSessionMessage(t, withCaller(ctx, backend), server, "resources/read",
+		map[string]interface{}{"uri": "memory://projects/frontend"}, &contents); !strings.Contains(msg, "may not access project frontend") {
+		t.Errorf("resources/read of another project error = %q", msg)
+	}
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 100)}
+	if err := server.mcpServer.RegisterSession(ctx, session); err != nil {
+		t.Fatalf("RegisterSession() error = %v", err)
+	}
+	sessionCtx := withCaller(server.mcpServer.WithContext(ctx, session), backend)
+	var empty struct{}
+	if msg := handleSessionMessage(t, sessionCtx, server, "resources/subscribe",
+		map[string]interface{}{"uri": "memory://projects/frontend"}, &empty); !strings.Contains(msg, "may not access project frontend") {
+		t.Errorf("resources/subscribe to another project error = %q", msg)
+	}
+	if msg := handleSessionMessage(t, sessionCtx, server, "resources/subscribe",
+		map[string]interface{}{"uri": "memory://projects/backend"}, &empty); msg != "" {
+		t.Errorf("resources/subscribe to an allowed project error = %q", msg)
+	}
+
+	// Prompts
+	var prompt struct{}
+	if msg := handleSessionMessage(t, withCaller(ctx, backend), server, "prompts/get",
+		map[string]interface{}{"name": "start_session"}, &prompt); !strings.Contains(msg, "pass project_id") {
+		t.Errorf("start_session without a project error = %q", msg)
+	}
+	if msg := handleSessionMessage(t, withCaller(ctx, backend), server, "prompts/get",
+		map[string]interface{}{"name": "start_session", "arguments": map[string]string{"project_id": "backend"}}, &prompt); msg != "" {
+		t.Errorf("start_session in an allowed project error = %q", msg)
+	}
+
+	// Without a caller everything is allowed
+	if got := call(nil, "list_all_tasks", nil); got.IsError {
+		t.Error("list_all_tasks without authentication denied")
+	}
+}
+
+func TestServer_Authorization_GlobalMemories(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "backend"}))
+	server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": "backend", "id": "task-1"}))
+	server.handleCreateMemory(ctx, createCallToolRequest("create_memory", map[string]interface{}{"title": "Style", "content": "Global deploy rule", "pinned": true}))
+	server.handleCreateMemory(ctx, createCallToolRequest("create_memory", map[string]interface{}{"project_id": "backend", "title": "Schema", "content": "Backend deploy rule", "pinned": true}))
+
+	reader := &Token{Name: "reader", Scopes: []Scope{ScopeRead}}
+	backend := &Token{Name: "backend-agent", Scopes: []Scope{ScopeRead}, Projects: []string{"backend"}}
+
+	// Every way to a project's memories; tokens limited to projects do not get the global ones
+	requests := map[string]map[string]interface{}{
+		"search_artifacts": {"name": "search_artifacts", "arguments": map[string]interface{}{"project_id": "backend", "query": "deploy"}},
+		"get_project":      {"name": "get_project", "arguments": map[string]interface{}{"id": "backend", "include_memories": true}},
+		"resume_task":      {"name": "resume_task", "arguments": map[string]interface{}{"project_id": "backend", "task_id": "task-1"}},
+		"start_session":    {"name": "start_session", "arguments": map[string]string{"project_id": "backend"}},
+		"resume_prompt":    {"name": "resume_task", "arguments": map[string]string{"project_id": "backend", "task_id": "task-1"}},
+	}
+	for name, params := range requests {
+		method := "tools/call"
+		if _, prompt := params["arguments"].(map[string]string); prompt {
+			method = "prompts/get"
+		}
+		for _, caller := range []*Token{reader, backend} {
+			var result json.RawMessage
+			if msg := handleSessionMessage(t, withCaller(ctx, caller), server, method, params, &result); msg != "" {
+				t.Fatalf("%s by %s: %s", name, caller.Name, msg)
+			}
+			if !strings.Contains(string(result), "Backend deploy rule") {
+				t.Errorf("%s by %s = %s, want the project memory", name, caller.Name, result)
+			}
+			if global := strings.Contains(string(result), "Global deploy rule"); global == caller.restricted() {
+				t.Errorf("%s by %s returned the global memory: %v, want %v", name, caller.Name, global, !caller.restricted())
+			}
+		}
+	}
+}
+
+func TestServer_Authorization_Links(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	save := func(projectID, content string) string {
+		server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": projectID}))
+		server.handleCreateTask(ctx, createCallToolRequest("create_task", map[string]interface{}{"project_id": projectID, "id": "task-1"}))
+		result, _ := server.handleSaveArtifact(ctx, createCallToolRequest("save_artifact", map[string]interface{}{
+			"project_id": projectID, "task_id": "task-1", "type": "note", "content": content,
+		}))
+		var saved struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &saved)
+		return saved.ID
+	}
+	backendID := save("backend", "Backend note")
+	billingID := save("billing", "Billing secret")
+
+	// Links can point to any project; the one making them had access to both
+	for _, args := range []map[string]interface{}{
+		{"relation": "relates_to", "target_project_id": "billing", "target_task_id": "task-1", "target_artifact_id": billingID},
+		{"relation": "derived_from", "target_project_id": "billing", "target_task_id": "task-1"},
+	} {
+		args["project_id"], args["task_id"], args["artifact_id"] = "backend", "task-1", backendID
+		if result, _ := server.handleLinkArtifact(ctx, createCallToolRequest("link_artifact", args)); result.IsError {
+			t.Fatalf("link_artifact(%v) = %v", args, result.Content)
+		}
+	}
+
+	reader := &Token{Name: "reader", Scopes: []Scope{ScopeRead}}
+	backend := &Token{Name: "backend-agent", Scopes: []Scope{ScopeRead}, Projects: []string{"backend"}}
+	params := map[string]interface{}{"name": "get_artifact", "arguments": map[string]interface{}{
+		"project_id": "backend", "task_id": "task-1", "artifact_id": backendID, "include_links": true,
+	}}
+	for _, caller := range []*Token{reader, backend} {
+		var result struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		if msg := handleSessionMessage(t, withCaller(ctx, caller), server, "tools/call", params, &result); msg != "" {
+			t.Fatalf("get_artifact by %s: %s", caller.Name, msg)
+		}
+		text := result.Content[0].Text
+		if leaked := strings.Contains(text, "Billing secret") || strings.Contains(text, `"task":`); leaked != !caller.restricted() {
+			t.Errorf("get_artifact(include_links) by %s inlined billing items: %v, want %v", caller.Name, leaked, !caller.restricted())
+		}
+	}
+}
+
+func TestServer_Authorization_ProjectCase(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	server.handleCreateProject(ctx, createCallToolRequest("create_project", map[string]interface{}{"id": "backend"}))
+
+	// Allow-lists and arguments are compared as the service normalizes IDs
+	if err := server.SetTokens([]Token{{Name: "backend-agent", Secret: "s", Scopes: []Scope{ScopeRead}, Projects: []string{"Backend"}}}); err != nil {
+		t.Fatalf("SetTokens() error = %v", err)
+	}
+	caller := withCaller(ctx, &server.tokens[0])
+
+	for _, id := range []string{"backend", "Backend", "BACKEND"} {
+		var result json.RawMessage
+		params := map[string]interface{}{"name": "get_project", "arguments": map[string]interface{}{"id": id}}
+		if msg := handleSessionMessage(t, caller, server, "tools/call", params, &result); msg != "" || strings.Contains(string(result), "forbidden") {
+			t.Errorf("get_project(%s) = %s %s, want allowed", id, result, msg)
+		}
+	}
+	var contents json.RawMessage
+	if msg := handleSessionMessage(t, caller, server, "resources/read", map[string]interface{}{"uri": "memory://projects/backend"}, &contents); msg != "" {
+		t.Errorf("resources/read(memory://projects/backend) error = %s, want allowed", msg)
+	}
+	var prompt json.RawMessage
+	if msg := handleSessionMessage(t, caller, server, "prompts/get", map[string]interface{}{"name": "start_session", "arguments": map[string]string{"project_id": "BACKEND"}}, &prompt); msg != "" {
+		t.Errorf("start_session(BACKEND) error = %s, want allowed", msg)
+	}
+}
+
+func TestServer_SetTokens(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		tokens  []Token
+		wantErr string
+	}{
+		{"valid", []Token{{Name: "a", Secret: "s1", Scopes: []Scope{ScopeRead}}, {Name: "b", Secret: "s2"}}, ""},
+		{"no name", []Token{{Secret: "s1"}}, "without a name"},
+		{"no secret", []Token{{Name: "a"}}, "no secret"},
+		{"shared secret", []Token{{Name: "a", Secret: "s"}, {Name: "b", Secret: "s"}}, "reuses the secret"},
+		{"unknown scope", []Token{{Name: "a", Secret: "s", Scopes: []Scope{"admin"}}}, "unknown scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := server.SetTokens(tt.tokens)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("SetTokens() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("SetTokens() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_HTTPAuthentication(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	if err := server.SetTokens([]Token{{Name: "reader", Secret: "s3cret", Scopes: []Scope{ScopeRead}}}); err != nil {
+		t.Fatalf("SetTokens() error = %v", err)
+	}
+	ts := httptest.NewUnstartedServer(nil)
+	handler, _, err := server.httpHandler(TransportHTTP, "/", ts.Config)
+	if err != nil {
+		t.Fatalf("httpHandler() error = %v", err)
+	}
+	ts.Config.Handler = handler
+	ts.Start()
+	defer ts.Close()
+
+	for _, authorization := range []string{"", "Bearer wrong", "Basic s3cret"} {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /mcp: %v", err)
+		}
+		var body struct {
+			Error struct {
+				Code int        `json:"code"`
+				Data *AuthError `json:"data"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: status = %d, want 401 with WWW-Authenticate", authorization, resp.StatusCode)
+		}
+		if body.Error.Code != codeUnauthorized || body.Error.Data == nil || body.Error.Data.Reason != "unauthorized" {
+			t.Errorf("Authorization %q: error = %+v, want unauthorized", authorization, body.Error)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := client.NewStreamableHttpClient(ts.URL+"/mcp",
+		clienttransport.WithHTTPHeaders(map[string]string{"Authorization": "Bearer s3cret"}))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	if result, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "list_projects"}}); err != nil || result.IsError {
+		t.Errorf("CallTool(list_projects) = %+v, %v", result, err)
+	}
+	result, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+		Name:      "create_project",
+		Arguments: map[string]interface{}{"id": "test-project"},
+	}})
+	if err != nil || !result.IsError || result.StructuredContent == nil {
+		t.Errorf("CallTool(create_project) with the read scope = %+v, %v, want a structured denial", result, err)
+	}
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo, err := filesystem.NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	writable := service.NewTaskService(repo, logger)
+	writable.CreateProject(ctx, service.CreateProjectRequest{ID: "test-project"})
+	writable.CreateTask(ctx, service.CreateTaskRequest{ProjectID: "test-project", ID: "task-1"})
+
+	readOnlyRepo := readonly.NewRepository(repo)
+	server := NewServer(service.NewTaskService(readOnlyRepo, logger), service.NewWorkspaceService(readOnlyRepo, logger), logger)
+
+	tools := server.mcpServer.ListTools()
+	for name := range toolScopes {
+		_, registered := tools[name]
+		if registered == mutates(name) {
+			t.Errorf("tool %s registered = %v on a read-only server", name, registered)
+		}
+	}
+	if _, ok := tools["get_task"]; !ok {
+		t.Error("get_task is not registered on a read-only server")
+	}
+
+	var prompts mcp.ListPromptsResult
+	handleMessage(t, server, "prompts/list", nil, &prompts)
+	var names []string
+	for _, p := range prompts.Prompts {
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"resume_task", "start_session"}) {
+		t.Errorf("prompts = %v, want resume_task and start_session", names)
+	}
+
+	// Reads still work
+	result, _ := server.handleGetTask(ctx, createCallToolRequest("get_task", map[string]interface{}{"project_id": "test-project", "task_id": "task-1"}))
+	if result.IsError {
+		t.Errorf("get_task on a read-only server failed: %v", result.Content)
+	}
+}
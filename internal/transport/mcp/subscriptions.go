@@ -0,0 +1,211 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"agent-memory/internal/application/service"
+)
+
+// mcp-go declares the subscribe capability but does not implement these
+// methods, so HandleMessage answers them.
+const (
+	methodResourcesSubscribe   mcp.MCPMethod = "resources/subscribe"
+	methodResourcesUnsubscribe mcp.MCPMethod = "resources/unsubscribe"
+)
+
+// HandleMessage handles a JSON-RPC message from a client: resource
+// subscriptions here, everything else in the MCP server.
+func (s *Server) HandleMessage(ctx context.Context, message json.RawMessage) mcp.JSONRPCMessage {
+	var sessionID string
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	if response, ok := s.handleSubscription(ctx, sessionID, message); ok {
+		return response
+	}
+	return s.mcpServer.HandleMessage(ctx, message)
+}
+
+// handleSubscription answers a resources/subscribe or resources/unsubscribe
+// request of a session, made by the caller in ctx. ok is false for any other
+// message.
+func (s *Server) handleSubscription(ctx context.Context, sessionID string, message json.RawMessage) (response mcp.JSONRPCMessage, ok bool) {
+	var request struct {
+		ID     mcp.RequestId `json:"id"`
+		Method mcp.MCPMethod `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(message, &request) != nil || request.ID.IsNil() || !isSubscriptionMethod(request.Method) {
+		return nil, false
+	}
+
+	if sessionID == "" {
+		return mcp.NewJSONRPCError(request.ID, mcp.INVALID_REQUEST, "resource subscriptions need a client session", nil), true
+	}
+	uri := request.Params.URI
+	if _, ok := parseResourceURI(uri); !ok {
+		return mcp.NewJSONRPCError(request.ID, mcp.RESOURCE_NOT_FOUND, "resource not found: "+uri, nil), true
+	}
+	if authErr := authorizeResource(callerFromContext(ctx), uri); authErr != nil {
+		return mcp.NewJSONRPCError(request.ID, authErr.Code, authErr.Message, authErr), true
+	}
+
+	if request.Method == methodResourcesSubscribe {
+		s.subscribe(sessionID, uri)
+	} else {
+		s.unsubscribe(sessionID, uri)
+	}
+	return mcp.NewJSONRPCResultResponse(request.ID, mcp.EmptyResult{}), true
+}
+
+func isSubscriptionMethod(method mcp.MCPMethod) bool {
+	return method == methodResourcesSubscribe || method == methodResourcesUnsubscribe
+}
+
+func (s *Server) subscribe(sessionID, uri string) {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+
+	if s.subscribers[uri] == nil {
+		s.subscribers[uri] = make(map[string]bool)
+	}
+	s.subscribers[uri][sessionID] = true
+}
+
+func (s *Server) unsubscribe(sessionID, uri string) {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+
+	delete(s.subscribers[uri], sessionID)
+	if len(s.subscribers[uri]) == 0 {
+		delete(s.subscribers, uri)
+	}
+}
+
+// dropSubscriptions removes the subscriptions of a closed session.
+func (s *Server) dropSubscriptions(sessionID string) {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+
+	for uri, sessions := range s.subscribers {
+		delete(sessions, sessionID)
+		if len(sessions) == 0 {
+			delete(s.subscribers, uri)
+		}
+	}
+}
+
+// handleEvent turns a change in the store into resource notifications. New
+// and deleted projects and tasks change the resource list, which mcp-go
+// announces with notifications/resources/list_changed when refreshResources,
+// scheduled in the background, registers or removes them. Subscribers of the changed resource and of the
+// resource listing it get notifications/resources/updated; deleting a
+// project or task also updates everything below it.
+func (s *Server) handleEvent(e service.Event) {
+	switch e.Kind {
+	case service.EventProjectCreated, service.EventProjectDeleted, service.EventTaskCreated, service.EventTaskDeleted:
+		s.scheduleRefresh()
+	}
+
+	switch e.Kind {
+	case service.EventProjectCreated, service.EventProjectUpdated:
+		s.notifyUpdated(projectURI(e.ProjectID), false)
+	case service.EventProjectDeleted:
+		s.notifyUpdated(projectURI(e.ProjectID), true)
+	case service.EventTaskCreated, service.EventTaskUpdated:
+		s.notifyUpdated(taskURI(e.ProjectID, e.TaskID), false)
+		s.notifyUpdated(projectURI(e.ProjectID), false)
+	case service.EventTaskDeleted:
+		s.notifyUpdated(taskURI(e.ProjectID, e.TaskID), true)
+		s.notifyUpdated(projectURI(e.ProjectID), false)
+	case service.EventArtifactSaved, service.EventArtifactUpdated, service.EventArtifactDeleted:
+		s.notifyUpdated(taskURI(e.ProjectID, e.TaskID)+"/artifacts/"+e.ArtifactID, false)
+		s.notifyUpdated(taskURI(e.ProjectID, e.TaskID), false)
+	}
+}
+
+// notifyUpdated sends notifications/resources/updated to the sessions
+// subscribed to uri, and with nested to those subscribed below it.
+func (s *Server) notifyUpdated(uri string, nested bool) {
+	s.subscriptionsMu.Lock()
+	notify := make(map[string][]string) // Session ID -> URIs
+	for subscribed, sessions := range s.subscribers {
+		if subscribed != uri && !(nested && strings.HasPrefix(subscribed, uri+"/")) {
+			continue
+		}
+		for sessionID := range sessions {
+			notify[sessionID] = append(notify[sessionID], subscribed)
+		}
+	}
+	s.subscriptionsMu.Unlock()
+
+	for sessionID, uris := range notify {
+		for _, u := range uris {
+			err := s.mcpServer.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": u})
+			if err != nil {
+				s.logger.Warn("failed to send resource update", "session", sessionID, "uri", u, "error", err)
+			}
+		}
+	}
+}
+
+// interceptSubscriptions returns a reader of the stdio input without the
+// subscription requests, which it answers on out through HandleMessage.
+// sessionCtx delivers the context of the stdio session once it is set up.
+func (s *Server) interceptSubscriptions(in io.Reader, out io.Writer, sessionCtx <-chan context.Context) io.Reader {
+	pr, pw := io.Pipe()
+
+	go func() {
+		reader := bufio.NewReader(in)
+		var ctx context.Context
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				var request struct {
+					Method mcp.MCPMethod `json:"method"`
+				}
+				if json.Unmarshal(line, &request) == nil && isSubscriptionMethod(request.Method) {
+					if ctx == nil {
+						ctx = <-sessionCtx
+					}
+					response, _ := json.Marshal(s.HandleMessage(ctx, line))
+					if _, err := out.Write(append(response, '\n')); err != nil {
+						s.logger.Error("failed to write response", "error", err)
+					}
+				} else if _, err := pw.Write(line); err != nil {
+					return // The stdio server stopped reading
+				}
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return pr
+}
+
+// syncWriter serializes writes, so lines written from several goroutines do
+// not interleave.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
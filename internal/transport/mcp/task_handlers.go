@@ -3,9 +3,13 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 
 	"agent-memory/internal/application/service"
 	"agent-memory/internal/domain/task"
@@ -38,6 +42,12 @@ func (s *Server) handleCreateProject(ctx context.Context, request mcp.CallToolRe
 		req.Metadata = meta
 	}
 
+	workflow, err := parseWorkflow(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	req.Workflow = workflow
+
 	p, err := s.taskService.CreateProject(ctx, req)
 	if err != nil {
 		if err == task.ErrProjectAlreadyExists {
@@ -46,6 +56,9 @@ func (s *Server) handleCreateProject(ctx context.Context, request mcp.CallToolRe
 		if err == task.ErrInvalidProjectID {
 			return errorResult(fmt.Sprintf("Invalid project ID '%s'. Use lowercase letters, numbers, and dashes.", id)), nil
 		}
+		if errors.Is(err, task.ErrInvalidWorkflow) {
+			return errorResult(err.Error()), nil
+		}
 		return errorResult(fmt.Sprintf("Failed to create project: %v", err)), nil
 	}
 
@@ -66,7 +79,22 @@ func (s *Server) handleGetProject(ctx context.Context, request mcp.CallToolReque
 		return errorResult(fmt.Sprintf("Failed to get project: %v", err)), nil
 	}
 
-	return jsonResult(projectToMap(p))
+	response := projectToMap(p)
+
+	if includeMemories, _ := request.GetArguments()["include_memories"].(bool); includeMemories {
+		pinned, err := s.taskService.PinnedMemories(ctx, id)
+		if err != nil {
+			return errorResult(fmt.Sprintf("Failed to load memories: %v", err)), nil
+		}
+		pinned = visibleMemories(ctx, pinned)
+		memories := make([]map[string]interface{}, 0, len(pinned))
+		for _, m := range pinned {
+			memories = append(memories, memoryToMap(m))
+		}
+		response["memories"] = memories
+	}
+
+	return jsonResult(response)
 }
 
 func (s *Server) handleListProjects(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -90,6 +118,10 @@ func (s *Server) handleListProjects(ctx context.Context, request mcp.CallToolReq
 		Limit:  limit,
 		Offset: offset,
 	}
+	// Tokens limited to some projects only see those
+	if caller := callerFromContext(ctx); caller != nil && caller.restricted() {
+		req.IDs = caller.Projects
+	}
 
 	result, err := s.taskService.ListProjects(ctx, req)
 	if err != nil {
@@ -149,11 +181,29 @@ func (s *Server) handleUpdateProject(ctx context.Context, request mcp.CallToolRe
 		req.Metadata = meta
 	}
 
+	workflow, err := parseWorkflow(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	req.Workflow = workflow
+
+	if _, ok := args["expected_revision"]; ok {
+		revision := request.GetInt("expected_revision", 0)
+		req.ExpectedRevision = &revision
+	}
+
 	p, err := s.taskService.UpdateProject(ctx, req)
 	if err != nil {
 		if err == task.ErrProjectNotFound {
 			return errorResult(fmt.Sprintf("Project '%s' not found", id)), nil
 		}
+		var conflict *task.ConflictError
+		if errors.As(err, &conflict) {
+			return conflictResult(fmt.Sprintf("Project '%s' was changed by someone else", id), conflict, projectToMap(conflict.Project))
+		}
+		if errors.Is(err, task.ErrInvalidWorkflow) {
+			return errorResult(err.Error()), nil
+		}
 		return errorResult(fmt.Sprintf("Failed to update project: %v", err)), nil
 	}
 
@@ -190,6 +240,7 @@ func (s *Server) handleCreateTask(ctx context.Context, request mcp.CallToolReque
 	name := request.GetString("name", "")
 	description := request.GetString("description", "")
 	workspacePath := request.GetString("workspace_path", "")
+	parentID := request.GetString("parent_id", "")
 
 	req := service.CreateTaskRequest{
 		ProjectID:     projectID,
@@ -197,10 +248,16 @@ func (s *Server) handleCreateTask(ctx context.Context, request mcp.CallToolReque
 		Name:          name,
 		Description:   description,
 		WorkspacePath: workspacePath,
+		ParentID:      parentID,
+		Priority:      task.Priority(request.GetString("priority", "")),
+		DueDate:       request.GetString("due_date", ""),
+		Actor:         actor(ctx, request),
 	}
 
-	// Parse metadata
 	args := request.GetArguments()
+	req.Tags, _ = stringList(args, "tags")
+
+	// Parse metadata
 	if metaRaw, ok := args["metadata"].(map[string]interface{}); ok {
 		meta := make(map[string]string, len(metaRaw))
 		for k, v := range metaRaw {
@@ -222,6 +279,12 @@ func (s *Server) handleCreateTask(ctx context.Context, request mcp.CallToolReque
 		if err == task.ErrInvalidTaskID {
 			return errorResult(fmt.Sprintf("Invalid task ID '%s'. Use lowercase letters, numbers, and dashes.", id)), nil
 		}
+		if errors.Is(err, task.ErrInvalidParent) {
+			return errorResult(fmt.Sprintf("Invalid parent_id: %v", err)), nil
+		}
+		if errors.Is(err, task.ErrInvalidPriority) || errors.Is(err, task.ErrInvalidDueDate) {
+			return errorResult(err.Error()), nil
+		}
 		return errorResult(fmt.Sprintf("Failed to create task: %v", err)), nil
 	}
 
@@ -274,11 +337,26 @@ func (s *Server) handleListTasks(ctx context.Context, request mcp.CallToolReques
 		}
 	}
 
+	tags, tagMatch, err := tagFilter(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	order, err := task.ParseTaskSort(request.GetString("sort", ""))
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
 	req := service.ListTasksRequest{
-		ProjectID: projectID,
-		Limit:     limit,
-		Offset:    offset,
-		Status:    status,
+		ProjectID:     projectID,
+		Limit:         limit,
+		Offset:        offset,
+		Status:        status,
+		ParentID:      request.GetString("parent_id", ""),
+		DescendantsOf: request.GetString("descendants_of", ""),
+		Tags:          tags,
+		TagMatch:      tagMatch,
+		Sort:          order,
+		Available:     request.GetBool("available", false),
 	}
 
 	result, err := s.taskService.ListTasks(ctx, req)
@@ -286,6 +364,9 @@ func (s *Server) handleListTasks(ctx context.Context, request mcp.CallToolReques
 		if err == task.ErrProjectNotFound {
 			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
 		}
+		if err == task.ErrTaskNotFound {
+			return errorResult(fmt.Sprintf("Parent task not found in project '%s'", projectID)), nil
+		}
 		return errorResult(fmt.Sprintf("Failed to list tasks: %v", err)), nil
 	}
 
@@ -330,10 +411,23 @@ func (s *Server) handleListAllTasks(ctx context.Context, request mcp.CallToolReq
 		}
 	}
 
+	tags, tagMatch, err := tagFilter(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+	order, err := task.ParseTaskSort(request.GetString("sort", ""))
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
 	req := service.ListAllTasksRequest{
-		Limit:  limit,
-		Offset: offset,
-		Status: status,
+		Limit:     limit,
+		Offset:    offset,
+		Status:    status,
+		Tags:      tags,
+		TagMatch:  tagMatch,
+		Sort:      order,
+		Available: request.GetBool("available", false),
 	}
 
 	result, err := s.taskService.ListAllTasks(ctx, req)
@@ -364,6 +458,8 @@ func (s *Server) handleUpdateTask(ctx context.Context, request mcp.CallToolReque
 	req := service.UpdateTaskRequest{
 		ProjectID: projectID,
 		ID:        taskID,
+		Reason:    request.GetString("reason", ""),
+		Actor:     actor(ctx, request),
 	}
 
 	args := request.GetArguments()
@@ -393,6 +489,29 @@ func (s *Server) handleUpdateTask(ctx context.Context, request mcp.CallToolReque
 		}
 	}
 
+	if priorityRaw, ok := args["priority"]; ok {
+		if priorityStr, ok := priorityRaw.(string); ok {
+			priority := task.Priority(priorityStr)
+			req.Priority = &priority
+		}
+	}
+
+	if dueRaw, ok := args["due_date"]; ok {
+		if due, ok := dueRaw.(string); ok {
+			req.DueDate = &due
+		}
+	}
+
+	if parentRaw, ok := args["parent_id"]; ok {
+		if parentID, ok := parentRaw.(string); ok {
+			req.ParentID = &parentID
+		}
+	}
+
+	if tags, ok := stringList(args, "tags"); ok {
+		req.Tags = &tags
+	}
+
 	if metaRaw, ok := args["metadata"].(map[string]interface{}); ok {
 		meta := make(map[string]string, len(metaRaw))
 		for k, v := range metaRaw {
@@ -403,6 +522,11 @@ func (s *Server) handleUpdateTask(ctx context.Context, request mcp.CallToolReque
 		req.Metadata = meta
 	}
 
+	if _, ok := args["expected_revision"]; ok {
+		revision := request.GetInt("expected_revision", 0)
+		req.ExpectedRevision = &revision
+	}
+
 	t, err := s.taskService.UpdateTask(ctx, req)
 	if err != nil {
 		if err == task.ErrProjectNotFound {
@@ -411,6 +535,22 @@ func (s *Server) handleUpdateTask(ctx context.Context, request mcp.CallToolReque
 		if err == task.ErrTaskNotFound {
 			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
 		}
+		var conflict *task.ConflictError
+		if errors.As(err, &conflict) {
+			return conflictResult(fmt.Sprintf("Task '%s' was changed by someone else", taskID), conflict, taskToMap(conflict.Task))
+		}
+		if errors.Is(err, task.ErrInvalidParent) || errors.Is(err, task.ErrTaskCycle) {
+			return errorResult(fmt.Sprintf("Invalid parent_id: %v", err)), nil
+		}
+		if errors.Is(err, task.ErrInvalidTransition) {
+			return errorResult(fmt.Sprintf("Cannot change status of task '%s': %v", taskID, err)), nil
+		}
+		if errors.Is(err, task.ErrTaskBlocked) {
+			return errorResult(fmt.Sprintf("Cannot start task '%s': %v. Finish the blockers first or remove the dependency.", taskID, err)), nil
+		}
+		if errors.Is(err, task.ErrInvalidPriority) || errors.Is(err, task.ErrInvalidDueDate) {
+			return errorResult(err.Error()), nil
+		}
 		return errorResult(fmt.Sprintf("Failed to update task: %v", err)), nil
 	}
 
@@ -444,56 +584,42 @@ func (s *Server) handleDeleteTask(ctx context.Context, request mcp.CallToolReque
 	return jsonResult(response)
 }
 
-// Artifact handlers
-
-func (s *Server) handleSaveArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (s *Server) handleGetTaskTree(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	projectID := request.GetString("project_id", "")
 	taskID := request.GetString("task_id", "")
-	content := request.GetString("content", "")
-	artifactType := request.GetString("type", "artifact")
-
-	req := service.SaveArtifactRequest{
-		ProjectID: projectID,
-		TaskID:    taskID,
-		Content:   content,
-		Type:      task.ArtifactType(artifactType),
-	}
-
-	// Parse metadata
-	args := request.GetArguments()
-	if metaRaw, ok := args["metadata"].(map[string]interface{}); ok {
-		meta := make(map[string]string, len(metaRaw))
-		for k, v := range metaRaw {
-			if str, ok := v.(string); ok {
-				meta[k] = str
-			}
-		}
-		req.Metadata = meta
-	}
 
-	a, err := s.taskService.SaveArtifact(ctx, req)
+	nodes, err := s.taskService.GetTaskTree(ctx, projectID, taskID)
 	if err != nil {
 		if err == task.ErrProjectNotFound {
 			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
 		}
 		if err == task.ErrTaskNotFound {
-			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'. Create the task first.", taskID, projectID)), nil
+			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
 		}
-		return errorResult(fmt.Sprintf("Failed to save artifact: %v", err)), nil
+		return errorResult(fmt.Sprintf("Failed to get task tree: %v", err)), nil
 	}
 
-	response := artifactToMap(a)
-	response["message"] = fmt.Sprintf("Artifact saved to task '%s' as %s", taskID, a.Filename())
+	trees := make([]map[string]interface{}, 0, len(nodes))
+	for _, n := range nodes {
+		trees = append(trees, taskNodeToMap(n))
+	}
+
+	response := map[string]interface{}{
+		"project_id": projectID,
+		"tasks":      trees,
+	}
+	if taskID != "" {
+		response["task_id"] = taskID
+	}
 
 	return jsonResult(response)
 }
 
-func (s *Server) handleGetArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (s *Server) handleGetTaskHistory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	projectID := request.GetString("project_id", "")
 	taskID := request.GetString("task_id", "")
-	artifactID := request.GetString("artifact_id", "")
 
-	a, err := s.taskService.GetArtifact(ctx, projectID, taskID, artifactID)
+	history, err := s.taskService.GetTaskHistory(ctx, projectID, taskID)
 	if err != nil {
 		if err == task.ErrProjectNotFound {
 			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
@@ -501,18 +627,77 @@ func (s *Server) handleGetArtifact(ctx context.Context, request mcp.CallToolRequ
 		if err == task.ErrTaskNotFound {
 			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
 		}
-		if err == task.ErrArtifactNotFound {
-			return errorResult(fmt.Sprintf("Artifact '%s' not found in task '%s'", artifactID, taskID)), nil
+		return errorResult(fmt.Sprintf("Failed to get task history: %v", err)), nil
+	}
+
+	changes := make([]map[string]interface{}, 0, len(history.Changes))
+	for _, c := range history.Changes {
+		change := map[string]interface{}{
+			"from": c.From,
+			"to":   c.To,
+			"at":   c.At.Format("2006-01-02T15:04:05Z"),
 		}
-		return errorResult(fmt.Sprintf("Failed to get artifact: %v", err)), nil
+		if c.Reason != "" {
+			change["reason"] = c.Reason
+		}
+		if c.Actor != "" {
+			change["actor"] = c.Actor
+		}
+		changes = append(changes, change)
 	}
 
-	return jsonResult(artifactToMap(a))
+	durations := make([]map[string]interface{}, 0, len(history.TimeInStatus))
+	for _, d := range history.TimeInStatus {
+		durations = append(durations, map[string]interface{}{
+			"status":   d.Status,
+			"seconds":  int64(d.Duration.Seconds()),
+			"duration": d.Duration.Round(time.Second).String(),
+			"visits":   d.Visits,
+			"current":  d.Current,
+		})
+	}
+
+	response := map[string]interface{}{
+		"project_id":     history.Task.ProjectID,
+		"task_id":        history.Task.ID,
+		"status":         history.Task.Status,
+		"history":        changes,
+		"time_in_status": durations,
+	}
+
+	return jsonResult(response)
 }
 
-func (s *Server) handleListArtifacts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (s *Server) handleAddDependency(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	req := dependencyRequest(request)
+
+	t, err := s.taskService.AddDependency(ctx, req)
+	if err != nil {
+		return dependencyError(req, err), nil
+	}
+
+	response := taskToMap(t)
+	response["message"] = fmt.Sprintf("Task '%s' is now blocked by '%s'", t.ID, req.BlockedByTaskID)
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleRemoveDependency(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	req := dependencyRequest(request)
+
+	t, err := s.taskService.RemoveDependency(ctx, req)
+	if err != nil {
+		return dependencyError(req, err), nil
+	}
+
+	response := taskToMap(t)
+	response["message"] = fmt.Sprintf("Task '%s' is no longer blocked by '%s'", t.ID, req.BlockedByTaskID)
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleListReadyTasks(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	projectID := request.GetString("project_id", "")
-	taskID := request.GetString("task_id", "")
 	args := request.GetArguments()
 
 	limit := 0
@@ -529,53 +714,39 @@ func (s *Server) handleListArtifacts(ctx context.Context, request mcp.CallToolRe
 		}
 	}
 
-	req := service.ListArtifactsRequest{
+	result, err := s.taskService.ListReadyTasks(ctx, service.ListReadyTasksRequest{
 		ProjectID: projectID,
-		TaskID:    taskID,
 		Limit:     limit,
 		Offset:    offset,
-	}
-
-	result, err := s.taskService.ListArtifacts(ctx, req)
+	})
 	if err != nil {
 		if err == task.ErrProjectNotFound {
 			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
 		}
-		if err == task.ErrTaskNotFound {
-			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
-		}
-		return errorResult(fmt.Sprintf("Failed to list artifacts: %v", err)), nil
+		return errorResult(fmt.Sprintf("Failed to list ready tasks: %v", err)), nil
 	}
 
-	artifactMaps := make([]map[string]interface{}, 0, len(result.Items))
-	for _, a := range result.Items {
-		// Include preview of content
-		preview := a.Content
-		if len(preview) > 200 {
-			preview = preview[:200] + "..."
-		}
-		m := artifactToMap(a)
-		m["content_preview"] = preview
-		artifactMaps = append(artifactMaps, m)
+	taskMaps := make([]map[string]interface{}, 0, len(result.Items))
+	for _, t := range result.Items {
+		taskMaps = append(taskMaps, taskToMap(t))
 	}
 
 	response := map[string]interface{}{
-		"project_id": projectID,
-		"task_id":    taskID,
-		"artifacts":  artifactMaps,
-		"total":      result.Total,
-		"limit":      result.Limit,
-		"offset":     result.Offset,
-		"has_more":   result.HasMore,
+		"tasks":    taskMaps,
+		"total":    result.Total,
+		"limit":    result.Limit,
+		"offset":   result.Offset,
+		"has_more": result.HasMore,
+	}
+	if projectID != "" {
+		response["project_id"] = projectID
 	}
 
 	return jsonResult(response)
 }
 
-func (s *Server) handleSearchArtifacts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query := request.GetString("query", "")
+func (s *Server) handleSuggestNextTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	projectID := request.GetString("project_id", "")
-	taskID := request.GetString("task_id", "")
 	args := request.GetArguments()
 
 	limit := 0
@@ -585,56 +756,106 @@ func (s *Server) handleSearchArtifacts(ctx context.Context, request mcp.CallTool
 		}
 	}
 
-	offset := 0
-	if offsetRaw, ok := args["offset"]; ok {
-		if offsetVal, ok := offsetRaw.(float64); ok {
-			offset = int(offsetVal)
+	suggestions, err := s.taskService.SuggestNextTask(ctx, service.SuggestNextTaskRequest{
+		ProjectID: projectID,
+		Limit:     limit,
+	})
+	if err != nil {
+		if err == task.ErrProjectNotFound {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
 		}
+		return errorResult(fmt.Sprintf("Failed to suggest tasks: %v", err)), nil
 	}
 
-	req := service.SearchArtifactsRequest{
-		Query:     query,
-		ProjectID: projectID,
-		TaskID:    taskID,
-		Limit:     limit,
-		Offset:    offset,
+	suggestionMaps := make([]map[string]interface{}, 0, len(suggestions))
+	for _, sg := range suggestions {
+		m := taskToMap(sg.Task)
+		m["score"] = sg.Score
+		m["blocked"] = sg.Blocked
+		m["factors"] = sg.Factors
+		m["explanation"] = sg.Explain()
+		suggestionMaps = append(suggestionMaps, m)
 	}
 
-	result, err := s.taskService.SearchArtifacts(ctx, req)
+	response := map[string]interface{}{
+		"suggestions": suggestionMaps,
+	}
+	if projectID != "" {
+		response["project_id"] = projectID
+	}
+	if len(suggestions) > 0 {
+		best := suggestions[0]
+		response["message"] = fmt.Sprintf("Work on '%s' next: %s", best.Task.Ref(), best.Explain())
+	} else {
+		response["message"] = "No open or in-progress tasks"
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleClaimTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	req := claimRequest(ctx, request)
+
+	t, err := s.taskService.ClaimTask(ctx, req)
 	if err != nil {
-		return errorResult(fmt.Sprintf("Failed to search artifacts: %v", err)), nil
+		return claimError(req, err), nil
 	}
 
-	artifactMaps := make([]map[string]interface{}, 0, len(result.Items))
-	for _, a := range result.Items {
-		// Include preview of content
-		preview := a.Content
-		if len(preview) > 200 {
-			preview = preview[:200] + "..."
-		}
-		m := artifactToMap(a)
-		m["content_preview"] = preview
-		artifactMaps = append(artifactMaps, m)
+	response := taskToMap(t)
+	response["message"] = fmt.Sprintf("Task '%s' claimed by '%s' until %s", t.ID, t.Claim.Owner, t.Claim.ExpiresAt.Format(time.RFC3339))
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleRenewClaim(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	req := claimRequest(ctx, request)
+
+	t, err := s.taskService.RenewClaim(ctx, req)
+	if err != nil {
+		return claimError(req, err), nil
 	}
 
-	response := map[string]interface{}{
-		"query":     query,
-		"artifacts": artifactMaps,
-		"total":     result.Total,
-		"limit":     result.Limit,
-		"offset":    result.Offset,
-		"has_more":  result.HasMore,
+	response := taskToMap(t)
+	response["message"] = fmt.Sprintf("Claim of '%s' on task '%s' renewed until %s", t.Claim.Owner, t.ID, t.Claim.ExpiresAt.Format(time.RFC3339))
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleReleaseTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	req := claimRequest(ctx, request)
+
+	t, err := s.taskService.ReleaseTask(ctx, req)
+	if err != nil {
+		return claimError(req, err), nil
 	}
 
+	response := taskToMap(t)
+	response["message"] = fmt.Sprintf("Task '%s' released", t.ID)
+
 	return jsonResult(response)
 }
 
-func (s *Server) handleDeleteArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// resumeOmittedLimit is the number of omitted items resume_task lists.
+const resumeOmittedLimit = 20
+
+func (s *Server) handleResumeTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	projectID := request.GetString("project_id", "")
 	taskID := request.GetString("task_id", "")
-	artifactID := request.GetString("artifact_id", "")
+	args := request.GetArguments()
 
-	err := s.taskService.DeleteArtifact(ctx, projectID, taskID, artifactID)
+	budget := 0
+	if budgetRaw, ok := args["token_budget"]; ok {
+		if budgetVal, ok := budgetRaw.(float64); ok {
+			budget = int(budgetVal)
+		}
+	}
+
+	pack, err := s.taskService.ResumeTask(ctx, service.ResumeTaskRequest{
+		ProjectID:             projectID,
+		TaskID:                taskID,
+		TokenBudget:           budget,
+		ExcludeGlobalMemories: hidesGlobalMemories(ctx),
+	})
 	if err != nil {
 		if err == task.ErrProjectNotFound {
 			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
@@ -642,23 +863,790 @@ func (s *Server) handleDeleteArtifact(ctx context.Context, request mcp.CallToolR
 		if err == task.ErrTaskNotFound {
 			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
 		}
-		if err == task.ErrArtifactNotFound {
-			return errorResult(fmt.Sprintf("Artifact '%s' not found in task '%s'", artifactID, taskID)), nil
-		}
-		return errorResult(fmt.Sprintf("Failed to delete artifact: %v", err)), nil
+		return errorResult(fmt.Sprintf("Failed to resume task: %v", err)), nil
+	}
+
+	omitted := make([]map[string]interface{}, 0, min(len(pack.Omitted), resumeOmittedLimit))
+	for _, item := range pack.Omitted[:min(len(pack.Omitted), resumeOmittedLimit)] {
+		omitted = append(omitted, omittedItemToMap(item))
 	}
 
 	response := map[string]interface{}{
-		"project_id":  projectID,
-		"task_id":     taskID,
-		"artifact_id": artifactID,
-		"message":     fmt.Sprintf("Artifact '%s' deleted from task '%s'", artifactID, taskID),
+		"task": taskToMap(pack.Task),
+		"project": map[string]interface{}{
+			"id":             pack.Project.ID,
+			"name":           pack.Project.Name,
+			"description":    pack.Project.Description,
+			"workspace_path": pack.Project.WorkspacePath,
+		},
+		"memories":      contextItemMaps(pack.Section(service.SectionMemory)),
+		"decisions":     contextItemMaps(pack.Section(service.SectionDecision)),
+		"questions":     contextItemMaps(pack.Section(service.SectionQuestion)),
+		"notes":         contextItemMaps(pack.Section(service.SectionNote)),
+		"other":         contextItemMaps(pack.Section(service.SectionOther)),
+		"omitted":       omitted,
+		"omitted_total": len(pack.Omitted),
+		"token_budget":  pack.Budget,
+		"tokens_used":   pack.UsedTokens,
+	}
+
+	message := fmt.Sprintf("Resumed '%s' with %d items (~%d of %d tokens)", pack.Task.Ref(), len(pack.Items), pack.UsedTokens, pack.Budget)
+	if len(pack.Omitted) > 0 {
+		message += fmt.Sprintf("; %d left out, fetch them with get_artifact or get_memory", len(pack.Omitted))
 	}
+	response["message"] = message
 
 	return jsonResult(response)
 }
 
-// File operation handlers
+func (s *Server) handleListTags(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+
+	tags, err := s.taskService.ListTags(ctx, projectID)
+	if err != nil {
+		if err == task.ErrProjectNotFound {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to list tags: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"tags":  tags,
+		"total": len(tags),
+	}
+	if projectID != "" {
+		response["project_id"] = projectID
+	}
+
+	return jsonResult(response)
+}
+
+// Artifact handlers
+
+func (s *Server) handleSaveArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	content := request.GetString("content", "")
+	artifactType := request.GetString("type", "artifact")
+
+	req := service.SaveArtifactRequest{
+		ProjectID: projectID,
+		TaskID:    taskID,
+		Content:   content,
+		Type:      task.ArtifactType(artifactType),
+	}
+
+	args := request.GetArguments()
+	req.Tags, _ = stringList(args, "tags")
+
+	// Parse metadata
+	if metaRaw, ok := args["metadata"].(map[string]interface{}); ok {
+		meta := make(map[string]string, len(metaRaw))
+		for k, v := range metaRaw {
+			if str, ok := v.(string); ok {
+				meta[k] = str
+			}
+		}
+		req.Metadata = meta
+	}
+
+	a, err := s.taskService.SaveArtifact(ctx, req)
+	if err != nil {
+		if err == task.ErrProjectNotFound {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
+		}
+		if err == task.ErrTaskNotFound {
+			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'. Create the task first.", taskID, projectID)), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to save artifact: %v", err)), nil
+	}
+
+	response := artifactToMap(a)
+	response["message"] = fmt.Sprintf("Artifact saved to task '%s' as %s", taskID, a.Filename())
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleGetArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	artifactID := request.GetString("artifact_id", "")
+
+	a, err := s.taskService.GetArtifact(ctx, projectID, taskID, artifactID)
+	if err != nil {
+		if err == task.ErrProjectNotFound {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
+		}
+		if err == task.ErrTaskNotFound {
+			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
+		}
+		if err == task.ErrArtifactNotFound {
+			return errorResult(fmt.Sprintf("Artifact '%s' not found in task '%s'", artifactID, taskID)), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to get artifact: %v", err)), nil
+	}
+
+	response := artifactToMap(a)
+	if includeLinks, _ := request.GetArguments()["include_links"].(bool); includeLinks {
+		items, err := s.taskService.GetLinkedItems(ctx, a)
+		if err != nil {
+			return errorResult(fmt.Sprintf("Failed to resolve links: %v", err)), nil
+		}
+		items = visibleLinkedItems(ctx, items)
+		linked := make([]map[string]interface{}, 0, len(items))
+		for _, item := range items {
+			linked = append(linked, linkedItemToMap(item))
+		}
+		response["linked"] = linked
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleLinkArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	req := linkRequest(request)
+
+	a, err := s.taskService.LinkArtifact(ctx, req)
+	if err != nil {
+		if errors.Is(err, task.ErrInvalidLink) {
+			return errorResult(err.Error()), nil
+		}
+		return artifactError(err, req.ProjectID, req.TaskID, req.ArtifactID, "link artifact"), nil
+	}
+
+	response := artifactToMap(a)
+	response["message"] = fmt.Sprintf("Artifact '%s' %s %s", a.ID, req.Relation, linkTarget(req))
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleUnlinkArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	req := linkRequest(request)
+
+	a, err := s.taskService.UnlinkArtifact(ctx, req)
+	if err != nil {
+		if errors.Is(err, task.ErrInvalidLink) || errors.Is(err, task.ErrLinkNotFound) {
+			return errorResult(err.Error()), nil
+		}
+		return artifactError(err, req.ProjectID, req.TaskID, req.ArtifactID, "unlink artifact"), nil
+	}
+
+	response := artifactToMap(a)
+	response["message"] = fmt.Sprintf("Removed link '%s' from artifact '%s' to %s", req.Relation, a.ID, linkTarget(req))
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleUpdateArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	artifactID := request.GetString("artifact_id", "")
+
+	req := service.UpdateArtifactRequest{
+		ProjectID:  projectID,
+		TaskID:     taskID,
+		ArtifactID: artifactID,
+	}
+
+	args := request.GetArguments()
+	if contentRaw, ok := args["content"]; ok {
+		if content, ok := contentRaw.(string); ok {
+			req.Content = &content
+		}
+	}
+
+	if tags, ok := stringList(args, "tags"); ok {
+		req.Tags = &tags
+	}
+
+	// Parse metadata
+	if metaRaw, ok := args["metadata"].(map[string]interface{}); ok {
+		meta := make(map[string]string, len(metaRaw))
+		for k, v := range metaRaw {
+			if str, ok := v.(string); ok {
+				meta[k] = str
+			}
+		}
+		req.Metadata = meta
+	}
+
+	a, err := s.taskService.UpdateArtifact(ctx, req)
+	if err != nil {
+		if errors.Is(err, task.ErrProjectNotFound) {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
+		}
+		if errors.Is(err, task.ErrTaskNotFound) {
+			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
+		}
+		if errors.Is(err, task.ErrArtifactNotFound) {
+			return errorResult(fmt.Sprintf("Artifact '%s' not found in task '%s'", artifactID, taskID)), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to update artifact: %v", err)), nil
+	}
+
+	response := artifactToMap(a)
+	response["message"] = fmt.Sprintf("Artifact '%s' is now at version %d", artifactID, a.Version)
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleListArtifactVersions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	artifactID := request.GetString("artifact_id", "")
+
+	versions, err := s.taskService.ListArtifactVersions(ctx, projectID, taskID, artifactID)
+	if err != nil {
+		return artifactError(err, projectID, taskID, artifactID, "list artifact versions"), nil
+	}
+
+	versionMaps := make([]map[string]interface{}, 0, len(versions))
+	for _, v := range versions {
+		versionMaps = append(versionMaps, map[string]interface{}{
+			"version":    v.Version,
+			"written_at": versionTime(v).Format("2006-01-02T15:04:05Z"),
+			"tags":       tagList(v.Tags),
+			"size":       len(v.Content),
+			"summary":    firstLine(v.Content),
+		})
+	}
+
+	response := map[string]interface{}{
+		"project_id":  projectID,
+		"task_id":     taskID,
+		"artifact_id": artifactID,
+		"versions":    versionMaps,
+		"current":     versions[len(versions)-1].Version,
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleGetArtifactVersion(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	artifactID := request.GetString("artifact_id", "")
+	version := 0
+	if versionRaw, ok := request.GetArguments()["version"].(float64); ok {
+		version = int(versionRaw)
+	}
+
+	a, err := s.taskService.GetArtifactVersion(ctx, projectID, taskID, artifactID, version)
+	if err != nil {
+		if errors.Is(err, task.ErrArtifactVersionNotFound) {
+			return errorResult(fmt.Sprintf("Artifact '%s' has no version %d. Use list_artifact_versions to see the versions.", artifactID, version)), nil
+		}
+		return artifactError(err, projectID, taskID, artifactID, "get artifact version"), nil
+	}
+
+	return jsonResult(artifactToMap(a))
+}
+
+func (s *Server) handleDiffArtifactVersions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	artifactID := request.GetString("artifact_id", "")
+
+	req := service.DiffArtifactVersionsRequest{
+		ProjectID:  projectID,
+		TaskID:     taskID,
+		ArtifactID: artifactID,
+	}
+
+	args := request.GetArguments()
+	if fromRaw, ok := args["from"].(float64); ok {
+		req.From = int(fromRaw)
+	}
+	if toRaw, ok := args["to"].(float64); ok {
+		req.To = int(toRaw)
+	}
+
+	d, err := s.taskService.DiffArtifactVersions(ctx, req)
+	if err != nil {
+		if errors.Is(err, task.ErrArtifactVersionNotFound) {
+			return errorResult(fmt.Sprintf("%v. Use list_artifact_versions to see the versions.", err)), nil
+		}
+		return artifactError(err, projectID, taskID, artifactID, "diff artifact versions"), nil
+	}
+
+	response := map[string]interface{}{
+		"project_id":  projectID,
+		"task_id":     taskID,
+		"artifact_id": artifactID,
+		"from":        d.From.Version,
+		"to":          d.To.Version,
+		"added":       d.Added,
+		"removed":     d.Removed,
+		"diff":        d.Diff,
+	}
+	switch {
+	case d.TooLarge:
+		response["message"] = fmt.Sprintf("Versions %d and %d differ in too many lines to show a diff. Use get_artifact_version to read them.", d.From.Version, d.To.Version)
+	case d.Diff == "":
+		response["message"] = fmt.Sprintf("Content is the same in versions %d and %d", d.From.Version, d.To.Version)
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleListArtifacts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	args := request.GetArguments()
+
+	limit := 0
+	if limitRaw, ok := args["limit"]; ok {
+		if limitVal, ok := limitRaw.(float64); ok {
+			limit = int(limitVal)
+		}
+	}
+
+	offset := 0
+	if offsetRaw, ok := args["offset"]; ok {
+		if offsetVal, ok := offsetRaw.(float64); ok {
+			offset = int(offsetVal)
+		}
+	}
+
+	tags, tagMatch, err := tagFilter(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	includeSuperseded, _ := args["include_superseded"].(bool)
+
+	req := service.ListArtifactsRequest{
+		ProjectID:         projectID,
+		TaskID:            taskID,
+		Limit:             limit,
+		Offset:            offset,
+		Tags:              tags,
+		TagMatch:          tagMatch,
+		IncludeSuperseded: includeSuperseded,
+	}
+
+	result, err := s.taskService.ListArtifacts(ctx, req)
+	if err != nil {
+		if err == task.ErrProjectNotFound {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
+		}
+		if err == task.ErrTaskNotFound {
+			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to list artifacts: %v", err)), nil
+	}
+
+	artifactMaps := make([]map[string]interface{}, 0, len(result.Items))
+	for _, a := range result.Items {
+		// Include preview of content
+		preview := a.Content
+		if len(preview) > 200 {
+			preview = preview[:200] + "..."
+		}
+		m := artifactToMap(a)
+		m["content_preview"] = preview
+		artifactMaps = append(artifactMaps, m)
+	}
+
+	response := map[string]interface{}{
+		"project_id": projectID,
+		"task_id":    taskID,
+		"artifacts":  artifactMaps,
+		"total":      result.Total,
+		"limit":      result.Limit,
+		"offset":     result.Offset,
+		"has_more":   result.HasMore,
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleSearchArtifacts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query := request.GetString("query", "")
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	args := request.GetArguments()
+
+	limit := 0
+	if limitRaw, ok := args["limit"]; ok {
+		if limitVal, ok := limitRaw.(float64); ok {
+			limit = int(limitVal)
+		}
+	}
+
+	offset := 0
+	if offsetRaw, ok := args["offset"]; ok {
+		if offsetVal, ok := offsetRaw.(float64); ok {
+			offset = int(offsetVal)
+		}
+	}
+
+	tags, tagMatch, err := tagFilter(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	includeSuperseded, _ := args["include_superseded"].(bool)
+	includeMemories := true
+	if v, ok := args["include_memories"].(bool); ok {
+		includeMemories = v
+	}
+
+	req := service.SearchArtifactsRequest{
+		Query:             query,
+		ProjectID:         projectID,
+		TaskID:            taskID,
+		Limit:             limit,
+		Offset:            offset,
+		Tags:              tags,
+		TagMatch:          tagMatch,
+		IncludeSuperseded: includeSuperseded,
+	}
+
+	result, err := s.taskService.SearchArtifacts(ctx, req)
+	if err != nil {
+		if errors.Is(err, task.ErrInvalidQuery) {
+			return errorResult(err.Error()), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to search artifacts: %v", err)), nil
+	}
+
+	artifactMaps := make([]map[string]interface{}, 0, len(result.Items))
+	for _, r := range result.Items {
+		// Include preview of content
+		preview := r.Artifact.Content
+		if len(preview) > 200 {
+			preview = preview[:200] + "..."
+		}
+		m := artifactToMap(r.Artifact)
+		m["content_preview"] = preview
+		m["score"] = r.Score
+		m["snippets"] = r.Snippets
+		artifactMaps = append(artifactMaps, m)
+	}
+
+	response := map[string]interface{}{
+		"query":     query,
+		"artifacts": artifactMaps,
+		"total":     result.Total,
+		"limit":     result.Limit,
+		"offset":    result.Offset,
+		"has_more":  result.HasMore,
+	}
+
+	// Memories are listed once, next to the first page of artifacts. They
+	// have no task, so a search limited to one finds none.
+	if includeMemories && offset == 0 && taskID == "" {
+		memories, err := s.taskService.SearchMemories(ctx, service.SearchMemoriesRequest{
+			Query:         query,
+			ProjectID:     projectID,
+			Tags:          tags,
+			TagMatch:      tagMatch,
+			ExcludeGlobal: hidesGlobalMemories(ctx),
+			Limit:         searchMemoryLimit,
+		})
+		if err != nil {
+			return errorResult(fmt.Sprintf("Failed to search memories: %v", err)), nil
+		}
+
+		memoryMaps := make([]map[string]interface{}, 0, len(memories.Items))
+		for _, h := range memories.Items {
+			m := memoryToMap(h.Memory)
+			m["score"] = h.Score
+			m["snippets"] = h.Snippets
+			memoryMaps = append(memoryMaps, m)
+		}
+		response["memories"] = memoryMaps
+		response["memories_total"] = memories.Total
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleRebuildSearchIndex(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	n, err := s.taskService.RebuildSearchIndex(ctx)
+	if err != nil {
+		return errorResult(fmt.Sprintf("Failed to rebuild search index: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"indexed": n,
+		"message": fmt.Sprintf("Search index rebuilt with %d artifacts and memories", n),
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleCompactArtifacts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	args := request.GetArguments()
+
+	req := service.CompactRequest{
+		ProjectID: projectID,
+		TaskID:    taskID,
+	}
+	if daysRaw, ok := args["older_than_days"]; ok {
+		if days, ok := daysRaw.(float64); ok {
+			req.OlderThan = time.Duration(days * float64(24*time.Hour))
+		}
+	}
+	if types, ok := stringList(args, "types"); ok {
+		for _, t := range types {
+			req.Types = append(req.Types, task.ArtifactType(strings.TrimSpace(t)))
+		}
+	}
+	if dryRun, ok := args["dry_run"].(bool); ok {
+		req.DryRun = dryRun
+	}
+	useSampling := true
+	if v, ok := args["use_sampling"].(bool); ok {
+		useSampling = v
+	}
+	if useSampling && clientSupportsSampling(ctx) {
+		req.Summarizer = samplingSummarizer{mcpServer: s.mcpServer}
+	}
+
+	compactions, err := s.taskService.Compact(ctx, req)
+	if err != nil {
+		if err == task.ErrProjectNotFound {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
+		}
+		if err == task.ErrTaskNotFound {
+			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to compact artifacts: %v", err)), nil
+	}
+
+	compacted := 0
+	compactionMaps := make([]map[string]interface{}, 0, len(compactions))
+	for _, c := range compactions {
+		ids := make([]string, 0, len(c.Artifacts))
+		for _, a := range c.Artifacts {
+			ids = append(ids, a.ID)
+		}
+		compacted += len(ids)
+
+		summary := map[string]interface{}{
+			"content":  c.Summary.Content,
+			"metadata": c.Summary.Metadata,
+		}
+		if !req.DryRun {
+			summary["id"] = c.Summary.ID
+		}
+
+		compactionMaps = append(compactionMaps, map[string]interface{}{
+			"project_id":   c.Task.ProjectID,
+			"task_id":      c.Task.ID,
+			"compacted":    len(ids),
+			"artifact_ids": ids,
+			"summary":      summary,
+		})
+	}
+
+	response := map[string]interface{}{
+		"compactions": compactionMaps,
+		"compacted":   compacted,
+		"dry_run":     req.DryRun,
+	}
+	switch {
+	case len(compactions) == 0:
+		response["message"] = "Nothing to compact"
+	case req.DryRun:
+		response["message"] = fmt.Sprintf("Would compact %d artifacts in %d tasks (dry run, nothing saved)", compacted, len(compactions))
+	default:
+		response["message"] = fmt.Sprintf("Compacted %d artifacts in %d tasks into summaries; the originals are superseded and still available with get_artifact", compacted, len(compactions))
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleDeleteArtifact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	taskID := request.GetString("task_id", "")
+	artifactID := request.GetString("artifact_id", "")
+
+	err := s.taskService.DeleteArtifact(ctx, projectID, taskID, artifactID)
+	if err != nil {
+		if err == task.ErrProjectNotFound {
+			return errorResult(fmt.Sprintf("Project '%s' not found", projectID)), nil
+		}
+		if err == task.ErrTaskNotFound {
+			return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID)), nil
+		}
+		if err == task.ErrArtifactNotFound {
+			return errorResult(fmt.Sprintf("Artifact '%s' not found in task '%s'", artifactID, taskID)), nil
+		}
+		return errorResult(fmt.Sprintf("Failed to delete artifact: %v", err)), nil
+	}
+
+	response := map[string]interface{}{
+		"project_id":  projectID,
+		"task_id":     taskID,
+		"artifact_id": artifactID,
+		"message":     fmt.Sprintf("Artifact '%s' deleted from task '%s'", artifactID, taskID),
+	}
+
+	return jsonResult(response)
+}
+
+// Memory handlers
+
+// searchMemoryLimit is the number of memories search_artifacts lists.
+const searchMemoryLimit = 5
+
+func (s *Server) handleCreateMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	args := request.GetArguments()
+
+	tags, _ := stringList(args, "tags")
+	pinned, _ := args["pinned"].(bool)
+
+	req := service.CreateMemoryRequest{
+		ProjectID: projectID,
+		ID:        request.GetString("id", ""),
+		Title:     request.GetString("title", ""),
+		Content:   request.GetString("content", ""),
+		Tags:      tags,
+		Pinned:    pinned,
+	}
+
+	m, err := s.taskService.CreateMemory(ctx, req)
+	if err != nil {
+		if errors.Is(err, task.ErrMemoryAlreadyExists) {
+			id := req.ID
+			if id == "" {
+				id = req.Title
+			}
+			return errorResult(fmt.Sprintf("Memory '%s' already exists in %s, use update_memory to change it", task.NewMemoryID(id), memoryScopeName(projectID))), nil
+		}
+		if errors.Is(err, task.ErrInvalidMemoryID) {
+			return errorResult("Invalid memory ID. Set an id or title containing letters or numbers."), nil
+		}
+		return memoryError(err, projectID, req.ID, "create"), nil
+	}
+
+	response := memoryToMap(m)
+	response["message"] = fmt.Sprintf("Memory '%s' saved in %s", m.ID, memoryScopeName(projectID))
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleGetMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	memoryID := request.GetString("memory_id", "")
+
+	m, err := s.taskService.GetMemory(ctx, projectID, memoryID)
+	if err != nil {
+		return memoryError(err, projectID, memoryID, "get"), nil
+	}
+
+	return jsonResult(memoryToMap(m))
+}
+
+func (s *Server) handleListMemories(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	args := request.GetArguments()
+
+	limit := 0
+	if limitRaw, ok := args["limit"]; ok {
+		if limitVal, ok := limitRaw.(float64); ok {
+			limit = int(limitVal)
+		}
+	}
+
+	offset := 0
+	if offsetRaw, ok := args["offset"]; ok {
+		if offsetVal, ok := offsetRaw.(float64); ok {
+			offset = int(offsetVal)
+		}
+	}
+
+	tags, tagMatch, err := tagFilter(args)
+	if err != nil {
+		return errorResult(err.Error()), nil
+	}
+
+	result, err := s.taskService.ListMemories(ctx, service.ListMemoriesRequest{
+		ProjectID: projectID,
+		Tags:      tags,
+		TagMatch:  tagMatch,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return memoryError(err, projectID, "", "list"), nil
+	}
+
+	memories := make([]map[string]interface{}, 0, len(result.Items))
+	for _, m := range result.Items {
+		memories = append(memories, memoryToMap(m))
+	}
+
+	response := map[string]interface{}{
+		"project_id": projectID,
+		"memories":   memories,
+		"total":      result.Total,
+		"limit":      result.Limit,
+		"offset":     result.Offset,
+		"has_more":   result.HasMore,
+	}
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleUpdateMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	memoryID := request.GetString("memory_id", "")
+
+	req := service.UpdateMemoryRequest{
+		ProjectID: projectID,
+		ID:        memoryID,
+	}
+
+	args := request.GetArguments()
+	if title, ok := args["title"].(string); ok {
+		req.Title = &title
+	}
+	if content, ok := args["content"].(string); ok {
+		req.Content = &content
+	}
+	if tags, ok := stringList(args, "tags"); ok {
+		req.Tags = &tags
+	}
+	if pinned, ok := args["pinned"].(bool); ok {
+		req.Pinned = &pinned
+	}
+
+	m, err := s.taskService.UpdateMemory(ctx, req)
+	if err != nil {
+		return memoryError(err, projectID, memoryID, "update"), nil
+	}
+
+	response := memoryToMap(m)
+	response["message"] = fmt.Sprintf("Memory '%s' updated", m.ID)
+
+	return jsonResult(response)
+}
+
+func (s *Server) handleDeleteMemory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	memoryID := request.GetString("memory_id", "")
+
+	if err := s.taskService.DeleteMemory(ctx, projectID, memoryID); err != nil {
+		return memoryError(err, projectID, memoryID, "delete"), nil
+	}
+
+	response := map[string]interface{}{
+		"project_id": projectID,
+		"memory_id":  memoryID,
+		"message":    fmt.Sprintf("Memory '%s' deleted from %s", memoryID, memoryScopeName(projectID)),
+	}
+
+	return jsonResult(response)
+}
+
+// File operation handlers
 
 func (s *Server) handleReadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	projectID := request.GetString("project_id", "")
@@ -829,6 +1817,8 @@ func (s *Server) handleSearchFiles(ctx context.Context, request mcp.CallToolRequ
 			"file_path":   m.FilePath,
 			"line_number": m.LineNumber,
 			"line":        m.Line,
+			"offset":      m.Offset,
+			"highlights":  m.Highlights,
 		})
 	}
 
@@ -843,43 +1833,398 @@ func (s *Server) handleSearchFiles(ctx context.Context, request mcp.CallToolRequ
 
 // Helper functions
 
+// parseWorkflow decodes the optional "workflow" argument. It returns nil when
+// the argument is absent.
+func parseWorkflow(args map[string]interface{}) (*task.Workflow, error) {
+	raw, ok := args["workflow"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow: %v", err)
+	}
+	var w task.Workflow
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("invalid workflow: expected {\"statuses\": [{\"name\": ..., \"transitions\": [...], \"terminal\": bool}]}: %v", err)
+	}
+	return &w, nil
+}
+
+// stringList decodes an optional array of strings. ok is false when the
+// argument is absent; non-string items are skipped.
+func stringList(args map[string]interface{}, name string) (values []string, ok bool) {
+	raw, ok := args[name].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	values = make([]string, 0, len(raw))
+	for _, v := range raw {
+		if str, ok := v.(string); ok {
+			values = append(values, str)
+		}
+	}
+	return values, true
+}
+
+// tagFilter decodes the "tags" and "tag_match" arguments of list and search tools.
+func tagFilter(args map[string]interface{}) ([]string, task.TagMatch, error) {
+	tags, _ := stringList(args, "tags")
+	mode, _ := args["tag_match"].(string)
+	match, err := task.ParseTagMatch(mode)
+	if err != nil {
+		return nil, "", err
+	}
+	return tags, match, nil
+}
+
+// actor returns who is making a change: the "actor" argument, or else the
+// name the MCP client reported when it connected.
+func actor(ctx context.Context, request mcp.CallToolRequest) string {
+	if a := request.GetString("actor", ""); a != "" {
+		return a
+	}
+	if session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo); ok {
+		return session.GetClientInfo().Name
+	}
+	return ""
+}
+
 func projectToMap(p *task.Project) map[string]interface{} {
 	return map[string]interface{}{
 		"id":             p.ID,
 		"name":           p.Name,
 		"description":    p.Description,
 		"workspace_path": p.WorkspacePath,
+		"workflow":       p.TaskWorkflow(),
 		"metadata":       p.Metadata,
+		"revision":       p.Revision,
 		"created_at":     p.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		"updated_at":     p.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 }
 
 func taskToMap(t *task.Task) map[string]interface{} {
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"id":             t.ID,
 		"project_id":     t.ProjectID,
+		"parent_id":      t.ParentID,
 		"name":           t.Name,
 		"description":    t.Description,
 		"status":         t.Status,
+		"priority":       t.EffectivePriority(),
+		"due_date":       formatDueDate(t.DueDate),
 		"workspace_path": t.WorkspacePath,
+		"tags":           tagList(t.Tags),
+		"blocked_by":     taskRefs(t.BlockedBy),
+		"blocks":         taskRefs(t.Blocks),
 		"metadata":       t.Metadata,
+		"revision":       t.Revision,
 		"created_at":     t.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		"updated_at":     t.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 	}
+	if t.Claim != nil {
+		m["claim"] = map[string]interface{}{
+			"owner":      t.Claim.Owner,
+			"claimed_at": t.Claim.ClaimedAt.Format(time.RFC3339),
+			"expires_at": t.Claim.ExpiresAt.Format(time.RFC3339),
+			"active":     t.Claim.Active(time.Now()),
+		}
+	}
+	return m
+}
+
+// taskRefs returns refs, or an empty list so it is never rendered as null.
+func taskRefs(refs []task.TaskRef) []task.TaskRef {
+	if refs == nil {
+		return []task.TaskRef{}
+	}
+	return refs
+}
+
+// formatDueDate returns the due date in RFC 3339, or "" when there is none.
+func formatDueDate(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	return due.Format(time.RFC3339)
+}
+
+// tagList returns tags, or an empty list so it is never rendered as null.
+func tagList(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func taskNodeToMap(n *service.TaskNode) map[string]interface{} {
+	children := make([]map[string]interface{}, 0, len(n.Children))
+	for _, c := range n.Children {
+		children = append(children, taskNodeToMap(c))
+	}
+
+	m := taskToMap(n.Task)
+	m["children"] = children
+	m["rollup"] = n.Rollup
+	m["progress"] = fmt.Sprintf("%d/%d subtasks completed", n.Rollup.Completed, n.Rollup.Total)
+	return m
 }
 
 func artifactToMap(a *task.Artifact) map[string]interface{} {
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"id":         a.ID,
 		"project_id": a.ProjectID,
 		"task_id":    a.TaskID,
 		"type":       a.Type,
 		"content":    a.Content,
+		"tags":       tagList(a.Tags),
 		"metadata":   a.Metadata,
 		"filename":   a.Filename(),
+		"version":    max(a.Version, 1),
 		"created_at": a.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
+	if a.UpdatedAt != nil {
+		m["updated_at"] = a.UpdatedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if len(a.Links) > 0 {
+		links := make([]map[string]interface{}, 0, len(a.Links))
+		for _, l := range a.Links {
+			links = append(links, linkToMap(l))
+		}
+		m["links"] = links
+	}
+	if a.IsSuperseded() {
+		m["superseded_by"] = a.SupersededBy
+	}
+	return m
+}
+
+func memoryToMap(m *task.Memory) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         m.ID,
+		"project_id": m.ProjectID,
+		"global":     m.IsGlobal(),
+		"title":      m.Title,
+		"content":    m.Content,
+		"tags":       tagList(m.Tags),
+		"pinned":     m.Pinned,
+		"created_at": m.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"updated_at": m.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// contextItemMaps describes the included items of a context pack.
+func contextItemMaps(items []*service.ContextItem) []map[string]interface{} {
+	maps := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		m := contextItemRef(item)
+		m["content"] = item.Content
+		m["truncated"] = item.Truncated
+		maps = append(maps, m)
+	}
+	return maps
+}
+
+// omittedItemToMap describes an item left out of a context pack, with enough
+// to decide whether to fetch it.
+func omittedItemToMap(item *service.ContextItem) map[string]interface{} {
+	m := contextItemRef(item)
+	m["section"] = item.Section
+	if item.Memory != nil {
+		m["summary"] = firstLine(item.Memory.Content)
+	} else {
+		m["summary"] = firstLine(item.Artifact.Content)
+	}
+	return m
+}
+
+// contextItemRef identifies a memory or artifact in a context pack.
+func contextItemRef(item *service.ContextItem) map[string]interface{} {
+	if m := item.Memory; m != nil {
+		return map[string]interface{}{
+			"memory_id":  m.ID,
+			"project_id": m.ProjectID,
+			"global":     m.IsGlobal(),
+			"title":      m.Title,
+			"tokens":     item.Tokens,
+		}
+	}
+
+	a := item.Artifact
+	return map[string]interface{}{
+		"artifact_id": a.ID,
+		"type":        a.Type,
+		"tags":        tagList(a.Tags),
+		"written_at":  versionTime(a).Format("2006-01-02T15:04:05Z"),
+		"tokens":      item.Tokens,
+	}
+}
+
+// memoryScopeName describes where a memory lives, for messages.
+func memoryScopeName(projectID string) string {
+	if projectID == "" {
+		return "global memories"
+	}
+	return fmt.Sprintf("project '%s'", projectID)
+}
+
+// memoryError reports a failed memory operation.
+func memoryError(err error, projectID, memoryID, action string) *mcp.CallToolResult {
+	switch {
+	case errors.Is(err, task.ErrProjectNotFound):
+		return errorResult(fmt.Sprintf("Project '%s' not found", projectID))
+	case errors.Is(err, task.ErrInvalidProjectID):
+		return errorResult(fmt.Sprintf("Invalid project ID '%s'. Use lowercase letters, numbers, and dashes.", projectID))
+	case errors.Is(err, task.ErrMemoryNotFound):
+		return errorResult(fmt.Sprintf("Memory '%s' not found in %s", memoryID, memoryScopeName(projectID)))
+	}
+	return errorResult(fmt.Sprintf("Failed to %s memory: %v", action, err))
+}
+
+func dependencyRequest(request mcp.CallToolRequest) service.DependencyRequest {
+	return service.DependencyRequest{
+		ProjectID:          request.GetString("project_id", ""),
+		TaskID:             request.GetString("task_id", ""),
+		BlockedByProjectID: request.GetString("blocked_by_project_id", ""),
+		BlockedByTaskID:    request.GetString("blocked_by_task_id", ""),
+	}
+}
+
+func dependencyError(req service.DependencyRequest, err error) *mcp.CallToolResult {
+	switch {
+	case err == task.ErrProjectNotFound:
+		return errorResult(fmt.Sprintf("Project '%s' not found", req.ProjectID))
+	case err == task.ErrTaskNotFound:
+		return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", req.TaskID, req.ProjectID))
+	case errors.Is(err, task.ErrInvalidDependency), errors.Is(err, task.ErrDependencyCycle):
+		return errorResult(fmt.Sprintf("Invalid dependency: %v", err))
+	default:
+		return errorResult(fmt.Sprintf("Failed to update dependencies: %v", err))
+	}
+}
+
+// claimRequest reads the arguments of claim_task, renew_claim and
+// release_task. The owner defaults to the MCP client name.
+func claimRequest(ctx context.Context, request mcp.CallToolRequest) service.ClaimRequest {
+	owner := request.GetString("owner", "")
+	if owner == "" {
+		owner = actor(ctx, request)
+	}
+	return service.ClaimRequest{
+		ProjectID: request.GetString("project_id", ""),
+		TaskID:    request.GetString("task_id", ""),
+		Owner:     owner,
+		Lease:     time.Duration(request.GetFloat("lease_minutes", 0) * float64(time.Minute)),
+	}
+}
+
+func claimError(req service.ClaimRequest, err error) *mcp.CallToolResult {
+	switch {
+	case err == task.ErrProjectNotFound:
+		return errorResult(fmt.Sprintf("Project '%s' not found", req.ProjectID))
+	case err == task.ErrTaskNotFound:
+		return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", req.TaskID, req.ProjectID))
+	case errors.Is(err, task.ErrTaskClaimed):
+		return errorResult(fmt.Sprintf("Task '%s' is not available: %v. Pick another task or wait for the claim to expire.", req.TaskID, err))
+	case errors.Is(err, task.ErrNotClaimed), errors.Is(err, task.ErrInvalidClaim):
+		return errorResult(err.Error())
+	default:
+		return errorResult(fmt.Sprintf("Failed to update claim: %v", err))
+	}
+}
+
+// linkRequest reads the arguments shared by link_artifact and unlink_artifact.
+func linkRequest(request mcp.CallToolRequest) service.LinkArtifactRequest {
+	return service.LinkArtifactRequest{
+		ProjectID:        request.GetString("project_id", ""),
+		TaskID:           request.GetString("task_id", ""),
+		ArtifactID:       request.GetString("artifact_id", ""),
+		Relation:         task.LinkRelation(request.GetString("relation", "")),
+		TargetProjectID:  request.GetString("target_project_id", ""),
+		TargetTaskID:     request.GetString("target_task_id", ""),
+		TargetArtifactID: request.GetString("target_artifact_id", ""),
+	}
+}
+
+// linkTarget describes the target of a link request for messages.
+func linkTarget(req service.LinkArtifactRequest) string {
+	projectID, taskID := req.TargetProjectID, req.TargetTaskID
+	if projectID == "" {
+		projectID = req.ProjectID
+	}
+	if taskID == "" {
+		taskID = req.TaskID
+	}
+	if req.TargetArtifactID == "" {
+		return fmt.Sprintf("task '%s/%s'", projectID, taskID)
+	}
+	return fmt.Sprintf("artifact '%s/%s/%s'", projectID, taskID, req.TargetArtifactID)
+}
+
+func linkToMap(l task.ArtifactLink) map[string]interface{} {
+	m := map[string]interface{}{
+		"relation":   l.Relation,
+		"project_id": l.Target.ProjectID,
+		"task_id":    l.Target.TaskID,
+	}
+	if !l.Target.IsTask() {
+		m["artifact_id"] = l.Target.ArtifactID
+	}
+	return m
+}
+
+func linkedItemToMap(item *service.LinkedItem) map[string]interface{} {
+	m := linkToMap(task.ArtifactLink{Relation: item.Relation, Target: item.Ref})
+	m["direction"] = "outgoing"
+	if item.Incoming {
+		m["direction"] = "incoming"
+	}
+	switch {
+	case item.Artifact != nil:
+		m["artifact"] = artifactToMap(item.Artifact)
+	case item.Task != nil:
+		m["task"] = taskToMap(item.Task)
+	default:
+		m["missing"] = true
+	}
+	return m
+}
+
+// artifactError maps the not-found errors shared by the artifact version tools.
+func artifactError(err error, projectID, taskID, artifactID, action string) *mcp.CallToolResult {
+	switch {
+	case errors.Is(err, task.ErrProjectNotFound):
+		return errorResult(fmt.Sprintf("Project '%s' not found", projectID))
+	case errors.Is(err, task.ErrTaskNotFound):
+		return errorResult(fmt.Sprintf("Task '%s' not found in project '%s'", taskID, projectID))
+	case errors.Is(err, task.ErrArtifactNotFound):
+		return errorResult(fmt.Sprintf("Artifact '%s' not found in task '%s'", artifactID, taskID))
+	default:
+		return errorResult(fmt.Sprintf("Failed to %s: %v", action, err))
+	}
+}
+
+// versionTime returns when an artifact version was written.
+func versionTime(a *task.Artifact) time.Time {
+	if a.UpdatedAt != nil {
+		return *a.UpdatedAt
+	}
+	return a.CreatedAt
+}
+
+// firstLine returns the first non-empty line of content, shortened to 80 characters.
+func firstLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if r := []rune(line); len(r) > 80 {
+				return string(r[:80]) + "..."
+			}
+			return line
+		}
+	}
+	return ""
 }
 
 func jsonResult(data interface{}) (*mcp.CallToolResult, error) {
@@ -891,6 +2236,25 @@ func jsonResult(data interface{}) (*mcp.CallToolResult, error) {
 	return mcp.NewToolResultText(string(jsonBytes)), nil
 }
 
+// conflictResult is the error result of an update made against a stale
+// revision. It carries the current state, so the agent can merge its change
+// into it and retry with the current revision.
+func conflictResult(message string, conflict *task.ConflictError, current map[string]interface{}) (*mcp.CallToolResult, error) {
+	content := map[string]interface{}{
+		"error":             fmt.Sprintf("%s: %v. Merge your change into 'current' and retry with expected_revision %d.", message, conflict, conflict.Current()),
+		"expected_revision": conflict.Revision,
+		"current_revision":  conflict.Current(),
+		"current":           current,
+	}
+	text, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return errorResult(fmt.Sprintf("Failed to marshal response: %v", err)), nil
+	}
+	result := mcp.NewToolResultStructured(content, string(text))
+	result.IsError = true
+	return result, nil
+}
+
 func errorResult(message string) *mcp.CallToolResult {
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{